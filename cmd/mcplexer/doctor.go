@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/revittco/mcplexer/internal/routing"
+	"github.com/revittco/mcplexer/internal/store/sqlite"
+)
+
+// cmdDoctor lints the stored route rules and fails if any finding is an
+// error.
+func cmdDoctor() error {
+	ctx := context.Background()
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	db, err := sqlite.New(ctx, cfg.DBDSN)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	findings, err := routing.NewEngine(db).Lint(ctx)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		fmt.Println("Route rules: no problems found")
+		return nil
+	}
+
+	fmt.Printf("Route rules: %d finding(s)\n", len(findings))
+	for _, f := range findings {
+		fmt.Printf("\n  %-7s  %-18s  %s (workspace %s)\n", f.Severity, f.Kind, f.RuleID, f.WorkspaceID)
+		fmt.Printf("    %s\n", f.Message)
+	}
+	if n := routing.LintErrors(findings); n > 0 {
+		return fmt.Errorf("%d route rule error(s)", n)
+	}
+	return nil
+}
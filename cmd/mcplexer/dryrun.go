@@ -2,19 +2,41 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
 
+	"github.com/revittco/mcplexer/internal/gitinfo"
 	"github.com/revittco/mcplexer/internal/routing"
 	"github.com/revittco/mcplexer/internal/store/sqlite"
 )
 
 func cmdDryRun(args []string) error {
+	var explain bool
+	var positional []string
+	for _, arg := range args {
+		if arg == "--explain" {
+			explain = true
+			continue
+		}
+		positional = append(positional, arg)
+	}
+	args = positional
 	if len(args) < 2 {
-		return fmt.Errorf("usage: mcplexer dry-run <workspace-id> <tool-name>")
+		return fmt.Errorf("usage: mcplexer dry-run <workspace-id> <tool-name> [arguments-json] [--explain]")
 	}
 	workspaceID := args[0]
 	toolName := args[1]
+	toolArgs := json.RawMessage(`{}`)
+	if len(args) > 2 {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(args[2]), &obj); err != nil {
+			return fmt.Errorf("arguments must be a JSON object: %w", err)
+		}
+		toolArgs = json.RawMessage(args[2])
+	}
 
 	ctx := context.Background()
 	cfg, err := loadConfig()
@@ -36,15 +58,35 @@ func cmdDryRun(args []string) error {
 	fmt.Printf("Dry-run: workspace=%s tool=%s\n", workspaceID, toolName)
 	fmt.Printf("  Workspace: %s (root=%s, default_policy=%s)\n\n", ws.Name, ws.RootPath, ws.DefaultPolicy)
 
+	cwd, _ := os.Getwd()
+	git, err := gitinfo.Resolve(cwd)
+	if err != nil {
+		fmt.Printf("  (git state unavailable: %v)\n", err)
+	} else if git != nil {
+		fmt.Printf("  Git: branch=%s remote=%s detached=%t dirty=%t\n\n",
+			git.Branch, git.Remote.Slug(), git.Detached, git.Dirty)
+	}
+
 	engine := routing.NewEngine(db)
 	rc := routing.RouteContext{
 		WorkspaceID: workspaceID,
 		ToolName:    toolName,
+		Arguments:   toolArgs,
+		Git:         git,
+	}
+	if explain {
+		rc.Trace = &routing.Trace{}
+		defer func() { printTrace(rc.Trace) }()
 	}
 	result, err := engine.Route(ctx, rc)
 	if err != nil {
 		if errors.Is(err, routing.ErrDenied) {
 			fmt.Printf("  DENIED: %v\n", err)
+			var de *routing.DeniedError
+			if errors.As(err, &de) {
+				printConditions(de.Conditions)
+				printSchedule(de.Schedule)
+			}
 		} else if errors.Is(err, routing.ErrNoRoute) {
 			fmt.Printf("  NO ROUTE: no matching rule found for tool %q\n", toolName)
 		} else {
@@ -57,8 +99,29 @@ func cmdDryRun(args []string) error {
 	fmt.Printf("    matched_rule:  %s\n", result.MatchedRuleID)
 	fmt.Printf("    downstream:    %s\n", result.DownstreamServerID)
 	fmt.Printf("    auth_scope:    %s\n", result.AuthScopeID)
+	printConditions(result.MatchedConditions)
+	printSchedule(result.MatchedSchedule)
 	if result.ApprovalMode != "" && result.ApprovalMode != "none" {
 		fmt.Printf("    approval:      %s (timeout=%ds)\n", result.ApprovalMode, result.ApprovalTimeout)
 	}
 	return nil
 }
+
+func printConditions(conds []string) {
+	for _, c := range conds {
+		fmt.Printf("    condition:     %s\n", c)
+	}
+}
+
+func printSchedule(s *routing.Schedule) {
+	if s != nil {
+		fmt.Printf("    schedule:      %s\n", s)
+	}
+}
+
+func printTrace(t *routing.Trace) {
+	fmt.Printf("\n  Trace:\n")
+	for _, line := range strings.Split(strings.TrimRight(t.String(), "\n"), "\n") {
+		fmt.Printf("    %s\n", line)
+	}
+}
@@ -32,6 +32,10 @@ func run() error {
 		return cmdStatus()
 	case "dry-run":
 		return cmdDryRun(args)
+	case "routes":
+		return cmdRoutes(args)
+	case "doctor":
+		return cmdDoctor()
 	case "secret":
 		return cmdSecret(args)
 	case "daemon":
@@ -41,6 +45,6 @@ func run() error {
 	case "control-server":
 		return cmdControlServer()
 	default:
-		return fmt.Errorf("unknown command: %s\nUsage: mcplexer [serve|connect|init|status|dry-run|secret|daemon|setup|control-server]", subcmd)
+		return fmt.Errorf("unknown command: %s\nUsage: mcplexer [serve|connect|init|status|dry-run|routes|doctor|secret|daemon|setup|control-server]", subcmd)
 	}
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+
+	"github.com/revittco/mcplexer/internal/addon"
+	"github.com/revittco/mcplexer/internal/approval"
+	"github.com/revittco/mcplexer/internal/audit"
+	"github.com/revittco/mcplexer/internal/config"
+	"github.com/revittco/mcplexer/internal/gateway"
+	"github.com/revittco/mcplexer/internal/routing"
+	"github.com/revittco/mcplexer/internal/store/sqlite"
+)
+
+// newMCPHTTPHandler builds the Streamable HTTP /mcp endpoint. Each MCP
+// session gets a fresh gateway bound from client-reported roots, as for
+// Unix socket connections.
+func newMCPHTTPHandler(
+	ctx context.Context,
+	s *sqlite.DB,
+	engine *routing.Engine,
+	lister gateway.ToolLister,
+	resources gateway.ResourceLister,
+	prompts gateway.PromptLister,
+	hub *gateway.Broadcaster,
+	auditor *audit.Logger,
+	approvalMgr *approval.Manager,
+	settingsSvc *config.SettingsService,
+	addonReg *addon.Registry,
+	addonExec *addon.Executor,
+) *gateway.HTTPHandler {
+	return gateway.NewHTTPHandler(ctx, func() *gateway.Server {
+		gwOpts := []gateway.ServerOption{
+			gateway.WithApprovals(approvalMgr),
+			gateway.WithSettings(settingsSvc),
+			gateway.WithResources(resources),
+			gateway.WithPrompts(prompts),
+			gateway.WithBroadcaster(hub),
+		}
+		if addonReg != nil {
+			gwOpts = append(gwOpts, gateway.WithAddons(addonReg, addonExec))
+		}
+		return gateway.NewServer(s, engine, lister, auditor, gateway.TransportSocket, gwOpts...)
+	})
+}
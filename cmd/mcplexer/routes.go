@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/revittco/mcplexer/internal/routing"
+	"github.com/revittco/mcplexer/internal/store"
+	"github.com/revittco/mcplexer/internal/store/sqlite"
+)
+
+func cmdRoutes(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: mcplexer routes <simulate> [args...]")
+	}
+	switch args[0] {
+	case "simulate":
+		return routesSimulate(args[1:])
+	default:
+		return fmt.Errorf("unknown routes command: %s\nUsage: mcplexer routes <simulate>", args[0])
+	}
+}
+
+// routesSimulate replays recent audit records through the current rules and
+// a proposed diff read from a JSON file ({"upsert": [...], "delete": [...]}).
+func routesSimulate(args []string) error {
+	const usage = "usage: mcplexer routes simulate <diff.json> [--limit=N] [--workspace=ID]"
+	var diffPath, workspaceID string
+	limit := 500
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--limit="):
+			n, err := strconv.Atoi(arg[8:])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid --limit %q", arg[8:])
+			}
+			limit = n
+		case strings.HasPrefix(arg, "--workspace="):
+			workspaceID = arg[12:]
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("unknown flag %s\n%s", arg, usage)
+		default:
+			diffPath = arg
+		}
+	}
+	if diffPath == "" {
+		return fmt.Errorf("%s", usage)
+	}
+
+	data, err := os.ReadFile(diffPath)
+	if err != nil {
+		return fmt.Errorf("read diff: %w", err)
+	}
+	var diff routing.RuleSetDiff
+	if err := json.Unmarshal(data, &diff); err != nil {
+		return fmt.Errorf("parse diff: %w", err)
+	}
+	if err := diff.Validate(); err != nil {
+		return fmt.Errorf("invalid diff: %w", err)
+	}
+
+	ctx := context.Background()
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	db, err := sqlite.New(ctx, cfg.DBDSN)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	filter := store.AuditFilter{Limit: limit}
+	if workspaceID != "" {
+		filter.WorkspaceID = &workspaceID
+	}
+	records, _, err := db.QueryAuditRecords(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("query audit records: %w", err)
+	}
+
+	report, err := routing.NewEngine(db).Replay(ctx, diff, records)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Replayed %d calls (%d skipped): %d would change\n", report.Replayed, report.Skipped, report.Changed)
+	for _, g := range report.Groups {
+		ws := g.WorkspaceName
+		if ws == "" {
+			ws = g.WorkspaceID
+		}
+		fmt.Printf("\n  %-8s -> %-8s  %4d  %s  (%s)\n", g.Before, g.After, g.Count, g.ToolName, ws)
+		if len(g.BeforeRules) > 0 {
+			fmt.Printf("    was:  %s\n", strings.Join(g.BeforeRules, ", "))
+		}
+		if len(g.AfterRules) > 0 {
+			fmt.Printf("    now:  %s\n", strings.Join(g.AfterRules, ", "))
+		}
+		fmt.Printf("    e.g.: %s\n", strings.Join(g.RecordIDs, ", "))
+	}
+	return nil
+}
@@ -9,9 +9,11 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/revittco/mcplexer/internal/addon"
 	"github.com/revittco/mcplexer/internal/api"
 	"github.com/revittco/mcplexer/internal/approval"
@@ -25,6 +27,7 @@ import (
 	"github.com/revittco/mcplexer/internal/oauth"
 	"github.com/revittco/mcplexer/internal/routing"
 	"github.com/revittco/mcplexer/internal/secrets"
+	"github.com/revittco/mcplexer/internal/store"
 	"github.com/revittco/mcplexer/internal/store/sqlite"
 	"golang.org/x/sync/errgroup"
 )
@@ -125,6 +128,7 @@ func runHTTP(ctx context.Context, cfg *Config, db *sqlite.DB, cfgSvc *config.Ser
 	defer manager.Shutdown(ctx) //nolint:errcheck
 
 	tc := buildToolCache(ctx, db)
+	lister := cache.NewCachingToolLister(manager, tc)
 
 	approvalBus := approval.NewBus()
 	approvalMgr := approval.NewManager(db, approvalBus)
@@ -136,9 +140,13 @@ func runHTTP(ctx context.Context, cfg *Config, db *sqlite.DB, cfgSvc *config.Ser
 		slog.Warn("mcp install manager unavailable", "error", err)
 	}
 
-	addonReg, _ := loadAddons(ctx, cfg, db, authInj)
+	addonReg, addonExec := loadAddons(ctx, cfg, db, authInj)
 
 	auditBus := audit.NewBus()
+	auditor := audit.NewLogger(db, db, auditBus)
+	manager.OnProcessEvent = auditProcessEvents(ctx, auditor)
+	hub := daemonBroadcaster(manager)
+	mcpHandler := newMCPHTTPHandler(ctx, db, engine, lister, manager, manager, hub, auditor, approvalMgr, settingsSvc, addonReg, addonExec)
 	router := api.NewRouter(api.RouterDeps{
 		Store:           db,
 		ConfigSvc:       cfgSvc,
@@ -153,6 +161,7 @@ func runHTTP(ctx context.Context, cfg *Config, db *sqlite.DB, cfgSvc *config.Ser
 		ToolCache:       tc,
 		InstallManager:  installMgr,
 		AddonRegistry:   addonReg,
+		MCPHandler:      mcpHandler,
 	})
 
 	srv := &http.Server{
@@ -204,9 +213,12 @@ func runStdio(ctx context.Context, cfg *Config, db *sqlite.DB, settingsSvc *conf
 	addonReg, addonExec := loadAddons(ctx, cfg, db, authInj)
 
 	auditor := audit.NewLogger(db, db, nil)
+	manager.OnProcessEvent = auditProcessEvents(ctx, auditor)
 	gwOpts := []gateway.ServerOption{
 		gateway.WithApprovals(approvalMgr),
 		gateway.WithSettings(settingsSvc),
+		gateway.WithResources(manager),
+		gateway.WithPrompts(manager),
 	}
 	if addonReg != nil {
 		gwOpts = append(gwOpts, gateway.WithAddons(addonReg, addonExec))
@@ -214,10 +226,58 @@ func runStdio(ctx context.Context, cfg *Config, db *sqlite.DB, settingsSvc *conf
 	gw := gateway.NewServer(db, engine, lister, auditor, gateway.TransportStdio, gwOpts...)
 
 	manager.OnToolsChanged = gw.InvalidateAndNotifyToolsChanged
+	manager.OnPromptsChanged = gw.InvalidateAndNotifyPromptsChanged
+	manager.OnResourcesChanged = gw.NotifyResourcesChanged
+	manager.OnResourceUpdated = gw.NotifyResourceUpdated
 
 	return gw.RunStdio(ctx)
 }
 
+// daemonBroadcaster fans the manager's change notifications out to every
+// socket and /mcp session of the daemon.
+func daemonBroadcaster(manager *downstream.Manager) *gateway.Broadcaster {
+	hub := gateway.NewBroadcaster()
+	manager.OnToolsChanged = hub.InvalidateAndNotifyToolsChanged
+	manager.OnPromptsChanged = hub.InvalidateAndNotifyPromptsChanged
+	manager.OnResourcesChanged = hub.NotifyResourcesChanged
+	manager.OnResourceUpdated = hub.NotifyResourceUpdated
+	return hub
+}
+
+// auditProcessEvents records downstream process crashes, restarts and
+// crash loops in the audit log as "process/<kind>" entries, so they show
+// up alongside tool calls on the dashboard.
+func auditProcessEvents(ctx context.Context, auditor *audit.Logger) func(downstream.ProcessEvent) {
+	return func(ev downstream.ProcessEvent) {
+		detail := map[string]any{"failures": ev.Failures}
+		if ev.Stderr != "" {
+			detail["stderr"] = ev.Stderr
+		}
+		if !ev.RetryAt.IsZero() {
+			detail["retry_at"] = ev.RetryAt
+		}
+		params, _ := json.Marshal(detail)
+
+		rec := &store.AuditRecord{
+			ID:                 uuid.NewString(),
+			Timestamp:          ev.Time,
+			ToolName:           "process/" + ev.Kind,
+			ParamsRedacted:     params,
+			DownstreamServerID: ev.Key.ServerID,
+			AuthScopeID:        ev.Key.AuthScopeID,
+			Status:             "success",
+		}
+		if ev.Kind != downstream.EventRestarted && ev.Error != "" {
+			rec.Status = "error"
+			rec.ErrorCode = strconv.Itoa(gateway.CodeProcessError)
+			rec.ErrorMessage = ev.Error
+		}
+		if err := auditor.Record(ctx, rec); err != nil {
+			slog.Error("audit record failed", "error", err)
+		}
+	}
+}
+
 // buildToolCache loads per-server cache configs from the DB and creates a ToolCache.
 func buildToolCache(ctx context.Context, db *sqlite.DB) *cache.ToolCache {
 	servers, err := db.ListDownstreamServers(ctx)
@@ -358,6 +418,8 @@ func runHTTPAndSocket(ctx context.Context, cfg *Config, db *sqlite.DB, cfgSvc *c
 
 	auditBus := audit.NewBus()
 	auditor := audit.NewLogger(db, db, auditBus)
+	manager.OnProcessEvent = auditProcessEvents(ctx, auditor)
+	hub := daemonBroadcaster(manager)
 	g, ctx := errgroup.WithContext(ctx)
 
 	// HTTP server
@@ -376,6 +438,7 @@ func runHTTPAndSocket(ctx context.Context, cfg *Config, db *sqlite.DB, cfgSvc *c
 			ToolCache:       tc,
 			InstallManager:  installMgr2,
 			AddonRegistry:   addonReg,
+			MCPHandler:      newMCPHTTPHandler(ctx, db, engine, lister, manager, manager, hub, auditor, approvalMgr, settingsSvc, addonReg, addonExec),
 		})
 		srv := &http.Server{Addr: cfg.HTTPAddr, Handler: router}
 		srv.ReadHeaderTimeout = 10 * time.Second
@@ -401,7 +464,7 @@ func runHTTPAndSocket(ctx context.Context, cfg *Config, db *sqlite.DB, cfgSvc *c
 
 	// Unix socket listener
 	g.Go(func() error {
-		return runSocket(ctx, cfg.SocketPath, db, engine, lister, auditor, approvalMgr, settingsSvc, addonReg, addonExec)
+		return runSocket(ctx, cfg.SocketPath, db, engine, lister, manager, manager, hub, auditor, approvalMgr, settingsSvc, addonReg, addonExec)
 	})
 
 	return g.Wait()
@@ -24,6 +24,9 @@ func runSocket(
 	s *sqlite.DB,
 	engine *routing.Engine,
 	lister gateway.ToolLister,
+	resources gateway.ResourceLister,
+	prompts gateway.PromptLister,
+	hub *gateway.Broadcaster,
 	auditor *audit.Logger,
 	approvalMgr *approval.Manager,
 	settingsSvc *config.SettingsService,
@@ -63,7 +66,7 @@ func runSocket(
 			}
 			return fmt.Errorf("accept: %w", err)
 		}
-		go handleSocketConn(ctx, conn, s, engine, lister, auditor, approvalMgr, settingsSvc, addonReg, addonExec)
+		go handleSocketConn(ctx, conn, s, engine, lister, resources, prompts, hub, auditor, approvalMgr, settingsSvc, addonReg, addonExec)
 	}
 }
 
@@ -73,6 +76,9 @@ func handleSocketConn(
 	s *sqlite.DB,
 	engine *routing.Engine,
 	lister gateway.ToolLister,
+	resources gateway.ResourceLister,
+	prompts gateway.PromptLister,
+	hub *gateway.Broadcaster,
 	auditor *audit.Logger,
 	approvalMgr *approval.Manager,
 	settingsSvc *config.SettingsService,
@@ -85,6 +91,9 @@ func handleSocketConn(
 	gwOpts := []gateway.ServerOption{
 		gateway.WithApprovals(approvalMgr),
 		gateway.WithSettings(settingsSvc),
+		gateway.WithResources(resources),
+		gateway.WithPrompts(prompts),
+		gateway.WithBroadcaster(hub),
 	}
 	if addonReg != nil {
 		gwOpts = append(gwOpts, gateway.WithAddons(addonReg, addonExec))
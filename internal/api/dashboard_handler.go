@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/revittco/mcplexer/internal/cache"
@@ -23,11 +24,27 @@ type dashboardHandler struct {
 }
 
 type downstreamStatus struct {
-	ServerID      string `json:"server_id"`
-	ServerName    string `json:"server_name"`
-	InstanceCount int    `json:"instance_count"`
-	State         string `json:"state"`
-	Disabled      bool   `json:"disabled"`
+	ServerID      string           `json:"server_id"`
+	ServerName    string           `json:"server_name"`
+	InstanceCount int              `json:"instance_count"`
+	State         string           `json:"state"`
+	Disabled      bool             `json:"disabled"`
+	Instances     []instanceStatus `json:"instances"`
+
+	// Recent process failures, when any.
+	Failures   int        `json:"failures,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+	StderrTail string     `json:"stderr_tail,omitempty"`
+	RetryAt    *time.Time `json:"retry_at,omitempty"`
+}
+
+// instanceStatus is one member of a server's instance pool.
+type instanceStatus struct {
+	AuthScopeID string `json:"auth_scope_id,omitempty"`
+	Scope       string `json:"scope,omitempty"` // "workspace:<root>" or "session:<id>"
+	Index       int    `json:"index"`
+	State       string `json:"state"`
+	InFlight    int    `json:"in_flight"`
 }
 
 type dashboardResponse struct {
@@ -95,7 +112,7 @@ func (h *dashboardHandler) get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Recent errors + blocked
+	// Recent errors, blocked and rate-limited calls
 	errStatus := "error"
 	errorRecords, _, err := h.auditStore.QueryAuditRecords(ctx, store.AuditFilter{
 		Status: &errStatus,
@@ -116,7 +133,18 @@ func (h *dashboardHandler) get(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to query recent blocked")
 		return
 	}
+	rateLimitedStatus := "rate_limited"
+	rateLimitedRecords, _, err := h.auditStore.QueryAuditRecords(ctx, store.AuditFilter{
+		Status: &rateLimitedStatus,
+		After:  &after,
+		Limit:  rc.errorsLimit,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query recent rate limited")
+		return
+	}
 	errorRecords = append(errorRecords, blockedRecords...)
+	errorRecords = append(errorRecords, rateLimitedRecords...)
 	slices.SortFunc(errorRecords, func(a, b store.AuditRecord) int {
 		return b.Timestamp.Compare(a.Timestamp)
 	})
@@ -257,18 +285,18 @@ func (h *dashboardHandler) buildDownstreamStatus(ctx context.Context) []downstre
 		return []downstreamStatus{}
 	}
 
-	// Build a map of serverID -> aggregated running instances from the manager.
-	type instanceAgg struct {
-		count int
-		state string // "best" state across instances
-	}
-	running := make(map[string]instanceAgg)
+	// Group running instances by server from the manager.
+	running := make(map[string][]downstream.InstanceInfo)
+	failing := make(map[string]downstream.ProcessHealth)
 	if h.manager != nil {
 		for _, info := range h.manager.ListInstances() {
-			agg := running[info.Key.ServerID]
-			agg.count++
-			agg.state = info.State.String()
-			running[info.Key.ServerID] = agg
+			running[info.Key.ServerID] = append(running[info.Key.ServerID], info)
+		}
+		// Keep the worst auth scope per server.
+		for _, ph := range h.manager.Health() {
+			if cur, ok := failing[ph.Key.ServerID]; !ok || ph.Failures > cur.Failures {
+				failing[ph.Key.ServerID] = ph
+			}
 		}
 	}
 
@@ -281,15 +309,81 @@ func (h *dashboardHandler) buildDownstreamStatus(ctx context.Context) []downstre
 		}
 		if srv.Disabled {
 			ds.State = "disabled"
-		} else if agg, ok := running[srv.ID]; ok {
-			ds.InstanceCount = agg.count
-			ds.State = agg.state
-		} else if srv.Transport == "http" {
+		} else if infos, ok := running[srv.ID]; ok {
+			ds.InstanceCount = len(infos)
+			ds.State = poolState(infos).String()
+			ds.Instances = instanceStatuses(infos)
+		} else if ph, ok := failing[srv.ID]; ok && ph.Halted {
+			ds.State = "crashed"
+		} else if ok && ph.Degraded {
+			ds.State = "degraded"
+		} else if downstream.IsRemoteTransport(srv.Transport) {
 			ds.State = "external"
 		} else {
 			ds.State = "stopped"
 		}
+		if ph, ok := failing[srv.ID]; ok && !srv.Disabled {
+			ds.Failures = ph.Failures
+			ds.LastError = ph.LastError
+			ds.StderrTail = ph.Stderr
+			if !ph.RetryAt.IsZero() && ph.RetryAt.After(time.Now()) {
+				retryAt := ph.RetryAt.UTC()
+				ds.RetryAt = &retryAt
+			}
+		}
+		if ds.Instances == nil {
+			ds.Instances = []instanceStatus{}
+		}
 		result = append(result, ds)
 	}
 	return result
 }
+
+// poolState summarizes a server's instances as the most active state
+// among them: busy beats ready/idle, which beat starting/stopping.
+func poolState(infos []downstream.InstanceInfo) downstream.InstanceState {
+	rank := func(s downstream.InstanceState) int {
+		switch s {
+		case downstream.StateBusy:
+			return 3
+		case downstream.StateReady, downstream.StateIdle:
+			return 2
+		case downstream.StateStarting:
+			return 1
+		default:
+			return 0
+		}
+	}
+	best := infos[0].State
+	for _, info := range infos[1:] {
+		if rank(info.State) > rank(best) {
+			best = info.State
+		}
+	}
+	return best
+}
+
+// instanceStatuses converts pool members to their API form, ordered by
+// auth scope, instance scope and pool position.
+func instanceStatuses(infos []downstream.InstanceInfo) []instanceStatus {
+	slices.SortFunc(infos, func(a, b downstream.InstanceInfo) int {
+		if c := strings.Compare(a.Key.AuthScopeID, b.Key.AuthScopeID); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Key.Scope, b.Key.Scope); c != 0 {
+			return c
+		}
+		return a.Index - b.Index
+	})
+	out := make([]instanceStatus, len(infos))
+	for i, info := range infos {
+		out[i] = instanceStatus{
+			AuthScopeID: info.Key.AuthScopeID,
+			Scope:       info.Key.Scope,
+			Index:       info.Index,
+			State:       info.State.String(),
+			InFlight:    info.InFlight,
+		}
+	}
+	return out
+}
@@ -2,10 +2,12 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"time"
 
+	"github.com/revittco/mcplexer/internal/gitinfo"
 	"github.com/revittco/mcplexer/internal/routing"
 	"github.com/revittco/mcplexer/internal/store"
 )
@@ -25,6 +27,18 @@ type dryRunRequest struct {
 	WorkspaceID string `json:"workspace_id"`
 	Subpath     string `json:"subpath"`
 	ToolName    string `json:"tool_name"`
+
+	// Arguments are the simulated call arguments, checked against rule
+	// conditions. Omitted arguments are treated as an empty object.
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+
+	// Git simulates the repository state checked by rule git_match. If
+	// omitted, it is read from ClientRoot when that is set.
+	Git        *gitinfo.Info `json:"git,omitempty"`
+	ClientRoot string        `json:"client_root,omitempty"`
+
+	// At is the time to evaluate rule schedules at; defaults to now.
+	At *time.Time `json:"at,omitempty"`
 }
 
 type dryRunAuthScope struct {
@@ -43,6 +57,21 @@ type dryRunResponse struct {
 	AuthScopeID      string                  `json:"auth_scope_id,omitempty"`
 	AuthScope        *dryRunAuthScope        `json:"auth_scope,omitempty"`
 	CandidateRules   []store.RouteRule       `json:"candidate_rules"`
+
+	// MatchedConditions are the conditions of the matched rule that held
+	// for the given arguments.
+	MatchedConditions []string `json:"matched_conditions,omitempty"`
+
+	// Git is the repository state the rules were matched against.
+	Git *gitinfo.Info `json:"git,omitempty"`
+
+	// EvaluatedAt is the time rule schedules were checked at, and
+	// MatchedSchedule the matched rule's schedule, if it has one.
+	EvaluatedAt     time.Time         `json:"evaluated_at"`
+	MatchedSchedule *routing.Schedule `json:"matched_schedule,omitempty"`
+
+	// Trace lists every rule considered and why each was skipped.
+	Trace *routing.Trace `json:"trace"`
 }
 
 func (h *dryRunHandler) run(w http.ResponseWriter, r *http.Request) {
@@ -57,6 +86,25 @@ func (h *dryRunHandler) run(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	args := req.Arguments
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage(`{}`)
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(args, &obj); err != nil {
+		writeError(w, http.StatusBadRequest, "arguments must be a JSON object")
+		return
+	}
+
+	git := req.Git
+	if git == nil && req.ClientRoot != "" {
+		var err error
+		if git, err = gitinfo.Resolve(req.ClientRoot); err != nil {
+			writeErrorDetail(w, http.StatusBadRequest, "failed to read git state", err.Error())
+			return
+		}
+	}
+
 	ctx := r.Context()
 
 	rules, err := h.routeStore.ListRouteRules(ctx, req.WorkspaceID)
@@ -65,13 +113,22 @@ func (h *dryRunHandler) run(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := dryRunResponse{CandidateRules: rules}
+	now := time.Now()
+	if req.At != nil {
+		now = *req.At
+	}
+	resp := dryRunResponse{CandidateRules: rules, Git: git, EvaluatedAt: now}
 
 	rc := routing.RouteContext{
 		WorkspaceID: req.WorkspaceID,
 		Subpath:     req.Subpath,
 		ToolName:    req.ToolName,
+		Arguments:   args,
+		Git:         git,
+		Now:         now,
+		Trace:       &routing.Trace{},
 	}
+	resp.Trace = rc.Trace
 
 	result, err := h.engine.Route(ctx, rc)
 	switch {
@@ -79,6 +136,8 @@ func (h *dryRunHandler) run(w http.ResponseWriter, r *http.Request) {
 		resp.Matched = true
 		resp.Policy = "allow"
 		resp.AuthScopeID = result.AuthScopeID
+		resp.MatchedConditions = result.MatchedConditions
+		resp.MatchedSchedule = result.MatchedSchedule
 
 		// Find the matched rule in the candidate list.
 		for i := range rules {
@@ -101,6 +160,8 @@ func (h *dryRunHandler) run(w http.ResponseWriter, r *http.Request) {
 
 		var de *routing.DeniedError
 		if errors.As(err, &de) {
+			resp.MatchedConditions = de.Conditions
+			resp.MatchedSchedule = de.Schedule
 			for i := range rules {
 				if rules[i].ID == de.RuleID {
 					resp.MatchedRule = &rules[i]
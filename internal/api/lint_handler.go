@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/revittco/mcplexer/internal/routing"
+)
+
+type lintHandler struct {
+	engine *routing.Engine
+}
+
+func (h *lintHandler) run(w http.ResponseWriter, r *http.Request) {
+	findings, err := h.engine.Lint(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to lint route rules")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"findings": findings,
+		"errors":   routing.LintErrors(findings),
+	})
+}
@@ -34,6 +34,7 @@ type RouterDeps struct {
 	ToolCache       *cache.ToolCache      // optional; enables cache stats/flush API
 	InstallManager  *mcpinstall.Manager   // optional; enables MCP install endpoints
 	AddonRegistry   *addon.Registry       // optional; enables addon tools in discovery
+	MCPHandler      http.Handler          // optional; enables the Streamable HTTP /mcp endpoint
 }
 
 // NewRouter creates an http.Handler with all API routes and SPA fallback.
@@ -62,6 +63,12 @@ func NewRouter(deps RouterDeps) http.Handler {
 	mux.HandleFunc("PUT /api/v1/routes/{id}", rt.update)
 	mux.HandleFunc("DELETE /api/v1/routes/{id}", rt.delete)
 
+	sim := &simulateHandler{engine: deps.Engine, auditStore: deps.Store}
+	mux.HandleFunc("POST /api/v1/routes/simulate", sim.run)
+
+	lint := &lintHandler{engine: deps.Engine}
+	mux.HandleFunc("GET /api/v1/routes/lint", lint.run)
+
 	auth := &authHandler{svc: deps.ConfigSvc, store: deps.Store}
 	mux.HandleFunc("GET /api/v1/auth-scopes", auth.list)
 	mux.HandleFunc("POST /api/v1/auth-scopes", auth.create)
@@ -184,6 +191,10 @@ func NewRouter(deps RouterDeps) http.Handler {
 		mux.HandleFunc("POST /api/v1/auth-scopes/oauth-quick-setup", of.quickSetup)
 	}
 
+	if deps.MCPHandler != nil {
+		mux.Handle("/mcp", deps.MCPHandler)
+	}
+
 	// SPA fallback: serve embedded static files
 	distFS, err := fs.Sub(web.StaticFiles, "dist")
 	if err == nil {
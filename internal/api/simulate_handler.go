@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/revittco/mcplexer/internal/routing"
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+const (
+	defaultSimulateLimit = 500
+	maxSimulateLimit     = 5000
+)
+
+type simulateHandler struct {
+	engine     *routing.Engine
+	auditStore store.AuditStore
+}
+
+type simulateRequest struct {
+	routing.RuleSetDiff
+
+	// Limit is how many of the most recent audit records to replay.
+	Limit int `json:"limit,omitempty"`
+	// WorkspaceID optionally replays only calls recorded in one workspace.
+	WorkspaceID string `json:"workspace_id,omitempty"`
+}
+
+func (h *simulateHandler) run(w http.ResponseWriter, r *http.Request) {
+	var req simulateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeErrorDetail(w, http.StatusBadRequest, "invalid rule diff", err.Error())
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultSimulateLimit
+	}
+	if req.Limit > maxSimulateLimit {
+		writeError(w, http.StatusBadRequest, "limit too large (max 5000)")
+		return
+	}
+
+	filter := store.AuditFilter{Limit: req.Limit}
+	if req.WorkspaceID != "" {
+		filter.WorkspaceID = &req.WorkspaceID
+	}
+	records, _, err := h.auditStore.QueryAuditRecords(r.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query audit records")
+		return
+	}
+
+	report, err := h.engine.Replay(r.Context(), req.RuleSetDiff, records)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to replay audit records")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -2,6 +2,7 @@ package approval
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"sync"
 	"time"
@@ -82,10 +83,12 @@ func (m *Manager) RequestApproval(ctx context.Context, a *store.ToolApproval) (b
 		if _, ok := m.pending[a.ID]; ok {
 			delete(m.pending, a.ID)
 			m.mu.Unlock()
+			reason := cancelReason(ctx)
 			_ = m.store.ResolveToolApproval(
-				context.Background(), a.ID, "cancelled", "", "system", "client disconnected",
+				context.Background(), a.ID, "cancelled", "", "system", reason,
 			)
 			a.Status = "cancelled"
+			a.Resolution = reason
 			if m.bus != nil {
 				m.bus.Publish(ApprovalEvent{Type: "resolved", Approval: a})
 			}
@@ -96,6 +99,21 @@ func (m *Manager) RequestApproval(ctx context.Context, a *store.ToolApproval) (b
 	}
 }
 
+// cancelReason describes why a waiting approval's context ended. Callers
+// that cancel with a cause (e.g. the client sent notifications/cancelled)
+// get that cause recorded; a plain cancellation means the client went away.
+func cancelReason(ctx context.Context) string {
+	cause := context.Cause(ctx)
+	switch {
+	case cause == nil, errors.Is(cause, context.Canceled):
+		return "client disconnected"
+	case errors.Is(cause, context.DeadlineExceeded):
+		return "request timed out"
+	default:
+		return cause.Error()
+	}
+}
+
 // Resolve approves or denies a pending approval. It validates that the
 // approver is not the same session as the requester (self-approval prevention).
 func (m *Manager) Resolve(
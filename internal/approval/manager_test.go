@@ -2,6 +2,7 @@ package approval
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -255,6 +256,44 @@ func TestRequestApproval_ContextCancelled(t *testing.T) {
 	if rec.Status != "cancelled" {
 		t.Errorf("status = %q, want cancelled", rec.Status)
 	}
+	if rec.Resolution != "client disconnected" {
+		t.Errorf("resolution = %q, want client disconnected", rec.Resolution)
+	}
+}
+
+func TestRequestApproval_CancelledWithCause(t *testing.T) {
+	s := newMemStore()
+	mgr := NewManager(s, NewBus())
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	a := &store.ToolApproval{
+		ID:               uuid.NewString(),
+		RequestSessionID: "session-1",
+		ToolName:         "github__create_issue",
+		Justification:    "test",
+		TimeoutSec:       60,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := mgr.RequestApproval(ctx, a)
+		done <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel(errors.New("request cancelled: user pressed escape"))
+	if err := <-done; err == nil {
+		t.Fatal("expected error after cancellation")
+	}
+
+	rec, _ := s.GetToolApproval(context.Background(), a.ID)
+	if rec.Status != "cancelled" {
+		t.Errorf("status = %q, want cancelled", rec.Status)
+	}
+	if rec.Resolution != "request cancelled: user pressed escape" {
+		t.Errorf("resolution = %q", rec.Resolution)
+	}
 }
 
 func TestConcurrentResolve(t *testing.T) {
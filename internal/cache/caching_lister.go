@@ -14,6 +14,13 @@ type ToolLister interface {
 	Call(ctx context.Context, serverID, authScopeID, toolName string, args json.RawMessage) (json.RawMessage, error)
 }
 
+// InstanceScoper is implemented by listers whose servers may run a separate
+// instance per workspace or session. Responses are cached per instance
+// scope so that one workspace or session never sees another's results.
+type InstanceScoper interface {
+	InstanceScope(ctx context.Context, serverID string) string
+}
+
 // CallResult wraps a tool call response with cache metadata.
 type CallResult struct {
 	Data     json.RawMessage
@@ -56,7 +63,7 @@ func (c *CachingToolLister) Call(ctx context.Context, serverID, authScopeID, too
 
 	// Cacheable reads: use GetOrLoad with singleflight.
 	if c.tc.IsCacheable(serverID, toolName) {
-		key := MakeKey(serverID, authScopeID, toolName, args)
+		key := c.makeKey(ctx, serverID, authScopeID, toolName, args)
 		return c.tc.GetOrLoad(key, func() (json.RawMessage, error) {
 			return c.inner.Call(ctx, serverID, authScopeID, toolName, args)
 		})
@@ -81,7 +88,7 @@ func (c *CachingToolLister) CallWithMeta(ctx context.Context, serverID, authScop
 
 	// Cacheable reads: check cache first (unless busting).
 	if c.tc.IsCacheable(serverID, toolName) {
-		key := MakeKey(serverID, authScopeID, toolName, args)
+		key := c.makeKey(ctx, serverID, authScopeID, toolName, args)
 
 		// Check cache directly for hit detection (skip if busting).
 		if !cacheBust {
@@ -104,6 +111,16 @@ func (c *CachingToolLister) CallWithMeta(ctx context.Context, serverID, authScop
 	return CallResult{Data: result, CacheHit: false}, err
 }
 
+// makeKey builds the cache key for a call, including the caller's instance
+// scope when the inner lister has one.
+func (c *CachingToolLister) makeKey(ctx context.Context, serverID, authScopeID, toolName string, args json.RawMessage) ToolCallKey {
+	key := MakeKey(serverID, authScopeID, toolName, args)
+	if s, ok := c.inner.(InstanceScoper); ok {
+		key.Scope = s.InstanceScope(ctx, serverID)
+	}
+	return key
+}
+
 // ToolCache returns the underlying ToolCache for stats/management.
 func (c *CachingToolLister) ToolCache() *ToolCache {
 	return c.tc
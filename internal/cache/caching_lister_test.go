@@ -184,3 +184,43 @@ func TestCachingLister_DisabledServer(t *testing.T) {
 		t.Fatalf("callCount = %d; want 2 (cache disabled)", inner.callCount)
 	}
 }
+
+// scopedLister runs a separate instance per session, named by the context.
+type scopedLister struct {
+	mockLister
+}
+
+type sessionKey struct{}
+
+func (s *scopedLister) InstanceScope(ctx context.Context, _ string) string {
+	id, _ := ctx.Value(sessionKey{}).(string)
+	return "session:" + id
+}
+
+func TestCachingLister_SeparatesInstanceScopes(t *testing.T) {
+	inner := &scopedLister{mockLister{result: json.RawMessage(`{"data":"ok"}`)}}
+	tc := NewToolCache(map[string]ServerCacheConfig{
+		"s1": DefaultServerCacheConfig(),
+	})
+	cl := NewCachingToolLister(inner, tc)
+	args := json.RawMessage(`{"id":"1"}`)
+
+	ctxA := context.WithValue(context.Background(), sessionKey{}, "a")
+	ctxB := context.WithValue(context.Background(), sessionKey{}, "b")
+	for _, ctx := range []context.Context{ctxA, ctxB, ctxA} {
+		if _, err := cl.Call(ctx, "s1", "auth1", "clickup__get_task", args); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.callCount != 2 {
+		t.Fatalf("callCount = %d; want 2 (one per session)", inner.callCount)
+	}
+
+	res, err := cl.CallWithMeta(ctxB, "s1", "auth1", "clickup__get_task", args, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.CacheHit {
+		t.Error("session b's own result not cached")
+	}
+}
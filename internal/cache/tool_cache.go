@@ -12,6 +12,7 @@ import (
 type ToolCallKey struct {
 	ServerID    string
 	AuthScopeID string
+	Scope       string // instance scope of a workspace- or session-scoped server
 	ToolName    string
 	ArgsHash    string
 }
@@ -0,0 +1,541 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/oauth"
+	"github.com/revittco/mcplexer/internal/routing"
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// Apply upserts the entities in cfg into the store in dependency order.
+// Items from YAML are tagged with source="yaml". Stale yaml-sourced rows
+// that no longer appear in the file are deleted automatically. References
+// that cannot be resolved, and names already taken by rows managed
+// elsewhere, are reported as a ValidationError and nothing is applied.
+func Apply(ctx context.Context, s store.Store, cfg *FileConfig) error {
+	return s.Tx(ctx, func(tx store.Store) error {
+		steps := []func(context.Context, store.Store, *FileConfig) ([]string, error){
+			applyOAuthProviders,
+			applyAuthScopes,
+			applyWorkspaces,
+			applyDownstreamServers,
+			applyRouteRules,
+		}
+		for _, step := range steps {
+			errs, err := step(ctx, tx, cfg)
+			if err != nil {
+				return err
+			}
+			if len(errs) > 0 {
+				return &ValidationError{Errors: errs}
+			}
+		}
+		return nil
+	})
+}
+
+// nameIndex resolves references to one entity type by name or id.
+type nameIndex struct {
+	byName map[string]string // name → id
+	byID   map[string]string // id → name
+}
+
+func newNameIndex() *nameIndex {
+	return &nameIndex{byName: map[string]string{}, byID: map[string]string{}}
+}
+
+func (x *nameIndex) add(id, name string) {
+	x.byID[id] = name
+	if name != "" {
+		x.byName[name] = id
+	}
+}
+
+// resolve returns the id for ref, matching names before ids.
+func (x *nameIndex) resolve(ref string) (string, bool) {
+	if id, ok := x.byName[ref]; ok {
+		return id, true
+	}
+	if _, ok := x.byID[ref]; ok {
+		return ref, true
+	}
+	return "", false
+}
+
+// nameConflict reports the id of another row already using name.
+func (x *nameIndex) nameConflict(id, name string) (string, bool) {
+	other, ok := x.byName[name]
+	return other, ok && other != id
+}
+
+func applyOAuthProviders(ctx context.Context, tx store.Store, cfg *FileConfig) ([]string, error) {
+	existing, err := tx.ListOAuthProviders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list oauth providers: %w", err)
+	}
+	current := make(map[string]*store.OAuthProvider, len(existing))
+	names := newNameIndex()
+	for i := range existing {
+		current[existing[i].ID] = &existing[i]
+		names.add(existing[i].ID, existing[i].Name)
+	}
+
+	var errs []string
+	yamlIDs := make(map[string]bool, len(cfg.OAuthProviders))
+	for _, p := range cfg.OAuthProviders {
+		yamlIDs[p.ID] = true
+	}
+	for i, p := range cfg.OAuthProviders {
+		if other, ok := names.nameConflict(p.ID, p.Name); ok && !yamlIDs[other] {
+			errs = append(errs, fmt.Sprintf("%s: name %q is already used by oauth provider %q",
+				itemRef("oauth_providers", i, p.line), p.Name, other))
+			continue
+		}
+
+		op := oauthProviderFromConfig(p)
+		if ep := current[p.ID]; ep != nil {
+			op.CreatedAt = ep.CreatedAt
+			op.EncryptedClientSecret = ep.EncryptedClientSecret
+			if err := tx.UpdateOAuthProvider(ctx, op); err != nil {
+				return nil, fmt.Errorf("update oauth provider %s: %w", p.ID, err)
+			}
+			continue
+		}
+		op.CreatedAt = op.UpdatedAt
+		if err := tx.CreateOAuthProvider(ctx, op); err != nil {
+			return nil, fmt.Errorf("create oauth provider %s: %w", p.ID, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs, nil
+	}
+
+	for _, p := range existing {
+		if p.Source == "yaml" && !yamlIDs[p.ID] {
+			slog.Info("pruning stale yaml oauth provider", "id", p.ID)
+			if err := tx.DeleteOAuthProvider(ctx, p.ID); err != nil {
+				return nil, fmt.Errorf("delete stale oauth provider %s: %w", p.ID, err)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// oauthProviderFromConfig builds the provider row, filling unset fields
+// from the provider template if one is named.
+func oauthProviderFromConfig(p oauthProviderConfig) *store.OAuthProvider {
+	op := &store.OAuthProvider{
+		ID: p.ID, Name: p.Name, TemplateID: p.TemplateID,
+		AuthorizeURL: p.AuthorizeURL, TokenURL: p.TokenURL, ClientID: p.ClientID,
+		Source: "yaml", UpdatedAt: time.Now().UTC(),
+	}
+	scopes := p.Scopes
+	if t := oauth.GetTemplate(p.TemplateID); t != nil {
+		if op.AuthorizeURL == "" {
+			op.AuthorizeURL = t.AuthorizeURL
+		}
+		if op.TokenURL == "" {
+			op.TokenURL = t.TokenURL
+		}
+		if scopes == nil {
+			scopes = t.Scopes
+		}
+		op.UsePKCE = t.UsePKCE
+	}
+	if p.UsePKCE != nil {
+		op.UsePKCE = *p.UsePKCE
+	}
+	if scopes != nil {
+		op.Scopes, _ = json.Marshal(scopes)
+	}
+	return op
+}
+
+func applyAuthScopes(ctx context.Context, tx store.Store, cfg *FileConfig) ([]string, error) {
+	providers, err := tx.ListOAuthProviders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list oauth providers: %w", err)
+	}
+	providerRefs := newNameIndex()
+	for _, p := range providers {
+		providerRefs.add(p.ID, p.Name)
+	}
+
+	existing, err := tx.ListAuthScopes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list auth scopes: %w", err)
+	}
+	current := make(map[string]*store.AuthScope, len(existing))
+	names := newNameIndex()
+	for i := range existing {
+		current[existing[i].ID] = &existing[i]
+		names.add(existing[i].ID, existing[i].Name)
+	}
+
+	var errs []string
+	yamlIDs := make(map[string]bool, len(cfg.AuthScopes))
+	for _, a := range cfg.AuthScopes {
+		yamlIDs[a.ID] = true
+	}
+	for i, a := range cfg.AuthScopes {
+		ref := itemRef("auth_scopes", i, a.line)
+		if other, ok := names.nameConflict(a.ID, a.Name); ok && !yamlIDs[other] {
+			errs = append(errs, fmt.Sprintf("%s: name %q is already used by auth scope %q", ref, a.Name, other))
+			continue
+		}
+		var providerID string
+		if a.OAuthProvider != "" {
+			id, ok := providerRefs.resolve(a.OAuthProvider)
+			if !ok {
+				errs = append(errs, fmt.Sprintf("%s: oauth_provider %q not found", ref, a.OAuthProvider))
+				continue
+			}
+			providerID = id
+		}
+
+		scopeType := a.Type
+		if scopeType == "" {
+			scopeType = "env"
+		}
+		as := &store.AuthScope{
+			ID: a.ID, Name: a.Name, Type: scopeType, OAuthProviderID: providerID,
+			Source: "yaml", UpdatedAt: time.Now().UTC(),
+		}
+		if a.RedactionHints != nil {
+			as.RedactionHints, _ = json.Marshal(a.RedactionHints)
+		}
+
+		// Credentials are never in the file; keep whatever was set in the UI.
+		if ea := current[a.ID]; ea != nil {
+			if err := tx.UpdateAuthScope(ctx, as); err != nil {
+				return nil, fmt.Errorf("update auth scope %s: %w", a.ID, err)
+			}
+			if ea.OAuthProviderID != providerID && len(ea.OAuthTokenData) > 0 {
+				// The token was issued by the previous provider.
+				if err := tx.UpdateAuthScopeTokenData(ctx, a.ID, nil); err != nil {
+					return nil, fmt.Errorf("clear token for auth scope %s: %w", a.ID, err)
+				}
+			}
+			continue
+		}
+		as.CreatedAt = as.UpdatedAt
+		if err := tx.CreateAuthScope(ctx, as); err != nil {
+			return nil, fmt.Errorf("create auth scope %s: %w", a.ID, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs, nil
+	}
+
+	for _, a := range existing {
+		if a.Source == "yaml" && !yamlIDs[a.ID] {
+			slog.Info("pruning stale yaml auth scope", "id", a.ID)
+			if err := tx.DeleteAuthScope(ctx, a.ID); err != nil {
+				return nil, fmt.Errorf("delete stale auth scope %s: %w", a.ID, err)
+			}
+		}
+	}
+	return nil, nil
+}
+
+func applyWorkspaces(ctx context.Context, tx store.Store, cfg *FileConfig) ([]string, error) {
+	existing, err := tx.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces: %w", err)
+	}
+	current := make(map[string]*store.Workspace, len(existing))
+	names := newNameIndex()
+	for i := range existing {
+		current[existing[i].ID] = &existing[i]
+		names.add(existing[i].ID, existing[i].Name)
+	}
+
+	var errs []string
+	yamlIDs := make(map[string]bool, len(cfg.Workspaces))
+	for _, w := range cfg.Workspaces {
+		yamlIDs[w.ID] = true
+	}
+	for i, w := range cfg.Workspaces {
+		if other, ok := names.nameConflict(w.ID, w.Name); ok && !yamlIDs[other] {
+			errs = append(errs, fmt.Sprintf("%s: name %q is already used by workspace %q",
+				itemRef("workspaces", i, w.line), w.Name, other))
+			continue
+		}
+
+		policy := w.DefaultPolicy
+		if policy == "" {
+			policy = "deny"
+		}
+		inherit := w.InheritMode
+		if inherit == "" {
+			inherit = routing.InheritModeInherit
+		}
+		ws := &store.Workspace{
+			ID: w.ID, Name: w.Name, RootPath: w.RootPath, DefaultPolicy: policy,
+			InheritMode: inherit, Source: "yaml", UpdatedAt: time.Now().UTC(),
+		}
+		if w.Tags != nil {
+			ws.Tags, _ = json.Marshal(w.Tags)
+		}
+		if !w.Matchers.IsZero() {
+			ws.Matchers, _ = json.Marshal(w.Matchers)
+		}
+		if ew := current[w.ID]; ew != nil {
+			ws.CreatedAt = ew.CreatedAt
+			if err := tx.UpdateWorkspace(ctx, ws); err != nil {
+				return nil, fmt.Errorf("update workspace %s: %w", w.ID, err)
+			}
+			continue
+		}
+		ws.CreatedAt = ws.UpdatedAt
+		if err := tx.CreateWorkspace(ctx, ws); err != nil {
+			return nil, fmt.Errorf("create workspace %s: %w", w.ID, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs, nil
+	}
+
+	for _, w := range existing {
+		if w.Source == "yaml" && !yamlIDs[w.ID] {
+			slog.Info("pruning stale yaml workspace", "id", w.ID)
+			if err := tx.DeleteWorkspace(ctx, w.ID); err != nil {
+				return nil, fmt.Errorf("delete stale workspace %s: %w", w.ID, err)
+			}
+		}
+	}
+	return nil, nil
+}
+
+func applyDownstreamServers(ctx context.Context, tx store.Store, cfg *FileConfig) ([]string, error) {
+	all, err := tx.ListDownstreamServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list downstreams: %w", err)
+	}
+	names := newNameIndex()
+	for _, d := range all {
+		names.add(d.ID, d.Name)
+	}
+
+	var errs []string
+	yamlIDs := make(map[string]bool, len(cfg.DownstreamServers))
+	for _, d := range cfg.DownstreamServers {
+		yamlIDs[d.ID] = true
+	}
+	for i, d := range cfg.DownstreamServers {
+		if other, ok := names.nameConflict(d.ID, d.Name); ok && !yamlIDs[other] {
+			errs = append(errs, fmt.Sprintf("%s: name %q is already used by downstream server %q",
+				itemRef("downstream_servers", i, d.line), d.Name, other))
+			continue
+		}
+
+		args, _ := json.Marshal(d.Args)
+		var cacheCfg json.RawMessage
+		if len(d.Cache) > 0 {
+			cacheCfg, _ = json.Marshal(d.Cache)
+		}
+		ds := &store.DownstreamServer{
+			ID: d.ID, Name: d.Name, Transport: d.Transport,
+			Command: d.Command, Args: args, Cwd: d.Cwd, ToolNamespace: d.ToolNamespace,
+			InstanceScope: d.InstanceScope, Discovery: d.Discovery, IdleTimeoutSec: d.IdleTimeoutSec,
+			MaxInstances: d.MaxInstances, MaxConcurrency: d.MaxConcurrency,
+			RestartPolicy: d.RestartPolicy, CacheConfig: cacheCfg,
+			Source: "yaml", UpdatedAt: time.Now().UTC(),
+		}
+		if len(d.Env) > 0 {
+			ds.Env, _ = json.Marshal(d.Env)
+		}
+		if len(d.Headers) > 0 {
+			ds.Headers, _ = json.Marshal(d.Headers)
+		}
+		if d.URL != "" {
+			ds.URL = &d.URL
+		}
+		existing, err := tx.GetDownstreamServer(ctx, d.ID)
+		if err != nil {
+			ds.CreatedAt = time.Now().UTC()
+			if err := tx.CreateDownstreamServer(ctx, ds); err != nil {
+				return nil, fmt.Errorf("create downstream %s: %w", d.ID, err)
+			}
+			continue
+		}
+		ds.CreatedAt = existing.CreatedAt
+		ds.CapabilitiesCache = existing.CapabilitiesCache
+		if err := tx.UpdateDownstreamServer(ctx, ds); err != nil {
+			return nil, fmt.Errorf("update downstream %s: %w", d.ID, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs, nil
+	}
+	return nil, pruneStaleDownstreams(ctx, tx, yamlIDs)
+}
+
+func pruneStaleDownstreams(ctx context.Context, tx store.Store, yamlIDs map[string]bool) error {
+	all, err := tx.ListDownstreamServers(ctx)
+	if err != nil {
+		return fmt.Errorf("list downstreams for prune: %w", err)
+	}
+	for _, d := range all {
+		if d.Source == "yaml" && !yamlIDs[d.ID] {
+			slog.Info("pruning stale yaml downstream", "id", d.ID)
+			if err := tx.DeleteDownstreamServer(ctx, d.ID); err != nil {
+				return fmt.Errorf("delete stale downstream %s: %w", d.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// routeRefs indexes the entities route rules may reference.
+type routeRefs struct {
+	workspaces, servers, scopes *nameIndex
+}
+
+func loadRouteRefs(ctx context.Context, tx store.Store) (*routeRefs, error) {
+	refs := &routeRefs{workspaces: newNameIndex(), servers: newNameIndex(), scopes: newNameIndex()}
+
+	workspaces, err := tx.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces: %w", err)
+	}
+	for _, w := range workspaces {
+		refs.workspaces.add(w.ID, w.Name)
+	}
+	servers, err := tx.ListDownstreamServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list downstreams: %w", err)
+	}
+	for _, d := range servers {
+		refs.servers.add(d.ID, d.Name)
+	}
+	scopes, err := tx.ListAuthScopes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list auth scopes: %w", err)
+	}
+	for _, a := range scopes {
+		refs.scopes.add(a.ID, a.Name)
+	}
+	return refs, nil
+}
+
+func applyRouteRules(ctx context.Context, tx store.Store, cfg *FileConfig) ([]string, error) {
+	refs, err := loadRouteRefs(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]*store.RouteRule, 0, len(cfg.RouteRules))
+	var errs []string
+	for i, r := range cfg.RouteRules {
+		rule, problems := routeRuleFromConfig(r, refs)
+		for _, p := range problems {
+			errs = append(errs, itemRef("route_rules", i, r.line)+": "+p)
+		}
+		rules = append(rules, rule)
+	}
+	if len(errs) > 0 {
+		return errs, nil
+	}
+
+	existing, err := tx.ListRouteRules(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("list route rules: %w", err)
+	}
+	current := make(map[string]*store.RouteRule, len(existing))
+	for i := range existing {
+		current[existing[i].ID] = &existing[i]
+	}
+
+	yamlIDs := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		yamlIDs[rule.ID] = true
+		if er := current[rule.ID]; er != nil {
+			rule.CreatedAt = er.CreatedAt
+			if err := tx.UpdateRouteRule(ctx, rule); err != nil {
+				return nil, fmt.Errorf("update route rule %s: %w", rule.ID, err)
+			}
+			continue
+		}
+		rule.CreatedAt = rule.UpdatedAt
+		if err := tx.CreateRouteRule(ctx, rule); err != nil {
+			return nil, fmt.Errorf("create route rule %s: %w", rule.ID, err)
+		}
+	}
+
+	for _, r := range existing {
+		if r.Source == "yaml" && !yamlIDs[r.ID] {
+			slog.Info("pruning stale yaml route rule", "id", r.ID)
+			if err := tx.DeleteRouteRule(ctx, r.ID); err != nil {
+				return nil, fmt.Errorf("delete stale route rule %s: %w", r.ID, err)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// routeRuleFromConfig resolves the rule's references and builds the row,
+// returning a message for each reference that cannot be resolved.
+func routeRuleFromConfig(r routeRuleConfig, refs *routeRefs) (*store.RouteRule, []string) {
+	var problems []string
+	resolve := func(x *nameIndex, field, ref string) string {
+		if ref == "" {
+			return ""
+		}
+		id, ok := x.resolve(ref)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s %q not found", field, ref))
+		}
+		return id
+	}
+
+	rule := &store.RouteRule{
+		ID: r.ID, Name: r.Name, Priority: r.Priority,
+		WorkspaceID:        resolve(refs.workspaces, "workspace", r.Workspace),
+		DownstreamServerID: resolve(refs.servers, "downstream_server", r.DownstreamServer),
+		AuthScopeID:        resolve(refs.scopes, "auth_scope", r.AuthScope),
+		PathGlob:           r.PathGlob,
+		Policy:             r.Policy,
+		LogLevel:           r.LogLevel,
+		ApprovalMode:       r.ApprovalMode,
+		ApprovalTimeout:    r.ApprovalTimeout,
+		Final:              r.Final,
+		Source:             "yaml",
+		UpdatedAt:          time.Now().UTC(),
+	}
+	if rule.PathGlob == "" {
+		rule.PathGlob = "**"
+	}
+	toolMatch := r.ToolMatch
+	if len(toolMatch) == 0 {
+		toolMatch = []string{"*"}
+	}
+	rule.ToolMatch, _ = json.Marshal(toolMatch)
+	if r.AllowedOrgs != nil {
+		rule.AllowedOrgs, _ = json.Marshal(r.AllowedOrgs)
+	}
+	if r.AllowedRepos != nil {
+		rule.AllowedRepos, _ = json.Marshal(r.AllowedRepos)
+	}
+	if r.Conditions != nil {
+		rule.Conditions, _ = json.Marshal(r.Conditions)
+	}
+	if !r.GitMatch.IsZero() {
+		rule.GitMatch, _ = json.Marshal(r.GitMatch)
+	}
+	if r.Schedule != nil {
+		rule.Schedule, _ = json.Marshal(r.Schedule)
+	}
+	if r.RateLimit != nil {
+		rule.RateLimit, _ = json.Marshal(r.RateLimit)
+	}
+	if rule.LogLevel == "" {
+		rule.LogLevel = "info"
+	}
+	return rule, problems
+}
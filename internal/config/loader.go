@@ -1,35 +1,108 @@
 package config
 
 import (
-	"context"
-	"encoding/json"
+	"bytes"
+	"errors"
 	"fmt"
-	"log/slog"
+	"io"
 	"os"
-	"time"
 
-	"github.com/revittco/mcplexer/internal/store"
+	"github.com/revittco/mcplexer/internal/routing"
 	"gopkg.in/yaml.v3"
 )
 
-// FileConfig represents the top-level mcplexer.yaml structure.
+// FileConfig represents the top-level mcplexer.yaml structure. Everything
+// except secret values (auth scope credentials, OAuth client secrets and
+// tokens) can be managed here; secrets are still set through the UI.
 type FileConfig struct {
+	OAuthProviders    []oauthProviderConfig    `yaml:"oauth_providers"`
+	Workspaces        []workspaceConfig        `yaml:"workspaces"`
+	AuthScopes        []authScopeConfig        `yaml:"auth_scopes"`
 	DownstreamServers []downstreamServerConfig `yaml:"downstream_servers"`
+	RouteRules        []routeRuleConfig        `yaml:"route_rules"`
+}
+
+type oauthProviderConfig struct {
+	ID           string   `yaml:"id"`
+	Name         string   `yaml:"name"`
+	TemplateID   string   `yaml:"template_id,omitempty"` // fills in URLs, scopes and PKCE when omitted
+	AuthorizeURL string   `yaml:"authorize_url,omitempty"`
+	TokenURL     string   `yaml:"token_url,omitempty"`
+	ClientID     string   `yaml:"client_id,omitempty"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+	UsePKCE      *bool    `yaml:"use_pkce,omitempty"`
+
+	line int
+}
+
+type workspaceConfig struct {
+	ID            string                     `yaml:"id"`
+	Name          string                     `yaml:"name"`
+	RootPath      string                     `yaml:"root_path,omitempty"`
+	Matchers      *routing.WorkspaceMatchers `yaml:"matchers,omitempty"` // further roots, path globs, git remotes
+	Tags          []string                   `yaml:"tags,omitempty"`
+	DefaultPolicy string                     `yaml:"default_policy,omitempty"` // "deny" (default) or "allow"
+	InheritMode   string                     `yaml:"inherit_mode,omitempty"`   // "inherit" (default), "isolate" or "override"
+
+	line int
+}
+
+type authScopeConfig struct {
+	ID             string   `yaml:"id"`
+	Name           string   `yaml:"name"`
+	Type           string   `yaml:"type,omitempty"`           // "env" (default), "header" or "oauth2"
+	OAuthProvider  string   `yaml:"oauth_provider,omitempty"` // provider name or id, for type oauth2
+	RedactionHints []string `yaml:"redaction_hints,omitempty"`
+
+	line int
 }
 
 type downstreamServerConfig struct {
-	ID             string         `yaml:"id"`
-	Name           string         `yaml:"name"`
-	Transport      string         `yaml:"transport"`
-	Command        string         `yaml:"command"`
-	Args           []string       `yaml:"args,omitempty"`
-	URL            string         `yaml:"url,omitempty"`
-	ToolNamespace  string         `yaml:"tool_namespace"`
-	Discovery      string         `yaml:"discovery,omitempty"` // "dynamic" (default) or "static"
-	IdleTimeoutSec int            `yaml:"idle_timeout_sec"`
-	MaxInstances   int            `yaml:"max_instances"`
-	RestartPolicy  string         `yaml:"restart_policy"`
-	Cache          map[string]any `yaml:"cache,omitempty"` // optional per-server cache config
+	ID             string            `yaml:"id"`
+	Name           string            `yaml:"name"`
+	Transport      string            `yaml:"transport"`
+	Command        string            `yaml:"command"`
+	Args           []string          `yaml:"args,omitempty"`
+	Env            map[string]string `yaml:"env,omitempty"`
+	Cwd            string            `yaml:"cwd,omitempty"`            // may start with ${workspace_root}
+	InstanceScope  string            `yaml:"instance_scope,omitempty"` // "global" (default), "workspace" or "session"
+	URL            string            `yaml:"url,omitempty"`
+	Headers        map[string]string `yaml:"headers,omitempty"` // static HTTP headers
+	ToolNamespace  string            `yaml:"tool_namespace"`
+	Discovery      string            `yaml:"discovery,omitempty"` // "dynamic" (default) or "static"
+	IdleTimeoutSec int               `yaml:"idle_timeout_sec"`
+	MaxInstances   int               `yaml:"max_instances"`
+	MaxConcurrency int               `yaml:"max_concurrency,omitempty"`
+	RestartPolicy  string            `yaml:"restart_policy"`
+	Cache          map[string]any    `yaml:"cache,omitempty"` // optional per-server cache config
+
+	line int
+}
+
+// routeRuleConfig references its workspace, server and auth scope by name
+// (or id), so the file does not depend on database-generated IDs.
+type routeRuleConfig struct {
+	ID               string             `yaml:"id"`
+	Name             string             `yaml:"name,omitempty"`
+	Priority         int                `yaml:"priority"`
+	Workspace        string             `yaml:"workspace,omitempty"`  // name or id; empty only for workspace-less rules like global-deny
+	PathGlob         string             `yaml:"path_glob,omitempty"`  // default "**"
+	ToolMatch        []string           `yaml:"tool_match,omitempty"` // default ["*"]
+	DownstreamServer string             `yaml:"downstream_server,omitempty"`
+	AuthScope        string             `yaml:"auth_scope,omitempty"`
+	AllowedOrgs      []string           `yaml:"allowed_orgs,omitempty"`
+	AllowedRepos     []string           `yaml:"allowed_repos,omitempty"`
+	Conditions       []string           `yaml:"conditions,omitempty"` // all must hold; see routing.Condition
+	GitMatch         *routing.GitMatch  `yaml:"git_match,omitempty"`
+	Schedule         *routing.Schedule  `yaml:"schedule,omitempty"`
+	RateLimit        *routing.RateLimit `yaml:"rate_limit,omitempty"`
+	Policy           string             `yaml:"policy"`
+	LogLevel         string             `yaml:"log_level,omitempty"`
+	ApprovalMode     string             `yaml:"approval_mode,omitempty"`
+	ApprovalTimeout  int                `yaml:"approval_timeout,omitempty"`
+	Final            bool               `yaml:"final,omitempty"` // deny not overridable by descendant workspaces; ignored on allows
+
+	line int
 }
 
 // LoadFile reads, parses, and validates a YAML config file.
@@ -41,77 +114,71 @@ func LoadFile(path string) (*FileConfig, error) {
 	return Parse(data)
 }
 
-// Parse parses and validates YAML config data.
+// Parse parses and validates YAML config data. Unknown keys are rejected
+// so that typos do not silently drop settings.
 func Parse(data []byte) (*FileConfig, error) {
 	var cfg FileConfig
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
 		return nil, fmt.Errorf("parse yaml: %w", err)
 	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err == nil {
+		cfg.recordLines(&doc)
+	}
+
 	if err := validate(&cfg); err != nil {
 		return nil, err
 	}
 	return &cfg, nil
 }
 
-// Apply upserts downstream servers from config into the store.
-// Items from YAML are tagged with source="yaml". Stale yaml-sourced rows
-// that no longer appear in the file are deleted automatically.
-func Apply(ctx context.Context, s store.Store, cfg *FileConfig) error {
-	return s.Tx(ctx, func(tx store.Store) error {
-		return applyDownstreamServers(ctx, tx, cfg.DownstreamServers)
-	})
-}
-
-func applyDownstreamServers(ctx context.Context, tx store.Store, items []downstreamServerConfig) error {
-	yamlIDs := make(map[string]bool, len(items))
-	for _, d := range items {
-		yamlIDs[d.ID] = true
-		args, _ := json.Marshal(d.Args)
-		var cacheCfg json.RawMessage
-		if len(d.Cache) > 0 {
-			cacheCfg, _ = json.Marshal(d.Cache)
-		}
-		ds := &store.DownstreamServer{
-			ID: d.ID, Name: d.Name, Transport: d.Transport,
-			Command: d.Command, Args: args, ToolNamespace: d.ToolNamespace,
-			Discovery: d.Discovery, IdleTimeoutSec: d.IdleTimeoutSec,
-			MaxInstances: d.MaxInstances, RestartPolicy: d.RestartPolicy,
-			CacheConfig: cacheCfg,
-			Source: "yaml", UpdatedAt: time.Now().UTC(),
-		}
-		if d.URL != "" {
-			ds.URL = &d.URL
-		}
-		existing, err := tx.GetDownstreamServer(ctx, d.ID)
-		if err != nil {
-			ds.CreatedAt = time.Now().UTC()
-			if err := tx.CreateDownstreamServer(ctx, ds); err != nil {
-				return fmt.Errorf("create downstream %s: %w", d.ID, err)
-			}
-			continue
+// recordLines copies the line of each list item from the parsed document
+// so validation and apply errors can point at it.
+func (c *FileConfig) recordLines(doc *yaml.Node) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return
+	}
+	lines := make(map[string][]int)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		for _, item := range root.Content[i+1].Content {
+			lines[root.Content[i].Value] = append(lines[root.Content[i].Value], item.Line)
 		}
-		ds.CreatedAt = existing.CreatedAt
-		ds.CapabilitiesCache = existing.CapabilitiesCache
-		if err := tx.UpdateDownstreamServer(ctx, ds); err != nil {
-			return fmt.Errorf("update downstream %s: %w", d.ID, err)
+	}
+	lineAt := func(section string, i int) int {
+		if i < len(lines[section]) {
+			return lines[section][i]
 		}
+		return 0
 	}
-	return pruneStaleDownstreams(ctx, tx, yamlIDs)
-}
 
-func pruneStaleDownstreams(ctx context.Context, tx store.Store, yamlIDs map[string]bool) error {
-	all, err := tx.ListDownstreamServers(ctx)
-	if err != nil {
-		return fmt.Errorf("list downstreams for prune: %w", err)
+	for i := range c.OAuthProviders {
+		c.OAuthProviders[i].line = lineAt("oauth_providers", i)
 	}
-	for _, d := range all {
-		if d.Source == "yaml" && !yamlIDs[d.ID] {
-			slog.Info("pruning stale yaml downstream", "id", d.ID)
-			if err := tx.DeleteDownstreamServer(ctx, d.ID); err != nil {
-				return fmt.Errorf("delete stale downstream %s: %w", d.ID, err)
-			}
-		}
+	for i := range c.Workspaces {
+		c.Workspaces[i].line = lineAt("workspaces", i)
+	}
+	for i := range c.AuthScopes {
+		c.AuthScopes[i].line = lineAt("auth_scopes", i)
+	}
+	for i := range c.DownstreamServers {
+		c.DownstreamServers[i].line = lineAt("downstream_servers", i)
+	}
+	for i := range c.RouteRules {
+		c.RouteRules[i].line = lineAt("route_rules", i)
 	}
-	return nil
 }
 
+// itemRef names a list item in errors, e.g. "route_rules[2] (line 41)".
+func itemRef(section string, i, line int) string {
+	if line > 0 {
+		return fmt.Sprintf("%s[%d] (line %d)", section, i, line)
+	}
+	return fmt.Sprintf("%s[%d]", section, i)
+}
@@ -0,0 +1,315 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/revittco/mcplexer/internal/store"
+	"github.com/revittco/mcplexer/internal/store/sqlite"
+)
+
+const fullConfig = `
+oauth_providers:
+  - id: acme-github
+    name: Acme GitHub
+    template_id: github
+    client_id: Iv1.abc
+workspaces:
+  - id: acme
+    name: Acme
+    root_path: /src/acme
+    tags: [work]
+    inherit_mode: override
+    matchers:
+      git_remotes: [acme/*]
+auth_scopes:
+  - id: acme-gh
+    name: Acme GitHub Token
+    type: oauth2
+    oauth_provider: Acme GitHub
+downstream_servers:
+  - id: gh
+    name: GitHub
+    transport: stdio
+    command: github-mcp
+    env:
+      GITHUB_TOOLSETS: repos
+    cwd: ${workspace_root}
+    instance_scope: workspace
+    headers:
+      X-Team: platform
+    tool_namespace: github
+route_rules:
+  - id: acme-gh-read
+    name: Acme GitHub reads
+    priority: 50
+    workspace: Acme
+    tool_match: ["github__get_*"]
+    conditions: ['owner == "acme"']
+    git_match:
+      remote: acme/*
+    schedule:
+      timezone: UTC
+      windows: ["Mon-Fri 09:00-18:00"]
+    rate_limit:
+      rate: 30
+      daily: 500
+      scope: workspace
+    downstream_server: GitHub
+    auth_scope: Acme GitHub Token
+    policy: allow
+  - id: acme-deny
+    priority: 0
+    workspace: Acme
+    policy: deny
+    final: true
+`
+
+func newTestDB(t *testing.T) store.Store {
+	t.Helper()
+	ctx := context.Background()
+	db, err := sqlite.New(ctx, t.TempDir()+"/test.db")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := SeedDefaultWorkspaces(ctx, db); err != nil {
+		t.Fatalf("seed workspaces: %v", err)
+	}
+	return db
+}
+
+func mustParse(t *testing.T, data string) *FileConfig {
+	t.Helper()
+	cfg, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return cfg
+}
+
+func TestParse_ValidationErrorsPointAtLines(t *testing.T) {
+	_, err := Parse([]byte(`
+workspaces:
+  - id: acme
+    name: Acme
+    inherit_mode: replace
+downstream_servers:
+  - id: fs
+    tool_namespace: fs
+    cwd: data
+    instance_scope: shared
+  - id: git
+    tool_namespace: git
+    args: ["--repo", "${workspace_root}"]
+route_rules:
+  - id: r1
+    workspace: Acme
+    policy: allow
+    conditions: ["branch =="]
+`))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want ValidationError", err)
+	}
+	want := []string{
+		"workspaces[0] (line 3): root_path or matchers is required",
+		`workspaces[0] (line 3): invalid inherit_mode "replace" (must be inherit, isolate or override)`,
+		`downstream_servers[0] (line 7): cwd: "data" is not an absolute path`,
+		`downstream_servers[0] (line 7): invalid instance_scope "shared" (must be global, workspace or session)`,
+		"downstream_servers[1] (line 11): ${workspace_root} requires instance_scope workspace or session",
+		"route_rules[0] (line 15): downstream_server is required for allow rules",
+		`route_rules[0] (line 15): conditions[0]: condition "branch ==": unexpected end of expression`,
+	}
+	if strings.Join(verr.Errors, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors =\n%s\nwant\n%s", strings.Join(verr.Errors, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParse_RejectsUnknownKeys(t *testing.T) {
+	_, err := Parse([]byte("route_rules:\n  - id: r1\n    polcy: deny\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") || !strings.Contains(err.Error(), "polcy") {
+		t.Fatalf("err = %v, want unknown field on line 3", err)
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	if _, err := Parse(nil); err != nil {
+		t.Fatalf("parse empty: %v", err)
+	}
+}
+
+func TestApply_UpsertsAllEntities(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	if err := Apply(ctx, db, mustParse(t, fullConfig)); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	p, err := db.GetOAuthProvider(ctx, "acme-github")
+	if err != nil {
+		t.Fatalf("provider: %v", err)
+	}
+	if p.Source != "yaml" || p.TokenURL == "" || !p.UsePKCE {
+		t.Errorf("provider = %+v, want template defaults", p)
+	}
+
+	scope, err := db.GetAuthScope(ctx, "acme-gh")
+	if err != nil {
+		t.Fatalf("auth scope: %v", err)
+	}
+	if scope.OAuthProviderID != "acme-github" {
+		t.Errorf("auth scope provider = %q, want resolved by name", scope.OAuthProviderID)
+	}
+
+	rule, err := db.GetRouteRule(ctx, "acme-gh-read")
+	if err != nil {
+		t.Fatalf("route rule: %v", err)
+	}
+	if rule.WorkspaceID != "acme" || rule.DownstreamServerID != "gh" || rule.AuthScopeID != "acme-gh" {
+		t.Errorf("rule refs = %q/%q/%q", rule.WorkspaceID, rule.DownstreamServerID, rule.AuthScopeID)
+	}
+	if rule.PathGlob != "**" || rule.Source != "yaml" {
+		t.Errorf("rule = %+v", rule)
+	}
+	deny, err := db.GetRouteRule(ctx, "acme-deny")
+	if err != nil {
+		t.Fatalf("deny rule: %v", err)
+	}
+	if string(deny.ToolMatch) != `["*"]` {
+		t.Errorf("deny tool_match = %s, want default", deny.ToolMatch)
+	}
+	if !deny.Final || rule.Final {
+		t.Errorf("final = %v/%v, want only acme-deny final", deny.Final, rule.Final)
+	}
+	ws, err := db.GetWorkspace(ctx, "acme")
+	if err != nil {
+		t.Fatalf("workspace: %v", err)
+	}
+	if ws.InheritMode != "override" {
+		t.Errorf("inherit_mode = %q, want override", ws.InheritMode)
+	}
+}
+
+func TestApply_PrunesAndKeepsSecrets(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	if err := Apply(ctx, db, mustParse(t, fullConfig)); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if err := db.UpdateAuthScopeEncryptedData(ctx, "acme-gh", []byte("secret")); err != nil {
+		t.Fatalf("set secret: %v", err)
+	}
+
+	// Drop the deny rule and the workspace tag; everything else stays.
+	trimmed := strings.Replace(fullConfig, `  - id: acme-deny
+    priority: 0
+    workspace: Acme
+    policy: deny
+`, "", 1)
+	trimmed = strings.Replace(trimmed, "    tags: [work]\n", "", 1)
+	if err := Apply(ctx, db, mustParse(t, trimmed)); err != nil {
+		t.Fatalf("re-apply: %v", err)
+	}
+
+	if _, err := db.GetRouteRule(ctx, "acme-deny"); err == nil {
+		t.Error("stale yaml route rule was not pruned")
+	}
+	if _, err := db.GetRouteRule(ctx, "acme-gh-read"); err != nil {
+		t.Errorf("kept route rule: %v", err)
+	}
+	scope, err := db.GetAuthScope(ctx, "acme-gh")
+	if err != nil {
+		t.Fatalf("auth scope: %v", err)
+	}
+	if string(scope.EncryptedData) != "secret" {
+		t.Error("auth scope secret was lost on re-apply")
+	}
+	if _, err := db.GetWorkspace(ctx, "global"); err != nil {
+		t.Error("non-yaml workspace was pruned")
+	}
+}
+
+func TestApply_UnresolvedReferences(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	cfg := mustParse(t, `
+route_rules:
+  - id: r1
+    workspace: Nowhere
+    downstream_server: ghost
+    policy: allow
+  - id: r2
+    workspace: global
+    policy: deny
+`)
+	err := Apply(ctx, db, cfg)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want ValidationError", err)
+	}
+	want := []string{
+		`route_rules[0] (line 3): workspace "Nowhere" not found`,
+		`route_rules[0] (line 3): downstream_server "ghost" not found`,
+	}
+	if strings.Join(verr.Errors, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors = %q", verr.Errors)
+	}
+	if _, err := db.GetRouteRule(ctx, "r2"); err == nil {
+		t.Error("valid rule was applied despite errors")
+	}
+}
+
+func TestApply_NameTakenByUIRow(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	cfg := mustParse(t, "workspaces:\n  - id: other\n    name: Global\n    root_path: /x\n")
+	err := Apply(ctx, db, cfg)
+	if err == nil || !strings.Contains(err.Error(), `workspaces[0] (line 2): name "Global" is already used by workspace "global"`) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestExport_RoundTrips(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	if err := Apply(ctx, db, mustParse(t, fullConfig)); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	cfg, err := NewService(db).Export(ctx)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	var rule *routeRuleConfig
+	for i := range cfg.RouteRules {
+		if cfg.RouteRules[i].ID == "acme-gh-read" {
+			rule = &cfg.RouteRules[i]
+		}
+	}
+	if rule == nil || rule.Workspace != "Acme" || rule.DownstreamServer != "GitHub" || rule.AuthScope != "Acme GitHub Token" {
+		t.Fatalf("exported rule = %+v, want references by name", rule)
+	}
+	for _, w := range cfg.Workspaces {
+		if w.ID == "acme" && w.InheritMode != "override" {
+			t.Errorf("exported inherit_mode = %q, want override", w.InheritMode)
+		}
+		if w.ID == "acme" && (w.Matchers == nil || len(w.Matchers.GitRemotes) != 1) {
+			t.Errorf("exported matchers = %+v, want git_remotes", w.Matchers)
+		}
+	}
+	for _, d := range cfg.DownstreamServers {
+		if d.ID == "gh" && (d.Env["GITHUB_TOOLSETS"] != "repos" || d.Cwd != "${workspace_root}" || d.InstanceScope != "workspace" || d.Headers["X-Team"] != "platform") {
+			t.Errorf("exported server = %+v, want env, cwd, instance_scope and headers", d)
+		}
+	}
+	for _, r := range cfg.RouteRules {
+		if r.Final != (r.ID == "acme-deny") {
+			t.Errorf("exported rule %s final = %v", r.ID, r.Final)
+		}
+	}
+	if err := validate(cfg); err != nil {
+		t.Errorf("exported config does not validate: %v", err)
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
+	"github.com/revittco/mcplexer/internal/downstream"
+	"github.com/revittco/mcplexer/internal/routing"
 	"github.com/revittco/mcplexer/internal/store"
 )
 
@@ -28,6 +31,15 @@ func (s *Service) CreateWorkspace(ctx context.Context, w *store.Workspace) error
 	if err := validatePolicy(w.DefaultPolicy); err != nil {
 		return err
 	}
+	if w.InheritMode == "" {
+		w.InheritMode = routing.InheritModeInherit
+	}
+	if err := validateInheritMode(w.InheritMode); err != nil {
+		return err
+	}
+	if _, err := routing.ParseWorkspaceMatchers(w.Matchers); err != nil {
+		return err
+	}
 	now := time.Now().UTC()
 	w.CreatedAt = now
 	w.UpdatedAt = now
@@ -39,13 +51,19 @@ func (s *Service) UpdateWorkspace(ctx context.Context, w *store.Workspace) error
 	if err := validatePolicy(w.DefaultPolicy); err != nil {
 		return err
 	}
+	if err := validateInheritMode(w.InheritMode); err != nil {
+		return err
+	}
+	if _, err := routing.ParseWorkspaceMatchers(w.Matchers); err != nil {
+		return err
+	}
 	w.UpdatedAt = time.Now().UTC()
 	return s.store.UpdateWorkspace(ctx, w)
 }
 
 // CreateDownstreamServer validates and creates a downstream server.
 func (s *Service) CreateDownstreamServer(ctx context.Context, d *store.DownstreamServer) error {
-	if err := validateTransport(d.Transport); err != nil {
+	if err := validateServerSettings(d); err != nil {
 		return err
 	}
 	if err := s.checkNamespaceUnique(ctx, d.ToolNamespace, d.ID); err != nil {
@@ -66,7 +84,7 @@ func (s *Service) CreateDownstreamServer(ctx context.Context, d *store.Downstrea
 
 // UpdateDownstreamServer validates and updates a downstream server.
 func (s *Service) UpdateDownstreamServer(ctx context.Context, d *store.DownstreamServer) error {
-	if err := validateTransport(d.Transport); err != nil {
+	if err := validateServerSettings(d); err != nil {
 		return err
 	}
 	if err := s.checkNamespaceUnique(ctx, d.ToolNamespace, d.ID); err != nil {
@@ -137,24 +155,79 @@ func (s *Service) UpdateAuthScope(ctx context.Context, a *store.AuthScope) error
 	return s.store.UpdateAuthScope(ctx, a)
 }
 
-// Export serializes the current downstream servers to a FileConfig for YAML export.
+// Export serializes the current configuration to a FileConfig for YAML
+// export. Secrets are omitted, and route rules reference other entities by
+// name.
 func (s *Service) Export(ctx context.Context) (*FileConfig, error) {
+	cfg := &FileConfig{}
+
+	providers, err := s.store.ListOAuthProviders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list oauth providers: %w", err)
+	}
+	providerNames := make(map[string]string, len(providers))
+	for _, p := range providers {
+		providerNames[p.ID] = p.Name
+		usePKCE := p.UsePKCE
+		cfg.OAuthProviders = append(cfg.OAuthProviders, oauthProviderConfig{
+			ID: p.ID, Name: p.Name, TemplateID: p.TemplateID,
+			AuthorizeURL: p.AuthorizeURL, TokenURL: p.TokenURL, ClientID: p.ClientID,
+			Scopes: jsonStrings(p.Scopes), UsePKCE: &usePKCE,
+		})
+	}
+
+	workspaces, err := s.store.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces: %w", err)
+	}
+	workspaceNames := make(map[string]string, len(workspaces))
+	for _, w := range workspaces {
+		workspaceNames[w.ID] = w.Name
+		cfg.Workspaces = append(cfg.Workspaces, workspaceConfig{
+			ID: w.ID, Name: w.Name, RootPath: w.RootPath,
+			Tags: jsonStrings(w.Tags), DefaultPolicy: w.DefaultPolicy,
+			InheritMode: inheritModeOrEmpty(w.InheritMode),
+			Matchers:    workspaceMatchersOrNil(w.Matchers),
+		})
+	}
+
+	scopes, err := s.store.ListAuthScopes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list auth scopes: %w", err)
+	}
+	scopeNames := make(map[string]string, len(scopes))
+	for _, a := range scopes {
+		scopeNames[a.ID] = a.Name
+		cfg.AuthScopes = append(cfg.AuthScopes, authScopeConfig{
+			ID: a.ID, Name: a.Name, Type: a.Type,
+			OAuthProvider:  providerNames[a.OAuthProviderID],
+			RedactionHints: jsonStrings(a.RedactionHints),
+		})
+	}
+
 	downstreams, err := s.store.ListDownstreamServers(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("list downstreams: %w", err)
 	}
-
-	cfg := &FileConfig{}
+	serverNames := make(map[string]string, len(downstreams))
 	for _, d := range downstreams {
+		serverNames[d.ID] = d.Name
 		var args []string
 		if len(d.Args) > 0 {
 			_ = json.Unmarshal(d.Args, &args)
 		}
+		env, _ := downstream.ParseEnv(d.Env)
+		headers, _ := downstream.ParseHeaders(d.Headers)
+		scope := d.InstanceScope
+		if scope == downstream.ScopeGlobal {
+			scope = ""
+		}
 		dc := downstreamServerConfig{
 			ID: d.ID, Name: d.Name, Transport: d.Transport,
-			Command: d.Command, Args: args, ToolNamespace: d.ToolNamespace,
+			Command: d.Command, Args: args, Env: env, Cwd: d.Cwd,
+			InstanceScope: scope, Headers: headerMap(headers), ToolNamespace: d.ToolNamespace,
 			IdleTimeoutSec: d.IdleTimeoutSec, MaxInstances: d.MaxInstances,
-			RestartPolicy: d.RestartPolicy,
+			MaxConcurrency: d.MaxConcurrency, RestartPolicy: d.RestartPolicy,
 		}
 		if d.URL != nil {
 			dc.URL = *d.URL
@@ -162,9 +235,102 @@ func (s *Service) Export(ctx context.Context) (*FileConfig, error) {
 		cfg.DownstreamServers = append(cfg.DownstreamServers, dc)
 	}
 
+	rules, err := s.store.ListRouteRules(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("list route rules: %w", err)
+	}
+	for _, r := range rules {
+		cfg.RouteRules = append(cfg.RouteRules, routeRuleConfig{
+			ID: r.ID, Name: r.Name, Priority: r.Priority,
+			Workspace:        nameOr(workspaceNames, r.WorkspaceID),
+			PathGlob:         r.PathGlob,
+			ToolMatch:        jsonStrings(r.ToolMatch),
+			DownstreamServer: nameOr(serverNames, r.DownstreamServerID),
+			AuthScope:        nameOr(scopeNames, r.AuthScopeID),
+			AllowedOrgs:      jsonStrings(r.AllowedOrgs),
+			AllowedRepos:     jsonStrings(r.AllowedRepos),
+			Conditions:       jsonStrings(r.Conditions),
+			GitMatch:         gitMatchOrNil(r.GitMatch),
+			Schedule:         scheduleOrNil(r.Schedule),
+			RateLimit:        rateLimitOrNil(r.RateLimit),
+			Policy:           r.Policy,
+			LogLevel:         r.LogLevel,
+			ApprovalMode:     r.ApprovalMode,
+			ApprovalTimeout:  r.ApprovalTimeout,
+			Final:            r.Final,
+		})
+	}
+
 	return cfg, nil
 }
 
+// jsonStrings decodes a JSON string array column, returning nil if it is
+// empty or malformed.
+func jsonStrings(raw json.RawMessage) []string {
+	var out []string
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &out)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// workspaceMatchersOrNil decodes a matchers column, returning nil if it
+// adds no matchers.
+func workspaceMatchersOrNil(raw json.RawMessage) *routing.WorkspaceMatchers {
+	m, _ := routing.ParseWorkspaceMatchers(raw)
+	return m
+}
+
+// gitMatchOrNil decodes a git_match column, returning nil if it places no
+// constraints.
+func gitMatchOrNil(raw json.RawMessage) *routing.GitMatch {
+	m, _ := routing.ParseGitMatch(raw)
+	return m
+}
+
+// scheduleOrNil decodes a schedule column, returning nil if it is unset.
+func scheduleOrNil(raw json.RawMessage) *routing.Schedule {
+	s, _ := routing.ParseSchedule(raw)
+	return s
+}
+
+// rateLimitOrNil decodes a rate_limit column, returning nil if it is unset.
+func rateLimitOrNil(raw json.RawMessage) *routing.RateLimit {
+	l, _ := routing.ParseRateLimit(raw)
+	return l
+}
+
+// nameOr returns the name for id, or id itself if it has no name.
+// headerMap flattens parsed headers back to the config file's map form.
+func headerMap(h http.Header) map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(h))
+	for k := range h {
+		m[k] = h.Get(k)
+	}
+	return m
+}
+
+func nameOr(names map[string]string, id string) string {
+	if name := names[id]; name != "" {
+		return name
+	}
+	return id
+}
+
+// inheritModeOrEmpty omits the default inheritance mode from exports.
+func inheritModeOrEmpty(m string) string {
+	if m == routing.InheritModeInherit {
+		return ""
+	}
+	return m
+}
+
 func (s *Service) checkNamespaceUnique(ctx context.Context, ns, excludeID string) error {
 	servers, err := s.store.ListDownstreamServers(ctx)
 	if err != nil {
@@ -206,6 +372,18 @@ func (s *Service) validateRouteRefs(ctx context.Context, r *store.RouteRule) err
 	if err := validateStringArray(r.AllowedRepos, "allowed_repos", validateAllowedRepoEntry); err != nil {
 		return err
 	}
+	if err := validateConditions(r.Conditions); err != nil {
+		return err
+	}
+	if err := validateGitMatch(r.GitMatch); err != nil {
+		return err
+	}
+	if err := validateSchedule(r.Schedule); err != nil {
+		return err
+	}
+	if err := validateRateLimit(r.RateLimit); err != nil {
+		return err
+	}
 	return validatePolicy(r.Policy)
 }
 
@@ -41,6 +41,7 @@ func BuiltinToolDefaults() map[string]string {
 		"mcpx__search_tools":           "Search for available tools across all connected MCP servers. Use this to discover tools that aren't listed by default. Returns tool names, descriptions, and input schemas.",
 		"mcpx__load_tools":             "Load tools into the active session by name or glob pattern (e.g. \"github__*\"). Loaded tools appear in tools/list. Use search_tools first to discover available tools.",
 		"mcpx__unload_tools":           "Remove tools from the active session. Accepts tool names or glob patterns.",
+		"mcpx__explain_last_denial":    "Explain why your most recent tool call in this session was denied by policy or matched no route. Lists each workspace tried and, for every route rule considered, whether it matched or why it was skipped (path glob, tool pattern, namespace, git state, schedule or argument conditions).",
 		"mcpx__flush_cache":            "Flush the tool call cache to force fresh data on subsequent calls. Use this when you suspect cached data is stale or after making changes that should be reflected immediately. Optionally specify a server_id to flush only that server's cache. Note: you can also pass `_cache_bust: true` as an argument to any individual tool call to bypass the cache for that specific request without flushing the entire cache.",
 		"mcpx__list_pending_approvals": "List pending tool call approvals waiting for review. Returns approval IDs, tool names, justifications, and requesting agent info. Your own pending requests are excluded.",
 		"mcpx__approve_tool_call":      "Approve a pending tool call request. You cannot approve your own requests.",
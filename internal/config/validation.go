@@ -1,9 +1,15 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strings"
+
+	"github.com/revittco/mcplexer/internal/downstream"
+	"github.com/revittco/mcplexer/internal/oauth"
+	"github.com/revittco/mcplexer/internal/routing"
+	"github.com/revittco/mcplexer/internal/store"
 )
 
 // ValidationError holds all validation failures for a config file.
@@ -15,29 +21,137 @@ func (e *ValidationError) Error() string {
 	return fmt.Sprintf("config validation failed: %s", strings.Join(e.Errors, "; "))
 }
 
-// validate checks the parsed config for correctness.
+// validate checks the parsed config for correctness. References to other
+// entities are resolved later by Apply, since they may name rows that are
+// not in the file (e.g. the default global workspace).
 func validate(cfg *FileConfig) error {
 	var errs []string
+	fail := func(section string, i, line int, format string, args ...any) {
+		errs = append(errs, itemRef(section, i, line)+": "+fmt.Sprintf(format, args...))
+	}
+	unique := func(seen map[string]bool, section string, i, line int, field, v string) {
+		if v == "" {
+			return
+		}
+		if seen[v] {
+			fail(section, i, line, "duplicate %s %q", field, v)
+		}
+		seen[v] = true
+	}
+
+	ids, names := map[string]bool{}, map[string]bool{}
+	for i, p := range cfg.OAuthProviders {
+		const sec = "oauth_providers"
+		requireIDAndName(fail, sec, i, p.line, p.ID, p.Name)
+		unique(ids, sec, i, p.line, "id", p.ID)
+		unique(names, sec, i, p.line, "name", p.Name)
+		if p.TemplateID != "" {
+			if oauth.GetTemplate(p.TemplateID) == nil {
+				fail(sec, i, p.line, "unknown template_id %q", p.TemplateID)
+			}
+		} else if p.AuthorizeURL == "" || p.TokenURL == "" {
+			fail(sec, i, p.line, "authorize_url and token_url are required without template_id")
+		}
+	}
+
+	ids, names = map[string]bool{}, map[string]bool{}
+	for i, w := range cfg.Workspaces {
+		const sec = "workspaces"
+		requireIDAndName(fail, sec, i, w.line, w.ID, w.Name)
+		unique(ids, sec, i, w.line, "id", w.ID)
+		unique(names, sec, i, w.line, "name", w.Name)
+		if w.RootPath == "" && w.Matchers.IsZero() {
+			fail(sec, i, w.line, "root_path or matchers is required")
+		}
+		if err := w.Matchers.Validate(); err != nil {
+			fail(sec, i, w.line, "%v", err)
+		}
+		if err := validatePolicy(w.DefaultPolicy); err != nil {
+			fail(sec, i, w.line, "%v", err)
+		}
+		if err := validateInheritMode(w.InheritMode); err != nil {
+			fail(sec, i, w.line, "%v", err)
+		}
+	}
+
+	ids, names = map[string]bool{}, map[string]bool{}
+	for i, a := range cfg.AuthScopes {
+		const sec = "auth_scopes"
+		requireIDAndName(fail, sec, i, a.line, a.ID, a.Name)
+		unique(ids, sec, i, a.line, "id", a.ID)
+		unique(names, sec, i, a.line, "name", a.Name)
+		if err := validateAuthScopeType(a.Type); err != nil {
+			fail(sec, i, a.line, "%v", err)
+		}
+		if a.Type == "oauth2" && a.OAuthProvider == "" {
+			fail(sec, i, a.line, "oauth_provider is required for type oauth2")
+		}
+		if a.Type != "oauth2" && a.OAuthProvider != "" {
+			fail(sec, i, a.line, "oauth_provider is only valid for type oauth2")
+		}
+	}
 
 	dsIDs := make(map[string]bool, len(cfg.DownstreamServers))
 	nsSet := make(map[string]bool, len(cfg.DownstreamServers))
 	for i, ds := range cfg.DownstreamServers {
+		const sec = "downstream_servers"
 		if ds.ID == "" {
-			errs = append(errs, fmt.Sprintf("downstream_servers[%d]: id is required", i))
-		}
-		if dsIDs[ds.ID] {
-			errs = append(errs, fmt.Sprintf("downstream_servers[%d]: duplicate id %q", i, ds.ID))
+			fail(sec, i, ds.line, "id is required")
 		}
-		dsIDs[ds.ID] = true
+		unique(dsIDs, sec, i, ds.line, "id", ds.ID)
 		if ds.ToolNamespace == "" {
-			errs = append(errs, fmt.Sprintf("downstream_servers[%d]: tool_namespace is required", i))
-		}
-		if nsSet[ds.ToolNamespace] {
-			errs = append(errs, fmt.Sprintf("downstream_servers[%d]: duplicate namespace %q", i, ds.ToolNamespace))
+			fail(sec, i, ds.line, "tool_namespace is required")
 		}
-		nsSet[ds.ToolNamespace] = true
+		unique(nsSet, sec, i, ds.line, "namespace", ds.ToolNamespace)
 		if err := validateTransport(ds.Transport); err != nil {
-			errs = append(errs, fmt.Sprintf("downstream_servers[%d]: %v", i, err))
+			fail(sec, i, ds.line, "%v", err)
+		}
+		for _, err := range []error{
+			downstream.ValidateEnv(ds.Env),
+			downstream.ValidateCwd(ds.Cwd),
+			downstream.ValidateHeaders(ds.Headers),
+			downstream.ValidateInstanceScope(ds.InstanceScope),
+			downstream.ValidateWorkspaceRoot(ds.InstanceScope, ds.Cwd, ds.Args),
+		} {
+			if err != nil {
+				fail(sec, i, ds.line, "%v", err)
+			}
+		}
+	}
+
+	ids = map[string]bool{}
+	for i, r := range cfg.RouteRules {
+		const sec = "route_rules"
+		if r.ID == "" {
+			fail(sec, i, r.line, "id is required")
+		}
+		unique(ids, sec, i, r.line, "id", r.ID)
+		switch r.Policy {
+		case "":
+			fail(sec, i, r.line, "policy is required")
+		case "allow":
+			if r.DownstreamServer == "" {
+				fail(sec, i, r.line, "downstream_server is required for allow rules")
+			}
+		}
+		for _, err := range []error{
+			validatePolicy(r.Policy),
+			validateGlob(r.PathGlob),
+			validateApprovalMode(r.ApprovalMode),
+			validateStrings(r.ToolMatch, "tool_match", nil),
+			validateStrings(r.AllowedOrgs, "allowed_orgs", nil),
+			validateStrings(r.AllowedRepos, "allowed_repos", validateAllowedRepoEntry),
+			validateConditionList(r.Conditions),
+			r.GitMatch.Validate(),
+			r.Schedule.Validate(),
+			r.RateLimit.Validate(),
+		} {
+			if err != nil {
+				fail(sec, i, r.line, "%v", err)
+			}
+		}
+		if r.ApprovalTimeout < 0 {
+			fail(sec, i, r.line, "approval_timeout must not be negative")
 		}
 	}
 
@@ -47,6 +161,75 @@ func validate(cfg *FileConfig) error {
 	return nil
 }
 
+func requireIDAndName(fail func(string, int, int, string, ...any), section string, i, line int, id, name string) {
+	if id == "" {
+		fail(section, i, line, "id is required")
+	}
+	if name == "" {
+		fail(section, i, line, "name is required")
+	}
+}
+
+func validateAuthScopeType(t string) error {
+	switch t {
+	case "env", "header", "oauth2", "":
+		return nil
+	default:
+		return fmt.Errorf("invalid type %q (must be env, header, or oauth2)", t)
+	}
+}
+
+func validateApprovalMode(m string) error {
+	switch m {
+	case "none", "write", "all", "":
+		return nil
+	default:
+		return fmt.Errorf("invalid approval_mode %q (must be none, write, or all)", m)
+	}
+}
+
+// validateStrings is validateStringArray for values decoded from YAML.
+func validateStrings(vals []string, field string, entryValidator func(string) error) error {
+	if vals == nil {
+		return nil
+	}
+	raw, _ := json.Marshal(vals)
+	return validateStringArray(raw, field, entryValidator)
+}
+
+func validateConditionList(conds []string) error {
+	if conds == nil {
+		return nil
+	}
+	raw, _ := json.Marshal(conds)
+	return validateConditions(raw)
+}
+
+// validateConditions ensures conditions is a JSON array of expressions
+// that compile.
+func validateConditions(raw json.RawMessage) error {
+	_, err := routing.ParseConditions(raw)
+	return err
+}
+
+// validateGitMatch ensures git_match is a valid routing.GitMatch object.
+func validateGitMatch(raw json.RawMessage) error {
+	_, err := routing.ParseGitMatch(raw)
+	return err
+}
+
+// validateSchedule ensures schedule is a valid routing.Schedule object.
+func validateSchedule(raw json.RawMessage) error {
+	_, err := routing.ParseSchedule(raw)
+	return err
+}
+
+// validateRateLimit ensures rate_limit is a valid routing.RateLimit object.
+func validateRateLimit(raw json.RawMessage) error {
+	_, err := routing.ParseRateLimit(raw)
+	return err
+}
+
 func validatePolicy(p string) error {
 	switch p {
 	case "allow", "deny", "":
@@ -56,13 +239,47 @@ func validatePolicy(p string) error {
 	}
 }
 
+func validateInheritMode(m string) error {
+	if !routing.ValidInheritMode(m) {
+		return fmt.Errorf("invalid inherit_mode %q (must be inherit, isolate or override)", m)
+	}
+	return nil
+}
+
 func validateTransport(t string) error {
 	switch t {
-	case "stdio", "http", "internal", "":
+	case "stdio", "http", "sse", "websocket", "internal", "":
 		return nil
 	default:
-		return fmt.Errorf("invalid transport %q (must be stdio, http, or internal)", t)
+		return fmt.Errorf("invalid transport %q (must be stdio, http, sse, websocket, or internal)", t)
+	}
+}
+
+// validateServerSettings checks a downstream server's transport, env, cwd,
+// headers and instance scope, and its use of ${workspace_root}.
+func validateServerSettings(d *store.DownstreamServer) error {
+	if err := validateTransport(d.Transport); err != nil {
+		return err
+	}
+	if _, err := downstream.ParseEnv(d.Env); err != nil {
+		return err
+	}
+	if _, err := downstream.ParseHeaders(d.Headers); err != nil {
+		return err
+	}
+	if err := downstream.ValidateInstanceScope(d.InstanceScope); err != nil {
+		return err
+	}
+	if err := downstream.ValidateCwd(d.Cwd); err != nil {
+		return err
+	}
+	var args []string
+	if len(d.Args) > 0 {
+		if err := json.Unmarshal(d.Args, &args); err != nil {
+			return fmt.Errorf("args: %w", err)
+		}
 	}
+	return downstream.ValidateWorkspaceRoot(d.InstanceScope, d.Cwd, args)
 }
 
 func validateGlob(pattern string) error {
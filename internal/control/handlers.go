@@ -26,6 +26,7 @@ var handlers = map[string]handlerFunc{
 	"delete_workspace": handleDeleteWorkspace,
 	// Route
 	"list_routes":  handleListRoutes,
+	"lint_routes":  handleLintRoutes,
 	"create_route": handleCreateRoute,
 	"update_route": handleUpdateRoute,
 	"delete_route": handleDeleteRoute,
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/revittco/mcplexer/internal/routing"
 	"github.com/revittco/mcplexer/internal/store"
 )
 
@@ -29,6 +30,19 @@ func handleListRoutes(
 	return jsonResult(rules)
 }
 
+func handleLintRoutes(
+	ctx context.Context, s store.Store, _ json.RawMessage,
+) (json.RawMessage, error) {
+	findings, err := routing.NewEngine(s).Lint(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lint routes: %w", err)
+	}
+	return jsonResult(map[string]any{
+		"findings": findings,
+		"errors":   routing.LintErrors(findings),
+	})
+}
+
 func handleCreateRoute(
 	ctx context.Context, s store.Store, args json.RawMessage,
 ) (json.RawMessage, error) {
@@ -45,6 +59,18 @@ func handleCreateRoute(
 	if r.Policy == "" {
 		return nil, fmt.Errorf("policy is required")
 	}
+	if _, err := routing.ParseConditions(r.Conditions); err != nil {
+		return nil, err
+	}
+	if _, err := routing.ParseGitMatch(r.GitMatch); err != nil {
+		return nil, err
+	}
+	if _, err := routing.ParseSchedule(r.Schedule); err != nil {
+		return nil, err
+	}
+	if _, err := routing.ParseRateLimit(r.RateLimit); err != nil {
+		return nil, err
+	}
 	if err := s.CreateRouteRule(ctx, &r); err != nil {
 		return nil, fmt.Errorf("create route: %w", err)
 	}
@@ -66,6 +92,18 @@ func handleUpdateRoute(
 		return nil, fmt.Errorf("invalid params: %w", err)
 	}
 	r.ID = id
+	if _, err := routing.ParseConditions(r.Conditions); err != nil {
+		return nil, err
+	}
+	if _, err := routing.ParseGitMatch(r.GitMatch); err != nil {
+		return nil, err
+	}
+	if _, err := routing.ParseSchedule(r.Schedule); err != nil {
+		return nil, err
+	}
+	if _, err := routing.ParseRateLimit(r.RateLimit); err != nil {
+		return nil, err
+	}
 	if err := s.UpdateRouteRule(ctx, r); err != nil {
 		return nil, fmt.Errorf("update route: %w", err)
 	}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/revittco/mcplexer/internal/downstream"
 	"github.com/revittco/mcplexer/internal/store"
 )
 
@@ -45,6 +46,9 @@ func handleCreateServer(
 	if srv.Transport == "" {
 		srv.Transport = "stdio"
 	}
+	if err := validateServerSettings(&srv); err != nil {
+		return nil, err
+	}
 	if err := s.CreateDownstreamServer(ctx, &srv); err != nil {
 		return nil, fmt.Errorf("create server: %w", err)
 	}
@@ -67,12 +71,39 @@ func handleUpdateServer(
 		return nil, fmt.Errorf("invalid params: %w", err)
 	}
 	srv.ID = id // ensure ID is not overwritten
+	if err := validateServerSettings(srv); err != nil {
+		return nil, err
+	}
 	if err := s.UpdateDownstreamServer(ctx, srv); err != nil {
 		return nil, fmt.Errorf("update server: %w", err)
 	}
 	return jsonResult(srv)
 }
 
+// validateServerSettings checks a server's env, cwd, headers and instance
+// scope, and its use of ${workspace_root}.
+func validateServerSettings(srv *store.DownstreamServer) error {
+	if _, err := downstream.ParseEnv(srv.Env); err != nil {
+		return err
+	}
+	if _, err := downstream.ParseHeaders(srv.Headers); err != nil {
+		return err
+	}
+	if err := downstream.ValidateInstanceScope(srv.InstanceScope); err != nil {
+		return err
+	}
+	if err := downstream.ValidateCwd(srv.Cwd); err != nil {
+		return err
+	}
+	var args []string
+	if len(srv.Args) > 0 {
+		if err := json.Unmarshal(srv.Args, &args); err != nil {
+			return fmt.Errorf("args: %w", err)
+		}
+	}
+	return downstream.ValidateWorkspaceRoot(srv.InstanceScope, srv.Cwd, args)
+}
+
 func handleDeleteServer(
 	ctx context.Context, s store.Store, args json.RawMessage,
 ) (json.RawMessage, error) {
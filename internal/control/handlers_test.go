@@ -267,6 +267,46 @@ func TestHandleCreateAndListRoutes(t *testing.T) {
 	}
 }
 
+func TestHandleLintRoutes(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	ws := seedWorkspace(t, db)
+	srv := seedServer(t, db)
+	for _, r := range []store.RouteRule{
+		{Name: "first", Priority: 10, WorkspaceID: ws.ID, PathGlob: "**", ToolMatch: json.RawMessage(`["test__*"]`), DownstreamServerID: srv.ID, Policy: "allow"},
+		{Name: "second", WorkspaceID: ws.ID, PathGlob: "**", ToolMatch: json.RawMessage(`["test__*"]`), DownstreamServerID: srv.ID, Policy: "allow"},
+		{Name: "dangling", WorkspaceID: ws.ID, PathGlob: "docs/**", DownstreamServerID: "missing", Policy: "allow"},
+	} {
+		if err := db.CreateRouteRule(ctx, &r); err != nil {
+			t.Fatalf("create route %s: %v", r.Name, err)
+		}
+	}
+
+	result, err := handleLintRoutes(ctx, db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := parseToolResult(t, result)
+
+	var report struct {
+		Findings []struct {
+			Kind string `json:"kind"`
+		} `json:"findings"`
+		Errors int `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(text), &report); err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]int)
+	for _, f := range report.Findings {
+		kinds[f.Kind]++
+	}
+	if kinds["shadowed"] != 1 || kinds["missing_server"] != 1 || report.Errors != 1 {
+		t.Errorf("findings = %+v, errors = %d", report.Findings, report.Errors)
+	}
+}
+
 func TestHandleCreateAuthScope(t *testing.T) {
 	db := newTestDB(t)
 	ctx := context.Background()
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/revittco/mcplexer/internal/routing"
 	"github.com/revittco/mcplexer/internal/store"
 )
 
@@ -45,6 +46,12 @@ func handleCreateWorkspace(
 	if ws.DefaultPolicy == "" {
 		ws.DefaultPolicy = "deny"
 	}
+	if !routing.ValidInheritMode(ws.InheritMode) {
+		return nil, fmt.Errorf("invalid inherit_mode %q", ws.InheritMode)
+	}
+	if _, err := routing.ParseWorkspaceMatchers(ws.Matchers); err != nil {
+		return nil, err
+	}
 	if err := s.CreateWorkspace(ctx, &ws); err != nil {
 		return nil, fmt.Errorf("create workspace: %w", err)
 	}
@@ -66,6 +73,12 @@ func handleUpdateWorkspace(
 		return nil, fmt.Errorf("invalid params: %w", err)
 	}
 	ws.ID = id
+	if !routing.ValidInheritMode(ws.InheritMode) {
+		return nil, fmt.Errorf("invalid inherit_mode %q", ws.InheritMode)
+	}
+	if _, err := routing.ParseWorkspaceMatchers(ws.Matchers); err != nil {
+		return nil, err
+	}
 	if err := s.UpdateWorkspace(ctx, ws); err != nil {
 		return nil, fmt.Errorf("update workspace: %w", err)
 	}
@@ -24,13 +24,18 @@ func allTools() []gateway.Tool {
 			Description: "Create a new downstream MCP server",
 			InputSchema: schema(props{
 				"name":             propStr("Unique server name"),
-				"transport":        propStr("Transport type: stdio"),
+				"transport":        propStr("Transport type: stdio, http, sse or websocket"),
 				"command":          propStr("Command to run"),
 				"args":             propArr("Command arguments"),
+				"env":              propObj("Environment variables for stdio servers, merged under auth-injected ones"),
+				"cwd":              propStr("Working directory for stdio servers; may start with ${workspace_root}"),
+				"headers":          propObj("Static HTTP headers for http servers, overridden by auth headers"),
+				"instance_scope":   propStr("Instance scope: global (default), workspace or session"),
 				"tool_namespace":   propStr("Tool namespace prefix"),
 				"discovery":        propStr("Discovery mode: static or dynamic"),
 				"idle_timeout_sec": propInt("Idle timeout in seconds"),
 				"max_instances":    propInt("Maximum concurrent instances"),
+				"max_concurrency":  propInt("Maximum in-flight requests per instance (0 = default)"),
 				"restart_policy":   propStr("Restart policy: never, on-failure, always"),
 			}, []string{"name", "command", "tool_namespace"}),
 		},
@@ -43,10 +48,15 @@ func allTools() []gateway.Tool {
 				"transport":        propStr("Transport type"),
 				"command":          propStr("Command to run"),
 				"args":             propArr("Command arguments"),
+				"env":              propObj("Environment variables for stdio servers, merged under auth-injected ones"),
+				"cwd":              propStr("Working directory for stdio servers; may start with ${workspace_root}"),
+				"headers":          propObj("Static HTTP headers for http servers, overridden by auth headers"),
+				"instance_scope":   propStr("Instance scope: global (default), workspace or session"),
 				"tool_namespace":   propStr("Tool namespace prefix"),
 				"discovery":        propStr("Discovery mode"),
 				"idle_timeout_sec": propInt("Idle timeout in seconds"),
 				"max_instances":    propInt("Maximum concurrent instances"),
+				"max_concurrency":  propInt("Maximum in-flight requests per instance (0 = default)"),
 				"restart_policy":   propStr("Restart policy"),
 			}, []string{"id"}),
 		},
@@ -73,7 +83,9 @@ func allTools() []gateway.Tool {
 			InputSchema: schema(props{
 				"name":           propStr("Unique workspace name"),
 				"root_path":      propStr("Root file path for the workspace"),
+				"matchers":       propObj("Further matches: {roots: [paths], path_globs: [\"/tmp/**/repo\"], git_remotes: [\"owner/repo\"]}"),
 				"default_policy": propStr("Default routing policy: allow or deny"),
+				"inherit_mode":   propStr("Fallback to parent workspaces' rules: inherit (default), isolate or override"),
 				"tags":           propArr("Workspace tags"),
 			}, []string{"name"}),
 		},
@@ -84,7 +96,9 @@ func allTools() []gateway.Tool {
 				"id":             propStr("Workspace ID"),
 				"name":           propStr("Unique workspace name"),
 				"root_path":      propStr("Root file path"),
+				"matchers":       propObj("Further matches: {roots, path_globs, git_remotes}"),
 				"default_policy": propStr("Default routing policy"),
+				"inherit_mode":   propStr("Fallback to parent workspaces' rules: inherit, isolate or override"),
 				"tags":           propArr("Workspace tags"),
 			}, []string{"id"}),
 		},
@@ -102,6 +116,11 @@ func allTools() []gateway.Tool {
 				"workspace_id": propStr("Workspace ID"),
 			}, []string{"workspace_id"}),
 		},
+		{
+			Name:        "lint_routes",
+			Description: "Check all route rules for shadowed rules, overlapping allow/deny pairs, missing or disabled servers and auth scopes, and namespace mismatches",
+			InputSchema: schema(nil, nil),
+		},
 		{
 			Name:        "create_route",
 			Description: "Create a new route rule",
@@ -112,10 +131,15 @@ func allTools() []gateway.Tool {
 				"tool_match":           propObj("Tool match criteria"),
 				"allowed_orgs":         propArr("Allowed GitHub orgs"),
 				"allowed_repos":        propArr("Allowed GitHub repos (owner/repo)"),
+				"conditions":           propArr("Argument conditions that must all hold, e.g. branch != \"main\""),
+				"git_match":            propObj("Git state to require: branch glob, remote owner/repo glob, detached, dirty"),
+				"schedule":             propObj("Time windows: {timezone, windows: [\"Mon-Fri 09:00-17:00\"], outside}"),
+				"rate_limit":           propObj("Call limits: {rate, per: \"1m\", burst, daily, scope: session|workspace|global}"),
 				"downstream_server_id": propStr("Downstream server ID"),
 				"auth_scope_id":        propStr("Auth scope ID"),
 				"policy":               propStr("Policy: allow or deny"),
 				"log_level":            propStr("Log level override"),
+				"final":                propBool("Evaluate before, and never be overridden by, rules of child workspaces"),
 			}, []string{"workspace_id", "downstream_server_id", "policy"}),
 		},
 		{
@@ -128,10 +152,15 @@ func allTools() []gateway.Tool {
 				"tool_match":           propObj("Tool match criteria"),
 				"allowed_orgs":         propArr("Allowed GitHub orgs"),
 				"allowed_repos":        propArr("Allowed GitHub repos (owner/repo)"),
+				"conditions":           propArr("Argument conditions that must all hold, e.g. branch != \"main\""),
+				"git_match":            propObj("Git state to require: branch glob, remote owner/repo glob, detached, dirty"),
+				"schedule":             propObj("Time windows: {timezone, windows: [\"Mon-Fri 09:00-17:00\"], outside}"),
+				"rate_limit":           propObj("Call limits: {rate, per: \"1m\", burst, daily, scope: session|workspace|global}"),
 				"downstream_server_id": propStr("Downstream server ID"),
 				"auth_scope_id":        propStr("Auth scope ID"),
 				"policy":               propStr("Policy"),
 				"log_level":            propStr("Log level override"),
+				"final":                propBool("Evaluate before, and never be overridden by, rules of child workspaces"),
 			}, []string{"id"}),
 		},
 		{
@@ -224,6 +253,10 @@ func propInt(desc string) map[string]string {
 	return map[string]string{"type": "integer", "description": desc}
 }
 
+func propBool(desc string) map[string]string {
+	return map[string]string{"type": "boolean", "description": desc}
+}
+
 func propArr(desc string) map[string]any {
 	return map[string]any{
 		"type":        "array",
@@ -0,0 +1,110 @@
+package downstream
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// callTracker holds the lifecycle state every transport shares: it counts
+// calls in flight, moves the instance between busy and idle, and stops it
+// once it has sat idle for idleTimeout. mu also guards the embedding
+// instance's own fields.
+type callTracker struct {
+	serverID string // for logs
+	onIdle   func() // stops the instance when the idle timer fires
+
+	mu          sync.Mutex
+	state       InstanceState
+	inFlight    int // calls awaiting a response
+	holds       int // reasons to stay up while idle, see hold
+	idleTimeout time.Duration
+	idleTimer   *time.Timer
+}
+
+func newCallTracker(serverID string, idleTimeout time.Duration, onIdle func()) callTracker {
+	return callTracker{
+		serverID:    serverID,
+		onIdle:      onIdle,
+		state:       StateStopped,
+		idleTimeout: idleTimeout,
+	}
+}
+
+func (t *callTracker) getState() InstanceState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state
+}
+
+func (t *callTracker) load() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.inFlight
+}
+
+// beginCall marks the instance busy and holds off the idle timer.
+func (t *callTracker) beginCall() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inFlight++
+	t.stopIdleTimer()
+	if t.state == StateReady || t.state == StateIdle {
+		t.state = StateBusy
+	}
+}
+
+// endCall marks the instance idle once no calls remain in flight.
+func (t *callTracker) endCall() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inFlight--
+	if t.inFlight > 0 || t.state != StateBusy {
+		return
+	}
+	t.state = StateIdle
+	if t.holds == 0 {
+		t.resetIdleTimer()
+	}
+}
+
+// hold keeps the instance from idling out until a matching release. The
+// server keeps state for us, such as resource subscriptions, that an idle
+// stop would silently drop.
+func (t *callTracker) hold() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.holds++
+	t.stopIdleTimer()
+}
+
+// release undoes a hold, starting the idle countdown if nothing else keeps
+// the instance busy.
+func (t *callTracker) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.holds--
+	if t.holds == 0 && t.inFlight == 0 && t.state == StateIdle {
+		t.resetIdleTimer()
+	}
+}
+
+// resetIdleTimer restarts the idle countdown. Callers hold mu.
+func (t *callTracker) resetIdleTimer() {
+	if t.idleTimeout <= 0 || t.onIdle == nil {
+		return
+	}
+	t.stopIdleTimer()
+	t.idleTimer = time.AfterFunc(t.idleTimeout, func() {
+		slog.Info("idle timeout, stopping instance",
+			"server", t.serverID)
+		t.onIdle()
+	})
+}
+
+// stopIdleTimer cancels a pending idle stop. Callers hold mu.
+func (t *callTracker) stopIdleTimer() {
+	if t.idleTimer != nil {
+		t.idleTimer.Stop()
+	}
+}
@@ -0,0 +1,30 @@
+package downstream
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallTracker_HoldSuppressesIdleStop(t *testing.T) {
+	var stops atomic.Int32
+	tr := newCallTracker("test-server", 20*time.Millisecond, func() { stops.Add(1) })
+	tr.state = StateReady
+
+	tr.hold()
+	tr.beginCall()
+	tr.endCall()
+	time.Sleep(60 * time.Millisecond)
+	if n := stops.Load(); n != 0 {
+		t.Fatalf("stopped %d times while held, want 0", n)
+	}
+
+	tr.release()
+	deadline := time.Now().Add(time.Second)
+	for stops.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := stops.Load(); n != 1 {
+		t.Errorf("stopped %d times after release, want 1", n)
+	}
+}
@@ -1,6 +1,7 @@
 package downstream
 
 import (
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -45,6 +46,9 @@ var commonPaths = func() []string {
 // MergeEnv merges environment variables with priority:
 // authEnv > serverEnv > osEnv.
 // Later maps override earlier ones for the same key.
+// ${VAR} references in serverEnv and authEnv expand against the layers
+// below only, never against entries of the same map, so the result does
+// not depend on map order.
 // PATH is automatically augmented with common binary directories.
 func MergeEnv(osEnv []string, serverEnv, authEnv map[string]string) []string {
 	merged := make(map[string]string, len(osEnv))
@@ -60,14 +64,13 @@ func MergeEnv(osEnv []string, serverEnv, authEnv map[string]string) []string {
 	// Augment PATH with common binary directories.
 	merged["PATH"] = augmentPath(merged["PATH"])
 
-	// Apply server config env (lower priority).
-	for k, v := range serverEnv {
-		merged[k] = expandVars(v, merged)
-	}
-
-	// Apply auth env (highest priority).
-	for k, v := range authEnv {
-		merged[k] = expandVars(v, merged)
+	// Apply server config env (lower priority), then auth env (highest
+	// priority), each expanded against what lies below it.
+	for _, layer := range []map[string]string{serverEnv, authEnv} {
+		below := maps.Clone(merged)
+		for k, v := range layer {
+			merged[k] = expandVars(v, below)
+		}
 	}
 
 	out := make([]string, 0, len(merged))
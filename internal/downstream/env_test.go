@@ -28,6 +28,23 @@ func TestMergeEnvPriority(t *testing.T) {
 	}
 }
 
+func TestMergeEnvExpandsAgainstLowerLayers(t *testing.T) {
+	os := []string{"DATA=/os"}
+	srv := map[string]string{"DATA": "/srv", "CACHE": "${DATA}/cache", "LOGS": "${DATA}/logs"}
+	auth := map[string]string{"TOKEN_FILE": "${DATA}/token", "DATA": "/auth"}
+
+	// Map order varies between runs, so repeat to catch order dependence.
+	for range 20 {
+		m := envToMap(MergeEnv(os, srv, auth))
+		if m["CACHE"] != "/os/cache" || m["LOGS"] != "/os/logs" {
+			t.Fatalf("CACHE = %q, LOGS = %q, want expanded against the os env", m["CACHE"], m["LOGS"])
+		}
+		if m["TOKEN_FILE"] != "/srv/token" || m["DATA"] != "/auth" {
+			t.Fatalf("TOKEN_FILE = %q, DATA = %q, want expanded against the server env", m["TOKEN_FILE"], m["DATA"])
+		}
+	}
+}
+
 func TestMergeEnvAugmentsPath(t *testing.T) {
 	env := MergeEnv([]string{"PATH=/usr/bin:/bin"}, nil, nil)
 	m := envToMap(env)
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"sync"
@@ -19,35 +20,53 @@ import (
 var ErrAuthRequired = errors.New("downstream server requires authentication")
 
 // HTTPInstance communicates with a remote MCP server over Streamable HTTP
-// (JSON-RPC over HTTP POST). Each request is a separate HTTP POST.
+// (JSON-RPC over HTTP POST). Each request is a separate HTTP POST. When
+// notifications are wanted, a standalone GET event stream is kept open for
+// messages the server sends outside any request.
 type HTTPInstance struct {
+	callTracker
+
 	key    InstanceKey
 	url    string
 	client *http.Client
+	stream *http.Client // the standalone GET stream, which has no deadline
+
+	onNotify func(method string, params json.RawMessage) // called when downstream sends a notification
+
+	initMu sync.Mutex // serializes starting a new session
 
-	mu          sync.Mutex
-	state       InstanceState
+	headers     http.Header // static headers from the server config
 	authHeaders http.Header
 	sessionID   string // Mcp-Session-Id from server
 
-	idleTimeout time.Duration
-	idleTimer   *time.Timer
-	reqID       atomic.Int64
+	// lastEventID is the ID of the last event read on the GET stream of
+	// session lastEventSession, sent as Last-Event-ID to resume it.
+	lastEventID      string
+	lastEventSession string
+
+	cancel     context.CancelFunc // stops the GET stream
+	listenDone chan struct{}      // closed when the GET stream goroutine exits
+
+	reqID atomic.Int64
 
 	sessionURL string // may be updated by server via Location header
 }
 
-func newHTTPInstance(key InstanceKey, url string, idleTimeout time.Duration, headers http.Header) *HTTPInstance {
-	return &HTTPInstance{
+// newHTTPInstance creates a stopped instance. Auth headers override static
+// headers of the same name.
+func newHTTPInstance(key InstanceKey, url string, idleTimeout time.Duration, static, auth http.Header) *HTTPInstance {
+	h := &HTTPInstance{
 		key:         key,
 		url:         url,
-		state:       StateStopped,
-		authHeaders: headers,
+		headers:     static,
+		authHeaders: auth,
 		client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
-		idleTimeout: idleTimeout,
+		stream: &http.Client{},
 	}
+	h.callTracker = newCallTracker(key.ServerID, idleTimeout, h.stop)
+	return h
 }
 
 // SetAuthHeaders updates the authorization headers injected on every request.
@@ -57,12 +76,6 @@ func (h *HTTPInstance) SetAuthHeaders(headers http.Header) {
 	h.authHeaders = headers
 }
 
-func (h *HTTPInstance) getState() InstanceState {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	return h.state
-}
-
 func (h *HTTPInstance) start(ctx context.Context) error {
 	h.mu.Lock()
 	if h.state != StateStopped {
@@ -74,25 +87,41 @@ func (h *HTTPInstance) start(ctx context.Context) error {
 	h.mu.Unlock()
 
 	// Perform MCP initialize handshake over HTTP (mutex released so doRPC can read authHeaders).
+	if err := h.initialize(ctx); err != nil {
+		h.mu.Lock()
+		h.state = StateStopped
+		h.mu.Unlock()
+		return fmt.Errorf("initialize: %w", err)
+	}
+
+	h.mu.Lock()
+	h.state = StateReady
+	if h.onNotify != nil {
+		// The stream outlives the call that started it; stop cancels it.
+		listenCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		h.cancel = cancel
+		h.listenDone = make(chan struct{})
+		go h.listen(listenCtx, h.listenDone)
+	}
+	h.mu.Unlock()
+	return nil
+}
+
+// initialize performs the MCP initialize handshake, starting a new session.
+func (h *HTTPInstance) initialize(ctx context.Context) error {
 	initReq := jsonRPCRequest{
 		JSONRPC: "2.0",
 		ID:      json.RawMessage(`1`),
 		Method:  "initialize",
 		Params: json.RawMessage(`{
 			"protocolVersion": "2025-03-26",
-			"capabilities": {},
+			"capabilities": {"sampling": {}, "elicitation": {}},
 			"clientInfo": {"name": "mcplexer", "version": "0.1.0"}
 		}`),
 	}
-
-	resp, err := h.doRPC(ctx, initReq)
-	if err != nil {
-		h.mu.Lock()
-		h.state = StateStopped
-		h.mu.Unlock()
-		return fmt.Errorf("initialize: %w", err)
+	if _, err := h.doRPC(ctx, initReq); err != nil {
+		return err
 	}
-	_ = resp // initialize response not needed
 
 	// Send initialized notification (no ID = notification).
 	notif := jsonRPCRequest{
@@ -101,35 +130,51 @@ func (h *HTTPInstance) start(ctx context.Context) error {
 	}
 	// Non-fatal: some servers don't handle initialized notifications.
 	_, _ = h.doRPC(ctx, notif)
+	return nil
+}
+
+// reinitialize starts a new session after the server answered 404 for
+// staleSID. Callers that hit the same expired session share one handshake.
+func (h *HTTPInstance) reinitialize(ctx context.Context, staleSID string) error {
+	h.initMu.Lock()
+	defer h.initMu.Unlock()
 
 	h.mu.Lock()
-	h.state = StateReady
+	renewed := h.sessionID != staleSID
+	if !renewed {
+		h.sessionID = ""
+	}
 	h.mu.Unlock()
-	return nil
+	if renewed {
+		return nil
+	}
+
+	slog.Info("downstream session expired, reinitializing",
+		"server", h.key.ServerID)
+	return h.initialize(ctx)
 }
 
 func (h *HTTPInstance) stop() {
 	h.mu.Lock()
-	defer h.mu.Unlock()
+	h.stopIdleTimer()
+	h.state = StateStopped
+	cancel, done := h.cancel, h.listenDone
+	h.cancel, h.listenDone = nil, nil
+	h.mu.Unlock()
 
-	if h.idleTimer != nil {
-		h.idleTimer.Stop()
+	if cancel != nil {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
 	}
-	h.state = StateStopped
 }
 
 // ListTools sends a tools/list request to the HTTP MCP server.
 func (h *HTTPInstance) ListTools(ctx context.Context) (json.RawMessage, error) {
-	h.mu.Lock()
-	h.state = StateBusy
-	h.mu.Unlock()
-
-	defer func() {
-		h.mu.Lock()
-		h.state = StateIdle
-		h.resetIdleTimer()
-		h.mu.Unlock()
-	}()
+	h.beginCall()
+	defer h.endCall()
 
 	id := h.reqID.Add(1)
 	req := jsonRPCRequest{
@@ -139,23 +184,15 @@ func (h *HTTPInstance) ListTools(ctx context.Context) (json.RawMessage, error) {
 		Params:  json.RawMessage(`{}`),
 	}
 
-	return h.doRPC(ctx, req)
+	return h.rpcWithSession(ctx, req)
 }
 
 // Call sends a tools/call request to the HTTP MCP server.
 func (h *HTTPInstance) Call(
 	ctx context.Context, method string, params json.RawMessage,
 ) (json.RawMessage, error) {
-	h.mu.Lock()
-	h.state = StateBusy
-	h.mu.Unlock()
-
-	defer func() {
-		h.mu.Lock()
-		h.state = StateIdle
-		h.resetIdleTimer()
-		h.mu.Unlock()
-	}()
+	h.beginCall()
+	defer h.endCall()
 
 	id := h.reqID.Add(1)
 	req := jsonRPCRequest{
@@ -164,7 +201,43 @@ func (h *HTTPInstance) Call(
 		Method:  method,
 		Params:  params,
 	}
+	if progressHandlerFrom(ctx) != nil {
+		req.Params = withProgressToken(params, req.ID)
+	}
+
+	result, err := h.rpcWithSession(ctx, req)
+	if ctx.Err() != nil {
+		h.forwardCancel(ctx, req.ID)
+	}
+	return result, err
+}
+
+// forwardCancel tells the server to stop working on a request whose
+// caller has gone away. Closing the POST alone does not cancel it.
+func (h *HTTPInstance) forwardCancel(ctx context.Context, id json.RawMessage) {
+	notifyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if _, err := h.doRPC(notifyCtx, cancelNotification(ctx, id)); err != nil {
+		slog.Debug("failed to forward cancellation",
+			"server", h.key.ServerID, "error", err)
+	}
+}
+
+// rpcWithSession sends a request with doRPC. If the server has forgotten
+// the session, it did not process the request, so a new session is
+// initialized and the request sent again.
+func (h *HTTPInstance) rpcWithSession(ctx context.Context, req jsonRPCRequest) (json.RawMessage, error) {
+	h.mu.Lock()
+	sid := h.sessionID
+	h.mu.Unlock()
 
+	result, err := h.doRPC(ctx, req)
+	if !errors.Is(err, errSessionGone) {
+		return result, err
+	}
+	if err := h.reinitialize(ctx, sid); err != nil {
+		return nil, fmt.Errorf("reinitialize session: %w", err)
+	}
 	return h.doRPC(ctx, req)
 }
 
@@ -175,32 +248,9 @@ func (h *HTTPInstance) doRPC(ctx context.Context, rpcReq jsonRPCRequest) (json.R
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	url := h.url
-	if h.sessionURL != "" {
-		url = h.sessionURL
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	httpReq, err := h.newPost(ctx, body)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Accept", "application/json, text/event-stream")
-
-	// Inject auth headers (e.g. Authorization: Bearer <token>).
-	h.mu.Lock()
-	headers := h.authHeaders
-	sid := h.sessionID
-	h.mu.Unlock()
-	for k, vals := range headers {
-		for _, v := range vals {
-			httpReq.Header.Set(k, v)
-		}
-	}
-
-	// Include session ID from previous initialize handshake.
-	if sid != "" {
-		httpReq.Header.Set("Mcp-Session-Id", sid)
+		return nil, err
 	}
 
 	resp, err := h.client.Do(httpReq)
@@ -219,6 +269,10 @@ func (h *HTTPInstance) doRPC(ctx context.Context, rpcReq jsonRPCRequest) (json.R
 	if resp.StatusCode == http.StatusUnauthorized {
 		return nil, ErrAuthRequired
 	}
+	// A 404 for a request carrying a session ID means the session expired.
+	if resp.StatusCode == http.StatusNotFound && httpReq.Header.Get("Mcp-Session-Id") != "" {
+		return nil, errSessionGone
+	}
 
 	// Notifications return 202 with no body.
 	if rpcReq.ID == nil {
@@ -238,7 +292,7 @@ func (h *HTTPInstance) doRPC(ctx context.Context, rpcReq jsonRPCRequest) (json.R
 
 	// Handle SSE responses (text/event-stream).
 	if strings.HasPrefix(ct, "text/event-stream") {
-		return h.readSSEResponse(resp.Body)
+		return h.readSSEResponse(ctx, resp.Body)
 	}
 
 	// Standard JSON response.
@@ -259,9 +313,80 @@ func (h *HTTPInstance) doRPC(ctx context.Context, rpcReq jsonRPCRequest) (json.R
 	return rpcResp.Result, nil
 }
 
+// newPost builds a POST to the MCP endpoint with auth and session headers.
+func (h *HTTPInstance) newPost(ctx context.Context, body []byte) (*http.Request, error) {
+	return h.newRequest(ctx, http.MethodPost, bytes.NewReader(body))
+}
+
+// newRequest builds a request to the MCP endpoint with auth and session
+// headers. A GET opens the standalone event stream.
+func (h *HTTPInstance) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	url := h.url
+	if h.sessionURL != "" {
+		url = h.sessionURL
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	// Static headers first; the protocol headers and auth headers (e.g.
+	// Authorization: Bearer <token>) override them.
+	h.mu.Lock()
+	headers := h.authHeaders
+	sid := h.sessionID
+	h.mu.Unlock()
+	for k, vals := range h.headers {
+		for _, v := range vals {
+			httpReq.Header.Set(k, v)
+		}
+	}
+	if method == http.MethodGet {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	} else {
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	}
+	for k, vals := range headers {
+		for _, v := range vals {
+			httpReq.Header.Set(k, v)
+		}
+	}
+
+	// Include session ID from previous initialize handshake.
+	if sid != "" {
+		httpReq.Header.Set("Mcp-Session-Id", sid)
+	}
+	return httpReq, nil
+}
+
+// replyToServerRequest relays a server-initiated request received on an SSE
+// stream to the in-flight caller and POSTs the JSON-RPC response back.
+func (h *HTTPInstance) replyToServerRequest(ctx context.Context, msg jsonRPCMessage) {
+	resp := serveServerRequest(ctx, serverRequestHandlerFrom(ctx), h.key.ServerID, msg)
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	httpReq, err := h.newPost(ctx, body)
+	if err != nil {
+		return
+	}
+	httpResp, err := h.client.Do(httpReq)
+	if err != nil {
+		slog.Warn("failed to reply to downstream request",
+			"server", h.key.ServerID, "method", msg.Method, "error", err)
+		return
+	}
+	_ = httpResp.Body.Close()
+}
+
 // readSSEResponse reads a text/event-stream response and extracts the JSON-RPC result.
 // Per MCP Streamable HTTP spec, the server sends SSE events with "data:" lines.
-func (h *HTTPInstance) readSSEResponse(body io.Reader) (json.RawMessage, error) {
+// Server-initiated requests and progress notifications interleaved on the
+// stream are relayed to the caller.
+func (h *HTTPInstance) readSSEResponse(ctx context.Context, body io.Reader) (json.RawMessage, error) {
 	scanner := bufio.NewScanner(body)
 	// GitHub's MCP API returns large tool lists that exceed the default 64KB buffer.
 	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024) // up to 4MB
@@ -271,10 +396,26 @@ func (h *HTTPInstance) readSSEResponse(body io.Reader) (json.RawMessage, error)
 			continue
 		}
 		data := strings.TrimPrefix(line, "data: ")
-		var rpcResp jsonRPCResponse
+		var rpcResp jsonRPCMessage
 		if err := json.Unmarshal([]byte(data), &rpcResp); err != nil {
 			continue // skip non-JSON data lines
 		}
+		if rpcResp.Method != "" {
+			switch {
+			case rpcResp.ID != nil:
+				go h.replyToServerRequest(ctx, rpcResp)
+			case rpcResp.Method == "notifications/progress":
+				// Everything on this stream belongs to the one request.
+				if ph := progressHandlerFrom(ctx); ph != nil {
+					if _, progress, ok := parseProgress(rpcResp.Params); ok {
+						ph(progress)
+					}
+				}
+			default:
+				h.forwardNotification(rpcResp.Method, rpcResp.Params)
+			}
+			continue
+		}
 		if rpcResp.Error != nil {
 			return nil, fmt.Errorf("rpc error %d: %s", rpcResp.Error.Code, rpcResp.Error.Message)
 		}
@@ -288,14 +429,144 @@ func (h *HTTPInstance) readSSEResponse(body io.Reader) (json.RawMessage, error)
 	return nil, fmt.Errorf("no result in sse stream")
 }
 
-func (h *HTTPInstance) resetIdleTimer() {
-	if h.idleTimeout <= 0 {
+// errStreamUnsupported is returned when the server does not offer the
+// standalone event stream, which the spec allows.
+var errStreamUnsupported = errors.New("downstream offers no event stream")
+
+// listen keeps the standalone GET event stream open so the server can send
+// messages outside any request. A dropped stream is resumed with
+// Last-Event-ID, a new session is initialized when the server has
+// forgotten the old one, and listening stops if the server offers no
+// stream or rejects the credentials.
+func (h *HTTPInstance) listen(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	for attempt := 1; ; attempt++ {
+		h.mu.Lock()
+		sid := h.sessionID
+		h.mu.Unlock()
+
+		opened, err := h.listenOnce(ctx, sid)
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case errors.Is(err, errStreamUnsupported):
+			slog.Debug("downstream offers no event stream",
+				"server", h.key.ServerID)
+			return
+		case errors.Is(err, ErrAuthRequired):
+			slog.Warn("downstream rejected event stream credentials, not listening",
+				"server", h.key.ServerID)
+			return
+		case errors.Is(err, errSessionGone):
+			initCtx, cancel := context.WithTimeout(ctx, remoteConnectTimeout)
+			err = h.reinitialize(initCtx, sid)
+			cancel()
+			if err == nil {
+				attempt = 0
+				continue
+			}
+			slog.Warn("downstream session reinitialize failed",
+				"server", h.key.ServerID, "error", err)
+		case err != nil:
+			slog.Debug("downstream event stream failed",
+				"server", h.key.ServerID, "attempt", attempt, "error", err)
+		}
+		if opened {
+			attempt = 1
+		}
+
+		t := time.NewTimer(reconnectBackoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// listenOnce opens the event stream for session sid and reads it until it
+// ends. opened reports whether the server accepted the stream.
+func (h *HTTPInstance) listenOnce(ctx context.Context, sid string) (opened bool, err error) {
+	req, err := h.newRequest(ctx, http.MethodGet, nil)
+	if err != nil {
+		return false, err
+	}
+	h.mu.Lock()
+	if h.lastEventID != "" && h.lastEventSession == sid {
+		req.Header.Set("Last-Event-ID", h.lastEventID)
+	}
+	h.mu.Unlock()
+
+	resp, err := h.stream.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("http get: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusMethodNotAllowed:
+		return false, errStreamUnsupported
+	case resp.StatusCode == http.StatusUnauthorized:
+		return false, ErrAuthRequired
+	case resp.StatusCode == http.StatusNotFound && sid != "":
+		return false, errSessionGone
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return false, fmt.Errorf("http %d: %s", resp.StatusCode, body)
+	case !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"):
+		return false, errStreamUnsupported
+	}
+
+	events := newSSEReader(resp.Body)
+	for {
+		ev, err := events.next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			return true, err
+		}
+		if ev.ID != "" {
+			h.mu.Lock()
+			h.lastEventID, h.lastEventSession = ev.ID, sid
+			h.mu.Unlock()
+		}
+		if ev.Event == "message" && ev.Data != "" {
+			h.handleStreamMessage(ctx, []byte(ev.Data))
+		}
+	}
+}
+
+// handleStreamMessage handles a message the server sent on the standalone
+// stream. No call is waiting on it, so server requests are answered
+// without a caller and responses are dropped.
+func (h *HTTPInstance) handleStreamMessage(ctx context.Context, data []byte) {
+	var msg jsonRPCMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		slog.Debug("dropping malformed stream message",
+			"server", h.key.ServerID, "error", err)
 		return
 	}
-	if h.idleTimer != nil {
-		h.idleTimer.Stop()
+	switch {
+	case msg.Method != "" && msg.ID != nil:
+		go h.replyToServerRequest(ctx, msg)
+	case msg.Method != "":
+		h.forwardNotification(msg.Method, msg.Params)
+	default:
+		slog.Debug("dropping response on event stream",
+			"server", h.key.ServerID, "id", string(msg.ID))
+	}
+}
+
+// forwardNotification calls onNotify, if set, with a notification the
+// server sent.
+func (h *HTTPInstance) forwardNotification(method string, params json.RawMessage) {
+	if h.onNotify == nil {
+		return
 	}
-	h.idleTimer = time.AfterFunc(h.idleTimeout, func() {
-		h.stop()
-	})
+	slog.Debug("downstream notification",
+		"server", h.key.ServerID, "method", method)
+	h.onNotify(method, params)
 }
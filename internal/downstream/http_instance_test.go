@@ -0,0 +1,311 @@
+package downstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// streamableServer is an in-process MCP server on the Streamable HTTP
+// transport, with a standalone GET event stream per session.
+type streamableServer struct {
+	mu           sync.Mutex
+	sessions     map[string]bool
+	streams      map[string]chan string // session ID -> events for its open GET stream
+	nextSession  int
+	nextEvent    int
+	inits        int
+	lastEventIDs []string // Last-Event-ID of each accepted GET
+	noStream     bool     // answer GETs with 405
+	rejectStream bool     // answer GETs with 401
+}
+
+func newStreamableServer(t *testing.T) (*streamableServer, *httptest.Server) {
+	s := &streamableServer{sessions: make(map[string]bool), streams: make(map[string]chan string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /mcp", s.post)
+	mux.HandleFunc("GET /mcp", s.get)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func (s *streamableServer) post(w http.ResponseWriter, r *http.Request) {
+	var msg jsonRPCMessage
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	if msg.Method == "initialize" {
+		s.inits++
+		s.nextSession++
+		sid := fmt.Sprintf("s%d", s.nextSession)
+		s.sessions[sid] = true
+		s.mu.Unlock()
+		w.Header().Set("Mcp-Session-Id", sid)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"protocolVersion":"2025-03-26"}}`, msg.ID)
+		return
+	}
+	known := s.sessions[r.Header.Get("Mcp-Session-Id")]
+	s.mu.Unlock()
+	switch {
+	case !known:
+		http.Error(w, "unknown session", http.StatusNotFound)
+	case msg.ID == nil:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"tools":[{"name":"echo"}]}}`, msg.ID)
+	}
+}
+
+func (s *streamableServer) get(w http.ResponseWriter, r *http.Request) {
+	sid := r.Header.Get("Mcp-Session-Id")
+	s.mu.Lock()
+	if s.noStream {
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if s.rejectStream {
+		s.lastEventIDs = append(s.lastEventIDs, r.Header.Get("Last-Event-ID"))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if !s.sessions[sid] {
+		s.mu.Unlock()
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	events := make(chan string, 16)
+	s.streams[sid] = events
+	s.lastEventIDs = append(s.lastEventIDs, r.Header.Get("Last-Event-ID"))
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.(http.Flusher).Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return // drop the stream
+			}
+			s.mu.Lock()
+			s.nextEvent++
+			id := s.nextEvent
+			s.mu.Unlock()
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", id, ev)
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+// waitStream waits until the nth GET stream has been accepted and returns
+// the events channel of session sid.
+func (s *streamableServer) waitStream(t *testing.T, sid string, n int) chan string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		events, count := s.streams[sid], len(s.lastEventIDs)
+		s.mu.Unlock()
+		if events != nil && count >= n {
+			return events
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("stream %d for session %s not opened", n, sid)
+	return nil
+}
+
+// drop closes the open stream of session sid, keeping the session.
+func (s *streamableServer) drop(sid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.streams[sid])
+	delete(s.streams, sid)
+}
+
+// expire forgets session sid and closes its stream.
+func (s *streamableServer) expire(sid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sid)
+	if events := s.streams[sid]; events != nil {
+		close(events)
+		delete(s.streams, sid)
+	}
+}
+
+func TestHTTPInstance_StreamNotificationsAndResume(t *testing.T) {
+	srv, ts := newStreamableServer(t)
+	inst := newHTTPInstance(InstanceKey{ServerID: "remote"}, ts.URL+"/mcp", 0, nil, nil)
+	notified := make(chan string, 4)
+	inst.onNotify = func(method string, _ json.RawMessage) { notified <- method }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := inst.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	events := srv.waitStream(t, "s1", 1)
+	events <- `{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`
+	select {
+	case m := <-notified:
+		if m != "notifications/tools/list_changed" {
+			t.Errorf("notification = %s", m)
+		}
+	case <-ctx.Done():
+		t.Fatal("notification not forwarded")
+	}
+
+	srv.drop("s1")
+	srv.waitStream(t, "s1", 2)
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if got := srv.lastEventIDs; got[0] != "" || got[1] != "1" {
+		t.Errorf("Last-Event-ID = %q, want resume from event 1", got)
+	}
+}
+
+func TestHTTPInstance_StreamReinitializesExpiredSession(t *testing.T) {
+	srv, ts := newStreamableServer(t)
+	inst := newHTTPInstance(InstanceKey{ServerID: "remote"}, ts.URL+"/mcp", 0, nil, nil)
+	inst.onNotify = func(string, json.RawMessage) {}
+
+	if err := inst.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	srv.waitStream(t, "s1", 1) <- `{"jsonrpc":"2.0","method":"notifications/message"}`
+	srv.expire("s1")
+	srv.waitStream(t, "s2", 2)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.inits != 2 {
+		t.Errorf("initialize sent %d times, want 2", srv.inits)
+	}
+	if got := srv.lastEventIDs[1]; got != "" {
+		t.Errorf("new session resumed from event %q", got)
+	}
+}
+
+func TestHTTPInstance_CallReinitializesExpiredSession(t *testing.T) {
+	srv, ts := newStreamableServer(t)
+	inst := newHTTPInstance(InstanceKey{ServerID: "remote"}, ts.URL+"/mcp", 0, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := inst.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	srv.expire("s1")
+	res, err := inst.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("call after session expired: %v", err)
+	}
+	if !strings.Contains(string(res), `"echo"`) {
+		t.Errorf("tools = %s", res)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.inits != 2 {
+		t.Errorf("initialize sent %d times, want 2", srv.inits)
+	}
+}
+
+func TestHTTPInstance_NoStreamStopsListening(t *testing.T) {
+	srv, ts := newStreamableServer(t)
+	srv.noStream = true
+	inst := newHTTPInstance(InstanceKey{ServerID: "remote"}, ts.URL+"/mcp", 0, nil, nil)
+	inst.onNotify = func(string, json.RawMessage) {}
+
+	if err := inst.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	inst.mu.Lock()
+	done := inst.listenDone
+	inst.mu.Unlock()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener kept retrying after 405")
+	}
+}
+
+func TestHTTPInstance_UnauthorizedStreamStopsListening(t *testing.T) {
+	srv, ts := newStreamableServer(t)
+	srv.rejectStream = true
+	inst := newHTTPInstance(InstanceKey{ServerID: "remote"}, ts.URL+"/mcp", 0, nil, nil)
+	inst.onNotify = func(string, json.RawMessage) {}
+
+	if err := inst.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	inst.mu.Lock()
+	done := inst.listenDone
+	inst.mu.Unlock()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener kept retrying after 401")
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if n := len(srv.lastEventIDs); n != 1 {
+		t.Errorf("stream requested %d times, want 1", n)
+	}
+}
+
+func TestHTTPInstance_ResponseStreamForwardsNotifications(t *testing.T) {
+	inst := newHTTPInstance(InstanceKey{ServerID: "remote"}, "http://example.invalid/mcp", 0, nil, nil)
+	var notified []string
+	inst.onNotify = func(method string, _ json.RawMessage) { notified = append(notified, method) }
+
+	res, err := inst.readSSEResponse(context.Background(), strings.NewReader(
+		"data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/tools/list_changed\"}\n\n"+
+			"data: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{}}\n\n"))
+	if err != nil || string(res) != "{}" {
+		t.Fatalf("result = %s, %v", res, err)
+	}
+	if len(notified) != 1 || notified[0] != "notifications/tools/list_changed" {
+		t.Errorf("notifications = %q", notified)
+	}
+}
+
+func TestCreateInstance_HTTP(t *testing.T) {
+	url := "https://example.invalid/mcp"
+	d, err := NewManager(nil, nil).createInstance(context.Background(), InstanceKey{ServerID: "remote"},
+		&store.DownstreamServer{ID: "remote", Name: "remote", Transport: "http", URL: &url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst, ok := d.(*HTTPInstance)
+	if !ok || inst.onNotify == nil {
+		t.Fatalf("instance = %T, want *HTTPInstance forwarding notifications", d)
+	}
+}
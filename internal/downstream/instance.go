@@ -12,7 +12,6 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
@@ -51,43 +50,73 @@ func (s InstanceState) String() string {
 type InstanceKey struct {
 	ServerID    string
 	AuthScopeID string
+
+	// Scope separates the pools of servers whose instance_scope is not
+	// global: "workspace:<root>" or "session:<id>". Empty for global.
+	Scope string
 }
 
-// Instance manages a single downstream MCP server process.
+// defaultMaxConcurrency bounds in-flight requests to a stdio instance when
+// the server does not configure max_concurrency.
+const defaultMaxConcurrency = 8
+
+// Instance manages a single downstream MCP server process. Requests are
+// multiplexed over the process's stdio: any number up to maxConcurrency may
+// be in flight, and a reader goroutine delivers responses by JSON-RPC ID.
 type Instance struct {
+	callTracker
+
 	key     InstanceKey
 	command string
 	args    []string
 	env     []string
+	dir     string // working directory; empty = the daemon's
+	rpc     *rpcSession
 
-	idleTimeout time.Duration
-	idleTimer   *time.Timer
+	onNotify func(method string, params json.RawMessage) // called when downstream sends a notification
 
-	onNotify func(method string) // called when downstream sends a notification
+	// onExit is called when the process exits without being stopped,
+	// with how long it ran and the tail of its stderr.
+	onExit func(err error, ranFor time.Duration, stderr string)
 
-	mu    sync.Mutex
-	state InstanceState
-	cmd   *exec.Cmd
-	stdin io.WriteCloser
-	queue *requestQueue
-	reqID atomic.Int64
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	started time.Time
+	stderr  *stderrTail
+
+	writeMu sync.Mutex    // serializes writes to stdin
+	slots   chan struct{} // bounds in-flight calls
 
 	cancel context.CancelFunc
-	done   chan struct{}
+	done   chan struct{} // closed when the reader goroutine exits
 }
 
 // newInstance creates a new stopped instance.
-func newInstance(key InstanceKey, command string, args, env []string, idleTimeout time.Duration) *Instance {
-	return &Instance{
-		key:         key,
-		command:     command,
-		args:        args,
-		env:         env,
-		idleTimeout: idleTimeout,
-		state:       StateStopped,
-		done:        make(chan struct{}),
-		queue:       newRequestQueue(64),
+func newInstance(
+	key InstanceKey, command string, args, env []string,
+	idleTimeout time.Duration, maxConcurrency int,
+) *Instance {
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultMaxConcurrency
+	}
+	inst := &Instance{
+		key:     key,
+		command: command,
+		args:    args,
+		env:     env,
+		done:    make(chan struct{}),
+		slots:   make(chan struct{}, maxConcurrency),
+	}
+	inst.callTracker = newCallTracker(key.ServerID, idleTimeout, inst.stop)
+	inst.rpc = newRPCSession(key.ServerID, func(_ context.Context, msg any) error {
+		return inst.writeLine(msg)
+	})
+	inst.rpc.onNotify = func(method string, params json.RawMessage) {
+		if inst.onNotify != nil {
+			inst.onNotify(method, params)
+		}
 	}
+	return inst
 }
 
 func (inst *Instance) start(ctx context.Context) error {
@@ -99,7 +128,8 @@ func (inst *Instance) start(ctx context.Context) error {
 	}
 	inst.state = StateStarting
 
-	childCtx, cancel := context.WithCancel(ctx)
+	// The process outlives the call that started it; stop cancels it.
+	childCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
 	inst.cancel = cancel
 
 	// Resolve the command to an absolute path using the augmented PATH
@@ -115,6 +145,9 @@ func (inst *Instance) start(ctx context.Context) error {
 
 	cmd := exec.CommandContext(childCtx, cmdPath, inst.args...)
 	cmd.Env = inst.env
+	cmd.Dir = inst.dir
+	inst.stderr = &stderrTail{}
+	cmd.Stderr = inst.stderr
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -139,12 +172,16 @@ func (inst *Instance) start(ctx context.Context) error {
 	inst.cmd = cmd
 	inst.stdin = stdin
 	inst.done = make(chan struct{})
+	inst.rpc.open()
+
+	go inst.readLoop(stdout, inst.done)
 
 	// Perform MCP initialize handshake with timeout.
-	initCtx, initCancel := context.WithTimeout(childCtx, 30*time.Second)
-	if err := inst.initialize(initCtx, stdin, stdout); err != nil {
+	initCtx, initCancel := context.WithTimeout(ctx, 30*time.Second)
+	if err := inst.initialize(initCtx); err != nil {
 		initCancel()
 		_ = cmd.Process.Kill()
+		_ = cmd.Wait() // reap and flush stderr for diagnostics
 		cancel()
 		inst.state = StateStopped
 		return fmt.Errorf("initialize: %w", err)
@@ -152,209 +189,83 @@ func (inst *Instance) start(ctx context.Context) error {
 	initCancel()
 
 	inst.state = StateReady
-
-	// Start the processing loop and monitor goroutines.
-	go inst.processLoop(stdout)
+	inst.started = time.Now()
 	go inst.monitorProcess(cmd)
 
 	return nil
 }
 
-func (inst *Instance) initialize(ctx context.Context, stdin io.Writer, stdout io.Reader) error {
-	initReq := jsonRPCRequest{
-		JSONRPC: "2.0",
-		ID:      json.RawMessage(`1`),
-		Method:  "initialize",
-		Params: json.RawMessage(`{
-			"protocolVersion": "2025-03-26",
-			"capabilities": {},
-			"clientInfo": {"name": "mcplexer", "version": "0.1.0"}
-		}`),
-	}
-	if err := writeJSONLine(stdin, initReq); err != nil {
-		return fmt.Errorf("write initialize: %w", err)
-	}
-
-	// Read response with context timeout support.
-	type scanResult struct {
-		line []byte
-		err  error
-	}
-	ch := make(chan scanResult, 1)
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
-		if scanner.Scan() {
-			ch <- scanResult{line: append([]byte{}, scanner.Bytes()...)}
-		} else {
-			ch <- scanResult{err: fmt.Errorf("no initialize response")}
-		}
-	}()
-
-	select {
-	case <-ctx.Done():
-		return fmt.Errorf("initialize timed out: %w", ctx.Err())
-	case res := <-ch:
-		if res.err != nil {
-			return res.err
+func (inst *Instance) initialize(ctx context.Context) error {
+	params := json.RawMessage(`{
+		"protocolVersion": "2025-03-26",
+		"capabilities": {"sampling": {}, "elicitation": {}},
+		"clientInfo": {"name": "mcplexer", "version": "0.1.0"}
+	}`)
+	if _, err := inst.rpc.roundTrip(ctx, "initialize", params); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("initialize timed out: %w", ctx.Err())
 		}
+		return err
 	}
-
-	// Send initialized notification.
-	notif := jsonRPCRequest{
-		JSONRPC: "2.0",
-		Method:  "notifications/initialized",
-	}
-	return writeJSONLine(stdin, notif)
+	return inst.rpc.notify(ctx, "notifications/initialized")
 }
 
-func (inst *Instance) processLoop(stdout io.Reader) {
-	defer close(inst.done)
+// readLoop feeds messages from the process's stdout to the RPC session
+// until it closes, then fails the requests still waiting.
+func (inst *Instance) readLoop(stdout io.Reader, done chan struct{}) {
+	defer close(done)
 
 	scanner := bufio.NewScanner(stdout)
 	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
-
-	for {
-		req, ok := inst.queue.dequeue()
-		if !ok {
-			return
-		}
-
-		inst.mu.Lock()
-		inst.state = StateBusy
-		inst.mu.Unlock()
-
-		result, err := inst.handleRequest(req, scanner)
-
-		req.Result <- response{Data: result, Err: err}
-
-		inst.mu.Lock()
-		inst.state = StateIdle
-		inst.resetIdleTimer()
-		inst.mu.Unlock()
-	}
-}
-
-func (inst *Instance) handleRequest(
-	req request, scanner *bufio.Scanner,
-) (json.RawMessage, error) {
-	rpcReq := jsonRPCRequest{
-		JSONRPC: "2.0",
-		ID:      json.RawMessage(fmt.Sprintf(`%d`, req.ID)),
-		Method:  req.Method,
-		Params:  req.Params,
-	}
-
-	inst.mu.Lock()
-	w := inst.stdin
-	inst.mu.Unlock()
-
-	if err := writeJSONLine(w, rpcReq); err != nil {
-		return nil, fmt.Errorf("write request: %w", err)
-	}
-
-	return inst.readResponse(scanner)
-}
-
-// readResponse scans lines until finding a JSON-RPC response (has an id field).
-// Any interleaved notifications (no id) are forwarded via onNotify.
-func (inst *Instance) readResponse(scanner *bufio.Scanner) (json.RawMessage, error) {
-	for {
-		if !scanner.Scan() {
-			return nil, fmt.Errorf("no response from downstream")
-		}
-
-		var rpcResp jsonRPCResponse
-		if err := json.Unmarshal(scanner.Bytes(), &rpcResp); err != nil {
-			return nil, fmt.Errorf("unmarshal response: %w", err)
-		}
-
-		// No id means this is a notification, not a response.
-		if rpcResp.ID == nil {
-			inst.forwardNotification(scanner.Bytes())
-			continue
-		}
-
-		if rpcResp.Error != nil {
-			return nil, fmt.Errorf("downstream error %d: %s",
-				rpcResp.Error.Code, rpcResp.Error.Message)
-		}
-
-		return rpcResp.Result, nil
+	for scanner.Scan() {
+		inst.rpc.dispatch(scanner.Bytes())
 	}
-}
 
-// forwardNotification extracts the method from a JSON-RPC notification
-// and calls onNotify if set.
-func (inst *Instance) forwardNotification(data []byte) {
-	if inst.onNotify == nil {
-		return
-	}
-	var notif struct {
-		Method string `json:"method"`
+	err := fmt.Errorf("no response from downstream")
+	if scanErr := scanner.Err(); scanErr != nil {
+		err = fmt.Errorf("read downstream: %w", scanErr)
 	}
-	if err := json.Unmarshal(data, &notif); err != nil || notif.Method == "" {
-		return
-	}
-	slog.Debug("downstream notification",
-		"server", inst.key.ServerID, "method", notif.Method)
-	inst.onNotify(notif.Method)
+	inst.rpc.close(err)
 }
 
-func (inst *Instance) getState() InstanceState {
-	inst.mu.Lock()
-	defer inst.mu.Unlock()
-	return inst.state
+// writeLine writes a JSON-RPC message to the process's stdin. Writes are
+// serialized because requests and server-request replies are sent from
+// many goroutines.
+func (inst *Instance) writeLine(v any) error {
+	inst.writeMu.Lock()
+	defer inst.writeMu.Unlock()
+	return writeJSONLine(inst.stdin, v)
 }
 
-// Call sends a request via the queue and waits for the response.
+// Call sends a request to the process and waits for its response. At most
+// maxConcurrency calls are in flight at once; further calls wait for a
+// free slot.
 func (inst *Instance) Call(
 	ctx context.Context, method string, params json.RawMessage,
 ) (json.RawMessage, error) {
-	resultCh := make(chan response, 1)
-	id := int(inst.reqID.Add(1))
-
-	inst.queue.enqueue(request{
-		ID:     id,
-		Method: method,
-		Params: params,
-		Result: resultCh,
-	})
-
 	select {
+	case inst.slots <- struct{}{}:
 	case <-ctx.Done():
 		return nil, ctx.Err()
-	case resp := <-resultCh:
-		return resp.Data, resp.Err
 	}
+	defer func() { <-inst.slots }()
+
+	inst.beginCall()
+	defer inst.endCall()
+
+	return inst.rpc.roundTrip(ctx, method, params)
 }
 
 // ListTools sends a tools/list request to the downstream instance.
 func (inst *Instance) ListTools(ctx context.Context) (json.RawMessage, error) {
-	resultCh := make(chan response, 1)
-	id := int(inst.reqID.Add(1))
-
-	inst.queue.enqueue(request{
-		ID:     id,
-		Method: "tools/list",
-		Params: json.RawMessage(`{}`),
-		Result: resultCh,
-	})
-
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case resp := <-resultCh:
-		return resp.Data, resp.Err
-	}
+	return inst.Call(ctx, "tools/list", json.RawMessage(`{}`))
 }
 
 func (inst *Instance) monitorProcess(cmd *exec.Cmd) {
 	err := cmd.Wait()
 	inst.mu.Lock()
-	defer inst.mu.Unlock()
-
 	if inst.state == StateStopping {
+		inst.mu.Unlock()
 		return
 	}
 
@@ -363,6 +274,22 @@ func (inst *Instance) monitorProcess(cmd *exec.Cmd) {
 			"server", inst.key.ServerID, "error", err)
 	}
 	inst.state = StateStopped
+	inst.stopIdleTimer()
+	ranFor := time.Since(inst.started)
+	onExit := inst.onExit
+	inst.mu.Unlock()
+
+	if onExit != nil {
+		onExit(err, ranFor, inst.stderrTail())
+	}
+}
+
+// stderrTail returns the last lines the process wrote to stderr.
+func (inst *Instance) stderrTail() string {
+	if inst.stderr == nil {
+		return ""
+	}
+	return inst.stderr.String()
 }
 
 func (inst *Instance) stop() {
@@ -372,12 +299,9 @@ func (inst *Instance) stop() {
 		return
 	}
 	inst.state = StateStopping
-	if inst.idleTimer != nil {
-		inst.idleTimer.Stop()
-	}
+	inst.stopIdleTimer()
 	inst.mu.Unlock()
 
-	inst.queue.close()
 	if inst.cancel != nil {
 		inst.cancel()
 	}
@@ -395,20 +319,6 @@ func (inst *Instance) stop() {
 	inst.mu.Unlock()
 }
 
-func (inst *Instance) resetIdleTimer() {
-	if inst.idleTimeout <= 0 {
-		return
-	}
-	if inst.idleTimer != nil {
-		inst.idleTimer.Stop()
-	}
-	inst.idleTimer = time.AfterFunc(inst.idleTimeout, func() {
-		slog.Info("idle timeout, stopping instance",
-			"server", inst.key.ServerID)
-		inst.stop()
-	})
-}
-
 // lookPathInEnv resolves a command name to its absolute path using the PATH
 // from the given environment slice (not the current process's PATH). This is
 // needed because Go's exec.Command uses os.Getenv("PATH") for resolution,
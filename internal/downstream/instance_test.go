@@ -2,68 +2,83 @@ package downstream
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
-func TestReadResponse_SkipsNotifications(t *testing.T) {
-	// Simulate a downstream that sends a notification before the response.
-	lines := strings.Join([]string{
-		`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`,
-		`{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ok"}]}}`,
-	}, "\n") + "\n"
+// testInstance returns a running instance whose stdin is w, for feeding
+// its reader directly.
+func testInstance(w io.WriteCloser) *Instance {
+	inst := newInstance(InstanceKey{ServerID: "test-server"}, "", nil, nil, 0, 0)
+	inst.stdin = w
+	inst.rpc.open()
+	return inst
+}
 
-	scanner := bufio.NewScanner(strings.NewReader(lines))
-	scanner.Buffer(make([]byte, 64*1024), 64*1024)
+// trackRequest registers an in-flight request on inst as roundTrip would.
+func trackRequest(inst *Instance, id int64, ctx context.Context) chan response {
+	ch := make(chan response, 1)
+	inst.rpc.pending[id] = &request{ID: id, Result: ch, Ctx: ctx}
+	return ch
+}
+
+// runReadLoop feeds lines to inst's reader and waits for it to finish.
+func runReadLoop(inst *Instance, lines ...string) {
+	inst.readLoop(strings.NewReader(strings.Join(lines, "\n")+"\n"), make(chan struct{}))
+}
 
+func TestReadLoop_SkipsNotifications(t *testing.T) {
 	var notified atomic.Int32
-	inst := &Instance{
-		key: InstanceKey{ServerID: "test-server"},
-		onNotify: func(method string) {
-			if method == "notifications/tools/list_changed" {
-				notified.Add(1)
-			}
-		},
+	inst := testInstance(nil)
+	inst.onNotify = func(method string, _ json.RawMessage) {
+		if method == "notifications/tools/list_changed" {
+			notified.Add(1)
+		}
 	}
+	ch := trackRequest(inst, 1, nil)
 
-	result, err := inst.readResponse(scanner)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	// Simulate a downstream that sends a notification before the response.
+	runReadLoop(inst,
+		`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`,
+		`{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ok"}]}}`,
+	)
+
+	resp := <-ch
+	if resp.Err != nil {
+		t.Fatalf("unexpected error: %v", resp.Err)
 	}
-	if result == nil {
+	if resp.Data == nil {
 		t.Fatal("result is nil")
 	}
-
 	if notified.Load() != 1 {
 		t.Errorf("notification callback called %d times, want 1", notified.Load())
 	}
 }
 
-func TestReadResponse_MultipleNotificationsBeforeResponse(t *testing.T) {
-	lines := strings.Join([]string{
+func TestReadLoop_MultipleNotificationsBeforeResponse(t *testing.T) {
+	var methods []string
+	inst := testInstance(nil)
+	inst.onNotify = func(method string, _ json.RawMessage) {
+		methods = append(methods, method)
+	}
+	ch := trackRequest(inst, 5, nil)
+
+	runReadLoop(inst,
 		`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`,
 		`{"jsonrpc":"2.0","method":"notifications/progress","params":{"token":"abc"}}`,
 		`{"jsonrpc":"2.0","id":5,"result":{"tools":[]}}`,
-	}, "\n") + "\n"
-
-	scanner := bufio.NewScanner(strings.NewReader(lines))
-	scanner.Buffer(make([]byte, 64*1024), 64*1024)
-
-	var methods []string
-	inst := &Instance{
-		key: InstanceKey{ServerID: "test-server"},
-		onNotify: func(method string) {
-			methods = append(methods, method)
-		},
-	}
+	)
 
-	result, err := inst.readResponse(scanner)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if result == nil {
-		t.Fatal("result is nil")
+	if resp := <-ch; resp.Err != nil || resp.Data == nil {
+		t.Fatalf("response = %+v", resp)
 	}
 	if len(methods) != 2 {
 		t.Fatalf("got %d notifications, want 2", len(methods))
@@ -76,40 +91,376 @@ func TestReadResponse_MultipleNotificationsBeforeResponse(t *testing.T) {
 	}
 }
 
-func TestReadResponse_NoNotifications(t *testing.T) {
-	lines := `{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}` + "\n"
+func TestReadLoop_DownstreamError(t *testing.T) {
+	inst := testInstance(nil)
+	ch := trackRequest(inst, 1, nil)
 
-	scanner := bufio.NewScanner(strings.NewReader(lines))
-	scanner.Buffer(make([]byte, 64*1024), 64*1024)
+	runReadLoop(inst, `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"bad request"}}`)
 
-	inst := &Instance{
-		key: InstanceKey{ServerID: "test-server"},
+	resp := <-ch
+	if resp.Err == nil {
+		t.Fatal("expected error")
 	}
+	if !strings.Contains(resp.Err.Error(), "bad request") {
+		t.Errorf("error = %q, want to contain 'bad request'", resp.Err.Error())
+	}
+}
+
+func TestReadLoop_OutOfOrderResponses(t *testing.T) {
+	inst := testInstance(nil)
+	first := trackRequest(inst, 1, nil)
+	second := trackRequest(inst, 2, nil)
+
+	runReadLoop(inst,
+		`{"jsonrpc":"2.0","id":2,"result":{"n":2}}`,
+		`{"jsonrpc":"2.0","id":1,"result":{"n":1}}`,
+	)
 
-	result, err := inst.readResponse(scanner)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if resp := <-first; string(resp.Data) != `{"n":1}` {
+		t.Errorf("request 1 got %s", resp.Data)
 	}
-	if result == nil {
-		t.Fatal("result is nil")
+	if resp := <-second; string(resp.Data) != `{"n":2}` {
+		t.Errorf("request 2 got %s", resp.Data)
 	}
 }
 
-func TestReadResponse_DownstreamError(t *testing.T) {
-	lines := `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"bad request"}}` + "\n"
+func TestReadLoop_EOFFailsPending(t *testing.T) {
+	inst := testInstance(nil)
+	ch := trackRequest(inst, 1, nil)
 
-	scanner := bufio.NewScanner(strings.NewReader(lines))
-	scanner.Buffer(make([]byte, 64*1024), 64*1024)
+	runReadLoop(inst, `{"jsonrpc":"2.0","method":"notifications/progress"}`)
 
-	inst := &Instance{
-		key: InstanceKey{ServerID: "test-server"},
+	if resp := <-ch; resp.Err == nil {
+		t.Fatal("expected error after downstream exited")
+	}
+	if _, err := inst.rpc.roundTrip(context.Background(), "ping", nil); err == nil {
+		t.Error("expected new requests to fail after exit")
 	}
+}
 
-	_, err := inst.readResponse(scanner)
-	if err == nil {
-		t.Fatal("expected error")
+func TestReadLoop_RelaysServerRequest(t *testing.T) {
+	var gotMethod atomic.Value
+	ctx := WithServerRequestHandler(context.Background(),
+		func(_ context.Context, method string, _ json.RawMessage) (json.RawMessage, error) {
+			gotMethod.Store(method)
+			return json.RawMessage(`{"role":"assistant","content":{"type":"text","text":"hi"}}`), nil
+		})
+
+	pr, pw := io.Pipe()
+	inst := testInstance(pw)
+	ch := trackRequest(inst, 1, ctx)
+
+	// The reply is written asynchronously, so keep the request in flight
+	// until it has been read.
+	go runReadLoop(inst,
+		`{"jsonrpc":"2.0","id":"srv-1","method":"sampling/createMessage","params":{"maxTokens":10}}`)
+
+	reply := bufio.NewScanner(pr)
+	if !reply.Scan() {
+		t.Fatal("no reply written to stdin")
+	}
+	var resp jsonRPCResponse
+	if err := json.Unmarshal(reply.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal reply: %v", err)
+	}
+	if string(resp.ID) != `"srv-1"` {
+		t.Errorf("reply id = %s, want \"srv-1\"", resp.ID)
+	}
+	if resp.Error != nil || !strings.Contains(string(resp.Result), "assistant") {
+		t.Errorf("reply = %s", reply.Bytes())
+	}
+	if gotMethod.Load() != "sampling/createMessage" {
+		t.Errorf("handler method = %v", gotMethod.Load())
+	}
+	<-ch // failed by EOF once the reader finishes
+}
+
+func TestReadLoop_ServerRequestWithSeveralCallers(t *testing.T) {
+	// Two client sessions have calls in flight on a shared instance; the
+	// request could belong to either, so neither may see it.
+	var relayed atomic.Int32
+	handler := func(_ context.Context, _ string, _ json.RawMessage) (json.RawMessage, error) {
+		relayed.Add(1)
+		return json.RawMessage(`{}`), nil
+	}
+	pr, pw := io.Pipe()
+	inst := testInstance(pw)
+	ch1 := trackRequest(inst, 1, WithServerRequestHandler(context.Background(), handler))
+	ch2 := trackRequest(inst, 2, WithServerRequestHandler(context.Background(), handler))
+
+	go runReadLoop(inst, `{"jsonrpc":"2.0","id":"srv-1","method":"elicitation/create","params":{}}`)
+
+	reply := bufio.NewScanner(pr)
+	if !reply.Scan() {
+		t.Fatal("no reply written to stdin")
+	}
+	var resp jsonRPCResponse
+	if err := json.Unmarshal(reply.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal reply: %v", err)
+	}
+	if resp.Error == nil {
+		t.Errorf("reply = %s, want an error", reply.Bytes())
+	}
+	if n := relayed.Load(); n != 0 {
+		t.Errorf("request relayed to %d callers, want none", n)
+	}
+	<-ch1
+	<-ch2
+}
+
+func TestReadLoop_ServerRequestWithoutHandler(t *testing.T) {
+	pr, pw := io.Pipe()
+	inst := testInstance(pw)
+
+	go runReadLoop(inst, `{"jsonrpc":"2.0","id":7,"method":"elicitation/create","params":{}}`)
+
+	reply := bufio.NewScanner(pr)
+	if !reply.Scan() {
+		t.Fatal("no reply written to stdin")
+	}
+	var resp jsonRPCResponse
+	if err := json.Unmarshal(reply.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal reply: %v", err)
+	}
+	if resp.Error == nil || resp.Error.Code != -32601 {
+		t.Errorf("reply = %s, want method-not-found error", reply.Bytes())
+	}
+}
+
+// fakeProcess is an in-memory downstream: it reads requests the instance
+// writes to stdin and lets the test answer them in any order.
+type fakeProcess struct {
+	inst     *Instance
+	requests chan jsonRPCRequest
+	stdout   *io.PipeWriter
+	writeMu  sync.Mutex
+}
+
+func newFakeProcess(t *testing.T, maxConcurrency int) *fakeProcess {
+	t.Helper()
+	inst := newInstance(InstanceKey{ServerID: "fake"}, "", nil, nil, 0, maxConcurrency)
+	inst.state = StateReady
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	inst.stdin = inW
+	inst.rpc.open()
+	go inst.readLoop(outR, inst.done)
+
+	fp := &fakeProcess{inst: inst, requests: make(chan jsonRPCRequest, 16), stdout: outW}
+	go func() {
+		scanner := bufio.NewScanner(inR)
+		for scanner.Scan() {
+			var req jsonRPCRequest
+			if err := json.Unmarshal(scanner.Bytes(), &req); err == nil {
+				fp.requests <- req
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		_ = outW.Close()
+		_ = inW.Close()
+	})
+	return fp
+}
+
+func (fp *fakeProcess) respond(t *testing.T, req jsonRPCRequest, result string) {
+	t.Helper()
+	fp.writeMu.Lock()
+	defer fp.writeMu.Unlock()
+	line := fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":%s}`+"\n", req.ID, result)
+	if _, err := io.WriteString(fp.stdout, line); err != nil {
+		t.Fatalf("write response: %v", err)
+	}
+}
+
+func (fp *fakeProcess) next(t *testing.T) jsonRPCRequest {
+	t.Helper()
+	select {
+	case req := <-fp.requests:
+		return req
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for request")
+		return jsonRPCRequest{}
+	}
+}
+
+func TestCall_ConcurrentOutOfOrder(t *testing.T) {
+	fp := newFakeProcess(t, 4)
+
+	type result struct {
+		name string
+		data string
+		err  error
+	}
+	results := make(chan result, 2)
+	for _, name := range []string{"slow", "fast"} {
+		go func() {
+			params, _ := json.Marshal(map[string]string{"name": name})
+			data, err := fp.inst.Call(context.Background(), "tools/call", params)
+			results <- result{name, string(data), err}
+		}()
+	}
+
+	// Both requests are in flight before either is answered.
+	byName := make(map[string]jsonRPCRequest)
+	for range 2 {
+		req := fp.next(t)
+		var p struct{ Name string }
+		_ = json.Unmarshal(req.Params, &p)
+		byName[p.Name] = req
+	}
+	if fp.inst.getState() != StateBusy {
+		t.Errorf("state = %s, want busy", fp.inst.getState())
+	}
+
+	fp.respond(t, byName["fast"], `"fast-result"`)
+	if r := <-results; r.name != "fast" || r.data != `"fast-result"` || r.err != nil {
+		t.Fatalf("first result = %+v", r)
+	}
+	fp.respond(t, byName["slow"], `"slow-result"`)
+	if r := <-results; r.name != "slow" || r.data != `"slow-result"` || r.err != nil {
+		t.Fatalf("second result = %+v", r)
+	}
+	if fp.inst.getState() != StateIdle {
+		t.Errorf("state = %s, want idle", fp.inst.getState())
+	}
+}
+
+func TestCall_ConcurrencyLimit(t *testing.T) {
+	fp := newFakeProcess(t, 1)
+
+	done := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := fp.inst.Call(context.Background(), "tools/list", json.RawMessage(`{}`))
+			done <- err
+		}()
+	}
+
+	first := fp.next(t)
+	select {
+	case req := <-fp.requests:
+		t.Fatalf("request %s sent while limit was reached", req.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fp.respond(t, first, `{}`)
+	second := fp.next(t)
+	fp.respond(t, second, `{}`)
+
+	for range 2 {
+		if err := <-done; err != nil {
+			t.Errorf("call error: %v", err)
+		}
+	}
+}
+
+func TestCall_ContextCancelWhileWaitingForSlot(t *testing.T) {
+	fp := newFakeProcess(t, 1)
+
+	go func() { _, _ = fp.inst.Call(context.Background(), "tools/list", json.RawMessage(`{}`)) }()
+	fp.next(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := fp.inst.Call(ctx, "tools/list", json.RawMessage(`{}`)); err == nil {
+		t.Fatal("expected context error while waiting for a slot")
+	}
+}
+
+func TestCall_ForwardsCancellation(t *testing.T) {
+	fp := newFakeProcess(t, 1)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := fp.inst.Call(ctx, "tools/call", json.RawMessage(`{"name":"slow"}`))
+		done <- err
+	}()
+
+	call := fp.next(t)
+	cancel(errors.New("user pressed stop"))
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("call error = %v, want context.Canceled", err)
+	}
+
+	note := fp.next(t)
+	if note.Method != "notifications/cancelled" || note.ID != nil {
+		t.Fatalf("got %+v, want notifications/cancelled", note)
+	}
+	var params struct {
+		RequestID json.RawMessage `json:"requestId"`
+		Reason    string          `json:"reason"`
+	}
+	_ = json.Unmarshal(note.Params, &params)
+	if string(params.RequestID) != string(call.ID) || params.Reason != "user pressed stop" {
+		t.Errorf("params = %s", note.Params)
+	}
+
+	// A late response for the cancelled request is dropped and the slot
+	// is free again.
+	fp.respond(t, call, `{}`)
+	go func() {
+		_, err := fp.inst.Call(context.Background(), "tools/list", json.RawMessage(`{}`))
+		done <- err
+	}()
+	fp.respond(t, fp.next(t), `{}`)
+	if err := <-done; err != nil {
+		t.Errorf("next call: %v", err)
+	}
+}
+
+func TestCall_RelaysProgress(t *testing.T) {
+	fp := newFakeProcess(t, 1)
+
+	var got []Progress
+	ctx := WithProgressHandler(context.Background(), func(p Progress) { got = append(got, p) })
+	done := make(chan error, 1)
+	go func() {
+		_, err := fp.inst.Call(ctx, "tools/call", json.RawMessage(`{"name":"test","arguments":{}}`))
+		done <- err
+	}()
+
+	req := fp.next(t)
+	var params struct {
+		Name string `json:"name"`
+		Meta struct {
+			ProgressToken json.RawMessage `json:"progressToken"`
+		} `json:"_meta"`
+	}
+	_ = json.Unmarshal(req.Params, &params)
+	if params.Name != "test" || string(params.Meta.ProgressToken) != string(req.ID) {
+		t.Fatalf("params = %s, want progressToken %s", req.Params, req.ID)
+	}
+
+	fp.writeMu.Lock()
+	_, _ = fmt.Fprintf(fp.stdout,
+		`{"jsonrpc":"2.0","method":"notifications/progress","params":{"progressToken":%s,"progress":3,"total":10,"message":"running"}}`+"\n",
+		req.ID)
+	fp.writeMu.Unlock()
+	fp.respond(t, req, `{}`)
+
+	if err := <-done; err != nil {
+		t.Fatalf("call error: %v", err)
+	}
+	if len(got) != 1 || got[0].Progress != 3 || got[0].Total == nil || *got[0].Total != 10 || got[0].Message != "running" {
+		t.Errorf("progress = %+v", got)
+	}
+}
+
+func TestWithProgressToken(t *testing.T) {
+	tests := []struct {
+		params, want string
+	}{
+		{``, `{"_meta":{"progressToken":4}}`},
+		{`{"name":"x"}`, `{"_meta":{"progressToken":4},"name":"x"}`},
+		{`{"_meta":{"progressToken":"client","other":1}}`, `{"_meta":{"other":1,"progressToken":4}}`},
+		{`[1,2]`, `[1,2]`},
 	}
-	if !strings.Contains(err.Error(), "bad request") {
-		t.Errorf("error = %q, want to contain 'bad request'", err.Error())
+	for _, tt := range tests {
+		if got := string(withProgressToken(json.RawMessage(tt.params), json.RawMessage(`4`))); got != tt.want {
+			t.Errorf("withProgressToken(%s) = %s, want %s", tt.params, got, tt.want)
+		}
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,34 +17,67 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-// downstream is the common interface for stdio and HTTP MCP instances.
+// downstream is the common interface for stdio and remote MCP instances.
 type downstream interface {
 	start(ctx context.Context) error
 	stop()
 	ListTools(ctx context.Context) (json.RawMessage, error)
 	Call(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error)
 	getState() InstanceState
+	load() int // requests currently in flight
+	hold()     // keep running while idle, until release
+	release()
+}
+
+// IsRemoteTransport reports whether servers with transport t are reached
+// over the network: "http" (Streamable HTTP), "sse" (legacy HTTP+SSE) or
+// "websocket".
+func IsRemoteTransport(t string) bool {
+	return t == "http" || t == "sse" || t == "websocket"
 }
 
 // Manager orchestrates downstream MCP server process lifecycles.
 type Manager struct {
-	store     store.Store
-	auth      *auth.Injector
-	mu        sync.Mutex
-	instances map[InstanceKey]downstream
+	store store.Store
+	auth  *auth.Injector
+	mu    sync.Mutex
+	pools map[InstanceKey]*pool
+
+	healthMu sync.Mutex
+	health   map[InstanceKey]*processHealth
+
+	subs resourceSubscriptions
 
 	// OnToolsChanged is called when a downstream server sends
 	// notifications/tools/list_changed. The gateway uses this to
 	// invalidate caches and propagate the notification upstream.
 	OnToolsChanged func()
+
+	// OnResourcesChanged is called when a downstream server sends
+	// notifications/resources/list_changed.
+	OnResourcesChanged func()
+
+	// OnPromptsChanged is called when a downstream server sends
+	// notifications/prompts/list_changed.
+	OnPromptsChanged func()
+
+	// OnResourceUpdated is called when a downstream server sends
+	// notifications/resources/updated for a subscribed resource. The uri
+	// is the downstream's original (un-namespaced) resource URI.
+	OnResourceUpdated func(serverID, uri string)
+
+	// OnProcessEvent is called when a downstream process crashes, fails to
+	// start, is restarted or enters a crash loop.
+	OnProcessEvent func(ProcessEvent)
 }
 
 // NewManager creates a new downstream process manager.
 func NewManager(s store.Store, authInj *auth.Injector) *Manager {
 	return &Manager{
-		store:     s,
-		auth:      authInj,
-		instances: make(map[InstanceKey]downstream),
+		store:  s,
+		auth:   authInj,
+		pools:  make(map[InstanceKey]*pool),
+		health: make(map[InstanceKey]*processHealth),
 	}
 }
 
@@ -54,9 +88,7 @@ func (m *Manager) Call(
 	serverID, authScopeID, toolName string,
 	args json.RawMessage,
 ) (json.RawMessage, error) {
-	key := InstanceKey{ServerID: serverID, AuthScopeID: authScopeID}
-
-	inst, err := m.getOrStart(ctx, key)
+	inst, err := m.getOrStart(ctx, serverID, authScopeID)
 	if err != nil {
 		return nil, fmt.Errorf("get or start instance: %w", err)
 	}
@@ -72,55 +104,149 @@ func (m *Manager) Call(
 	return inst.Call(ctx, "tools/call", json.RawMessage(params))
 }
 
-func (m *Manager) getOrStart(ctx context.Context, key InstanceKey) (downstream, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// InstanceScope returns the scope of the instance that would serve a call
+// to serverID made with ctx: empty for a global server, or the workspace
+// or session otherwise.
+func (m *Manager) InstanceScope(ctx context.Context, serverID string) string {
+	server, err := m.store.GetDownstreamServer(ctx, serverID)
+	if err != nil {
+		return ""
+	}
+	key, err := instanceKey(server, "", callerFrom(ctx))
+	if err != nil {
+		return ""
+	}
+	return key.Scope
+}
 
-	if inst, ok := m.instances[key]; ok {
-		if inst.getState() != StateStopped {
-			return inst, nil
+// getOrStart returns the pool member a request should use, starting a
+// process if the pool is empty or saturated. The pool is chosen by the
+// server's instance scope and the caller in ctx.
+func (m *Manager) getOrStart(ctx context.Context, serverID, authScopeID string) (downstream, error) {
+	key := InstanceKey{ServerID: serverID, AuthScopeID: authScopeID}
+	caller := callerFrom(ctx)
+	if server, err := m.store.GetDownstreamServer(ctx, serverID); err == nil {
+		if key, err = instanceKey(server, authScopeID, caller); err != nil {
+			return nil, err
 		}
-		// Instance stopped (idle timeout or crash); remove and restart.
-		delete(m.instances, key)
 	}
 
-	inst, err := m.createInstance(ctx, key)
+	m.mu.Lock()
+	p, ok := m.pools[key]
+	if !ok {
+		p = newPool(key)
+		p.caller = caller
+		m.pools[key] = p
+	}
+	m.mu.Unlock()
+
+	return p.acquire(ctx, func(ctx context.Context) (downstream, int, error) {
+		return m.startInstance(ctx, key)
+	})
+}
+
+// startInstance creates and starts a new instance for key, returning it
+// with the pool size configured for its server.
+func (m *Manager) startInstance(ctx context.Context, key InstanceKey) (downstream, int, error) {
+	server, err := m.store.GetDownstreamServer(ctx, key.ServerID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get server %s: %w", key.ServerID, err)
+	}
+
+	if err := m.checkRestart(ctx, key, server.RestartPolicy); err != nil {
+		return nil, 0, err
+	}
+
+	inst, err := m.createInstance(ctx, key, server)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if err := inst.start(ctx); err != nil {
-		return nil, fmt.Errorf("start instance: %w", err)
+		var stderr string
+		if si, ok := inst.(*Instance); ok {
+			stderr = si.stderrTail()
+		}
+		if ctx.Err() == nil {
+			m.recordStartFailure(key, err, stderr)
+		}
+		if line := lastLine(stderr); line != "" {
+			return nil, 0, fmt.Errorf("start instance: %w (stderr: %s)", err, line)
+		}
+		return nil, 0, fmt.Errorf("start instance: %w", err)
 	}
+	m.recordStart(key)
+	return inst, poolSize(server), nil
+}
 
-	m.instances[key] = inst
-	return inst, nil
+// lastLine returns the last non-empty line of s.
+func lastLine(s string) string {
+	s = strings.TrimRight(s, "\n")
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
 }
 
-func (m *Manager) createInstance(
-	ctx context.Context, key InstanceKey,
-) (downstream, error) {
-	server, err := m.store.GetDownstreamServer(ctx, key.ServerID)
-	if err != nil {
-		return nil, fmt.Errorf("get server %s: %w", key.ServerID, err)
+// authResolver returns a server's current auth headers. Long-lived
+// connections call it each time they connect, so a reconnect picks up a
+// refreshed OAuth token instead of retrying an expired one.
+type authResolver func(ctx context.Context) (http.Header, error)
+
+// poolSize is the number of instances a server may run per auth scope.
+// Remote servers have no process to scale out.
+func poolSize(server *store.DownstreamServer) int {
+	if IsRemoteTransport(server.Transport) || server.MaxInstances < 1 {
+		return 1
 	}
+	return server.MaxInstances
+}
 
+func (m *Manager) createInstance(
+	ctx context.Context, key InstanceKey, server *store.DownstreamServer,
+) (downstream, error) {
 	if server.Disabled {
 		return nil, fmt.Errorf("downstream server %q is disabled", server.Name)
 	}
 
 	timeout := time.Duration(server.IdleTimeoutSec) * time.Second
 
-	if server.Transport == "http" && server.URL != nil {
+	if IsRemoteTransport(server.Transport) && server.URL != nil {
+		static, err := ParseHeaders(server.Headers)
+		if err != nil {
+			return nil, err
+		}
 		var headers http.Header
 		if m.auth != nil && key.AuthScopeID != "" {
-			var err error
 			headers, err = m.auth.HeadersForDownstream(ctx, key.AuthScopeID)
 			if err != nil {
 				return nil, fmt.Errorf("resolve auth for scope %s: %w", key.AuthScopeID, err)
 			}
 		}
-		return newHTTPInstance(key, *server.URL, timeout, headers), nil
+		onNotify := func(method string, params json.RawMessage) {
+			m.handleDownstreamNotify(key, method, params)
+		}
+		var resolveAuth authResolver
+		if m.auth != nil && key.AuthScopeID != "" {
+			resolveAuth = func(ctx context.Context) (http.Header, error) {
+				return m.auth.HeadersForDownstream(ctx, key.AuthScopeID)
+			}
+		}
+		switch server.Transport {
+		case "sse":
+			inst := newSSEInstance(key, *server.URL, timeout, static, headers)
+			inst.onNotify = onNotify
+			inst.resolveAuth = resolveAuth
+			return inst, nil
+		case "websocket":
+			inst := newWebSocketInstance(key, *server.URL, timeout, static, headers)
+			inst.onNotify = onNotify
+			inst.resolveAuth = resolveAuth
+			return inst, nil
+		}
+		inst := newHTTPInstance(key, *server.URL, timeout, static, headers)
+		inst.onNotify = onNotify
+		return inst, nil
 	}
 
 	// Default: stdio transport
@@ -131,6 +257,23 @@ func (m *Manager) createInstance(
 		}
 	}
 
+	if err := ValidateWorkspaceRoot(server.InstanceScope, server.Cwd, cmdArgs); err != nil {
+		return nil, fmt.Errorf("server %q: %w", server.Name, err)
+	}
+	caller := callerFrom(ctx)
+	if caller.WorkspaceRoot == "" && usesWorkspaceRoot(server.Cwd, cmdArgs) {
+		return nil, fmt.Errorf("server %q uses %s, but the call has no workspace", server.Name, WorkspaceRootVar)
+	}
+	cmdArgs = expandArgs(cmdArgs, caller.WorkspaceRoot)
+	serverEnv, err := ParseEnv(server.Env)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := resolveCwd(server, caller)
+	if err != nil {
+		return nil, err
+	}
+
 	var authEnv map[string]string
 	if m.auth != nil {
 		var err error
@@ -140,10 +283,17 @@ func (m *Manager) createInstance(
 				"scope", key.AuthScopeID, "error", err)
 		}
 	}
-	env := MergeEnv(os.Environ(), nil, authEnv)
+	env := MergeEnv(os.Environ(), serverEnv, authEnv)
 
-	inst := newInstance(key, server.Command, cmdArgs, env, timeout)
-	inst.onNotify = m.handleDownstreamNotify
+	inst := newInstance(key, server.Command, cmdArgs, env, timeout, server.MaxConcurrency)
+	inst.dir = dir
+	inst.onNotify = func(method string, params json.RawMessage) {
+		m.handleDownstreamNotify(key, method, params)
+	}
+	policy := server.RestartPolicy
+	inst.onExit = func(err error, ranFor time.Duration, stderr string) {
+		m.handleExit(key, policy, err, ranFor, stderr)
+	}
 	return inst, nil
 }
 
@@ -151,9 +301,7 @@ func (m *Manager) createInstance(
 func (m *Manager) ListTools(
 	ctx context.Context, serverID, authScopeID string,
 ) (json.RawMessage, error) {
-	key := InstanceKey{ServerID: serverID, AuthScopeID: authScopeID}
-
-	inst, err := m.getOrStart(ctx, key)
+	inst, err := m.getOrStart(ctx, serverID, authScopeID)
 	if err != nil {
 		return nil, fmt.Errorf("get or start instance: %w", err)
 	}
@@ -177,6 +325,234 @@ func (m *Manager) ListAllTools(ctx context.Context) (map[string]json.RawMessage,
 
 // ListToolsForServers queries specific downstream servers for their tools in parallel.
 func (m *Manager) ListToolsForServers(ctx context.Context, serverIDs []string) (map[string]json.RawMessage, error) {
+	return m.fanOut(ctx, serverIDs, "tools/list", func(ctx context.Context, id, authScope string) (json.RawMessage, error) {
+		return m.ListTools(ctx, id, authScope)
+	})
+}
+
+// ListResourcesForServers queries specific downstream servers for their
+// resources in parallel. Servers that do not support resources are omitted.
+func (m *Manager) ListResourcesForServers(ctx context.Context, serverIDs []string) (map[string]json.RawMessage, error) {
+	return m.fanOut(ctx, serverIDs, "resources/list", func(ctx context.Context, id, authScope string) (json.RawMessage, error) {
+		return m.request(ctx, id, authScope, "resources/list", json.RawMessage(`{}`))
+	})
+}
+
+// ListResourceTemplatesForServers queries specific downstream servers for
+// their resource templates in parallel.
+func (m *Manager) ListResourceTemplatesForServers(ctx context.Context, serverIDs []string) (map[string]json.RawMessage, error) {
+	return m.fanOut(ctx, serverIDs, "resources/templates/list", func(ctx context.Context, id, authScope string) (json.RawMessage, error) {
+		return m.request(ctx, id, authScope, "resources/templates/list", json.RawMessage(`{}`))
+	})
+}
+
+// ReadResource sends a resources/read request for uri to a downstream instance.
+func (m *Manager) ReadResource(
+	ctx context.Context, serverID, authScopeID, uri string,
+) (json.RawMessage, error) {
+	params, err := json.Marshal(map[string]string{"uri": uri})
+	if err != nil {
+		return nil, fmt.Errorf("marshal read params: %w", err)
+	}
+	return m.request(ctx, serverID, authScopeID, "resources/read", params)
+}
+
+// SubscribeResource sends resources/subscribe (or resources/unsubscribe when
+// subscribe is false) for uri to a downstream instance. Instances are
+// shared between sessions, so subscribers are counted and only the first
+// subscribe and the last unsubscribe reach the server. The unsubscribe
+// goes to the pool member that took the subscribe, which stays up until
+// then.
+func (m *Manager) SubscribeResource(
+	ctx context.Context, serverID, authScopeID, uri string, subscribe bool,
+) error {
+	key := subscriptionKey{
+		ServerID:    serverID,
+		AuthScopeID: authScopeID,
+		Scope:       m.InstanceScope(ctx, serverID),
+		URI:         uri,
+	}
+	params, err := json.Marshal(map[string]string{"uri": uri})
+	if err != nil {
+		return fmt.Errorf("marshal subscribe params: %w", err)
+	}
+	if !subscribe {
+		return m.subs.remove(key, func(inst downstream) error {
+			_, err := inst.Call(ctx, "resources/unsubscribe", params)
+			return err
+		})
+	}
+	return m.subs.add(key, func() (downstream, error) {
+		inst, err := m.getOrStart(ctx, serverID, authScopeID)
+		if err != nil {
+			return nil, fmt.Errorf("get or start instance: %w", err)
+		}
+		if _, err := inst.Call(ctx, "resources/subscribe", params); err != nil {
+			return nil, err
+		}
+		return inst, nil
+	})
+}
+
+// subscriptionKey identifies a resource on one instance pool.
+type subscriptionKey struct {
+	ServerID    string
+	AuthScopeID string
+	Scope       string
+	URI         string
+}
+
+// resourceSubscriptions counts the sessions subscribed to each downstream
+// resource and remembers which pool member holds the subscription.
+type resourceSubscriptions struct {
+	mu      sync.Mutex
+	entries map[subscriptionKey]*subscription
+}
+
+// subscription is one resource's entry. Its mu serializes the requests
+// for that resource, so that a subscribe and an unsubscribe cannot reach
+// the server out of order; other resources are not held up.
+type subscription struct {
+	mu    sync.Mutex
+	count int        // subscribed sessions
+	inst  downstream // member the subscribe went to; held while count > 0
+	users int        // updates holding or waiting for mu; guarded by resourceSubscriptions.mu
+}
+
+// lock returns key's entry with its mu held, creating it if needed.
+func (s *resourceSubscriptions) lock(key subscriptionKey) *subscription {
+	s.mu.Lock()
+	if s.entries == nil {
+		s.entries = make(map[subscriptionKey]*subscription)
+	}
+	e := s.entries[key]
+	if e == nil {
+		e = &subscription{}
+		s.entries[key] = e
+	}
+	e.users++
+	s.mu.Unlock()
+
+	e.mu.Lock()
+	return e
+}
+
+// unlock releases e, dropping it once it has no subscribers or users.
+func (s *resourceSubscriptions) unlock(key subscriptionKey, e *subscription) {
+	s.mu.Lock()
+	e.users--
+	if e.users == 0 && e.count == 0 {
+		delete(s.entries, key)
+	}
+	s.mu.Unlock()
+	e.mu.Unlock()
+}
+
+// add records a subscriber for key. The first one, or the first after
+// the pinned member stopped and took the subscription with it, calls
+// subscribe, which returns the member it subscribed on. The count only
+// changes if subscribe succeeds.
+func (s *resourceSubscriptions) add(key subscriptionKey, subscribe func() (downstream, error)) error {
+	e := s.lock(key)
+	defer s.unlock(key, e)
+
+	if e.count > 0 && !stopped(e.inst) {
+		e.count++
+		return nil
+	}
+	inst, err := subscribe()
+	if err != nil {
+		return err
+	}
+	inst.hold()
+	if e.inst != nil {
+		e.inst.release()
+	}
+	e.inst = inst
+	e.count++
+	return nil
+}
+
+// remove drops a subscriber for key. The last one calls unsubscribe on
+// the member holding the subscription, unless it has stopped and the
+// subscription went with it. The count only changes if unsubscribe
+// succeeds.
+func (s *resourceSubscriptions) remove(key subscriptionKey, unsubscribe func(downstream) error) error {
+	e := s.lock(key)
+	defer s.unlock(key, e)
+
+	switch {
+	case e.count == 0:
+		return nil // nothing to release
+	case e.count > 1:
+		e.count--
+		return nil
+	}
+	if !stopped(e.inst) {
+		if err := unsubscribe(e.inst); err != nil {
+			return err
+		}
+	}
+	e.inst.release()
+	e.inst = nil
+	e.count = 0
+	return nil
+}
+
+// stopped reports whether inst has stopped or is stopping.
+func stopped(inst downstream) bool {
+	switch inst.getState() {
+	case StateStopped, StateStopping:
+		return true
+	}
+	return false
+}
+
+// ListPromptsForServers queries specific downstream servers for their
+// prompts in parallel. Servers that do not support prompts are omitted.
+func (m *Manager) ListPromptsForServers(ctx context.Context, serverIDs []string) (map[string]json.RawMessage, error) {
+	return m.fanOut(ctx, serverIDs, "prompts/list", func(ctx context.Context, id, authScope string) (json.RawMessage, error) {
+		return m.request(ctx, id, authScope, "prompts/list", json.RawMessage(`{}`))
+	})
+}
+
+// GetPrompt sends a prompts/get request for the (un-namespaced) prompt name
+// to a downstream instance.
+func (m *Manager) GetPrompt(
+	ctx context.Context, serverID, authScopeID, name string, args json.RawMessage,
+) (json.RawMessage, error) {
+	p := map[string]any{"name": name}
+	if len(args) > 0 {
+		p["arguments"] = args
+	}
+	params, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("marshal prompt params: %w", err)
+	}
+	return m.request(ctx, serverID, authScopeID, "prompts/get", params)
+}
+
+// request sends an arbitrary JSON-RPC request to a downstream instance,
+// lazy-starting it if needed.
+func (m *Manager) request(
+	ctx context.Context, serverID, authScopeID, method string, params json.RawMessage,
+) (json.RawMessage, error) {
+	inst, err := m.getOrStart(ctx, serverID, authScopeID)
+	if err != nil {
+		return nil, fmt.Errorf("get or start instance: %w", err)
+	}
+
+	return inst.Call(ctx, method, params)
+}
+
+// fanOut runs fn for each server in parallel and collects successful results
+// keyed by server ID. Per-server failures are logged and skipped.
+func (m *Manager) fanOut(
+	ctx context.Context,
+	serverIDs []string,
+	method string,
+	fn func(ctx context.Context, serverID, authScopeID string) (json.RawMessage, error),
+) (map[string]json.RawMessage, error) {
 	// Resolve auth scopes for each server from route rules so that
 	// HTTP downstreams get proper Authorization headers during discovery.
 	scopeByServer := m.resolveAuthScopes(ctx, serverIDs)
@@ -188,13 +564,14 @@ func (m *Manager) ListToolsForServers(ctx context.Context, serverIDs []string) (
 	for _, id := range serverIDs {
 		authScope := scopeByServer[id]
 		g.Go(func() error {
-			tools, err := m.ListTools(gCtx, id, authScope)
+			data, err := fn(gCtx, id, authScope)
 			if err != nil {
-				slog.Warn("failed to list tools", "server", id, "error", err)
+				slog.Warn("downstream request failed",
+					"server", id, "method", method, "error", err)
 				return nil
 			}
 			mu.Lock()
-			result[id] = tools
+			result[id] = data
 			mu.Unlock()
 			return nil
 		})
@@ -230,50 +607,110 @@ func (m *Manager) resolveAuthScopes(ctx context.Context, serverIDs []string) map
 
 // handleDownstreamNotify is called when a downstream instance receives a
 // notification (e.g. notifications/tools/list_changed).
-func (m *Manager) handleDownstreamNotify(method string) {
-	if method == "notifications/tools/list_changed" && m.OnToolsChanged != nil {
-		m.OnToolsChanged()
+func (m *Manager) handleDownstreamNotify(key InstanceKey, method string, params json.RawMessage) {
+	switch method {
+	case "notifications/tools/list_changed":
+		if m.OnToolsChanged != nil {
+			m.OnToolsChanged()
+		}
+	case "notifications/prompts/list_changed":
+		if m.OnPromptsChanged != nil {
+			m.OnPromptsChanged()
+		}
+	case "notifications/resources/list_changed":
+		if m.OnResourcesChanged != nil {
+			m.OnResourcesChanged()
+		}
+	case "notifications/resources/updated":
+		if m.OnResourceUpdated == nil {
+			return
+		}
+		var p struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil || p.URI == "" {
+			return
+		}
+		m.OnResourceUpdated(key.ServerID, p.URI)
 	}
 }
 
 // InstanceInfo describes a running downstream instance for status reporting.
 type InstanceInfo struct {
-	Key   InstanceKey
-	State InstanceState
+	Key      InstanceKey
+	Index    int // position within the server's instance pool
+	State    InstanceState
+	InFlight int // requests awaiting a response
 }
 
-// ListInstances returns info about all tracked (non-stopped) instances.
+// ListInstances returns info about all tracked (non-stopped) instances,
+// one entry per pool member.
 func (m *Manager) ListInstances() []InstanceInfo {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	pools := make([]*pool, 0, len(m.pools))
+	for _, p := range m.pools {
+		pools = append(pools, p)
+	}
+	m.mu.Unlock()
 
-	out := make([]InstanceInfo, 0, len(m.instances))
-	for key, inst := range m.instances {
-		s := inst.getState()
-		if s == StateStopped {
-			continue
+	var out []InstanceInfo
+	for _, p := range pools {
+		for i, inst := range p.snapshot() {
+			s := inst.getState()
+			if s == StateStopped {
+				continue
+			}
+			out = append(out, InstanceInfo{
+				Key:      p.key,
+				Index:    i,
+				State:    s,
+				InFlight: inst.load(),
+			})
 		}
-		out = append(out, InstanceInfo{Key: key, State: s})
 	}
 	return out
 }
 
-// Shutdown gracefully stops all running instances.
-func (m *Manager) Shutdown(ctx context.Context) error {
+// ReleaseSession stops and forgets the instances of session-scoped servers
+// started for sessionID.
+func (m *Manager) ReleaseSession(sessionID string) {
+	scope := ScopeSession + ":" + sessionID
 	m.mu.Lock()
-	instances := make([]downstream, 0, len(m.instances))
-	for _, inst := range m.instances {
-		instances = append(instances, inst)
+	var instances []downstream
+	for key, p := range m.pools {
+		if key.Scope == scope {
+			instances = append(instances, p.snapshot()...)
+			delete(m.pools, key)
+		}
 	}
 	m.mu.Unlock()
 
+	m.healthMu.Lock()
+	for key := range m.health {
+		if key.Scope == scope {
+			delete(m.health, key)
+		}
+	}
+	m.healthMu.Unlock()
+
 	for _, inst := range instances {
 		inst.stop()
 	}
+}
 
+// Shutdown gracefully stops all running instances.
+func (m *Manager) Shutdown(ctx context.Context) error {
 	m.mu.Lock()
-	m.instances = make(map[InstanceKey]downstream)
+	var instances []downstream
+	for _, p := range m.pools {
+		instances = append(instances, p.snapshot()...)
+	}
+	m.pools = make(map[InstanceKey]*pool)
 	m.mu.Unlock()
+
+	for _, inst := range instances {
+		inst.stop()
+	}
 	return nil
 }
 
@@ -293,6 +730,18 @@ type jsonRPCResponse struct {
 	Error   *jsonRPCError   `json:"error,omitempty"`
 }
 
+// jsonRPCMessage is any inbound JSON-RPC message: a response (id, no
+// method), a notification (method, no id) or a server-initiated request
+// (both id and method).
+type jsonRPCMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+}
+
 type jsonRPCError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
@@ -307,4 +756,3 @@ func writeJSONLine(w io.Writer, v any) error {
 	_, err = w.Write(data)
 	return err
 }
-
@@ -0,0 +1,119 @@
+package downstream
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResourceSubscriptions_CountsSessions(t *testing.T) {
+	var subs resourceSubscriptions
+	var sent []bool
+	member := &fakeMember{state: StateReady}
+	subscribe := func(key subscriptionKey) {
+		t.Helper()
+		err := subs.add(key, func() (downstream, error) {
+			sent = append(sent, true)
+			return member, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	unsubscribe := func(key subscriptionKey) {
+		t.Helper()
+		err := subs.remove(key, func(downstream) error {
+			sent = append(sent, false)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	file := subscriptionKey{ServerID: "fs", URI: "file:///a"}
+
+	subscribe(file)   // session A
+	subscribe(file)   // session B
+	unsubscribe(file) // session A leaves; B still subscribed
+	if len(sent) != 1 || !sent[0] {
+		t.Fatalf("sent = %v, want only the first subscribe", sent)
+	}
+	unsubscribe(file) // session B leaves
+	unsubscribe(file) // nothing left to release
+	if len(sent) != 2 || sent[1] {
+		t.Fatalf("sent = %v, want an unsubscribe after the last session", sent)
+	}
+
+	// Another instance scope is a separate subscription.
+	subscribe(subscriptionKey{ServerID: "fs", Scope: "session:s2", URI: "file:///a"})
+	if len(sent) != 3 {
+		t.Fatalf("sent = %v, want a subscribe for the scoped instance", sent)
+	}
+}
+
+func TestResourceSubscriptions_FailedSendNotCounted(t *testing.T) {
+	var subs resourceSubscriptions
+	key := subscriptionKey{ServerID: "fs", URI: "file:///a"}
+	if err := subs.add(key, func() (downstream, error) { return nil, errors.New("down") }); err == nil {
+		t.Fatal("expected send error")
+	}
+	calls := 0
+	err := subs.add(key, func() (downstream, error) {
+		calls++
+		return &fakeMember{state: StateReady}, nil
+	})
+	if err != nil || calls != 1 {
+		t.Fatalf("retry sent %d times, err %v; want the subscribe sent again", calls, err)
+	}
+}
+
+func TestResourceSubscriptions_PinnedToMember(t *testing.T) {
+	var subs resourceSubscriptions
+	key := subscriptionKey{ServerID: "fs", URI: "file:///a"}
+	first := &fakeMember{state: StateReady}
+	if err := subs.add(key, func() (downstream, error) { return first, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if first.holds != 1 {
+		t.Fatalf("holds = %d, want the subscribed member kept from idling", first.holds)
+	}
+
+	var got downstream
+	if err := subs.remove(key, func(inst downstream) error { got = inst; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if got != first {
+		t.Error("unsubscribe did not go to the member that took the subscribe")
+	}
+	if first.holds != 0 {
+		t.Errorf("holds = %d after the last unsubscribe, want 0", first.holds)
+	}
+}
+
+func TestResourceSubscriptions_ResubscribesAfterMemberStops(t *testing.T) {
+	var subs resourceSubscriptions
+	key := subscriptionKey{ServerID: "fs", URI: "file:///a"}
+	first, second := &fakeMember{state: StateReady}, &fakeMember{state: StateReady}
+	if err := subs.add(key, func() (downstream, error) { return first, nil }); err != nil {
+		t.Fatal(err)
+	}
+	first.setState(StateStopped) // crashed, taking the subscription with it
+
+	if err := subs.add(key, func() (downstream, error) { return second, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if second.holds != 1 || first.holds != 0 {
+		t.Fatalf("holds = %d, %d; want the subscription moved to the new member", first.holds, second.holds)
+	}
+
+	// Both sessions are now subscribed on the new member.
+	calls := 0
+	unsubscribe := func(downstream) error { calls++; return nil }
+	for range 2 {
+		if err := subs.remove(key, unsubscribe); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 || second.holds != 0 {
+		t.Errorf("unsubscribes = %d, holds = %d; want one unsubscribe releasing the member", calls, second.holds)
+	}
+}
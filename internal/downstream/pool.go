@@ -0,0 +1,129 @@
+package downstream
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// spawnFunc creates and starts a new pool member. It also returns the
+// pool size configured for the server so changes to max_instances take
+// effect on the next start.
+type spawnFunc func(ctx context.Context) (downstream, int, error)
+
+// pool holds the running instances for one InstanceKey. Calls go to the
+// least-busy member; when every member is busy and the pool is below its
+// size, another member is started. Members stop on their own idle timeout
+// and are pruned on the next acquire, so the pool shrinks back as load
+// drops.
+type pool struct {
+	key    InstanceKey
+	caller Caller // session the pool was created for; restarts reuse it
+
+	startMu sync.Mutex // serializes starting the first member
+
+	mu       sync.Mutex
+	members  []downstream
+	size     int // max members, from the server's max_instances
+	starting int // members being started outside mu
+}
+
+func newPool(key InstanceKey) *pool {
+	return &pool{key: key, size: 1}
+}
+
+// acquire returns the member a new call should use, starting one if needed.
+func (p *pool) acquire(ctx context.Context, spawn spawnFunc) (downstream, error) {
+	p.mu.Lock()
+	p.prune()
+	if len(p.members) == 0 {
+		p.mu.Unlock()
+		return p.startFirst(ctx, spawn)
+	}
+
+	best, load := p.leastBusy()
+	if load == 0 || len(p.members)+p.starting >= p.size {
+		p.mu.Unlock()
+		return best, nil
+	}
+	p.starting++
+	p.mu.Unlock()
+
+	inst, size, err := spawn(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.starting--
+	if err != nil {
+		// The pool still has a working member, so a failed scale-up
+		// only costs throughput.
+		slog.Warn("failed to start additional downstream instance",
+			"server", p.key.ServerID, "error", err)
+		return best, nil
+	}
+	p.size = size
+	p.members = append(p.members, inst)
+	return inst, nil
+}
+
+// startFirst starts a member for an empty pool. Concurrent callers wait
+// for the same start instead of each launching a process.
+func (p *pool) startFirst(ctx context.Context, spawn spawnFunc) (downstream, error) {
+	p.startMu.Lock()
+	defer p.startMu.Unlock()
+
+	p.mu.Lock()
+	p.prune()
+	if len(p.members) > 0 {
+		best, _ := p.leastBusy()
+		p.mu.Unlock()
+		return best, nil
+	}
+	p.mu.Unlock()
+
+	inst, size, err := spawn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.size = size
+	p.members = append(p.members, inst)
+	return inst, nil
+}
+
+// leastBusy returns the member with the fewest in-flight requests. Ties go
+// to the earliest member so that later ones receive traffic only under
+// load and can idle out. Callers must hold p.mu and have pruned the pool.
+func (p *pool) leastBusy() (downstream, int) {
+	var best downstream
+	bestLoad := 0
+	for _, inst := range p.members {
+		load := inst.load()
+		if best == nil || load < bestLoad {
+			best, bestLoad = inst, load
+		}
+	}
+	return best, bestLoad
+}
+
+// prune drops members that have stopped (idle timeout or crash) or are
+// shutting down. Callers must hold p.mu.
+func (p *pool) prune() {
+	live := p.members[:0]
+	for _, inst := range p.members {
+		if !stopped(inst) {
+			live = append(live, inst)
+		}
+	}
+	clear(p.members[len(live):])
+	p.members = live
+}
+
+// snapshot returns the current members in pool order.
+func (p *pool) snapshot() []downstream {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]downstream(nil), p.members...)
+}
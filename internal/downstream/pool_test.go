@@ -0,0 +1,232 @@
+package downstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// fakeMember is a pool member with settable state and load.
+type fakeMember struct {
+	mu       sync.Mutex
+	state    InstanceState
+	inFlight int
+	holds    int
+}
+
+func (f *fakeMember) start(context.Context) error { return nil }
+func (f *fakeMember) stop()                       { f.setState(StateStopped) }
+
+func (f *fakeMember) ListTools(context.Context) (json.RawMessage, error) { return nil, nil }
+
+func (f *fakeMember) Call(context.Context, string, json.RawMessage) (json.RawMessage, error) {
+	return nil, nil
+}
+
+func (f *fakeMember) getState() InstanceState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state
+}
+
+func (f *fakeMember) setState(s InstanceState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state = s
+}
+
+func (f *fakeMember) load() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.inFlight
+}
+
+func (f *fakeMember) hold() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.holds++
+}
+
+func (f *fakeMember) release() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.holds--
+}
+
+func (f *fakeMember) setLoad(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight = n
+}
+
+// fakeSpawner starts fakeMembers and reports a fixed pool size.
+type fakeSpawner struct {
+	size    int
+	err     error
+	spawned []*fakeMember
+}
+
+func (s *fakeSpawner) spawn(context.Context) (downstream, int, error) {
+	if s.err != nil {
+		return nil, 0, s.err
+	}
+	m := &fakeMember{state: StateReady}
+	s.spawned = append(s.spawned, m)
+	return m, s.size, nil
+}
+
+func TestPool_StartsFirstMember(t *testing.T) {
+	p := newPool(InstanceKey{ServerID: "s"})
+	sp := &fakeSpawner{size: 3}
+
+	inst, err := p.acquire(context.Background(), sp.spawn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sp.spawned) != 1 || inst != sp.spawned[0] {
+		t.Fatalf("spawned %d members", len(sp.spawned))
+	}
+
+	// An idle member is reused.
+	again, _ := p.acquire(context.Background(), sp.spawn)
+	if again != inst || len(sp.spawned) != 1 {
+		t.Errorf("idle member was not reused (spawned %d)", len(sp.spawned))
+	}
+}
+
+func TestPool_ScalesUpUnderLoad(t *testing.T) {
+	p := newPool(InstanceKey{ServerID: "s"})
+	sp := &fakeSpawner{size: 2}
+
+	first, _ := p.acquire(context.Background(), sp.spawn)
+	first.(*fakeMember).setLoad(1)
+
+	second, _ := p.acquire(context.Background(), sp.spawn)
+	if second == first || len(sp.spawned) != 2 {
+		t.Fatalf("expected a second member, spawned %d", len(sp.spawned))
+	}
+	second.(*fakeMember).setLoad(3)
+
+	// At size, calls go to the least-busy member.
+	got, _ := p.acquire(context.Background(), sp.spawn)
+	if got != first {
+		t.Error("expected least-busy member")
+	}
+	if len(sp.spawned) != 2 {
+		t.Errorf("pool grew past size: %d members", len(sp.spawned))
+	}
+}
+
+func TestPool_PrefersEarliestIdleMember(t *testing.T) {
+	p := newPool(InstanceKey{ServerID: "s"})
+	a, b := &fakeMember{state: StateIdle}, &fakeMember{state: StateIdle}
+	p.members = []downstream{a, b}
+	p.size = 2
+
+	got, _ := p.acquire(context.Background(), (&fakeSpawner{}).spawn)
+	if got != a {
+		t.Error("expected first member so later ones can idle out")
+	}
+}
+
+func TestPool_PrunesStoppedMembers(t *testing.T) {
+	p := newPool(InstanceKey{ServerID: "s"})
+	sp := &fakeSpawner{size: 2}
+
+	first, _ := p.acquire(context.Background(), sp.spawn)
+	first.(*fakeMember).setLoad(1)
+	second, _ := p.acquire(context.Background(), sp.spawn)
+
+	// The extra member hits its idle timeout.
+	second.stop()
+	if got, _ := p.acquire(context.Background(), sp.spawn); got == second {
+		t.Error("stopped member was returned")
+	}
+	if n := len(p.snapshot()); n != 2 {
+		t.Errorf("members = %d, want 2 after replacing the stopped one", n)
+	}
+
+	for _, inst := range p.snapshot() {
+		inst.stop()
+	}
+	if _, err := p.acquire(context.Background(), sp.spawn); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(p.snapshot()); n != 1 {
+		t.Errorf("members = %d, want 1 after restart", n)
+	}
+}
+
+func TestPool_ScaleUpFailureFallsBack(t *testing.T) {
+	p := newPool(InstanceKey{ServerID: "s"})
+	busy := &fakeMember{state: StateBusy, inFlight: 2}
+	p.members = []downstream{busy}
+	p.size = 2
+
+	got, err := p.acquire(context.Background(), (&fakeSpawner{err: errors.New("boom")}).spawn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != busy {
+		t.Error("expected the existing member after a failed scale-up")
+	}
+}
+
+func TestPool_FirstStartError(t *testing.T) {
+	p := newPool(InstanceKey{ServerID: "s"})
+	if _, err := p.acquire(context.Background(), (&fakeSpawner{err: errors.New("boom")}).spawn); err == nil {
+		t.Fatal("expected error when no member can start")
+	}
+}
+
+func TestManager_ListInstancesPerMember(t *testing.T) {
+	m := NewManager(nil, nil)
+	key := InstanceKey{ServerID: "s", AuthScopeID: "a"}
+	p := newPool(key)
+	p.members = []downstream{
+		&fakeMember{state: StateBusy, inFlight: 2},
+		&fakeMember{state: StateIdle},
+		&fakeMember{state: StateStopped},
+	}
+	m.pools[key] = p
+
+	infos := m.ListInstances()
+	if len(infos) != 2 {
+		t.Fatalf("got %d instances, want 2", len(infos))
+	}
+	if infos[0].Key != key || infos[0].Index != 0 || infos[0].State != StateBusy || infos[0].InFlight != 2 {
+		t.Errorf("infos[0] = %+v", infos[0])
+	}
+	if infos[1].Index != 1 || infos[1].State != StateIdle {
+		t.Errorf("infos[1] = %+v", infos[1])
+	}
+}
+
+func TestManager_ReleaseSession(t *testing.T) {
+	m := NewManager(nil, nil)
+	mine := InstanceKey{ServerID: "s", Scope: "session:s1"}
+	other := InstanceKey{ServerID: "s", Scope: "session:s2"}
+	global := InstanceKey{ServerID: "s"}
+	members := map[InstanceKey]*fakeMember{}
+	for _, key := range []InstanceKey{mine, other, global} {
+		members[key] = &fakeMember{state: StateIdle}
+		p := newPool(key)
+		p.members = []downstream{members[key]}
+		m.pools[key] = p
+	}
+	m.recordStartFailure(mine, errors.New("exit status 1"), "")
+
+	m.ReleaseSession("s1")
+
+	if _, ok := m.pools[mine]; ok || members[mine].getState() != StateStopped {
+		t.Errorf("session s1 pool still running")
+	}
+	if len(m.pools) != 2 || members[other].getState() != StateIdle || members[global].getState() != StateIdle {
+		t.Errorf("other pools affected: %d left", len(m.pools))
+	}
+	if len(m.Health()) != 0 {
+		t.Errorf("health = %+v, want none", m.Health())
+	}
+}
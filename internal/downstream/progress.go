@@ -0,0 +1,70 @@
+package downstream
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Progress is a notifications/progress update for an in-flight call.
+type Progress struct {
+	Progress float64  `json:"progress"`
+	Total    *float64 `json:"total,omitempty"`
+	Message  string   `json:"message,omitempty"`
+}
+
+// ProgressHandler receives progress a downstream server reports for a call.
+type ProgressHandler func(Progress)
+
+type progressKey struct{}
+
+// WithProgressHandler returns a context that requests progress for
+// downstream calls made with it. The call is sent with a progressToken
+// owned by this hop, and matching notifications are passed to h.
+func WithProgressHandler(ctx context.Context, h ProgressHandler) context.Context {
+	return context.WithValue(ctx, progressKey{}, h)
+}
+
+func progressHandlerFrom(ctx context.Context) ProgressHandler {
+	if ctx == nil {
+		return nil
+	}
+	h, _ := ctx.Value(progressKey{}).(ProgressHandler)
+	return h
+}
+
+// withProgressToken sets params._meta.progressToken, keeping any other
+// fields. Params that are not a JSON object are returned unchanged.
+func withProgressToken(params json.RawMessage, token json.RawMessage) json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &fields); err != nil || fields == nil {
+			return params
+		}
+	}
+	meta := map[string]json.RawMessage{}
+	if raw, ok := fields["_meta"]; ok {
+		if err := json.Unmarshal(raw, &meta); err != nil || meta == nil {
+			meta = map[string]json.RawMessage{}
+		}
+	}
+	meta["progressToken"] = token
+	fields["_meta"], _ = json.Marshal(meta)
+
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return params
+	}
+	return out
+}
+
+// parseProgress decodes notifications/progress params.
+func parseProgress(params json.RawMessage) (json.RawMessage, Progress, bool) {
+	var p struct {
+		Token json.RawMessage `json:"progressToken"`
+		Progress
+	}
+	if err := json.Unmarshal(params, &p); err != nil || p.Token == nil {
+		return nil, Progress{}, false
+	}
+	return p.Token, p.Progress, true
+}
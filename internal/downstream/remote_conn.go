@@ -0,0 +1,285 @@
+package downstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// remoteConnectTimeout bounds opening a connection and the initialize
+	// handshake.
+	remoteConnectTimeout = 30 * time.Second
+
+	// A dropped connection is reopened after reconnectBackoffBase,
+	// doubling for each failed attempt up to reconnectBackoffMax.
+	reconnectBackoffBase = 500 * time.Millisecond
+	reconnectBackoffMax  = 30 * time.Second
+)
+
+var (
+	// errInstanceStopped fails calls made to a stopped instance.
+	errInstanceStopped = errors.New("downstream instance stopped")
+
+	// errSessionGone is returned when the server no longer knows the
+	// session a message was sent for.
+	errSessionGone = errors.New("downstream session not found")
+)
+
+// dialFunc opens and initializes a connection. ctx bounds connecting; the
+// connection lives until runCtx ends, and the returned channel is closed
+// when it does.
+type dialFunc func(ctx, runCtx context.Context) (<-chan struct{}, error)
+
+// remoteConn is the part of the SSE and WebSocket instances that keeps a
+// long-lived connection to a remote server. Calls wait while it is down,
+// and a dropped connection is reopened with backoff and initialized again.
+// Requests in flight on a dropped connection fail, since their responses
+// would have arrived on it. If the server rejects the credentials, the
+// instance stops and calls fail with ErrAuthRequired.
+type remoteConn struct {
+	callTracker
+
+	key       InstanceKey
+	transport string // for logs and errors
+	rpc       *rpcSession
+	dial      dialFunc
+
+	connected chan struct{} // closed once the current connection is initialized
+	closeConn context.CancelFunc
+
+	cancel context.CancelFunc // closes the connection and stops reconnecting
+	done   chan struct{}      // closed when the reconnect goroutine exits
+	err    error              // why reconnecting gave up, if it did
+}
+
+func newRemoteConn(key InstanceKey, transport string, idleTimeout time.Duration) *remoteConn {
+	c := &remoteConn{
+		key:       key,
+		transport: transport,
+		connected: make(chan struct{}),
+	}
+	c.callTracker = newCallTracker(key.ServerID, idleTimeout, c.stop)
+	return c
+}
+
+func (c *remoteConn) start(ctx context.Context) error {
+	c.mu.Lock()
+	if c.state != StateStopped {
+		st := c.state
+		c.mu.Unlock()
+		return fmt.Errorf("cannot start %s instance in state %s", c.transport, st)
+	}
+	c.state = StateStarting
+	c.err = nil
+	// The connection outlives the call that started it; stop cancels it.
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	c.mu.Unlock()
+
+	connectCtx, connectCancel := context.WithTimeout(ctx, remoteConnectTimeout)
+	ended, err := c.dial(connectCtx, runCtx)
+	connectCancel()
+	if err != nil {
+		cancel()
+		close(c.done)
+		c.mu.Lock()
+		c.state = StateStopped
+		c.mu.Unlock()
+		return err
+	}
+
+	c.mu.Lock()
+	c.state = StateReady
+	c.mu.Unlock()
+	go c.run(runCtx, ended)
+	return nil
+}
+
+// markConnected lets calls through once a dial has initialized the
+// connection; closeConn drops it.
+func (c *remoteConn) markConnected(closeConn context.CancelFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeConn = closeConn
+	close(c.connected)
+}
+
+// run waits for the connection to end and reopens it until the instance
+// is stopped or the server rejects its credentials.
+func (c *remoteConn) run(runCtx context.Context, ended <-chan struct{}) {
+	defer close(c.done)
+	for {
+		<-ended
+		c.disconnected()
+		if runCtx.Err() != nil {
+			return
+		}
+		slog.Warn("downstream connection closed, reconnecting",
+			"server", c.key.ServerID, "transport", c.transport)
+		var err error
+		if ended, err = c.reconnect(runCtx); err != nil {
+			c.giveUp(err)
+			return
+		}
+		if ended == nil {
+			return
+		}
+	}
+}
+
+// reconnect reopens the connection with backoff until it succeeds or
+// runCtx ends. Retrying cannot fix rejected credentials, so it returns
+// ErrAuthRequired as soon as the server answers with it.
+func (c *remoteConn) reconnect(runCtx context.Context) (<-chan struct{}, error) {
+	for attempt := 1; ; attempt++ {
+		t := time.NewTimer(reconnectBackoff(attempt))
+		select {
+		case <-runCtx.Done():
+			t.Stop()
+			return nil, nil
+		case <-t.C:
+		}
+
+		ctx, cancel := context.WithTimeout(runCtx, remoteConnectTimeout)
+		ended, err := c.dial(ctx, runCtx)
+		cancel()
+		if err == nil {
+			slog.Info("downstream connection reopened",
+				"server", c.key.ServerID, "transport", c.transport, "attempts", attempt)
+			return ended, nil
+		}
+		if runCtx.Err() != nil {
+			return nil, nil
+		}
+		if errors.Is(err, ErrAuthRequired) {
+			return nil, err
+		}
+		slog.Warn("downstream reconnect failed",
+			"server", c.key.ServerID, "transport", c.transport, "attempt", attempt, "error", err)
+	}
+}
+
+// reconnectBackoff doubles from reconnectBackoffBase for each failed
+// attempt, capped at reconnectBackoffMax.
+func reconnectBackoff(attempt int) time.Duration {
+	d := reconnectBackoffBase
+	for i := 1; i < attempt && d < reconnectBackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, reconnectBackoffMax)
+}
+
+// giveUp stops the instance after reconnecting failed for good. Waiting
+// calls fail with err, and the pool replaces the instance on the next call.
+func (c *remoteConn) giveUp(err error) {
+	slog.Warn("downstream connection lost, not reconnecting",
+		"server", c.key.ServerID, "transport", c.transport, "error", err)
+	c.mu.Lock()
+	c.err = err
+	c.state = StateStopped
+	c.stopIdleTimer()
+	cancel := c.cancel
+	c.mu.Unlock()
+	cancel()
+	c.rpc.close(err)
+}
+
+// disconnected makes calls wait for the next connection.
+func (c *remoteConn) disconnected() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.connected:
+		c.connected = make(chan struct{})
+	default:
+	}
+}
+
+// dropConn closes the current connection so that run reopens it.
+func (c *remoteConn) dropConn() {
+	c.disconnected()
+	c.mu.Lock()
+	closeConn := c.closeConn
+	c.mu.Unlock()
+	if closeConn != nil {
+		closeConn()
+	}
+}
+
+func (c *remoteConn) stop() {
+	c.mu.Lock()
+	if c.state == StateStopped || c.state == StateStopping {
+		c.mu.Unlock()
+		return
+	}
+	c.state = StateStopping
+	c.stopIdleTimer()
+	cancel, done := c.cancel, c.done
+	c.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+	}
+	c.rpc.close(errInstanceStopped)
+
+	c.mu.Lock()
+	c.state = StateStopped
+	c.mu.Unlock()
+}
+
+// ListTools sends a tools/list request to the server.
+func (c *remoteConn) ListTools(ctx context.Context) (json.RawMessage, error) {
+	return c.Call(ctx, "tools/list", json.RawMessage(`{}`))
+}
+
+// Call sends a request and waits for its response. While the connection
+// is being reopened, calls wait for it. A request the server rejected for
+// an unknown session was not processed, so it is sent again on the next
+// connection.
+func (c *remoteConn) Call(
+	ctx context.Context, method string, params json.RawMessage,
+) (json.RawMessage, error) {
+	c.beginCall()
+	defer c.endCall()
+
+	for attempt := 0; ; attempt++ {
+		if err := c.waitConnected(ctx); err != nil {
+			return nil, err
+		}
+		result, err := c.rpc.roundTrip(ctx, method, params)
+		if errors.Is(err, errSessionGone) && attempt == 0 {
+			c.dropConn()
+			continue
+		}
+		return result, err
+	}
+}
+
+// waitConnected waits until the connection is up.
+func (c *remoteConn) waitConnected(ctx context.Context) error {
+	c.mu.Lock()
+	connected, done := c.connected, c.done
+	c.mu.Unlock()
+	select {
+	case <-connected:
+		return nil
+	case <-done:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.err != nil {
+			return c.err
+		}
+		return errInstanceStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,41 @@
+package downstream
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// request represents a JSON-RPC request awaiting a response from a
+// downstream process.
+type request struct {
+	ID     int64
+	Method string
+	Params json.RawMessage // raw JSON-RPC params
+	Result chan response
+	Ctx    context.Context // caller context; carries the ServerRequestHandler
+}
+
+// response is the result of a downstream request.
+type response struct {
+	Data json.RawMessage
+	Err  error
+}
+
+// cancelNotification builds the notifications/cancelled message telling a
+// downstream server to stop working on request id because ctx was
+// cancelled.
+func cancelNotification(ctx context.Context, id json.RawMessage) jsonRPCRequest {
+	reason := "request cancelled"
+	if cause := context.Cause(ctx); cause != nil {
+		reason = cause.Error()
+	}
+	params, _ := json.Marshal(map[string]any{
+		"requestId": id,
+		"reason":    reason,
+	})
+	return jsonRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "notifications/cancelled",
+		Params:  params,
+	}
+}
@@ -0,0 +1,393 @@
+package downstream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Restart policies for store.DownstreamServer.RestartPolicy. An empty
+// policy behaves like RestartOnFailure.
+const (
+	RestartAlways    = "always"
+	RestartOnFailure = "on-failure"
+	RestartNever     = "never"
+)
+
+const (
+	restartBackoffBase = time.Second
+	restartBackoffMax  = 5 * time.Minute
+
+	// crashLoopThreshold is the number of consecutive failures after which
+	// a server is degraded and calls fail fast until the backoff expires.
+	crashLoopThreshold = 5
+
+	// stableRunTime is how long a process must stay up for its earlier
+	// failures to be forgotten.
+	stableRunTime = time.Minute
+)
+
+// Process event kinds reported through Manager.OnProcessEvent.
+const (
+	EventCrashed     = "crashed"      // process exited with an error
+	EventExited      = "exited"       // process exited cleanly on its own
+	EventStartFailed = "start_failed" // process could not be started or initialized
+	EventRestarted   = "restarted"    // process started after earlier failures
+	EventDegraded    = "degraded"     // server entered a crash loop
+)
+
+// ProcessEvent describes a downstream process lifecycle change.
+type ProcessEvent struct {
+	Key      InstanceKey
+	Kind     string
+	Time     time.Time
+	Error    string
+	Stderr   string    // last lines the process wrote to stderr
+	Failures int       // consecutive failures, including this one
+	RetryAt  time.Time // when the next restart is allowed, if backing off
+}
+
+// ProcessError is returned instead of starting a process when its server
+// is crash-looping, or has exited under restart_policy "never".
+type ProcessError struct {
+	ServerID  string
+	Halted    bool // exited under restart_policy "never"
+	Failures  int
+	RetryAt   time.Time
+	LastError string
+	Stderr    string
+}
+
+func (e *ProcessError) Error() string {
+	var b strings.Builder
+	if e.Halted {
+		b.WriteString(`process exited and restart_policy is "never"`)
+	} else {
+		fmt.Fprintf(&b, "degraded after %d consecutive failures; next restart in %s",
+			e.Failures, time.Until(e.RetryAt).Round(time.Second))
+	}
+	if e.LastError != "" {
+		b.WriteString("; last error: " + e.LastError)
+	}
+	if e.Stderr != "" {
+		b.WriteString("; stderr: " + e.Stderr)
+	}
+	return b.String()
+}
+
+// ProcessHealth summarizes recent failures for one instance key.
+type ProcessHealth struct {
+	Key       InstanceKey
+	Failures  int
+	Degraded  bool
+	Halted    bool
+	RetryAt   time.Time
+	LastError string
+	Stderr    string
+}
+
+// processHealth tracks consecutive failures for one instance key. All
+// fields are guarded by Manager.healthMu.
+type processHealth struct {
+	failures     int
+	retryAt      time.Time
+	halted       bool
+	lastErr      string
+	stderr       string
+	runningSince time.Time // last successful start, zero after a failure
+}
+
+// settle forgets earlier failures once a process has stayed up for
+// stableRunTime.
+func (h *processHealth) settle(now time.Time) {
+	if !h.runningSince.IsZero() && now.Sub(h.runningSince) >= stableRunTime {
+		h.failures = 0
+		h.lastErr = ""
+		h.stderr = ""
+	}
+}
+
+func (h *processHealth) degraded() bool {
+	return h.failures >= crashLoopThreshold
+}
+
+// fail records a failure and schedules the next allowed start.
+func (h *processHealth) fail(now time.Time, err, stderr string) {
+	h.failures++
+	h.lastErr = err
+	h.stderr = stderr
+	h.runningSince = time.Time{}
+	h.retryAt = now.Add(restartBackoff(h.failures))
+}
+
+// restartBackoff doubles from restartBackoffBase for each consecutive
+// failure, capped at restartBackoffMax.
+func restartBackoff(failures int) time.Duration {
+	d := restartBackoffBase
+	for i := 1; i < failures && d < restartBackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, restartBackoffMax)
+}
+
+// stderrTailLines is how many lines of stderr are kept per process.
+const stderrTailLines = 20
+
+// stderrTail is an io.Writer that keeps the last few lines a process
+// wrote to stderr.
+type stderrTail struct {
+	mu      sync.Mutex
+	lines   []string
+	partial []byte
+}
+
+func (t *stderrTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data := append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		t.add(string(data[:i]))
+		data = data[i+1:]
+	}
+	// Bound memory for a process that never writes a newline.
+	if len(data) > 4096 {
+		t.add(string(data))
+		data = nil
+	}
+	t.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+func (t *stderrTail) add(line string) {
+	line = strings.TrimRight(line, "\r")
+	if line == "" {
+		return
+	}
+	if len(line) > 512 {
+		line = line[:512] + "…"
+	}
+	t.lines = append(t.lines, line)
+	if len(t.lines) > stderrTailLines {
+		t.lines = t.lines[len(t.lines)-stderrTailLines:]
+	}
+}
+
+// String returns the captured lines, including an unterminated last line.
+func (t *stderrTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := t.lines
+	if len(t.partial) > 0 {
+		lines = append(lines[:len(lines):len(lines)], string(t.partial))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checkRestart decides whether a new process may be started for key. It
+// fails fast while the server is degraded or halted, and otherwise waits
+// out any remaining backoff.
+func (m *Manager) checkRestart(ctx context.Context, key InstanceKey, policy string) error {
+	m.healthMu.Lock()
+	h := m.health[key]
+	if h == nil {
+		m.healthMu.Unlock()
+		return nil
+	}
+	h.settle(time.Now())
+	if h.halted && policy != RestartNever {
+		// The policy was changed since the process exited.
+		h.halted = false
+	}
+	if h.halted || (h.degraded() && time.Now().Before(h.retryAt)) {
+		err := &ProcessError{
+			ServerID:  key.ServerID,
+			Halted:    h.halted,
+			Failures:  h.failures,
+			RetryAt:   h.retryAt,
+			LastError: h.lastErr,
+			Stderr:    h.stderr,
+		}
+		m.healthMu.Unlock()
+		return err
+	}
+	wait := time.Until(h.retryAt)
+	m.healthMu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// recordStart notes a successful start, reporting a restart if the key
+// had failed before.
+func (m *Manager) recordStart(key InstanceKey) {
+	m.healthMu.Lock()
+	h := m.health[key]
+	if h == nil {
+		m.healthMu.Unlock()
+		return
+	}
+	now := time.Now()
+	h.halted = false
+	h.runningSince = now
+	ev := ProcessEvent{Key: key, Kind: EventRestarted, Time: now, Failures: h.failures}
+	m.healthMu.Unlock()
+
+	if ev.Failures > 0 {
+		m.emitProcessEvent(ev)
+	}
+}
+
+// recordStartFailure counts a failed start towards the crash loop.
+func (m *Manager) recordStartFailure(key InstanceKey, err error, stderr string) {
+	m.recordFailure(key, EventStartFailed, err.Error(), stderr, false)
+}
+
+// handleExit reacts to a process exiting on its own (not via stop). The
+// restart policy decides whether it is restarted eagerly, left for the
+// next call to start lazily, or not restarted at all.
+func (m *Manager) handleExit(key InstanceKey, policy string, exitErr error, ranFor time.Duration, stderr string) {
+	if policy == "" {
+		policy = RestartOnFailure
+	}
+
+	m.healthMu.Lock()
+	h := m.healthFor(key)
+	if ranFor >= stableRunTime {
+		h.failures = 0
+	}
+	m.healthMu.Unlock()
+
+	if exitErr == nil {
+		// A clean exit is not a failure. Under "always" it is restarted
+		// after the base backoff, so a process that keeps exiting does
+		// not spin; otherwise the next call restarts it.
+		m.emitProcessEvent(ProcessEvent{Key: key, Kind: EventExited, Time: time.Now(), Stderr: stderr})
+		if policy == RestartAlways {
+			slog.Info("downstream process exited, restarting",
+				"server", key.ServerID, "after", restartBackoffBase)
+			time.AfterFunc(restartBackoffBase, func() { m.restart(key) })
+		}
+		return
+	}
+
+	msg := exitErr.Error()
+	retryAt := m.recordFailure(key, EventCrashed, msg, stderr, policy == RestartNever)
+
+	switch policy {
+	case RestartNever:
+		slog.Error("downstream process exited, not restarting",
+			"server", key.ServerID, "error", msg)
+	default:
+		slog.Warn("downstream process exited, scheduling restart",
+			"server", key.ServerID, "error", msg, "retry_at", retryAt)
+		time.AfterFunc(time.Until(retryAt), func() { m.restart(key) })
+	}
+}
+
+// recordFailure updates the failure count for key and emits the matching
+// events. It returns when the next restart is allowed.
+func (m *Manager) recordFailure(key InstanceKey, kind, msg, stderr string, halt bool) time.Time {
+	now := time.Now()
+	m.healthMu.Lock()
+	h := m.healthFor(key)
+	wasDegraded := h.degraded()
+	h.fail(now, msg, stderr)
+	h.halted = halt
+	ev := ProcessEvent{
+		Key:      key,
+		Kind:     kind,
+		Time:     now,
+		Error:    msg,
+		Stderr:   stderr,
+		Failures: h.failures,
+		RetryAt:  h.retryAt,
+	}
+	nowDegraded := h.degraded()
+	m.healthMu.Unlock()
+
+	m.emitProcessEvent(ev)
+	if nowDegraded && !wasDegraded {
+		slog.Error("downstream server is crash-looping",
+			"server", key.ServerID, "failures", ev.Failures, "retry_at", ev.RetryAt)
+		ev.Kind = EventDegraded
+		m.emitProcessEvent(ev)
+	}
+	return ev.RetryAt
+}
+
+// restart brings a pool back up after its process exited, unless the pool
+// has since been shut down or is already running again.
+func (m *Manager) restart(key InstanceKey) {
+	m.mu.Lock()
+	p := m.pools[key]
+	m.mu.Unlock()
+	if p == nil {
+		return
+	}
+	_, err := p.startFirst(WithCaller(context.Background(), p.caller), func(ctx context.Context) (downstream, int, error) {
+		return m.startInstance(ctx, key)
+	})
+	if err != nil {
+		slog.Warn("downstream restart failed", "server", key.ServerID, "error", err)
+	}
+}
+
+// Health reports instance keys with recent failures.
+func (m *Manager) Health() []ProcessHealth {
+	now := time.Now()
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+
+	var out []ProcessHealth
+	for key, h := range m.health {
+		h.settle(now)
+		if h.failures == 0 && !h.halted {
+			continue
+		}
+		out = append(out, ProcessHealth{
+			Key:       key,
+			Failures:  h.failures,
+			Degraded:  h.degraded(),
+			Halted:    h.halted,
+			RetryAt:   h.retryAt,
+			LastError: h.lastErr,
+			Stderr:    h.stderr,
+		})
+	}
+	return out
+}
+
+// healthFor returns the tracker for key, creating it. Callers must hold
+// m.healthMu.
+func (m *Manager) healthFor(key InstanceKey) *processHealth {
+	h := m.health[key]
+	if h == nil {
+		h = &processHealth{}
+		m.health[key] = h
+	}
+	return h
+}
+
+func (m *Manager) emitProcessEvent(ev ProcessEvent) {
+	if m.OnProcessEvent != nil {
+		m.OnProcessEvent(ev)
+	}
+}
@@ -0,0 +1,172 @@
+package downstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{9, 256 * time.Second},
+		{10, restartBackoffMax},
+		{50, restartBackoffMax},
+	}
+	for _, tt := range tests {
+		if got := restartBackoff(tt.failures); got != tt.want {
+			t.Errorf("restartBackoff(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestStderrTail(t *testing.T) {
+	var tail stderrTail
+	for i := range stderrTailLines + 5 {
+		fmt.Fprintf(&tail, "line %d\n", i)
+	}
+	fmt.Fprint(&tail, "partial")
+
+	lines := strings.Split(tail.String(), "\n")
+	if len(lines) != stderrTailLines+1 {
+		t.Fatalf("got %d lines, want %d", len(lines), stderrTailLines+1)
+	}
+	if lines[0] != "line 5" {
+		t.Errorf("first line = %q, want oldest lines dropped", lines[0])
+	}
+	if lines[len(lines)-1] != "partial" {
+		t.Errorf("last line = %q, want unterminated line", lines[len(lines)-1])
+	}
+}
+
+func TestInstance_StartFailureCapturesStderr(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	inst := newInstance(InstanceKey{ServerID: "bad"}, sh,
+		[]string{"-c", "echo 'missing API_KEY' >&2; exit 3"}, nil, 0, 1)
+
+	if err := inst.start(context.Background()); err == nil {
+		t.Fatal("expected start to fail")
+	}
+	if got := inst.stderrTail(); got != "missing API_KEY" {
+		t.Errorf("stderr tail = %q", got)
+	}
+	if inst.getState() != StateStopped {
+		t.Errorf("state = %s, want stopped", inst.getState())
+	}
+}
+
+func TestManager_CrashLoopDegrades(t *testing.T) {
+	m := NewManager(nil, nil)
+	var kinds []string
+	m.OnProcessEvent = func(ev ProcessEvent) { kinds = append(kinds, ev.Kind) }
+	key := InstanceKey{ServerID: "flaky"}
+
+	for range crashLoopThreshold {
+		m.recordStartFailure(key, errors.New("initialize: no response from downstream"), "panic: boom")
+	}
+
+	degraded := 0
+	for _, k := range kinds {
+		if k == EventDegraded {
+			degraded++
+		}
+	}
+	if degraded != 1 {
+		t.Errorf("degraded events = %d, want 1 (events %v)", degraded, kinds)
+	}
+
+	// Degraded servers fail fast instead of waiting out the backoff.
+	start := time.Now()
+	err := m.checkRestart(context.Background(), key, RestartOnFailure)
+	var procErr *ProcessError
+	if !errors.As(err, &procErr) || procErr.Halted || procErr.Failures != crashLoopThreshold {
+		t.Fatalf("checkRestart = %v, want degraded ProcessError", err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("degraded check should not wait")
+	}
+	if !strings.Contains(err.Error(), "panic: boom") {
+		t.Errorf("error %q should include stderr", err)
+	}
+
+	health := m.Health()
+	if len(health) != 1 || !health[0].Degraded || health[0].Stderr != "panic: boom" {
+		t.Errorf("health = %+v", health)
+	}
+}
+
+func TestManager_BackoffWaitsBeforeRestart(t *testing.T) {
+	m := NewManager(nil, nil)
+	key := InstanceKey{ServerID: "flaky"}
+	m.recordStartFailure(key, errors.New("exit status 1"), "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.checkRestart(ctx, key, RestartOnFailure); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("checkRestart = %v, want to wait out the backoff", err)
+	}
+}
+
+func TestManager_RestartPolicyNever(t *testing.T) {
+	m := NewManager(nil, nil)
+	var events []ProcessEvent
+	m.OnProcessEvent = func(ev ProcessEvent) { events = append(events, ev) }
+	key := InstanceKey{ServerID: "once"}
+
+	m.handleExit(key, RestartNever, errors.New("exit status 2"), time.Second, "fatal: bad config")
+
+	if len(events) != 1 || events[0].Kind != EventCrashed || events[0].Stderr != "fatal: bad config" {
+		t.Fatalf("events = %+v", events)
+	}
+	var procErr *ProcessError
+	err := m.checkRestart(context.Background(), key, RestartNever)
+	if !errors.As(err, &procErr) || !procErr.Halted {
+		t.Fatalf("checkRestart = %v, want halted ProcessError", err)
+	}
+
+	// Changing the policy lifts the halt; only the backoff remains.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.checkRestart(ctx, key, RestartOnFailure); errors.As(err, &procErr) {
+		t.Fatalf("checkRestart after policy change = %v", err)
+	}
+}
+
+func TestManager_CleanExitIsNotAFailure(t *testing.T) {
+	for _, policy := range []string{RestartOnFailure, RestartAlways} {
+		t.Run(policy, func(t *testing.T) {
+			m := NewManager(nil, nil)
+			var events []ProcessEvent
+			m.OnProcessEvent = func(ev ProcessEvent) { events = append(events, ev) }
+			key := InstanceKey{ServerID: "oneshot"}
+
+			for range crashLoopThreshold {
+				m.handleExit(key, policy, nil, time.Second, "")
+			}
+
+			for _, ev := range events {
+				if ev.Kind != EventExited || ev.Failures != 0 {
+					t.Fatalf("events = %+v", events)
+				}
+			}
+			if err := m.checkRestart(context.Background(), key, policy); err != nil {
+				t.Errorf("checkRestart = %v, want immediate restart", err)
+			}
+			if h := m.Health(); len(h) != 0 {
+				t.Errorf("health = %+v, want none", h)
+			}
+		})
+	}
+}
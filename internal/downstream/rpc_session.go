@@ -0,0 +1,219 @@
+package downstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errConnectionClosed fails requests whose connection went away before
+// they were answered.
+var errConnectionClosed = errors.New("downstream connection closed")
+
+// rpcSession correlates JSON-RPC requests with responses for transports
+// that carry messages both ways over a long-lived connection (stdio,
+// legacy HTTP+SSE and WebSocket). The transport supplies send and feeds
+// every incoming message to dispatch.
+type rpcSession struct {
+	serverID string
+	send     func(ctx context.Context, msg any) error
+	onNotify func(method string, params json.RawMessage)
+
+	reqID atomic.Int64
+
+	mu      sync.Mutex
+	pending map[int64]*request // requests awaiting a response, by ID
+	closed  error              // set while the connection is down
+}
+
+func newRPCSession(serverID string, send func(ctx context.Context, msg any) error) *rpcSession {
+	return &rpcSession{
+		serverID: serverID,
+		send:     send,
+		pending:  make(map[int64]*request),
+		closed:   errConnectionClosed,
+	}
+}
+
+// open accepts requests again after the transport (re)connected.
+func (s *rpcSession) open() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = nil
+}
+
+// close fails every outstanding request with err and rejects new ones
+// until the next open.
+func (s *rpcSession) close(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = err
+	for id, req := range s.pending {
+		req.Result <- response{Err: err}
+		delete(s.pending, id)
+	}
+}
+
+// roundTrip sends a request and waits for its response. When ctx ends
+// first, the server is told to stop working on it.
+func (s *rpcSession) roundTrip(
+	ctx context.Context, method string, params json.RawMessage,
+) (json.RawMessage, error) {
+	req := &request{
+		ID:     s.reqID.Add(1),
+		Method: method,
+		Params: params,
+		Result: make(chan response, 1),
+		Ctx:    ctx,
+	}
+
+	s.mu.Lock()
+	if s.closed != nil {
+		err := s.closed
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.pending[req.ID] = req
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, req.ID)
+		s.mu.Unlock()
+	}()
+
+	rpcReq := jsonRPCRequest{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage(strconv.FormatInt(req.ID, 10)),
+		Method:  method,
+		Params:  params,
+	}
+	if method != "initialize" && progressHandlerFrom(ctx) != nil {
+		// The request ID doubles as this hop's progress token.
+		rpcReq.Params = withProgressToken(params, rpcReq.ID)
+	}
+	if err := s.send(ctx, rpcReq); err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		if method != "initialize" {
+			s.cancelRequest(ctx, rpcReq.ID)
+		}
+		return nil, ctx.Err()
+	case resp := <-req.Result:
+		return resp.Data, resp.Err
+	}
+}
+
+// cancelRequest forwards notifications/cancelled for a request whose
+// caller has gone away.
+func (s *rpcSession) cancelRequest(ctx context.Context, id json.RawMessage) {
+	notifyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := s.send(notifyCtx, cancelNotification(ctx, id)); err != nil {
+		slog.Debug("failed to forward cancellation",
+			"server", s.serverID, "error", err)
+	}
+}
+
+// notify sends a notification.
+func (s *rpcSession) notify(ctx context.Context, method string) error {
+	return s.send(ctx, jsonRPCRequest{JSONRPC: "2.0", Method: method})
+}
+
+// dispatch routes one incoming message: responses go to their waiting
+// callers, progress to the call that asked for it, other notifications to
+// onNotify, and server-initiated requests are relayed and answered.
+func (s *rpcSession) dispatch(data []byte) {
+	var msg jsonRPCMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		slog.Warn("invalid message from downstream",
+			"server", s.serverID, "error", err)
+		return
+	}
+
+	switch {
+	case msg.ID == nil && msg.Method == "notifications/progress" && s.deliverProgress(msg.Params):
+	case msg.ID == nil:
+		if s.onNotify != nil && msg.Method != "" {
+			slog.Debug("downstream notification",
+				"server", s.serverID, "method", msg.Method)
+			s.onNotify(msg.Method, msg.Params)
+		}
+	case msg.Method != "":
+		go s.handleServerRequest(s.serverRequestContext(), msg)
+	default:
+		s.deliver(msg)
+	}
+}
+
+// deliver hands a response to the caller waiting on its ID.
+func (s *rpcSession) deliver(msg jsonRPCMessage) {
+	id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+	s.mu.Lock()
+	req := s.pending[id]
+	delete(s.pending, id)
+	s.mu.Unlock()
+
+	if err != nil || req == nil {
+		slog.Debug("dropping response to unknown request",
+			"server", s.serverID, "id", string(msg.ID))
+		return
+	}
+	if msg.Error != nil {
+		req.Result <- response{Err: fmt.Errorf("downstream error %d: %s",
+			msg.Error.Code, msg.Error.Message)}
+		return
+	}
+	req.Result <- response{Data: msg.Result}
+}
+
+// deliverProgress passes a progress notification to the call whose
+// progress token (its request ID) it carries.
+func (s *rpcSession) deliverProgress(params json.RawMessage) bool {
+	token, progress, ok := parseProgress(params)
+	if !ok {
+		return false
+	}
+	id, err := strconv.ParseInt(string(token), 10, 64)
+	if err != nil {
+		return false
+	}
+	s.mu.Lock()
+	req := s.pending[id]
+	s.mu.Unlock()
+	if req == nil {
+		return false
+	}
+	h := progressHandlerFrom(req.Ctx)
+	if h == nil {
+		return false
+	}
+	h(progress)
+	return true
+}
+
+// handleServerRequest relays a server-initiated request to the handler
+// carried by ctx and sends the reply back.
+func (s *rpcSession) handleServerRequest(ctx context.Context, msg jsonRPCMessage) {
+	resp := serveServerRequest(ctx, serverRequestHandlerFrom(ctx), s.serverID, msg)
+	if err := s.send(context.WithoutCancel(ctx), resp); err != nil {
+		slog.Warn("failed to reply to downstream request",
+			"server", s.serverID, "method", msg.Method, "error", err)
+	}
+}
+
+// serverRequestContext picks the in-flight call a server-initiated request
+// belongs to; see relayContext.
+func (s *rpcSession) serverRequestContext() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return relayContext(s.serverID, s.pending)
+}
@@ -0,0 +1,225 @@
+package downstream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// WorkspaceRootVar is the placeholder in a server's cwd and args that is
+// replaced with the root of the calling session's workspace.
+const WorkspaceRootVar = "${workspace_root}"
+
+// Instance scopes: which callers share a server's instances.
+const (
+	ScopeGlobal    = "global"    // one pool per auth scope (default)
+	ScopeWorkspace = "workspace" // one pool per workspace root
+	ScopeSession   = "session"   // one pool per client session
+)
+
+// ValidateInstanceScope checks that s is an instance scope; empty means
+// global.
+func ValidateInstanceScope(s string) error {
+	switch s {
+	case "", ScopeGlobal, ScopeWorkspace, ScopeSession:
+		return nil
+	}
+	return fmt.Errorf("invalid instance_scope %q (must be global, workspace or session)", s)
+}
+
+// Caller describes the session a downstream request is made for. It picks
+// the pool of workspace- and session-scoped servers and fills in
+// ${workspace_root} and the working directory when an instance starts.
+type Caller struct {
+	SessionID     string
+	WorkspaceRoot string // root of the session's most specific workspace
+	ClientRoot    string // the session's client root
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context carrying the calling session.
+func WithCaller(ctx context.Context, c Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, c)
+}
+
+func callerFrom(ctx context.Context) Caller {
+	c, _ := ctx.Value(callerKey{}).(Caller)
+	return c
+}
+
+// ParseEnv decodes a server's env column. Empty input yields nil.
+func ParseEnv(raw json.RawMessage) (map[string]string, error) {
+	var env map[string]string
+	if err := decodeStringMap(raw, &env); err != nil {
+		return nil, fmt.Errorf("env: %w", err)
+	}
+	return env, ValidateEnv(env)
+}
+
+// ValidateEnv checks that every variable name is non-empty and contains no
+// "=" or NUL, and that no value contains NUL.
+func ValidateEnv(env map[string]string) error {
+	for k, v := range env {
+		if k == "" || strings.ContainsAny(k, "=\x00") {
+			return fmt.Errorf("env: invalid variable name %q", k)
+		}
+		if strings.ContainsRune(v, 0) {
+			return fmt.Errorf("env: value of %s contains NUL", k)
+		}
+	}
+	return nil
+}
+
+// ParseHeaders decodes a server's headers column. Empty input yields nil.
+func ParseHeaders(raw json.RawMessage) (http.Header, error) {
+	var m map[string]string
+	if err := decodeStringMap(raw, &m); err != nil {
+		return nil, fmt.Errorf("headers: %w", err)
+	}
+	if err := ValidateHeaders(m); err != nil {
+		return nil, err
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	h := make(http.Header, len(m))
+	for k, v := range m {
+		h.Set(k, v)
+	}
+	return h, nil
+}
+
+// ValidateHeaders checks that header names are HTTP tokens and values hold
+// no line breaks.
+func ValidateHeaders(headers map[string]string) error {
+	for k, v := range headers {
+		if k == "" || strings.IndexFunc(k, func(r rune) bool { return !isTokenRune(r) }) >= 0 {
+			return fmt.Errorf("headers: invalid header name %q", k)
+		}
+		if strings.ContainsAny(v, "\r\n\x00") {
+			return fmt.Errorf("headers: value of %s contains a line break", k)
+		}
+	}
+	return nil
+}
+
+func isTokenRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
+
+// ValidateCwd checks that a server's cwd is absolute, or starts with
+// ${workspace_root}.
+func ValidateCwd(cwd string) error {
+	if cwd == "" {
+		return nil
+	}
+	if rest, ok := strings.CutPrefix(cwd, WorkspaceRootVar); ok {
+		if rest != "" && !strings.HasPrefix(rest, "/") {
+			return fmt.Errorf("cwd: %q must continue with / after %s", cwd, WorkspaceRootVar)
+		}
+		return nil
+	}
+	if strings.Contains(cwd, "${") {
+		return fmt.Errorf("cwd: %q: only %s may be used", cwd, WorkspaceRootVar)
+	}
+	if !filepath.IsAbs(cwd) {
+		return fmt.Errorf("cwd: %q is not an absolute path", cwd)
+	}
+	return nil
+}
+
+// resolveCwd returns the working directory for an instance of server
+// started for caller. An explicit cwd wins, with ${workspace_root}
+// expanded; otherwise workspace-scoped instances run in the workspace root,
+// session-scoped ones in the client root, and global ones in the daemon's
+// directory.
+func resolveCwd(server *store.DownstreamServer, caller Caller) (string, error) {
+	cwd := server.Cwd
+	if cwd == "" {
+		switch server.InstanceScope {
+		case ScopeWorkspace:
+			return caller.WorkspaceRoot, nil
+		case ScopeSession:
+			return caller.ClientRoot, nil
+		}
+		return "", nil
+	}
+	if err := ValidateCwd(cwd); err != nil {
+		return "", err
+	}
+	return filepath.Clean(expandWorkspaceRoot(cwd, caller.WorkspaceRoot)), nil
+}
+
+// expandArgs replaces ${workspace_root} in each argument.
+func expandArgs(args []string, workspaceRoot string) []string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		out[i] = expandWorkspaceRoot(a, workspaceRoot)
+	}
+	return out
+}
+
+func expandWorkspaceRoot(s, root string) string {
+	return strings.ReplaceAll(s, WorkspaceRootVar, root)
+}
+
+// ValidateWorkspaceRoot checks that only workspace- and session-scoped
+// servers reference ${workspace_root} in cwd or args. A global server's
+// processes are shared by every session, so it has no root of its own.
+func ValidateWorkspaceRoot(scope, cwd string, args []string) error {
+	if scope == ScopeWorkspace || scope == ScopeSession || !usesWorkspaceRoot(cwd, args) {
+		return nil
+	}
+	return fmt.Errorf("%s requires instance_scope workspace or session", WorkspaceRootVar)
+}
+
+// usesWorkspaceRoot reports whether a server's cwd or args reference
+// ${workspace_root}.
+func usesWorkspaceRoot(cwd string, args []string) bool {
+	if strings.Contains(cwd, WorkspaceRootVar) {
+		return true
+	}
+	for _, a := range args {
+		if strings.Contains(a, WorkspaceRootVar) {
+			return true
+		}
+	}
+	return false
+}
+
+// instanceKey returns the key of the pool that serves caller for server.
+func instanceKey(server *store.DownstreamServer, authScopeID string, caller Caller) (InstanceKey, error) {
+	key := InstanceKey{ServerID: server.ID, AuthScopeID: authScopeID}
+	switch server.InstanceScope {
+	case ScopeWorkspace:
+		if caller.WorkspaceRoot == "" {
+			return key, fmt.Errorf("server %q is workspace-scoped, but the call has no workspace", server.Name)
+		}
+		key.Scope = ScopeWorkspace + ":" + caller.WorkspaceRoot
+	case ScopeSession:
+		if caller.SessionID == "" {
+			return key, fmt.Errorf("server %q is session-scoped, but the call has no session", server.Name)
+		}
+		key.Scope = ScopeSession + ":" + caller.SessionID
+	}
+	return key, nil
+}
+
+func decodeStringMap(raw json.RawMessage, into *map[string]string) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	return json.Unmarshal(raw, into)
+}
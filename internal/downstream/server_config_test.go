@@ -0,0 +1,190 @@
+package downstream
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+func TestParseEnv(t *testing.T) {
+	tests := []struct {
+		raw     string
+		wantErr string
+	}{
+		{"", ""},
+		{"{}", ""},
+		{`{"LOG_LEVEL":"debug","PATH":"${PATH}:/opt/bin"}`, ""},
+		{`{"A=B":"x"}`, "invalid variable name"},
+		{`{"":"x"}`, "invalid variable name"},
+		{`{"A":1}`, "env:"},
+	}
+	for _, tt := range tests {
+		_, err := ParseEnv(json.RawMessage(tt.raw))
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("ParseEnv(%s) = %v, want %q", tt.raw, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	h, err := ParseHeaders(json.RawMessage(`{"x-team":"platform"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Get("X-Team") != "platform" {
+		t.Errorf("X-Team = %q", h.Get("X-Team"))
+	}
+	for _, raw := range []string{`{"Bad Name":"x"}`, `{"X-A":"a\r\nX-B: b"}`} {
+		if _, err := ParseHeaders(json.RawMessage(raw)); err == nil {
+			t.Errorf("ParseHeaders(%s): expected error", raw)
+		}
+	}
+}
+
+func TestResolveCwd(t *testing.T) {
+	caller := Caller{SessionID: "s1", WorkspaceRoot: "/work/api", ClientRoot: "/work/api/cmd"}
+	tests := []struct {
+		cwd, scope string
+		caller     Caller
+		want       string
+		wantErr    bool
+	}{
+		{"", "", caller, "", false},
+		{"", ScopeWorkspace, caller, "/work/api", false},
+		{"", ScopeSession, caller, "/work/api/cmd", false},
+		{"/srv/tools", ScopeSession, caller, "/srv/tools", false},
+		{"${workspace_root}", "", caller, "/work/api", false},
+		{"${workspace_root}/sub/../pkg", "", caller, "/work/api/pkg", false},
+		{"${workspace_root}pkg", "", caller, "", true},
+		{"${HOME}/pkg", "", caller, "", true},
+		{"relative/dir", "", caller, "", true},
+	}
+	for _, tt := range tests {
+		srv := &store.DownstreamServer{Cwd: tt.cwd, InstanceScope: tt.scope}
+		got, err := resolveCwd(srv, tt.caller)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveCwd(%q, %q) = %q, %v; want %q, err %v", tt.cwd, tt.scope, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestInstanceKey(t *testing.T) {
+	caller := Caller{SessionID: "s1", WorkspaceRoot: "/work/api"}
+	tests := []struct {
+		scope   string
+		caller  Caller
+		want    string
+		wantErr bool
+	}{
+		{"", caller, "", false},
+		{ScopeGlobal, caller, "", false},
+		{ScopeWorkspace, caller, "workspace:/work/api", false},
+		{ScopeSession, caller, "session:s1", false},
+		{ScopeWorkspace, Caller{SessionID: "s1"}, "", true},
+		{ScopeSession, Caller{WorkspaceRoot: "/work/api"}, "", true},
+	}
+	for _, tt := range tests {
+		srv := &store.DownstreamServer{ID: "fs", Name: "fs", InstanceScope: tt.scope}
+		key, err := instanceKey(srv, "scope-a", tt.caller)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("instanceKey(%q) err = %v, want err %v", tt.scope, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (key.Scope != tt.want || key.ServerID != "fs" || key.AuthScopeID != "scope-a") {
+			t.Errorf("instanceKey(%q) = %+v, want scope %q", tt.scope, key, tt.want)
+		}
+	}
+}
+
+func TestCreateInstance_EnvAndCwd(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "info")
+	t.Setenv("DATA_ROOT", "/srv")
+	m := NewManager(nil, nil)
+	srv := &store.DownstreamServer{
+		ID: "fs", Name: "fs", Transport: "stdio", Command: "fs-server",
+		Env: json.RawMessage(`{"LOG_LEVEL":"debug","DATA":"${DATA_ROOT}/fs"}`),
+		Cwd: "${workspace_root}/data", InstanceScope: ScopeWorkspace,
+	}
+	ctx := WithCaller(context.Background(), Caller{WorkspaceRoot: "/work/api"})
+	d, err := m.createInstance(ctx, InstanceKey{ServerID: "fs"}, srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst := d.(*Instance)
+	if inst.dir != "/work/api/data" {
+		t.Errorf("dir = %q", inst.dir)
+	}
+	env := envToMap(inst.env)
+	if env["LOG_LEVEL"] != "debug" || env["DATA"] != "/srv/fs" {
+		t.Errorf("LOG_LEVEL = %q, DATA = %q", env["LOG_LEVEL"], env["DATA"])
+	}
+
+	// Without a workspace, a templated cwd cannot be resolved.
+	if _, err := m.createInstance(context.Background(), InstanceKey{ServerID: "fs"}, srv); err == nil {
+		t.Error("expected error without a workspace root")
+	}
+
+	// ${workspace_root} is expanded in args too.
+	srv.Cwd = ""
+	srv.Args = json.RawMessage(`["--root", "${workspace_root}"]`)
+	d, err = m.createInstance(ctx, InstanceKey{ServerID: "fs"}, srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(d.(*Instance).args, " "); got != "--root /work/api" {
+		t.Errorf("args = %q", got)
+	}
+
+	// A global server has no workspace root of its own.
+	srv.InstanceScope = ScopeGlobal
+	if _, err := m.createInstance(ctx, InstanceKey{ServerID: "fs"}, srv); err == nil {
+		t.Error("expected error for a global server using the workspace root")
+	}
+}
+
+func TestValidateWorkspaceRoot(t *testing.T) {
+	tests := []struct {
+		scope, cwd string
+		args       []string
+		wantErr    bool
+	}{
+		{"", "/srv", []string{"--root", "/srv"}, false},
+		{"", "${workspace_root}", nil, true},
+		{ScopeGlobal, "", []string{"--root=${workspace_root}"}, true},
+		{ScopeWorkspace, "${workspace_root}", []string{"${workspace_root}"}, false},
+		{ScopeSession, "", []string{"${workspace_root}"}, false},
+	}
+	for _, tt := range tests {
+		if err := ValidateWorkspaceRoot(tt.scope, tt.cwd, tt.args); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateWorkspaceRoot(%q, %q, %q) = %v, want err %v", tt.scope, tt.cwd, tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHTTPInstance_HeaderPrecedence(t *testing.T) {
+	static := http.Header{}
+	static.Set("X-Team", "platform")
+	static.Set("Authorization", "Bearer static")
+	static.Set("Accept", "text/plain")
+	auth := http.Header{}
+	auth.Set("Authorization", "Bearer scoped")
+
+	h := newHTTPInstance(InstanceKey{ServerID: "remote"}, "http://example.invalid/mcp", 0, static, auth)
+	req, err := h.newPost(context.Background(), []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("X-Team"); got != "platform" {
+		t.Errorf("X-Team = %q", got)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer scoped" {
+		t.Errorf("Authorization = %q, want auth scope's", got)
+	}
+	if got := req.Header.Get("Accept"); got != "application/json, text/event-stream" {
+		t.Errorf("Accept = %q, want protocol value", got)
+	}
+}
@@ -0,0 +1,96 @@
+package downstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+)
+
+// ServerRequestHandler handles a JSON-RPC request initiated by a downstream
+// server (e.g. sampling/createMessage or elicitation/create) and returns the
+// result to send back to it.
+type ServerRequestHandler func(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error)
+
+// RequestError is returned by a ServerRequestHandler to send a specific
+// JSON-RPC error code back to the downstream server.
+type RequestError struct {
+	Code    int
+	Message string
+}
+
+func (e *RequestError) Error() string { return e.Message }
+
+type serverRequestKey struct{}
+
+// WithServerRequestHandler returns a context that relays server-initiated
+// requests received while a downstream call made with it is in flight.
+func WithServerRequestHandler(ctx context.Context, h ServerRequestHandler) context.Context {
+	return context.WithValue(ctx, serverRequestKey{}, h)
+}
+
+func serverRequestHandlerFrom(ctx context.Context) ServerRequestHandler {
+	if ctx == nil {
+		return nil
+	}
+	h, _ := ctx.Value(serverRequestKey{}).(ServerRequestHandler)
+	return h
+}
+
+// relayContext returns the context of the in-flight call a
+// server-initiated request is relayed to. Transports do not say which call
+// a request belongs to, and the calls on a shared instance may come from
+// different client sessions, so a request is only relayed when exactly one
+// call can relay it. With several, it is refused rather than risk showing
+// one client's sampling prompt or elicitation form to another.
+func relayContext(serverID string, pending map[int64]*request) context.Context {
+	var owner *request
+	for _, req := range pending {
+		if serverRequestHandlerFrom(req.Ctx) == nil {
+			continue
+		}
+		if owner != nil {
+			return WithServerRequestHandler(context.Background(),
+				func(_ context.Context, method string, _ json.RawMessage) (json.RawMessage, error) {
+					slog.Warn("refusing downstream request with several in-flight callers",
+						"server", serverID, "method", method)
+					return nil, &RequestError{Code: -32603, Message: "cannot tell which call " + method + " belongs to"}
+				})
+		}
+		owner = req
+	}
+	if owner == nil {
+		return context.Background()
+	}
+	return owner.Ctx
+}
+
+// serveServerRequest runs h for a server-initiated request and builds the
+// JSON-RPC response to send back downstream.
+func serveServerRequest(
+	ctx context.Context, h ServerRequestHandler, serverID string, msg jsonRPCMessage,
+) jsonRPCResponse {
+	resp := jsonRPCResponse{JSONRPC: "2.0", ID: msg.ID}
+	if h == nil {
+		slog.Warn("dropping downstream request with no in-flight caller",
+			"server", serverID, "method", msg.Method)
+		resp.Error = &jsonRPCError{Code: -32601, Message: "method not supported: " + msg.Method}
+		return resp
+	}
+
+	result, err := h(ctx, msg.Method, msg.Params)
+	if err != nil {
+		var reqErr *RequestError
+		if errors.As(err, &reqErr) {
+			resp.Error = &jsonRPCError{Code: reqErr.Code, Message: reqErr.Message}
+		} else {
+			resp.Error = &jsonRPCError{Code: -32603, Message: err.Error()}
+		}
+		return resp
+	}
+	if result == nil {
+		result = json.RawMessage(`{}`)
+	}
+	resp.Result = result
+	return resp
+}
@@ -0,0 +1,64 @@
+package downstream
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// sseEvent is one event read from a text/event-stream.
+type sseEvent struct {
+	ID    string
+	Event string // "message" when the server gives no type
+	Data  string // data lines joined with "\n"
+}
+
+// sseReader parses server-sent events from a stream.
+type sseReader struct {
+	scanner *bufio.Scanner
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	scanner := bufio.NewScanner(r)
+	// Tool lists can be large; allow events of up to 4MB.
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	return &sseReader{scanner: scanner}
+}
+
+// next returns the next event with data. It returns io.EOF when the stream
+// ends cleanly, dropping any unterminated event.
+func (r *sseReader) next() (sseEvent, error) {
+	var ev sseEvent
+	var data []string
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			if len(data) == 0 {
+				ev = sseEvent{}
+				continue
+			}
+			ev.Data = strings.Join(data, "\n")
+			if ev.Event == "" {
+				ev.Event = "message"
+			}
+			return ev, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment, often a keepalive
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			ev.ID = value
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return sseEvent{}, err
+	}
+	return sseEvent{}, io.EOF
+}
@@ -0,0 +1,240 @@
+package downstream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEInstance communicates with a remote MCP server over the legacy
+// HTTP+SSE transport (protocol version 2024-11-05). The server sends
+// responses and notifications on a long-lived GET event stream; requests
+// are POSTed to the URL it announces in the stream's "endpoint" event.
+// A dropped stream is reopened with backoff and the session initialized
+// again.
+type SSEInstance struct {
+	*remoteConn
+
+	url    string
+	client *http.Client // POSTs to the endpoint
+	stream *http.Client // the event stream, which has no deadline
+
+	onNotify    func(method string, params json.RawMessage) // called when downstream sends a notification
+	resolveAuth authResolver                                // if set, refreshes authHeaders on each connect
+
+	mu          sync.Mutex
+	headers     http.Header // static headers from the server config
+	authHeaders http.Header
+	endpoint    string // POST URL announced on the current stream
+}
+
+// newSSEInstance creates a stopped instance. Auth headers override static
+// headers of the same name.
+func newSSEInstance(key InstanceKey, url string, idleTimeout time.Duration, static, auth http.Header) *SSEInstance {
+	s := &SSEInstance{
+		remoteConn:  newRemoteConn(key, "sse", idleTimeout),
+		url:         url,
+		headers:     static,
+		authHeaders: auth,
+		client:      &http.Client{Timeout: 60 * time.Second},
+		stream:      &http.Client{},
+	}
+	s.dial = s.connect
+	s.rpc = newRPCSession(key.ServerID, s.post)
+	s.rpc.onNotify = func(method string, params json.RawMessage) {
+		if s.onNotify != nil {
+			s.onNotify(method, params)
+		}
+	}
+	return s
+}
+
+// connect opens the event stream, waits for its endpoint event and
+// initializes the session.
+func (s *SSEInstance) connect(ctx, runCtx context.Context) (<-chan struct{}, error) {
+	if s.resolveAuth != nil {
+		auth, err := s.resolveAuth(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("resolve auth: %w", err)
+		}
+		s.mu.Lock()
+		s.authHeaders = auth
+		s.mu.Unlock()
+	}
+
+	streamCtx, closeStream := context.WithCancel(runCtx)
+	stopAbort := context.AfterFunc(ctx, closeStream)
+	defer stopAbort()
+
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, s.url, nil)
+	if err != nil {
+		closeStream()
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	s.setHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := s.stream.Do(req)
+	if err != nil {
+		closeStream()
+		return nil, fmt.Errorf("open event stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+		closeStream()
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, ErrAuthRequired
+		}
+		return nil, fmt.Errorf("open event stream: http %d: %s", resp.StatusCode, body)
+	}
+
+	events := newSSEReader(resp.Body)
+	endpoint, err := s.readEndpoint(events)
+	if err != nil {
+		_ = resp.Body.Close()
+		closeStream()
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("wait for endpoint: %w", ctx.Err())
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.endpoint = endpoint
+	s.mu.Unlock()
+	s.rpc.open()
+
+	ended := make(chan struct{})
+	go s.readLoop(events, resp.Body, ended)
+
+	if err := s.initialize(ctx); err != nil {
+		closeStream()
+		<-ended
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+
+	s.markConnected(closeStream)
+	return ended, nil
+}
+
+// readEndpoint reads events until the server announces where to POST
+// requests, resolved against the stream URL. The endpoint must be on the
+// stream's origin, as requests to it carry the server's auth headers.
+func (s *SSEInstance) readEndpoint(events *sseReader) (string, error) {
+	for {
+		ev, err := events.next()
+		if err != nil {
+			return "", fmt.Errorf("wait for endpoint: %w", err)
+		}
+		if ev.Event != "endpoint" {
+			continue
+		}
+		base, err := url.Parse(s.url)
+		if err != nil {
+			return "", fmt.Errorf("parse url: %w", err)
+		}
+		ref, err := url.Parse(ev.Data)
+		if err != nil {
+			return "", fmt.Errorf("invalid endpoint %q: %w", ev.Data, err)
+		}
+		endpoint := base.ResolveReference(ref)
+		if !strings.EqualFold(endpoint.Scheme, base.Scheme) || !strings.EqualFold(endpoint.Host, base.Host) {
+			return "", fmt.Errorf("endpoint %q is not on the origin of %s", ev.Data, s.url)
+		}
+		return endpoint.String(), nil
+	}
+}
+
+func (s *SSEInstance) initialize(ctx context.Context) error {
+	params := json.RawMessage(`{
+		"protocolVersion": "2024-11-05",
+		"capabilities": {"sampling": {}, "elicitation": {}},
+		"clientInfo": {"name": "mcplexer", "version": "0.1.0"}
+	}`)
+	if _, err := s.rpc.roundTrip(ctx, "initialize", params); err != nil {
+		return err
+	}
+	return s.rpc.notify(ctx, "notifications/initialized")
+}
+
+// readLoop passes the stream's messages to the session until it ends,
+// then fails the requests still waiting on it.
+func (s *SSEInstance) readLoop(events *sseReader, body io.Closer, done chan struct{}) {
+	defer close(done)
+	defer s.rpc.close(errConnectionClosed)
+	defer func() { _ = body.Close() }()
+	for {
+		ev, err := events.next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				slog.Debug("sse stream read failed",
+					"server", s.key.ServerID, "error", err)
+			}
+			return
+		}
+		if ev.Event == "message" {
+			s.rpc.dispatch([]byte(ev.Data))
+		}
+	}
+}
+
+// post sends a JSON-RPC message to the endpoint. The server acknowledges
+// it; any response arrives on the event stream.
+func (s *SSEInstance) post(ctx context.Context, msg any) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+	s.mu.Lock()
+	endpoint := s.endpoint
+	s.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	s.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http post: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return ErrAuthRequired
+	case resp.StatusCode == http.StatusNotFound:
+		return errSessionGone
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("http %d: %s", resp.StatusCode, respBody)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// setHeaders applies the static headers, then the auth headers, which
+// take precedence.
+func (s *SSEInstance) setHeaders(req *http.Request) {
+	s.mu.Lock()
+	auth := s.authHeaders
+	s.mu.Unlock()
+	for _, h := range []http.Header{s.headers, auth} {
+		for k, vals := range h {
+			for _, v := range vals {
+				req.Header.Set(k, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package downstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// legacySSEServer is an in-process MCP server on the HTTP+SSE transport.
+// Each GET /sse opens a session whose messages are POSTed to /messages.
+type legacySSEServer struct {
+	mu       sync.Mutex
+	sessions map[string]chan string // session ID -> events to stream
+	streams  int
+	inits    int
+	auth     []string // Authorization header of each stream
+}
+
+func newLegacySSEServer(t *testing.T) (*legacySSEServer, *httptest.Server) {
+	s := &legacySSEServer{sessions: make(map[string]chan string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sse", s.stream)
+	mux.HandleFunc("POST /messages", s.message)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func (s *legacySSEServer) stream(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.streams++
+	id := fmt.Sprint(s.streams)
+	events := make(chan string, 16)
+	s.sessions[id] = events
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	fmt.Fprintf(w, ": welcome\n\nevent: endpoint\ndata: /messages?session=%s\n\n", id)
+	w.(http.Flusher).Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return // drop the stream
+			}
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", ev)
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+func (s *legacySSEServer) message(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	events := s.sessions[r.URL.Query().Get("session")]
+	s.mu.Unlock()
+	if events == nil {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	var msg jsonRPCMessage
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	switch msg.Method {
+	case "initialize":
+		s.mu.Lock()
+		s.inits++
+		s.mu.Unlock()
+		events <- fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"protocolVersion":"2024-11-05"}}`, msg.ID)
+	case "tools/list":
+		// A notification first, then the response, as servers often do.
+		events <- `{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`
+		events <- fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"tools":[{"name":"echo"}]}}`, msg.ID)
+	}
+}
+
+// drop closes the stream of session id.
+func (s *legacySSEServer) drop(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.sessions[id])
+	delete(s.sessions, id)
+}
+
+func TestSSEInstance_CallsAndNotifications(t *testing.T) {
+	srv, ts := newLegacySSEServer(t)
+	auth := http.Header{}
+	auth.Set("Authorization", "Bearer scoped")
+	inst := newSSEInstance(InstanceKey{ServerID: "legacy"}, ts.URL+"/sse", 0, nil, auth)
+	notified := make(chan string, 4)
+	inst.onNotify = func(method string, _ json.RawMessage) { notified <- method }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := inst.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	res, err := inst.ListTools(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(res), `"echo"`) {
+		t.Errorf("tools = %s", res)
+	}
+	select {
+	case m := <-notified:
+		if m != "notifications/tools/list_changed" {
+			t.Errorf("notification = %s", m)
+		}
+	case <-ctx.Done():
+		t.Fatal("notification not forwarded")
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.auth[0] != "Bearer scoped" {
+		t.Errorf("stream Authorization = %q", srv.auth[0])
+	}
+}
+
+func TestSSEInstance_ReconnectsAndReinitializes(t *testing.T) {
+	srv, ts := newLegacySSEServer(t)
+	inst := newSSEInstance(InstanceKey{ServerID: "legacy"}, ts.URL+"/sse", 0, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := inst.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	srv.drop("1")
+
+	// The call waits for the stream to come back.
+	if _, err := inst.ListTools(ctx); err != nil {
+		t.Fatalf("call after reconnect: %v", err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.inits != 2 {
+		t.Errorf("initialize sent %d times, want 2", srv.inits)
+	}
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if inst.endpoint != ts.URL+"/messages?session=2" {
+		t.Errorf("endpoint = %q, want the new session's", inst.endpoint)
+	}
+}
+
+func TestSSEInstance_ReconnectResolvesAuth(t *testing.T) {
+	srv, ts := newLegacySSEServer(t)
+	inst := newSSEInstance(InstanceKey{ServerID: "legacy"}, ts.URL+"/sse", 0, nil, nil)
+	var tokens atomic.Int32
+	inst.resolveAuth = func(context.Context) (http.Header, error) {
+		h := http.Header{}
+		h.Set("Authorization", fmt.Sprintf("Bearer t%d", tokens.Add(1)))
+		return h, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := inst.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	srv.drop("1")
+	if _, err := inst.ListTools(ctx); err != nil {
+		t.Fatalf("call after reconnect: %v", err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if got := strings.Join(srv.auth, ","); got != "Bearer t1,Bearer t2" {
+		t.Errorf("stream Authorization = %s, want a fresh token per connection", got)
+	}
+}
+
+func TestSSEInstance_StartFailsWithoutStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	inst := newSSEInstance(InstanceKey{ServerID: "legacy"}, ts.URL, 0, nil, nil)
+	if err := inst.start(context.Background()); err != ErrAuthRequired {
+		t.Fatalf("start = %v, want ErrAuthRequired", err)
+	}
+	if inst.getState() != StateStopped {
+		t.Errorf("state = %s", inst.getState())
+	}
+	if _, err := inst.Call(context.Background(), "tools/list", nil); err != errInstanceStopped {
+		t.Errorf("call = %v, want errInstanceStopped", err)
+	}
+}
+
+func TestSSEInstance_RejectsCrossOriginEndpoint(t *testing.T) {
+	var posts atomic.Int32
+	other := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		posts.Add(1)
+	}))
+	defer other.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: endpoint\ndata: %s/messages\n\n", other.URL)
+	}))
+	defer ts.Close()
+
+	auth := http.Header{}
+	auth.Set("Authorization", "Bearer scoped")
+	inst := newSSEInstance(InstanceKey{ServerID: "legacy"}, ts.URL+"/sse", 0, nil, auth)
+	err := inst.start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "origin") {
+		t.Fatalf("start = %v, want cross-origin endpoint rejected", err)
+	}
+	if n := posts.Load(); n != 0 {
+		t.Errorf("%d requests sent to the other origin", n)
+	}
+}
+
+func TestSSEReader(t *testing.T) {
+	r := newSSEReader(strings.NewReader(
+		": keepalive\n\nid: 7\nevent: endpoint\ndata: /messages\n\ndata: {\"a\":\ndata: 1}\n\ndata: unterminated"))
+	var got []string
+	for {
+		ev, err := r.next()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatal(err)
+			}
+			break
+		}
+		got = append(got, ev.ID+"|"+ev.Event+"|"+ev.Data)
+	}
+	want := "7|endpoint|/messages |message|{\"a\":\n1}"
+	if strings.Join(got, " ") != want {
+		t.Errorf("events = %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestCreateInstance_SSE(t *testing.T) {
+	url := "http://example.invalid/sse"
+	d, err := NewManager(nil, nil).createInstance(context.Background(), InstanceKey{ServerID: "legacy"},
+		&store.DownstreamServer{ID: "legacy", Name: "legacy", Transport: "sse", URL: &url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst, ok := d.(*SSEInstance)
+	if !ok || inst.onNotify == nil {
+		t.Fatalf("instance = %T, want *SSEInstance forwarding notifications", d)
+	}
+}
@@ -0,0 +1,184 @@
+package downstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// wsPingInterval is how often an idle-looking connection is pinged. A
+// connection that has not answered the previous ping by the next one is
+// considered dead and reopened.
+const wsPingInterval = 30 * time.Second
+
+// WebSocketInstance communicates with a remote MCP server over a
+// WebSocket, one JSON-RPC message per text frame in both directions. The
+// connection is kept alive with pings; a dead or dropped connection is
+// reopened with backoff and the session initialized again.
+type WebSocketInstance struct {
+	*remoteConn
+
+	url          string
+	pingInterval time.Duration
+
+	onNotify    func(method string, params json.RawMessage) // called when downstream sends a notification
+	resolveAuth authResolver                                // if set, refreshes authHeaders on each connect
+
+	mu          sync.Mutex
+	headers     http.Header // static headers from the server config
+	authHeaders http.Header
+	ws          *wsConn // current connection
+}
+
+// newWebSocketInstance creates a stopped instance. Auth headers override
+// static headers of the same name.
+func newWebSocketInstance(key InstanceKey, url string, idleTimeout time.Duration, static, auth http.Header) *WebSocketInstance {
+	w := &WebSocketInstance{
+		remoteConn:   newRemoteConn(key, "websocket", idleTimeout),
+		url:          url,
+		pingInterval: wsPingInterval,
+		headers:      static,
+		authHeaders:  auth,
+	}
+	w.dial = w.connect
+	w.rpc = newRPCSession(key.ServerID, w.send)
+	w.rpc.onNotify = func(method string, params json.RawMessage) {
+		if w.onNotify != nil {
+			w.onNotify(method, params)
+		}
+	}
+	return w
+}
+
+// connect opens the WebSocket and initializes the session.
+func (w *WebSocketInstance) connect(ctx, runCtx context.Context) (<-chan struct{}, error) {
+	if w.resolveAuth != nil {
+		auth, err := w.resolveAuth(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("resolve auth: %w", err)
+		}
+		w.mu.Lock()
+		w.authHeaders = auth
+		w.mu.Unlock()
+	}
+	ws, err := dialWebSocket(ctx, w.url, w.handshakeHeaders())
+	if err != nil {
+		return nil, err
+	}
+	connCtx, closeConn := context.WithCancel(runCtx)
+	context.AfterFunc(connCtx, ws.close)
+
+	var awaitingPong atomic.Bool
+	ws.onPong = func() { awaitingPong.Store(false) }
+
+	w.mu.Lock()
+	w.ws = ws
+	w.mu.Unlock()
+	w.rpc.open()
+
+	ended := make(chan struct{})
+	go w.readLoop(ws, closeConn, ended)
+	go w.keepalive(connCtx, ws, &awaitingPong)
+
+	if err := w.initialize(ctx); err != nil {
+		closeConn()
+		<-ended
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	w.markConnected(closeConn)
+	return ended, nil
+}
+
+// handshakeHeaders merges the static headers with the auth headers, which
+// take precedence.
+func (w *WebSocketInstance) handshakeHeaders() http.Header {
+	w.mu.Lock()
+	auth := w.authHeaders
+	w.mu.Unlock()
+	h := make(http.Header)
+	for _, src := range []http.Header{w.headers, auth} {
+		for k, vals := range src {
+			for _, v := range vals {
+				h.Set(k, v)
+			}
+		}
+	}
+	return h
+}
+
+func (w *WebSocketInstance) initialize(ctx context.Context) error {
+	params := json.RawMessage(`{
+		"protocolVersion": "2025-03-26",
+		"capabilities": {"sampling": {}, "elicitation": {}},
+		"clientInfo": {"name": "mcplexer", "version": "0.1.0"}
+	}`)
+	if _, err := w.rpc.roundTrip(ctx, "initialize", params); err != nil {
+		return err
+	}
+	return w.rpc.notify(ctx, "notifications/initialized")
+}
+
+// readLoop passes the connection's messages to the session until it ends,
+// then fails the requests still waiting on it.
+func (w *WebSocketInstance) readLoop(ws *wsConn, closeConn context.CancelFunc, done chan struct{}) {
+	defer close(done)
+	defer w.rpc.close(errConnectionClosed)
+	defer closeConn()
+	for {
+		_, data, err := ws.readMessage()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				slog.Debug("websocket read failed",
+					"server", w.key.ServerID, "error", err)
+			}
+			return
+		}
+		w.rpc.dispatch(data)
+	}
+}
+
+// keepalive pings the server every pingInterval and closes the connection
+// if the previous ping went unanswered.
+func (w *WebSocketInstance) keepalive(ctx context.Context, ws *wsConn, awaitingPong *atomic.Bool) {
+	t := time.NewTicker(w.pingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if awaitingPong.Swap(true) {
+			slog.Warn("downstream websocket missed a pong, reconnecting",
+				"server", w.key.ServerID)
+			ws.close()
+			return
+		}
+		if err := ws.writeMessage(wsOpPing, nil); err != nil {
+			ws.close()
+			return
+		}
+	}
+}
+
+// send writes a JSON-RPC message as a text frame.
+func (w *WebSocketInstance) send(_ context.Context, msg any) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+	w.mu.Lock()
+	ws := w.ws
+	w.mu.Unlock()
+	if ws == nil {
+		return errConnectionClosed
+	}
+	return ws.writeMessage(wsOpText, body)
+}
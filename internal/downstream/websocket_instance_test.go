@@ -0,0 +1,353 @@
+package downstream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// acceptWebSocket completes the server side of the opening handshake.
+func acceptWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", wsAcceptKey(r.Header.Get("Sec-WebSocket-Key")))
+	if err := rw.Flush(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, br: rw.Reader}, nil
+}
+
+// wsMCPServer is an in-process MCP server on the WebSocket transport.
+type wsMCPServer struct {
+	mu          sync.Mutex
+	conns       []*wsConn
+	inits       int
+	auth        []string // Authorization header of each connection
+	protocols   []string // offered subprotocols of each connection
+	ignorePings bool     // drop pings instead of answering them
+	rejectAuth  bool     // answer new handshakes with 401
+}
+
+func newWSMCPServer(t *testing.T) (*wsMCPServer, *httptest.Server) {
+	s := &wsMCPServer{}
+	ts := httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func (s *wsMCPServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	reject := s.rejectAuth
+	s.mu.Unlock()
+	if reject {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	ws, err := acceptWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.conns = append(s.conns, ws)
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+	s.protocols = append(s.protocols, r.Header.Get("Sec-WebSocket-Protocol"))
+	ignorePings := s.ignorePings
+	s.mu.Unlock()
+
+	for {
+		var data []byte
+		if ignorePings {
+			_, op, payload, err := ws.readFrame()
+			if err != nil {
+				return
+			}
+			if op != wsOpText {
+				continue
+			}
+			data = payload
+		} else {
+			_, payload, err := ws.readMessage()
+			if err != nil {
+				return
+			}
+			data = payload
+		}
+
+		var msg jsonRPCMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return
+		}
+		switch msg.Method {
+		case "initialize":
+			s.mu.Lock()
+			s.inits++
+			s.mu.Unlock()
+			_ = ws.writeMessage(wsOpText, fmt.Appendf(nil,
+				`{"jsonrpc":"2.0","id":%s,"result":{"protocolVersion":"2025-03-26"}}`, msg.ID))
+		case "tools/list":
+			_ = ws.writeMessage(wsOpText, []byte(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`))
+			_ = ws.writeMessage(wsOpText, fmt.Appendf(nil,
+				`{"jsonrpc":"2.0","id":%s,"result":{"tools":[{"name":"echo"}]}}`, msg.ID))
+		}
+	}
+}
+
+func (s *wsMCPServer) connCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// waitDisconnected waits until c notices its connection dropped.
+func waitDisconnected(t *testing.T, c *remoteConn) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		connected := c.connected
+		c.mu.Unlock()
+		select {
+		case <-connected:
+			time.Sleep(5 * time.Millisecond)
+		default:
+			return
+		}
+	}
+	t.Fatal("connection drop not noticed")
+}
+
+func wsURL(ts *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+func TestWebSocketInstance_CallsAndNotifications(t *testing.T) {
+	srv, ts := newWSMCPServer(t)
+	static := http.Header{}
+	static.Set("Authorization", "Bearer static")
+	auth := http.Header{}
+	auth.Set("Authorization", "Bearer scoped")
+	inst := newWebSocketInstance(InstanceKey{ServerID: "ws"}, wsURL(ts), 0, static, auth)
+	notified := make(chan string, 4)
+	inst.onNotify = func(method string, _ json.RawMessage) { notified <- method }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := inst.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	res, err := inst.ListTools(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(res), `"echo"`) {
+		t.Errorf("tools = %s", res)
+	}
+	select {
+	case m := <-notified:
+		if m != "notifications/tools/list_changed" {
+			t.Errorf("notification = %s", m)
+		}
+	case <-ctx.Done():
+		t.Fatal("notification not forwarded")
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.auth[0] != "Bearer scoped" || srv.protocols[0] != "mcp" {
+		t.Errorf("handshake Authorization = %q, protocol = %q", srv.auth[0], srv.protocols[0])
+	}
+}
+
+func TestWebSocketInstance_ReconnectsAndReinitializes(t *testing.T) {
+	srv, ts := newWSMCPServer(t)
+	inst := newWebSocketInstance(InstanceKey{ServerID: "ws"}, wsURL(ts), 0, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := inst.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	srv.mu.Lock()
+	_ = srv.conns[0].conn.Close()
+	srv.mu.Unlock()
+
+	// Wait for the drop to be noticed so the call waits for the new
+	// connection rather than racing the old one.
+	waitDisconnected(t, inst.remoteConn)
+	if _, err := inst.ListTools(ctx); err != nil {
+		t.Fatalf("call after reconnect: %v", err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.inits != 2 {
+		t.Errorf("initialize sent %d times, want 2", srv.inits)
+	}
+}
+
+func TestWebSocketInstance_ReconnectResolvesAuth(t *testing.T) {
+	srv, ts := newWSMCPServer(t)
+	inst := newWebSocketInstance(InstanceKey{ServerID: "ws"}, wsURL(ts), 0, nil, nil)
+	var tokens atomic.Int32
+	inst.resolveAuth = func(context.Context) (http.Header, error) {
+		h := http.Header{}
+		h.Set("Authorization", fmt.Sprintf("Bearer t%d", tokens.Add(1)))
+		return h, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := inst.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	srv.mu.Lock()
+	_ = srv.conns[0].conn.Close()
+	srv.mu.Unlock()
+	waitDisconnected(t, inst.remoteConn)
+	if _, err := inst.ListTools(ctx); err != nil {
+		t.Fatalf("call after reconnect: %v", err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if got := strings.Join(srv.auth, ","); got != "Bearer t1,Bearer t2" {
+		t.Errorf("handshake Authorization = %s, want a fresh token per connection", got)
+	}
+}
+
+func TestWebSocketInstance_StopsReconnectingWhenUnauthorized(t *testing.T) {
+	srv, ts := newWSMCPServer(t)
+	inst := newWebSocketInstance(InstanceKey{ServerID: "ws"}, wsURL(ts), 0, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := inst.start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	srv.mu.Lock()
+	srv.rejectAuth = true
+	_ = srv.conns[0].conn.Close()
+	srv.mu.Unlock()
+	waitDisconnected(t, inst.remoteConn)
+
+	if _, err := inst.ListTools(ctx); !errors.Is(err, ErrAuthRequired) {
+		t.Fatalf("call = %v, want ErrAuthRequired", err)
+	}
+	if st := inst.getState(); st != StateStopped {
+		t.Errorf("state = %s, want stopped", st)
+	}
+}
+
+func TestWebSocketInstance_MissedPongReconnects(t *testing.T) {
+	srv, ts := newWSMCPServer(t)
+	srv.ignorePings = true
+	inst := newWebSocketInstance(InstanceKey{ServerID: "ws"}, wsURL(ts), 0, nil, nil)
+	inst.pingInterval = 20 * time.Millisecond
+
+	if err := inst.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.connCount() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("unanswered pings did not reopen the connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebSocketInstance_PongKeepsConnection(t *testing.T) {
+	srv, ts := newWSMCPServer(t)
+	inst := newWebSocketInstance(InstanceKey{ServerID: "ws"}, wsURL(ts), 0, nil, nil)
+	inst.pingInterval = 10 * time.Millisecond
+
+	if err := inst.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer inst.stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if n := srv.connCount(); n != 1 {
+		t.Errorf("connections = %d, want 1", n)
+	}
+}
+
+func TestWebSocketInstance_HandshakeUnauthorized(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	inst := newWebSocketInstance(InstanceKey{ServerID: "ws"}, wsURL(ts), 0, nil, nil)
+	if err := inst.start(context.Background()); err != ErrAuthRequired {
+		t.Fatalf("start = %v, want ErrAuthRequired", err)
+	}
+	if inst.getState() != StateStopped {
+		t.Errorf("state = %s", inst.getState())
+	}
+}
+
+func TestWSConn_Framing(t *testing.T) {
+	a, b := net.Pipe()
+	client := &wsConn{conn: a, br: bufio.NewReader(a), client: true}
+	server := &wsConn{conn: b, br: bufio.NewReader(b)}
+	defer a.Close()
+	defer b.Close()
+
+	large := bytes.Repeat([]byte("x"), 70000)
+	go func() {
+		_ = client.writeMessage(wsOpText, large)
+		// A fragmented message with a ping between its fragments.
+		_, _ = a.Write([]byte{wsOpText, 0x80 | 2, 0, 0, 0, 0, 'h', 'e'})
+		_, _ = a.Write([]byte{0x80 | wsOpPing, 0x80, 0, 0, 0, 0})
+		_, _ = a.Write([]byte{0x80 | wsOpContinuation, 0x80 | 3, 1, 1, 1, 1, 'l' ^ 1, 'l' ^ 1, 'o' ^ 1})
+	}()
+	go func() {
+		// Drain the pong the server sends back.
+		_, _, _, _ = client.readFrame()
+	}()
+
+	if _, got, err := server.readMessage(); err != nil || !bytes.Equal(got, large) {
+		t.Fatalf("large message: %d bytes, %v", len(got), err)
+	}
+	if op, got, err := server.readMessage(); err != nil || op != wsOpText || string(got) != "hello" {
+		t.Fatalf("fragmented message = %q (op %d), %v", got, op, err)
+	}
+}
+
+func TestCreateInstance_WebSocket(t *testing.T) {
+	url := "wss://example.invalid/mcp"
+	d, err := NewManager(nil, nil).createInstance(context.Background(), InstanceKey{ServerID: "ws"},
+		&store.DownstreamServer{ID: "ws", Name: "ws", Transport: "websocket", URL: &url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst, ok := d.(*WebSocketInstance)
+	if !ok || inst.onNotify == nil {
+		t.Fatalf("instance = %T, want *WebSocketInstance forwarding notifications", d)
+	}
+}
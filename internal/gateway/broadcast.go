@@ -0,0 +1,65 @@
+package gateway
+
+import "sync"
+
+// Broadcaster fans downstream change notifications out to every running
+// session of a daemon, where each socket connection and Streamable HTTP
+// session has its own Server. Servers built WithBroadcaster join it for
+// as long as they run.
+type Broadcaster struct {
+	mu      sync.Mutex
+	servers map[*Server]struct{}
+}
+
+// NewBroadcaster returns a Broadcaster with no sessions.
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{servers: make(map[*Server]struct{})}
+}
+
+func (b *Broadcaster) add(s *Server) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.servers[s] = struct{}{}
+}
+
+func (b *Broadcaster) remove(s *Server) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.servers, s)
+}
+
+// each calls fn for every running session.
+func (b *Broadcaster) each(fn func(*Server)) {
+	b.mu.Lock()
+	servers := make([]*Server, 0, len(b.servers))
+	for s := range b.servers {
+		servers = append(servers, s)
+	}
+	b.mu.Unlock()
+	for _, s := range servers {
+		fn(s)
+	}
+}
+
+// NotifyResourcesChanged sends resources/list_changed to every session.
+func (b *Broadcaster) NotifyResourcesChanged() {
+	b.each((*Server).NotifyResourcesChanged)
+}
+
+// InvalidateAndNotifyToolsChanged sends tools/list_changed to every
+// session.
+func (b *Broadcaster) InvalidateAndNotifyToolsChanged() {
+	b.each((*Server).InvalidateAndNotifyToolsChanged)
+}
+
+// InvalidateAndNotifyPromptsChanged sends prompts/list_changed to every
+// session.
+func (b *Broadcaster) InvalidateAndNotifyPromptsChanged() {
+	b.each((*Server).InvalidateAndNotifyPromptsChanged)
+}
+
+// NotifyResourceUpdated forwards resources/updated to the sessions
+// subscribed to the resource.
+func (b *Broadcaster) NotifyResourceUpdated(serverID, uri string) {
+	b.each(func(s *Server) { s.NotifyResourceUpdated(serverID, uri) })
+}
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBroadcaster_FansOutToSessions(t *testing.T) {
+	b := NewBroadcaster()
+	rl := &mockResourceLister{}
+	var handlers []*handler
+	var notifiers []*recordingNotifier
+	for range 2 {
+		h := newResourceTestHandler(rl)
+		n := &recordingNotifier{}
+		h.setNotifier(n)
+		handlers = append(handlers, h)
+		notifiers = append(notifiers, n)
+		b.add(&Server{handler: h})
+	}
+
+	// Only the first session subscribes.
+	params, _ := json.Marshal(ReadResourceRequest{URI: "mcpx://fs/file:///tmp/a.txt"})
+	if _, rpcErr := handlers[0].handleResourcesSubscribe(context.Background(), params, true); rpcErr != nil {
+		t.Fatalf("subscribe: %s", rpcErr.Message)
+	}
+
+	b.NotifyResourcesChanged()
+	b.NotifyResourceUpdated("fs", "file:///tmp/a.txt")
+	if got := notifiers[0].methods; len(got) != 2 {
+		t.Errorf("subscribed session got %v, want list_changed and updated", got)
+	}
+	if got := notifiers[1].methods; len(got) != 1 || got[0] != "notifications/resources/list_changed" {
+		t.Errorf("other session got %v, want only list_changed", got)
+	}
+}
+
+func TestBroadcaster_LeftWhenSessionEnds(t *testing.T) {
+	b := NewBroadcaster()
+	s := &Server{handler: newResourceTestHandler(&mockResourceLister{}), broadcaster: b}
+	if err := s.RunConn(context.Background(), strings.NewReader(""), io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.servers) != 0 {
+		t.Errorf("%d sessions still registered after the connection closed", len(b.servers))
+	}
+}
+
+func TestBroadcaster_PromptsChanged(t *testing.T) {
+	b := NewBroadcaster()
+	var notifiers []*recordingNotifier
+	for range 2 {
+		h := newPromptTestHandler(&mockPromptLister{})
+		n := &recordingNotifier{}
+		h.setNotifier(n)
+		notifiers = append(notifiers, n)
+		b.add(&Server{handler: h})
+	}
+
+	b.InvalidateAndNotifyPromptsChanged()
+	for i, n := range notifiers {
+		if len(n.methods) != 1 || n.methods[0] != "notifications/prompts/list_changed" {
+			t.Errorf("session %d got %v, want prompts/list_changed", i, n.methods)
+		}
+	}
+}
+
+func TestBroadcaster_ToolsChanged(t *testing.T) {
+	b := NewBroadcaster()
+	var notifiers []*recordingNotifier
+	for range 2 {
+		h := newHandler(&mockStore{}, nil, &mockToolLister{}, nil, TransportSocket, nil, nil, nil, nil)
+		n := &recordingNotifier{}
+		h.setNotifier(n)
+		notifiers = append(notifiers, n)
+		b.add(&Server{handler: h})
+	}
+
+	b.InvalidateAndNotifyToolsChanged()
+	for i, n := range notifiers {
+		if len(n.methods) != 1 || n.methods[0] != "notifications/tools/list_changed" {
+			t.Errorf("session %d got %v, want tools/list_changed", i, n.methods)
+		}
+	}
+}
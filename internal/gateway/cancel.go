@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+)
+
+// cancelledError is the context cause for a request the client cancelled
+// with notifications/cancelled, or abandoned by disconnecting.
+type cancelledError struct {
+	reason string
+}
+
+func (e *cancelledError) Error() string { return "request cancelled: " + e.reason }
+
+// cancellationReason reports whether ctx was cancelled on the client's
+// behalf, and why.
+func cancellationReason(ctx context.Context) (string, bool) {
+	var ce *cancelledError
+	if errors.As(context.Cause(ctx), &ce) {
+		return ce.reason, true
+	}
+	return "", false
+}
+
+// CancelledParams is the payload of notifications/cancelled.
+type CancelledParams struct {
+	RequestID json.RawMessage `json:"requestId"`
+	Reason    string          `json:"reason,omitempty"`
+}
+
+// inflightRequest is a client request that has been read but not yet
+// answered.
+type inflightRequest struct {
+	cancel    context.CancelCauseFunc
+	cancelled bool // guarded by Server.inflightMu
+}
+
+// trackRequest registers a client request so it can be cancelled, and
+// returns the context to handle it with. initialize cannot be cancelled.
+func (s *Server) trackRequest(ctx context.Context, id json.RawMessage, method string) (context.Context, string) {
+	if id == nil || method == "" || method == "initialize" {
+		return ctx, ""
+	}
+	key := compactID(id)
+	reqCtx, cancel := context.WithCancelCause(ctx)
+
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+	if s.inflight == nil {
+		s.inflight = make(map[string]*inflightRequest)
+	}
+	s.inflight[key] = &inflightRequest{cancel: cancel}
+	return reqCtx, key
+}
+
+// finishRequest releases a tracked request and reports whether the client
+// cancelled it, in which case no response is sent.
+func (s *Server) finishRequest(key string) bool {
+	if key == "" {
+		return false
+	}
+	s.inflightMu.Lock()
+	req := s.inflight[key]
+	delete(s.inflight, key)
+	s.inflightMu.Unlock()
+	if req == nil {
+		return false
+	}
+	req.cancel(nil)
+	return req.cancelled
+}
+
+// cancelRequest aborts the in-flight request with the given ID. Unknown
+// IDs are ignored: the request may already have completed.
+func (s *Server) cancelRequest(id json.RawMessage, reason string) {
+	s.inflightMu.Lock()
+	req := s.inflight[compactID(id)]
+	if req != nil {
+		req.cancelled = true
+	}
+	s.inflightMu.Unlock()
+	if req == nil {
+		return
+	}
+	if reason == "" {
+		reason = "cancelled by client"
+	}
+	slog.Info("client cancelled request", "id", string(id), "reason", reason)
+	req.cancel(&cancelledError{reason: reason})
+}
+
+// cancelAll aborts every in-flight request, e.g. when the client disconnects.
+func (s *Server) cancelAll(reason string) {
+	s.inflightMu.Lock()
+	reqs := make([]*inflightRequest, 0, len(s.inflight))
+	for _, req := range s.inflight {
+		req.cancelled = true
+		reqs = append(reqs, req)
+	}
+	s.inflightMu.Unlock()
+
+	for _, req := range reqs {
+		req.cancel(&cancelledError{reason: reason})
+	}
+}
+
+// handleCancellation applies a notifications/cancelled message from the
+// client. It runs on the reader goroutine so it can interrupt the request
+// the worker is handling. Returns false if line is not a cancellation.
+func (s *Server) handleCancellation(line []byte) bool {
+	if !bytes.Contains(line, []byte(`"notifications/cancelled"`)) {
+		return false
+	}
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params CancelledParams `json:"params"`
+	}
+	if err := json.Unmarshal(line, &msg); err != nil ||
+		msg.ID != nil || msg.Method != "notifications/cancelled" {
+		return false
+	}
+	if msg.Params.RequestID != nil {
+		s.cancelRequest(msg.Params.RequestID, msg.Params.Reason)
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// blockingToolLister blocks every Call until its context is cancelled and
+// reports the cancellation cause.
+type blockingToolLister struct {
+	mockToolLister
+	started chan struct{}
+	causes  chan error
+}
+
+func (b *blockingToolLister) Call(ctx context.Context, _, _, _ string, _ json.RawMessage) (json.RawMessage, error) {
+	close(b.started)
+	<-ctx.Done()
+	b.causes <- context.Cause(ctx)
+	return nil, ctx.Err()
+}
+
+func TestServer_ClientCancelsInFlightCall(t *testing.T) {
+	lister := &blockingToolLister{started: make(chan struct{}), causes: make(chan error, 1)}
+	h := newServerRequestTestHandler(nil)
+	h.manager = lister
+	s := &Server{handler: h}
+
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- s.RunConn(ctx, serverR, serverW) }()
+
+	write := func(line string) {
+		t.Helper()
+		if _, err := io.WriteString(clientW, line+"\n"); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	write(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"github__search","arguments":{}}}`)
+
+	select {
+	case <-lister.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("tool call never reached the downstream")
+	}
+	write(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"user pressed stop"}}`)
+
+	select {
+	case cause := <-lister.causes:
+		var ce *cancelledError
+		if !errors.As(cause, &ce) || ce.reason != "user pressed stop" {
+			t.Errorf("cause = %v, want client cancellation", cause)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("downstream call was not cancelled")
+	}
+
+	// No response is sent for the cancelled request; the next one is
+	// answered normally.
+	write(`{"jsonrpc":"2.0","id":8,"method":"ping"}`)
+	out := bufio.NewScanner(clientR)
+	if !out.Scan() {
+		t.Fatal("no response after cancellation")
+	}
+	var resp Response
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if string(resp.ID) != "8" {
+		t.Errorf("got response for id %s, want 8 only", resp.ID)
+	}
+
+	clientW.Close()
+	go io.Copy(io.Discard, clientR) //nolint:errcheck
+	if err := <-done; err != nil {
+		t.Errorf("run: %v", err)
+	}
+}
+
+func TestServer_DisconnectCancelsInFlightCall(t *testing.T) {
+	lister := &blockingToolLister{started: make(chan struct{}), causes: make(chan error, 1)}
+	h := newServerRequestTestHandler(nil)
+	h.manager = lister
+	s := &Server{handler: h}
+
+	serverR, clientW := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- s.RunConn(context.Background(), serverR, io.Discard) }()
+
+	if _, err := io.WriteString(clientW,
+		`{"jsonrpc":"2.0","id":"a","method":"tools/call","params":{"name":"github__search"}}`+"\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	<-lister.started
+	clientW.Close()
+
+	select {
+	case cause := <-lister.causes:
+		var ce *cancelledError
+		if !errors.As(cause, &ce) || ce.reason != "client disconnected" {
+			t.Errorf("cause = %v, want disconnect", cause)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("downstream call was not cancelled on disconnect")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("run: %v", err)
+	}
+}
+
+func TestMarkCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(&cancelledError{reason: "client disconnected"})
+
+	rec := &store.AuditRecord{Status: "error", ErrorMessage: "context canceled"}
+	markCancelled(ctx, rec)
+	if rec.Status != "cancelled" || rec.ErrorMessage != "client disconnected" {
+		t.Errorf("record = %+v", rec)
+	}
+}
@@ -231,6 +231,13 @@ func extractFromGitHubURL(raw string, targets *githubTargets) {
 	if err != nil {
 		return
 	}
+	// The GitHub MCP server's resources: repo://owner/name/...
+	if strings.EqualFold(u.Scheme, "repo") {
+		if name, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "/"); name != "" {
+			targets.addRepo(u.Host + "/" + name)
+		}
+		return
+	}
 	host := strings.ToLower(u.Host)
 	if host != "github.com" && host != "www.github.com" && host != "api.github.com" {
 		return
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"math"
 	"sync"
 	"time"
 
@@ -24,6 +25,30 @@ type ToolLister interface {
 	Call(ctx context.Context, serverID, authScopeID, toolName string, args json.RawMessage) (json.RawMessage, error)
 }
 
+// SessionReleaser is implemented by a ToolLister that keeps downstream
+// instances per session; ReleaseSession stops them when the session ends.
+type SessionReleaser interface {
+	ReleaseSession(sessionID string)
+}
+
+// ResourceLister abstracts downstream resource discovery and reads.
+// Resource URIs passed to and returned from these methods are the
+// downstream's original (un-namespaced) URIs.
+type ResourceLister interface {
+	ListResourcesForServers(ctx context.Context, serverIDs []string) (map[string]json.RawMessage, error)
+	ListResourceTemplatesForServers(ctx context.Context, serverIDs []string) (map[string]json.RawMessage, error)
+	ReadResource(ctx context.Context, serverID, authScopeID, uri string) (json.RawMessage, error)
+	SubscribeResource(ctx context.Context, serverID, authScopeID, uri string, subscribe bool) error
+}
+
+// PromptLister abstracts downstream prompt discovery and retrieval.
+// Prompt names passed to GetPrompt are the downstream's original
+// (un-namespaced) names.
+type PromptLister interface {
+	ListPromptsForServers(ctx context.Context, serverIDs []string) (map[string]json.RawMessage, error)
+	GetPrompt(ctx context.Context, serverID, authScopeID, name string, args json.RawMessage) (json.RawMessage, error)
+}
+
 // CachingCaller extends ToolLister with cache-aware calling.
 type CachingCaller interface {
 	ToolLister
@@ -41,9 +66,19 @@ type handler struct {
 	approvals      *approval.Manager // nil = approval system disabled
 	settingsSvc    *config.SettingsService
 	toolsListCache *cache.Cache[string, json.RawMessage]
-	notifier       Notifier // set at runtime for sending notifications
+	notifier       Notifier        // set at runtime for sending notifications
+	requester      ClientRequester // set at runtime for server→client requests
 	addonRegistry  *addon.Registry // nil = no addons loaded
 	addonExecutor  *addon.Executor // nil = no addons loaded
+	resources      ResourceLister  // nil = resource proxying disabled
+	prompts        PromptLister    // nil = prompt proxying disabled
+
+	promptsListCache *cache.Cache[string, json.RawMessage]
+
+	// subscriptions holds the resources the client subscribed to, or is
+	// subscribing to, by namespaced URI.
+	subscriptions   map[string]*resourceSubscription
+	subscriptionsMu sync.Mutex
 
 	// bgCtx is a long-lived context for background goroutines (set from run()).
 	bgCtx context.Context
@@ -76,16 +111,17 @@ func newHandler(
 		}
 	}
 	return &handler{
-		store:          s,
-		engine:         e,
-		manager:        m,
-		sessions:       newSessionManager(s, e, t),
-		auditor:        a,
-		approvals:      approvals,
-		settingsSvc:    settingsSvc,
-		toolsListCache: cache.New[string, json.RawMessage](10, ttl),
-		addonRegistry:  addonReg,
-		addonExecutor:  addonExec,
+		store:            s,
+		engine:           e,
+		manager:          m,
+		sessions:         newSessionManager(s, e, t),
+		auditor:          a,
+		approvals:        approvals,
+		settingsSvc:      settingsSvc,
+		toolsListCache:   cache.New[string, json.RawMessage](10, ttl),
+		promptsListCache: cache.New[string, json.RawMessage](10, ttl),
+		addonRegistry:    addonReg,
+		addonExecutor:    addonExec,
 	}
 }
 
@@ -100,6 +136,7 @@ func (h *handler) handleInitialize(
 	if err := h.sessions.create(ctx, p.ClientInfo, p.Roots); err != nil {
 		slog.Error("create session", "error", err)
 	}
+	h.sessions.setClientCapabilities(p.Capabilities)
 
 	result := InitializeResult{
 		ProtocolVersion: "2025-03-26",
@@ -108,6 +145,12 @@ func (h *handler) handleInitialize(
 		},
 		ServerInfo: ServerInfo{Name: "mcplexer", Version: "0.1.6"},
 	}
+	if h.resources != nil {
+		result.Capabilities.Resources = &ResourceCapability{Subscribe: true, ListChanged: true}
+	}
+	if h.prompts != nil {
+		result.Capabilities.Prompts = &PromptCapability{ListChanged: true}
+	}
 
 	data, err := json.Marshal(result)
 	if err != nil {
@@ -124,6 +167,34 @@ func (h *handler) InvalidateAndNotifyToolsChanged() {
 	h.sendToolsListChanged()
 }
 
+// InvalidateAndNotifyPromptsChanged clears the prompts/list cache and sends
+// a prompts/list_changed notification to the connected client.
+func (h *handler) InvalidateAndNotifyPromptsChanged() {
+	h.promptsListCache.Flush()
+	if h.notifier == nil || h.prompts == nil {
+		return
+	}
+	if err := h.notifier.Notify("notifications/prompts/list_changed", nil); err != nil {
+		slog.Warn("failed to send prompts/list_changed notification", "error", err)
+	}
+}
+
+// mapRateLimitError converts a rate limit refusal into a CodeRateLimited
+// error whose data tells the client when to retry.
+func mapRateLimitError(err error) *RPCError {
+	var rl *routing.RateLimitedError
+	if !errors.As(err, &rl) {
+		return &RPCError{Code: CodeInternalError, Message: err.Error()}
+	}
+	data, _ := json.Marshal(map[string]any{
+		"rule_id":         rl.RuleID,
+		"scope":           rl.Scope,
+		"quota":           rl.Quota,
+		"retry_after_sec": int(math.Ceil(rl.RetryAfter.Seconds())),
+	})
+	return &RPCError{Code: CodeRateLimited, Message: rl.Error(), Data: data}
+}
+
 func mapRouteError(err error) *RPCError {
 	switch {
 	case errors.Is(err, routing.ErrNoRoute):
@@ -49,8 +49,9 @@ func (h *handler) recordAuditWithCache(
 		wsID = route.MatchedWorkspaceID
 		wsName = route.MatchedWorkspaceName
 		subpath = route.Subpath
-	} else if ancestors := h.sessions.workspaceAncestors(ctx); len(ancestors) > 0 {
-		subpath = routing.ComputeSubpath(h.sessions.clientRoot(), ancestors[0].RootPath)
+	} else if root := h.sessions.auditRoot(ctx); len(root.Chain) > 0 {
+		wsID, wsName = root.Chain[0].ID, root.Chain[0].Name
+		subpath = routing.ComputeSubpath(root.Path, root.Chain[0].RootPath)
 	}
 
 	rec := &store.AuditRecord{
@@ -80,13 +81,16 @@ func (h *handler) recordAuditWithCache(
 		rec.Status = "error"
 		rec.ErrorCode = fmt.Sprintf("%d", rpcErr.Code)
 		rec.ErrorMessage = rpcErr.Message
+		markCancelled(ctx, rec)
 	} else if isToolError(result) {
 		rec.Status = "error"
 		rec.ErrorCode = "tool_error"
 		rec.ErrorMessage = extractToolErrorText(result)
 	}
 
-	if err := h.auditor.Record(ctx, rec); err != nil {
+	// The call's context may have been cancelled by the client; the
+	// record must still be written.
+	if err := h.auditor.Record(context.WithoutCancel(ctx), rec); err != nil {
 		slog.Error("audit record failed", "error", err)
 	}
 }
@@ -101,6 +105,49 @@ func (h *handler) recordAuditBlocked(
 	result json.RawMessage,
 	rpcErr *RPCError,
 	start time.Time,
+) {
+	h.recordAuditRejected(ctx, "blocked", toolName, params, route, result, rpcErr, nil, start)
+}
+
+// recordAuditRouteRejected creates an audit record with status "blocked" for
+// calls denied by a route rule or matching no route, keeping the routing
+// trace that explains why.
+func (h *handler) recordAuditRouteRejected(
+	ctx context.Context,
+	toolName string,
+	params json.RawMessage,
+	trace *routing.Trace,
+	rpcErr *RPCError,
+	start time.Time,
+) {
+	h.recordAuditRejected(ctx, "blocked", toolName, params, nil, nil, rpcErr, trace, start)
+}
+
+// recordAuditRateLimited creates an audit record with status "rate_limited"
+// for calls refused by a route rule's rate limit or daily quota.
+func (h *handler) recordAuditRateLimited(
+	ctx context.Context,
+	toolName string,
+	params json.RawMessage,
+	route *routing.RouteResult,
+	rpcErr *RPCError,
+	start time.Time,
+) {
+	h.recordAuditRejected(ctx, "rate_limited", toolName, params, route, nil, rpcErr, nil, start)
+}
+
+// recordAuditRejected creates an audit record for a call refused before
+// dispatch, with the given status. trace is set for routing refusals.
+func (h *handler) recordAuditRejected(
+	ctx context.Context,
+	status string,
+	toolName string,
+	params json.RawMessage,
+	route *routing.RouteResult,
+	result json.RawMessage,
+	rpcErr *RPCError,
+	trace *routing.Trace,
+	start time.Time,
 ) {
 	if h.auditor == nil {
 		return
@@ -113,8 +160,9 @@ func (h *handler) recordAuditBlocked(
 		wsID = route.MatchedWorkspaceID
 		wsName = route.MatchedWorkspaceName
 		subpath = route.Subpath
-	} else if ancestors := h.sessions.workspaceAncestors(ctx); len(ancestors) > 0 {
-		subpath = routing.ComputeSubpath(h.sessions.clientRoot(), ancestors[0].RootPath)
+	} else if root := h.sessions.auditRoot(ctx); len(root.Chain) > 0 {
+		wsID, wsName = root.Chain[0].ID, root.Chain[0].Name
+		subpath = routing.ComputeSubpath(root.Path, root.Chain[0].RootPath)
 	}
 
 	rec := &store.AuditRecord{
@@ -128,7 +176,7 @@ func (h *handler) recordAuditBlocked(
 		Subpath:        subpath,
 		ToolName:       toolName,
 		ParamsRedacted: params,
-		Status:         "blocked",
+		Status:         status,
 		LatencyMs:      int(time.Since(start).Milliseconds()),
 		ResponseSize:   len(result),
 	}
@@ -138,20 +186,37 @@ func (h *handler) recordAuditBlocked(
 		rec.DownstreamServerID = route.DownstreamServerID
 		rec.AuthScopeID = route.AuthScopeID
 	}
+	if trace != nil {
+		rec.RouteRuleID = trace.RuleID
+		rec.RouteTrace, _ = json.Marshal(trace)
+	}
 
 	if rpcErr != nil {
 		rec.ErrorCode = fmt.Sprintf("%d", rpcErr.Code)
 		rec.ErrorMessage = rpcErr.Message
+		markCancelled(ctx, rec)
 	} else if isToolError(result) {
 		rec.ErrorCode = "blocked"
 		rec.ErrorMessage = extractToolErrorText(result)
 	}
 
-	if err := h.auditor.Record(ctx, rec); err != nil {
+	if err := h.auditor.Record(context.WithoutCancel(ctx), rec); err != nil {
 		slog.Error("audit record failed", "error", err)
 	}
 }
 
+// markCancelled records a failure caused by the client cancelling the
+// request (or disconnecting) as "cancelled" rather than an error.
+func markCancelled(ctx context.Context, rec *store.AuditRecord) {
+	reason, ok := cancellationReason(ctx)
+	if !ok {
+		return
+	}
+	rec.Status = "cancelled"
+	rec.ErrorCode = "cancelled"
+	rec.ErrorMessage = reason
+}
+
 // isToolError checks whether a tools/call result has isError set.
 func isToolError(result json.RawMessage) bool {
 	if len(result) == 0 {
@@ -71,6 +71,9 @@ func (h *handler) handleBuiltinCall(
 		}
 		return h.handleResolveApproval(args.ApprovalID, args.Reason, false)
 
+	case "mcpx__explain_last_denial":
+		return h.handleExplainLastDenial(ctx)
+
 	case "mcpx__flush_cache":
 		var args struct {
 			ServerID string `json:"server_id"`
@@ -196,6 +199,7 @@ func (h *handler) handleApprovalGate(
 	route *routing.RouteResult,
 	originalTool string,
 	start time.Time,
+	progress *progressReporter,
 ) (json.RawMessage, *RPCError) {
 	// Parse arguments to check for _justification.
 	var args map[string]json.RawMessage
@@ -246,7 +250,10 @@ func (h *handler) handleApprovalGate(
 		TimeoutSec:         timeout,
 	}
 
+	// Let the client show that the call is waiting on a human.
+	stopProgress := progress.awaitingApproval(req.Name)
 	approved, err := h.approvals.RequestApproval(ctx, rec)
+	stopProgress()
 	if err != nil {
 		rpcErr := &RPCError{
 			Code:    CodeInternalError,
@@ -349,6 +349,9 @@ func (h *handler) codeModeBuiltinTools(hasDynamicServers bool) []Tool {
 	if _, ok := h.manager.(CachingCaller); ok {
 		tools = append(tools, flushCacheToolDefinition())
 	}
+	if h.auditor != nil {
+		tools = append(tools, explainToolDefinition())
+	}
 	return tools
 }
 
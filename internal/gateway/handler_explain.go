@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/routing"
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// explainSearchLimit is how many recent blocked calls are searched for one
+// with a routing trace; approval refusals are blocked but have none.
+const explainSearchLimit = 50
+
+// explainToolDefinition returns the built-in mcpx__explain_last_denial Tool.
+func explainToolDefinition() Tool {
+	return Tool{
+		Name:        "mcpx__explain_last_denial",
+		Description: "Explain why your most recent tool call in this session was denied by policy or matched no route. Lists each workspace tried and, for every route rule considered, whether it matched or why it was skipped (path glob, tool pattern, namespace, git state, schedule or argument conditions).",
+		InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`),
+		Extras: withAnnotations(ToolAnnotations{
+			Title:           "Explain Last Denial",
+			ReadOnlyHint:    boolPtr(true),
+			DestructiveHint: boolPtr(false),
+			OpenWorldHint:   boolPtr(false),
+		}),
+	}
+}
+
+func (h *handler) handleExplainLastDenial(ctx context.Context) (json.RawMessage, *RPCError) {
+	if h.auditor == nil || h.store == nil {
+		return marshalErrorResult("Audit logging is not enabled."), nil
+	}
+	sessionID := h.sessions.sessionID()
+	status := "blocked"
+	records, _, err := h.store.QueryAuditRecords(ctx, store.AuditFilter{
+		SessionID: &sessionID,
+		Status:    &status,
+		Limit:     explainSearchLimit,
+	})
+	if err != nil {
+		return nil, &RPCError{Code: CodeInternalError, Message: err.Error()}
+	}
+	for _, rec := range records {
+		if len(rec.RouteTrace) == 0 {
+			continue
+		}
+		var trace routing.Trace
+		if err := json.Unmarshal(rec.RouteTrace, &trace); err != nil {
+			continue
+		}
+		return marshalToolResult(fmt.Sprintf("Call at %s\n%s",
+			rec.Timestamp.Format(time.RFC3339), trace.String())), nil
+	}
+	return marshalToolResult("No denied or unrouted tool calls in this session."), nil
+}
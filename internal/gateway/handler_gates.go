@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/routing"
+)
+
+// chargeRateLimits charges each route's rate limit and daily quota, once
+// per rule and workspace. When one refuses, the charges already made are
+// given back and the refusing route's index is returned with the error.
+// refund gives back every charge, for calls a later check refuses.
+func (h *handler) chargeRateLimits(ctx context.Context, routes []*routing.RouteResult, now time.Time) (refund func(), refused int, err error) {
+	sessionID := h.sessions.sessionID()
+	var charged []*routing.RouteResult
+	refund = func() {
+		for _, r := range charged {
+			h.engine.Refund(r, sessionID, now)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, r := range routes {
+		key := r.MatchedRuleID + "\x00" + r.MatchedWorkspaceID
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err := h.engine.Allow(ctx, r, sessionID, now); err != nil {
+			refund()
+			return nil, i, err
+		}
+		charged = append(charged, r)
+	}
+	return refund, 0, nil
+}
+
+// mergeRoutes combines the routes a call matched under each of its roots.
+// They must lead to the same downstream server and auth scope. The first
+// route is returned, held to the strictest approval mode among them.
+func mergeRoutes(routes []*routing.RouteResult) (*routing.RouteResult, error) {
+	rank := map[string]int{"write": 1, "all": 2}
+	first, strictest := routes[0], routes[0]
+	for _, r := range routes[1:] {
+		if r.DownstreamServerID != first.DownstreamServerID || r.AuthScopeID != first.AuthScopeID {
+			return nil, fmt.Errorf("the call's roots route it differently (rules %s and %s); call each root separately",
+				first.MatchedRuleID, r.MatchedRuleID)
+		}
+		if rank[r.ApprovalMode] > rank[strictest.ApprovalMode] {
+			strictest = r
+		}
+	}
+	if strictest == first {
+		return first, nil
+	}
+	merged := *first
+	merged.ApprovalMode = strictest.ApprovalMode
+	merged.ApprovalTimeout = strictest.ApprovalTimeout
+	return &merged, nil
+}
+
+// enforceGitHubAllowlists checks the arguments of a github__ call against
+// the org and repo allowlists of every route it matched.
+func enforceGitHubAllowlists(name string, routes []*routing.RouteResult, args json.RawMessage) *RPCError {
+	if !strings.HasPrefix(name, "github__") {
+		return nil
+	}
+	for _, r := range routes {
+		policy, err := newGitHubScopePolicy(r.AllowedOrgs, r.AllowedRepos)
+		if err != nil {
+			return &RPCError{
+				Code:    CodeInvalidParams,
+				Message: fmt.Sprintf("invalid route allowlist configuration: %v", err),
+			}
+		}
+		if err := policy.Enforce(args); err != nil {
+			return &RPCError{Code: CodeInvalidParams, Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// approvalRefusal refuses a resource or prompt request whose route holds
+// every call for approval. Such requests carry no arguments to put a
+// justification in, so they cannot be approved; under approval mode
+// "write" they pass as reads.
+func (h *handler) approvalRefusal(route *routing.RouteResult, what string) *RPCError {
+	if h.approvals == nil || route.ApprovalMode != "all" {
+		return nil
+	}
+	return &RPCError{
+		Code:    CodeInvalidRequest,
+		Message: what + " requires approval, which only tool calls can request",
+	}
+}
@@ -0,0 +1,191 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/routing"
+)
+
+func (h *handler) handlePromptsList(ctx context.Context) (json.RawMessage, *RPCError) {
+	if h.prompts == nil {
+		return nil, &RPCError{Code: CodeMethodNotFound, Message: "prompts are not enabled"}
+	}
+
+	servers, err := h.store.ListDownstreamServers(ctx)
+	if err != nil {
+		return nil, &RPCError{
+			Code:    CodeInternalError,
+			Message: fmt.Sprintf("list servers: %v", err),
+		}
+	}
+
+	var serverIDs []string
+	namespaces := make(map[string]string, len(servers))
+	for _, srv := range servers {
+		if srv.Transport == "internal" || srv.Disabled {
+			continue
+		}
+		serverIDs = append(serverIDs, srv.ID)
+		namespaces[srv.ID] = srv.ToolNamespace
+	}
+
+	// Cache the aggregated downstream results; route filtering below is
+	// per-session and always re-evaluated.
+	cached, err := h.promptsListCache.GetOrLoad(strings.Join(serverIDs, ","), func() (json.RawMessage, error) {
+		result, err := h.prompts.ListPromptsForServers(ctx, serverIDs)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(result)
+	})
+	if err != nil {
+		return nil, &RPCError{
+			Code:    CodeInternalError,
+			Message: fmt.Sprintf("list prompts: %v", err),
+		}
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(cached, &raw); err != nil {
+		return nil, &RPCError{Code: CodeInternalError, Message: err.Error()}
+	}
+
+	var prompts []namespacedPrompt
+	for serverID, result := range raw {
+		p, err := extractNamespacedPrompts(namespaces[serverID], result)
+		if err != nil {
+			slog.Warn("failed to extract prompts",
+				"server", serverID, "error", err)
+			continue
+		}
+		prompts = append(prompts, p...)
+	}
+
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].name < prompts[j].name })
+
+	filtered := make([]map[string]json.RawMessage, 0, len(prompts))
+	for _, p := range prompts {
+		if h.routeAllowed(ctx, p.name) {
+			filtered = append(filtered, p.fields)
+		}
+	}
+
+	data, err := json.Marshal(map[string]any{"prompts": filtered})
+	if err != nil {
+		return nil, &RPCError{Code: CodeInternalError, Message: err.Error()}
+	}
+	return data, nil
+}
+
+// namespacedPrompt is a downstream prompt with its name rewritten as
+// namespace__name. All other fields are preserved verbatim.
+type namespacedPrompt struct {
+	name   string
+	fields map[string]json.RawMessage
+}
+
+// extractNamespacedPrompts parses a prompts/list result and prefixes prompt
+// names with the server namespace.
+func extractNamespacedPrompts(namespace string, promptsResult json.RawMessage) ([]namespacedPrompt, error) {
+	if len(promptsResult) == 0 || string(promptsResult) == "{}" {
+		return nil, nil
+	}
+
+	var result struct {
+		Prompts []map[string]json.RawMessage `json:"prompts"`
+	}
+	if err := json.Unmarshal(promptsResult, &result); err != nil {
+		return nil, err
+	}
+
+	out := make([]namespacedPrompt, 0, len(result.Prompts))
+	for _, p := range result.Prompts {
+		var name string
+		if err := json.Unmarshal(p["name"], &name); err != nil || name == "" {
+			continue
+		}
+		name = namespace + "__" + name
+		p["name"], _ = json.Marshal(name)
+		out = append(out, namespacedPrompt{name: name, fields: p})
+	}
+	return out, nil
+}
+
+func (h *handler) handlePromptsGet(
+	ctx context.Context, params json.RawMessage,
+) (json.RawMessage, *RPCError) {
+	start := time.Now()
+
+	if h.prompts == nil {
+		return nil, &RPCError{Code: CodeMethodNotFound, Message: "prompts are not enabled"}
+	}
+
+	var req GetPromptRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, &RPCError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+
+	// Route with the prompt's arguments so conditioned rules see them.
+	routeArgs := req.Arguments
+	if routeArgs == nil {
+		routeArgs = json.RawMessage(`{}`)
+	}
+	route, err := h.engine.RouteWithFallback(ctx, routing.RouteContext{
+		ToolName:  req.Name,
+		Arguments: routeArgs,
+		Git:       h.sessions.gitInfo(),
+	}, h.sessions.clientRoot(), h.sessions.workspaceAncestors(ctx))
+	if err != nil {
+		rpcErr := mapRouteError(err)
+		h.recordAuditBlocked(ctx, req.Name, req.Arguments, nil, nil, rpcErr, start)
+		return nil, rpcErr
+	}
+
+	if route.DownstreamServerID == "" || route.DownstreamServerID == "mcpx-builtin" {
+		rpcErr := &RPCError{
+			Code:    CodeInvalidParams,
+			Message: fmt.Sprintf("unknown prompt %q", req.Name),
+		}
+		h.recordAudit(ctx, req.Name, req.Arguments, route, nil, rpcErr, start)
+		return nil, rpcErr
+	}
+
+	if rpcErr := h.approvalRefusal(route, fmt.Sprintf("prompt %q", req.Name)); rpcErr != nil {
+		h.recordAuditBlocked(ctx, req.Name, req.Arguments, route, nil, rpcErr, start)
+		return nil, rpcErr
+	}
+	if rpcErr := enforceGitHubAllowlists(req.Name, []*routing.RouteResult{route}, req.Arguments); rpcErr != nil {
+		h.recordAudit(ctx, req.Name, req.Arguments, route, nil, rpcErr, start)
+		return nil, rpcErr
+	}
+	if _, _, err := h.chargeRateLimits(ctx, []*routing.RouteResult{route}, start); err != nil {
+		rpcErr := mapRateLimitError(err)
+		h.recordAuditRateLimited(ctx, req.Name, req.Arguments, route, rpcErr, start)
+		return nil, rpcErr
+	}
+
+	result, err := h.prompts.GetPrompt(
+		ctx,
+		route.DownstreamServerID,
+		route.AuthScopeID,
+		extractOriginalToolName(req.Name),
+		req.Arguments,
+	)
+	if err != nil {
+		rpcErr := &RPCError{
+			Code:    CodeProcessError,
+			Message: formatDownstreamError(h.serverName(ctx, route.DownstreamServerID), err),
+		}
+		h.recordAudit(ctx, req.Name, req.Arguments, route, nil, rpcErr, start)
+		return nil, rpcErr
+	}
+
+	h.recordAudit(ctx, req.Name, req.Arguments, route, result, nil, start)
+	return result, nil
+}
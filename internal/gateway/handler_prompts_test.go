@@ -0,0 +1,223 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/revittco/mcplexer/internal/approval"
+	"github.com/revittco/mcplexer/internal/routing"
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// mockPromptLister implements PromptLister for testing.
+type mockPromptLister struct {
+	prompts   map[string]json.RawMessage
+	listCount int
+
+	lastGet struct {
+		serverID string
+		name     string
+		args     json.RawMessage
+	}
+}
+
+func (m *mockPromptLister) ListPromptsForServers(_ context.Context, serverIDs []string) (map[string]json.RawMessage, error) {
+	m.listCount++
+	out := make(map[string]json.RawMessage)
+	for _, id := range serverIDs {
+		if p, ok := m.prompts[id]; ok {
+			out[id] = p
+		}
+	}
+	return out, nil
+}
+
+func (m *mockPromptLister) GetPrompt(_ context.Context, serverID, _, name string, args json.RawMessage) (json.RawMessage, error) {
+	m.lastGet.serverID = serverID
+	m.lastGet.name = name
+	m.lastGet.args = args
+	return json.RawMessage(`{"messages":[{"role":"user","content":{"type":"text","text":"hi"}}]}`), nil
+}
+
+func newPromptTestHandler(pl *mockPromptLister) *handler {
+	ms := &mockStore{
+		servers: []store.DownstreamServer{
+			{ID: "gh", Name: "GitHub", ToolNamespace: "github", Discovery: "static"},
+			{ID: "linear", Name: "Linear", ToolNamespace: "linear", Discovery: "static"},
+		},
+		workspaces: []mockWorkspace{{id: "ws-global", rootPath: "/"}},
+		routeRules: map[string][]store.RouteRule{
+			"ws-global": {
+				{
+					ID: "allow-gh", WorkspaceID: "ws-global",
+					Priority: 1, PathGlob: "**", Policy: "allow",
+					ToolMatch:          json.RawMessage(`["github__*"]`),
+					DownstreamServerID: "gh",
+				},
+			},
+		},
+	}
+	h := newHandler(ms, routing.NewEngine(ms), &mockToolLister{}, nil, TransportSocket, nil, nil, nil, nil)
+	h.prompts = pl
+	h.sessions.clientPath = "/test"
+	h.sessions.wsChain = []routing.WorkspaceAncestor{{ID: "ws-global", RootPath: "/"}}
+	return h
+}
+
+func TestHandlePromptsList_NamespacesAndFilters(t *testing.T) {
+	pl := &mockPromptLister{
+		prompts: map[string]json.RawMessage{
+			"gh": json.RawMessage(`{"prompts":[
+				{"name":"review_pr","description":"Review a PR","arguments":[{"name":"number","required":true}]}
+			]}`),
+			"linear": json.RawMessage(`{"prompts":[{"name":"triage"}]}`),
+		},
+	}
+	h := newPromptTestHandler(pl)
+
+	result, rpcErr := h.handlePromptsList(context.Background())
+	if rpcErr != nil {
+		t.Fatalf("unexpected error: %s", rpcErr.Message)
+	}
+
+	var parsed struct {
+		Prompts []map[string]json.RawMessage `json:"prompts"`
+	}
+	if err := json.Unmarshal(result, &parsed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	// linear has no allow route, so only the GitHub prompt is advertised.
+	if len(parsed.Prompts) != 1 {
+		t.Fatalf("got %d prompts, want 1", len(parsed.Prompts))
+	}
+	if got := string(parsed.Prompts[0]["name"]); got != `"github__review_pr"` {
+		t.Errorf("name = %s", got)
+	}
+	if _, ok := parsed.Prompts[0]["arguments"]; !ok {
+		t.Error("arguments not preserved")
+	}
+}
+
+func TestHandlePromptsGet_StripsNamespace(t *testing.T) {
+	pl := &mockPromptLister{}
+	h := newPromptTestHandler(pl)
+
+	params, _ := json.Marshal(GetPromptRequest{
+		Name:      "github__review_pr",
+		Arguments: json.RawMessage(`{"number":"42"}`),
+	})
+	if _, rpcErr := h.handlePromptsGet(context.Background(), params); rpcErr != nil {
+		t.Fatalf("unexpected error: %s", rpcErr.Message)
+	}
+	if pl.lastGet.serverID != "gh" || pl.lastGet.name != "review_pr" {
+		t.Errorf("get dispatched to (%q, %q)", pl.lastGet.serverID, pl.lastGet.name)
+	}
+	if string(pl.lastGet.args) != `{"number":"42"}` {
+		t.Errorf("args = %s", pl.lastGet.args)
+	}
+}
+
+func TestHandlePromptsGet_NoRoute(t *testing.T) {
+	pl := &mockPromptLister{}
+	h := newPromptTestHandler(pl)
+
+	params, _ := json.Marshal(GetPromptRequest{Name: "linear__triage"})
+	_, rpcErr := h.handlePromptsGet(context.Background(), params)
+	if rpcErr == nil || rpcErr.Code != CodeRouteNotFound {
+		t.Fatalf("expected route not found, got %v", rpcErr)
+	}
+	if pl.lastGet.name != "" {
+		t.Error("downstream should not be called")
+	}
+}
+
+func TestInvalidateAndNotifyPromptsChanged(t *testing.T) {
+	pl := &mockPromptLister{prompts: map[string]json.RawMessage{}}
+	h := newPromptTestHandler(pl)
+	n := &recordingNotifier{}
+	h.setNotifier(n)
+
+	for range 2 {
+		if _, rpcErr := h.handlePromptsList(context.Background()); rpcErr != nil {
+			t.Fatalf("unexpected error: %s", rpcErr.Message)
+		}
+	}
+	if pl.listCount != 1 {
+		t.Fatalf("list count = %d, want 1 (cached)", pl.listCount)
+	}
+
+	h.InvalidateAndNotifyPromptsChanged()
+	if len(n.methods) != 1 || n.methods[0] != "notifications/prompts/list_changed" {
+		t.Fatalf("notifications = %v", n.methods)
+	}
+
+	if _, rpcErr := h.handlePromptsList(context.Background()); rpcErr != nil {
+		t.Fatalf("unexpected error: %s", rpcErr.Message)
+	}
+	if pl.listCount != 2 {
+		t.Errorf("list count = %d, want 2 after invalidation", pl.listCount)
+	}
+}
+
+func TestHandlePromptsGet_AppliesRouteGates(t *testing.T) {
+	ms := &mockStore{
+		servers: []store.DownstreamServer{
+			{ID: "gh", Name: "GitHub", ToolNamespace: "github", Discovery: "static"},
+		},
+		workspaces: []mockWorkspace{{id: "ws-global", rootPath: "/"}},
+		routeRules: map[string][]store.RouteRule{
+			"ws-global": {
+				{
+					ID: "no-prod", WorkspaceID: "ws-global",
+					Priority: 10, PathGlob: "**", Policy: "deny",
+					ToolMatch:  json.RawMessage(`["github__deploy"]`),
+					Conditions: json.RawMessage(`["env == \"prod\""]`),
+				},
+				{
+					ID: "approve-release", WorkspaceID: "ws-global",
+					Priority: 10, PathGlob: "**", Policy: "allow",
+					ToolMatch:          json.RawMessage(`["github__release"]`),
+					DownstreamServerID: "gh",
+					ApprovalMode:       "all",
+				},
+				{
+					ID: "allow-gh", WorkspaceID: "ws-global",
+					Priority: 1, PathGlob: "**", Policy: "allow",
+					ToolMatch:          json.RawMessage(`["github__*"]`),
+					DownstreamServerID: "gh",
+					RateLimit:          json.RawMessage(`{"rate":2,"per":"1h"}`),
+				},
+			},
+		},
+	}
+	pl := &mockPromptLister{}
+	h := newHandler(ms, routing.NewEngine(ms), &mockToolLister{}, nil, TransportSocket,
+		approval.NewManager(ms, approval.NewBus()), nil, nil, nil)
+	h.prompts = pl
+	h.sessions.clientPath = "/test"
+	h.sessions.wsChain = []routing.WorkspaceAncestor{{ID: "ws-global", RootPath: "/"}}
+
+	tests := []struct {
+		name     string
+		prompt   string
+		args     string
+		wantCode int // 0 = allowed
+	}{
+		{"conditioned deny sees arguments", "github__deploy", `{"env":"prod"}`, CodeRouteNotFound},
+		{"requires approval", "github__release", ``, CodeInvalidRequest},
+		{"allowed", "github__deploy", `{"env":"staging"}`, 0},
+		{"allowed without arguments", "github__review_pr", ``, 0},
+		{"rate limited", "github__review_pr", ``, CodeRateLimited},
+	}
+	for _, tt := range tests {
+		params, _ := json.Marshal(GetPromptRequest{Name: tt.prompt, Arguments: json.RawMessage(tt.args)})
+		_, rpcErr := h.handlePromptsGet(context.Background(), params)
+		switch {
+		case tt.wantCode == 0 && rpcErr != nil:
+			t.Errorf("%s: unexpected error: %s", tt.name, rpcErr.Message)
+		case tt.wantCode != 0 && (rpcErr == nil || rpcErr.Code != tt.wantCode):
+			t.Errorf("%s: error = %+v, want code %d", tt.name, rpcErr, tt.wantCode)
+		}
+	}
+}
@@ -0,0 +1,498 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/downstream"
+	"github.com/revittco/mcplexer/internal/routing"
+)
+
+// resourceURIScheme prefixes every resource URI advertised to clients.
+// Downstream URIs are namespaced as mcpx://<tool_namespace>/<original-uri>
+// so reads can be routed back to the server that owns them.
+const resourceURIScheme = "mcpx://"
+
+// namespaceResourceURI wraps a downstream resource URI (or URI template)
+// with the server's tool namespace.
+func namespaceResourceURI(namespace, uri string) string {
+	return resourceURIScheme + namespace + "/" + uri
+}
+
+// splitResourceURI reverses namespaceResourceURI.
+func splitResourceURI(uri string) (namespace, original string, ok bool) {
+	rest, found := strings.CutPrefix(uri, resourceURIScheme)
+	if !found {
+		return "", "", false
+	}
+	namespace, original, ok = strings.Cut(rest, "/")
+	if !ok || namespace == "" || original == "" {
+		return "", "", false
+	}
+	return namespace, original, true
+}
+
+// resourceRouteName is the name a resource is routed and audited under.
+// It mirrors tool naming (namespace__name) so route rules can match
+// resources with the same tool_match patterns, e.g. "fs__file:///etc/*".
+func resourceRouteName(namespace, uri string) string {
+	return namespace + "__" + uri
+}
+
+// namespacedResource is a downstream resource or template entry with its
+// URI field rewritten for the client.
+type namespacedResource struct {
+	routeName string
+	uri       string
+	fields    map[string]json.RawMessage
+}
+
+func (h *handler) handleResourcesList(ctx context.Context) (json.RawMessage, *RPCError) {
+	return h.listResources(ctx, "resources", "uri", h.resources.ListResourcesForServers)
+}
+
+func (h *handler) handleResourceTemplatesList(ctx context.Context) (json.RawMessage, *RPCError) {
+	return h.listResources(ctx, "resourceTemplates", "uriTemplate", h.resources.ListResourceTemplatesForServers)
+}
+
+// listResources aggregates a list method across all downstream servers,
+// namespaces each entry's URI field, and drops entries the session's
+// workspace routes do not allow.
+func (h *handler) listResources(
+	ctx context.Context,
+	listKey, uriKey string,
+	list func(ctx context.Context, serverIDs []string) (map[string]json.RawMessage, error),
+) (json.RawMessage, *RPCError) {
+	if h.resources == nil {
+		return nil, &RPCError{Code: CodeMethodNotFound, Message: "resources are not enabled"}
+	}
+
+	servers, err := h.store.ListDownstreamServers(ctx)
+	if err != nil {
+		return nil, &RPCError{
+			Code:    CodeInternalError,
+			Message: fmt.Sprintf("list servers: %v", err),
+		}
+	}
+
+	var serverIDs []string
+	namespaces := make(map[string]string, len(servers))
+	for _, srv := range servers {
+		if srv.Transport == "internal" || srv.Disabled {
+			continue
+		}
+		serverIDs = append(serverIDs, srv.ID)
+		namespaces[srv.ID] = srv.ToolNamespace
+	}
+
+	raw, err := list(ctx, serverIDs)
+	if err != nil {
+		return nil, &RPCError{
+			Code:    CodeInternalError,
+			Message: fmt.Sprintf("list %s: %v", listKey, err),
+		}
+	}
+
+	var entries []namespacedResource
+	for serverID, result := range raw {
+		items, err := extractNamespacedResources(namespaces[serverID], result, listKey, uriKey)
+		if err != nil {
+			slog.Warn("failed to extract resources",
+				"server", serverID, "method", listKey, "error", err)
+			continue
+		}
+		entries = append(entries, items...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].uri < entries[j].uri })
+
+	out := make([]map[string]json.RawMessage, 0, len(entries))
+	for _, e := range entries {
+		if h.routeAllowed(ctx, e.routeName) {
+			out = append(out, e.fields)
+		}
+	}
+
+	data, err := json.Marshal(map[string]any{listKey: out})
+	if err != nil {
+		return nil, &RPCError{Code: CodeInternalError, Message: err.Error()}
+	}
+	return data, nil
+}
+
+// extractNamespacedResources parses a downstream list result and rewrites
+// the uriKey field of each entry under listKey with the server namespace.
+func extractNamespacedResources(
+	namespace string, result json.RawMessage, listKey, uriKey string,
+) ([]namespacedResource, error) {
+	if len(result) == 0 || string(result) == "{}" {
+		return nil, nil
+	}
+
+	var parsed map[string]json.RawMessage
+	if err := json.Unmarshal(result, &parsed); err != nil {
+		return nil, err
+	}
+	var items []map[string]json.RawMessage
+	if raw, ok := parsed[listKey]; ok {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]namespacedResource, 0, len(items))
+	for _, item := range items {
+		var uri string
+		if err := json.Unmarshal(item[uriKey], &uri); err != nil || uri == "" {
+			continue
+		}
+		nsURI := namespaceResourceURI(namespace, uri)
+		item[uriKey], _ = json.Marshal(nsURI)
+		out = append(out, namespacedResource{
+			routeName: resourceRouteName(namespace, uri),
+			uri:       nsURI,
+			fields:    item,
+		})
+	}
+	return out, nil
+}
+
+// routeAllowed reports whether the session's workspace chain can route name.
+func (h *handler) routeAllowed(ctx context.Context, name string) bool {
+	ancestors := h.sessions.workspaceAncestors(ctx)
+	if len(ancestors) == 0 {
+		return false
+	}
+	_, err := h.engine.RouteWithFallback(ctx, routing.RouteContext{
+		ToolName: name,
+		Git:      h.sessions.gitInfo(),
+	}, h.sessions.clientRoot(), ancestors)
+	return err == nil
+}
+
+// routeResource resolves a namespaced resource URI to its downstream route
+// and applies the route's approval mode and GitHub allowlist. Denials are
+// audited as blocked; the caller charges the rate limit and audits
+// everything else.
+func (h *handler) routeResource(
+	ctx context.Context, params json.RawMessage, start time.Time,
+) (routeName, original string, route *routing.RouteResult, rpcErr *RPCError) {
+	if h.resources == nil {
+		return "", "", nil, &RPCError{Code: CodeMethodNotFound, Message: "resources are not enabled"}
+	}
+
+	var req ReadResourceRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return "", "", nil, &RPCError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+
+	namespace, original, ok := splitResourceURI(req.URI)
+	if !ok {
+		return "", "", nil, &RPCError{
+			Code:    CodeInvalidParams,
+			Message: fmt.Sprintf("unknown resource URI %q", req.URI),
+		}
+	}
+	routeName = resourceRouteName(namespace, original)
+
+	route, err := h.engine.RouteWithFallback(ctx, routing.RouteContext{
+		ToolName: routeName,
+		Git:      h.sessions.gitInfo(),
+	}, h.sessions.clientRoot(), h.sessions.workspaceAncestors(ctx))
+	if err != nil {
+		rpcErr := mapRouteError(err)
+		h.recordAuditBlocked(ctx, routeName, params, nil, nil, rpcErr, start)
+		return "", "", nil, rpcErr
+	}
+
+	if route.DownstreamServerID == "" || route.DownstreamServerID == "mcpx-builtin" {
+		rpcErr := &RPCError{
+			Code:    CodeInvalidParams,
+			Message: fmt.Sprintf("no downstream server provides resource %q", req.URI),
+		}
+		h.recordAudit(ctx, routeName, params, route, nil, rpcErr, start)
+		return "", "", nil, rpcErr
+	}
+
+	if rpcErr := h.approvalRefusal(route, fmt.Sprintf("resource %q", req.URI)); rpcErr != nil {
+		h.recordAuditBlocked(ctx, routeName, params, route, nil, rpcErr, start)
+		return "", "", nil, rpcErr
+	}
+	uriArgs, _ := json.Marshal(map[string]string{"uri": original})
+	if rpcErr := enforceGitHubAllowlists(routeName, []*routing.RouteResult{route}, uriArgs); rpcErr != nil {
+		h.recordAudit(ctx, routeName, params, route, nil, rpcErr, start)
+		return "", "", nil, rpcErr
+	}
+
+	return routeName, original, route, nil
+}
+
+// chargeResource charges a resource request against its route's rate
+// limit, auditing a refusal.
+func (h *handler) chargeResource(
+	ctx context.Context, routeName string, params json.RawMessage, route *routing.RouteResult, start time.Time,
+) *RPCError {
+	if _, _, err := h.chargeRateLimits(ctx, []*routing.RouteResult{route}, start); err != nil {
+		rpcErr := mapRateLimitError(err)
+		h.recordAuditRateLimited(ctx, routeName, params, route, rpcErr, start)
+		return rpcErr
+	}
+	return nil
+}
+
+func (h *handler) handleResourcesRead(
+	ctx context.Context, params json.RawMessage,
+) (json.RawMessage, *RPCError) {
+	start := time.Now()
+
+	routeName, original, route, rpcErr := h.routeResource(ctx, params, start)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := h.chargeResource(ctx, routeName, params, route, start); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	result, err := h.resources.ReadResource(ctx, route.DownstreamServerID, route.AuthScopeID, original)
+	if err != nil {
+		rpcErr := &RPCError{
+			Code:    CodeProcessError,
+			Message: formatDownstreamError(h.serverName(ctx, route.DownstreamServerID), err),
+		}
+		h.recordAudit(ctx, routeName, params, route, nil, rpcErr, start)
+		return nil, rpcErr
+	}
+
+	namespace, _, _ := strings.Cut(routeName, "__")
+	result = namespaceReadResult(namespace, result)
+
+	h.recordAudit(ctx, routeName, params, route, result, nil, start)
+	return result, nil
+}
+
+// namespaceReadResult rewrites the uri of each entry in a resources/read
+// result's contents. Unparseable results are returned unchanged.
+func namespaceReadResult(namespace string, result json.RawMessage) json.RawMessage {
+	var parsed map[string]json.RawMessage
+	if err := json.Unmarshal(result, &parsed); err != nil {
+		return result
+	}
+	var contents []map[string]json.RawMessage
+	if err := json.Unmarshal(parsed["contents"], &contents); err != nil {
+		return result
+	}
+	for _, c := range contents {
+		var uri string
+		if err := json.Unmarshal(c["uri"], &uri); err != nil || uri == "" {
+			continue
+		}
+		c["uri"], _ = json.Marshal(namespaceResourceURI(namespace, uri))
+	}
+	parsed["contents"], _ = json.Marshal(contents)
+	data, err := json.Marshal(parsed)
+	if err != nil {
+		return result
+	}
+	return data
+}
+
+func (h *handler) handleResourcesSubscribe(
+	ctx context.Context, params json.RawMessage, subscribe bool,
+) (json.RawMessage, *RPCError) {
+	start := time.Now()
+
+	routeName, original, route, rpcErr := h.routeResource(ctx, params, start)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	namespace, _, _ := strings.Cut(routeName, "__")
+	nsURI := namespaceResourceURI(namespace, original)
+	result := json.RawMessage(`{}`)
+
+	// Downstream subscriptions are counted per session, so repeating a
+	// subscribe or unsubscribe must not be passed on. The check and the
+	// change to the session's subscriptions happen under one lock, so
+	// concurrent duplicates see each other.
+	if !subscribe {
+		h.subscriptionsMu.Lock()
+		sub := h.subscriptions[nsURI]
+		delete(h.subscriptions, nsURI)
+		forward := sub != nil && !sub.pending
+		h.subscriptionsMu.Unlock()
+		// A subscribe still in flight sees it was withdrawn and releases
+		// the downstream subscription itself.
+		if forward {
+			callCtx := downstream.WithCaller(ctx, sub.caller)
+			if err := h.resources.SubscribeResource(callCtx, sub.serverID, sub.authScopeID, sub.uri, false); err != nil {
+				h.subscriptionsMu.Lock()
+				if h.subscriptions != nil && h.subscriptions[nsURI] == nil {
+					h.subscriptions[nsURI] = sub
+				}
+				h.subscriptionsMu.Unlock()
+				return nil, h.subscribeFailed(ctx, routeName, params, route, err, start)
+			}
+		}
+		h.recordAudit(ctx, routeName, params, route, result, nil, start)
+		return result, nil
+	}
+
+	sub := &resourceSubscription{
+		serverID:    route.DownstreamServerID,
+		authScopeID: route.AuthScopeID,
+		uri:         original,
+		caller:      h.sessions.caller(h.sessions.primaryRoot(ctx)),
+		pending:     true,
+	}
+	h.subscriptionsMu.Lock()
+	if h.subscriptions[nsURI] != nil {
+		h.subscriptionsMu.Unlock()
+		h.recordAudit(ctx, routeName, params, route, result, nil, start)
+		return result, nil
+	}
+	if h.subscriptions == nil {
+		h.subscriptions = make(map[string]*resourceSubscription)
+	}
+	h.subscriptions[nsURI] = sub
+	h.subscriptionsMu.Unlock()
+
+	if rpcErr := h.chargeResource(ctx, routeName, params, route, start); rpcErr != nil {
+		h.dropSubscription(nsURI, sub)
+		return nil, rpcErr
+	}
+	if err := h.resources.SubscribeResource(ctx, sub.serverID, sub.authScopeID, sub.uri, true); err != nil {
+		h.dropSubscription(nsURI, sub)
+		return nil, h.subscribeFailed(ctx, routeName, params, route, err, start)
+	}
+
+	h.subscriptionsMu.Lock()
+	sub.pending = false
+	withdrawn := h.subscriptions[nsURI] != sub
+	h.subscriptionsMu.Unlock()
+	if withdrawn {
+		// Unsubscribed, or the session ended, while subscribing.
+		h.releaseSubscription(ctx, sub)
+	}
+
+	h.recordAudit(ctx, routeName, params, route, result, nil, start)
+	return result, nil
+}
+
+// subscribeFailed audits and returns the error for a subscribe or
+// unsubscribe the downstream rejected.
+func (h *handler) subscribeFailed(
+	ctx context.Context, routeName string, params json.RawMessage,
+	route *routing.RouteResult, err error, start time.Time,
+) *RPCError {
+	rpcErr := &RPCError{
+		Code:    CodeProcessError,
+		Message: formatDownstreamError(h.serverName(ctx, route.DownstreamServerID), err),
+	}
+	h.recordAudit(ctx, routeName, params, route, nil, rpcErr, start)
+	return rpcErr
+}
+
+// dropSubscription removes sub, if it is still the session's subscription
+// to nsURI.
+func (h *handler) dropSubscription(nsURI string, sub *resourceSubscription) {
+	h.subscriptionsMu.Lock()
+	defer h.subscriptionsMu.Unlock()
+	if h.subscriptions[nsURI] == sub {
+		delete(h.subscriptions, nsURI)
+	}
+}
+
+// resourceSubscription is a downstream resource the client subscribed to.
+type resourceSubscription struct {
+	serverID    string
+	authScopeID string
+	uri         string            // the downstream's original URI
+	caller      downstream.Caller // picks the instance the subscription went to
+	pending     bool              // the subscribe is still being forwarded
+}
+
+// releaseSubscriptions unsubscribes from every resource the session still
+// holds, so that the downstream stops sending updates nobody receives.
+func (h *handler) releaseSubscriptions(ctx context.Context) {
+	h.subscriptionsMu.Lock()
+	var subs []*resourceSubscription
+	for _, sub := range h.subscriptions {
+		// A pending subscribe releases itself once it sees it was withdrawn.
+		if !sub.pending {
+			subs = append(subs, sub)
+		}
+	}
+	h.subscriptions = nil
+	h.subscriptionsMu.Unlock()
+	if h.resources == nil {
+		return
+	}
+
+	for _, sub := range subs {
+		h.releaseSubscription(ctx, sub)
+	}
+}
+
+// releaseSubscription unsubscribes from a downstream resource on behalf of
+// a session that no longer wants it.
+func (h *handler) releaseSubscription(ctx context.Context, sub *resourceSubscription) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	ctx = downstream.WithCaller(ctx, sub.caller)
+	if err := h.resources.SubscribeResource(ctx, sub.serverID, sub.authScopeID, sub.uri, false); err != nil {
+		slog.Debug("failed to release resource subscription",
+			"server", sub.serverID, "uri", sub.uri, "error", err)
+	}
+}
+
+// serverName returns the display name of a downstream server, falling back
+// to its ID.
+func (h *handler) serverName(ctx context.Context, serverID string) string {
+	if srv, err := h.store.GetDownstreamServer(ctx, serverID); err == nil && srv != nil {
+		return srv.Name
+	}
+	return serverID
+}
+
+// notifyResourcesChanged sends a resources/list_changed notification.
+func (h *handler) notifyResourcesChanged() {
+	if h.notifier == nil || h.resources == nil {
+		return
+	}
+	if err := h.notifier.Notify("notifications/resources/list_changed", nil); err != nil {
+		slog.Warn("failed to send resources/list_changed notification", "error", err)
+	}
+}
+
+// notifyResourceUpdated forwards a downstream resources/updated notification
+// when the client is subscribed to the namespaced URI.
+func (h *handler) notifyResourceUpdated(serverID, uri string) {
+	if h.notifier == nil {
+		return
+	}
+	ctx := h.bgCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	srv, err := h.store.GetDownstreamServer(ctx, serverID)
+	if err != nil || srv == nil {
+		return
+	}
+	nsURI := namespaceResourceURI(srv.ToolNamespace, uri)
+
+	h.subscriptionsMu.Lock()
+	_, subscribed := h.subscriptions[nsURI]
+	h.subscriptionsMu.Unlock()
+	if !subscribed {
+		return
+	}
+
+	if err := h.notifier.Notify("notifications/resources/updated", map[string]string{"uri": nsURI}); err != nil {
+		slog.Warn("failed to send resources/updated notification", "error", err)
+	}
+}
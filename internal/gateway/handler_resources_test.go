@@ -0,0 +1,434 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/revittco/mcplexer/internal/approval"
+	"github.com/revittco/mcplexer/internal/routing"
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// mockResourceLister implements ResourceLister for testing.
+type mockResourceLister struct {
+	resources map[string]json.RawMessage
+	templates map[string]json.RawMessage
+	contents  map[string]json.RawMessage // keyed by original URI
+
+	lastRead struct {
+		serverID string
+		uri      string
+	}
+	subMu      sync.Mutex
+	subscribed map[string]bool
+	subCalls   int
+	subStarted chan struct{} // if set, signalled as each subscribe call starts
+	subGate    chan struct{} // if set, subscribe calls wait for it to close
+}
+
+func (m *mockResourceLister) ListResourcesForServers(_ context.Context, serverIDs []string) (map[string]json.RawMessage, error) {
+	out := make(map[string]json.RawMessage)
+	for _, id := range serverIDs {
+		if r, ok := m.resources[id]; ok {
+			out[id] = r
+		}
+	}
+	return out, nil
+}
+
+func (m *mockResourceLister) ListResourceTemplatesForServers(_ context.Context, serverIDs []string) (map[string]json.RawMessage, error) {
+	out := make(map[string]json.RawMessage)
+	for _, id := range serverIDs {
+		if r, ok := m.templates[id]; ok {
+			out[id] = r
+		}
+	}
+	return out, nil
+}
+
+func (m *mockResourceLister) ReadResource(_ context.Context, serverID, _, uri string) (json.RawMessage, error) {
+	m.lastRead.serverID = serverID
+	m.lastRead.uri = uri
+	return m.contents[uri], nil
+}
+
+func (m *mockResourceLister) SubscribeResource(_ context.Context, _, _, uri string, subscribe bool) error {
+	if m.subStarted != nil {
+		m.subStarted <- struct{}{}
+	}
+	if m.subGate != nil {
+		<-m.subGate
+	}
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	if m.subscribed == nil {
+		m.subscribed = make(map[string]bool)
+	}
+	m.subscribed[uri] = subscribe
+	m.subCalls++
+	return nil
+}
+
+// recordingNotifier captures notifications sent to the client.
+type recordingNotifier struct {
+	methods []string
+	params  []any
+}
+
+func (n *recordingNotifier) Notify(method string, params any) error {
+	n.methods = append(n.methods, method)
+	n.params = append(n.params, params)
+	return nil
+}
+
+func newResourceTestHandler(rl *mockResourceLister) *handler {
+	ms := &mockStore{
+		servers: []store.DownstreamServer{
+			{ID: "fs", Name: "Filesystem", ToolNamespace: "fs", Discovery: "static"},
+			{ID: "db", Name: "Postgres", ToolNamespace: "db", Discovery: "static"},
+		},
+		workspaces: []mockWorkspace{{id: "ws-global", rootPath: "/"}},
+		routeRules: map[string][]store.RouteRule{
+			"ws-global": {
+				{
+					ID: "deny-secrets", WorkspaceID: "ws-global",
+					Priority: 10, PathGlob: "**", Policy: "deny",
+					ToolMatch:          json.RawMessage(`["fs__file:///secrets/*"]`),
+					DownstreamServerID: "fs",
+				},
+				{
+					ID: "allow-fs", WorkspaceID: "ws-global",
+					Priority: 1, PathGlob: "**", Policy: "allow",
+					ToolMatch:          json.RawMessage(`["fs__*"]`),
+					DownstreamServerID: "fs",
+				},
+			},
+		},
+	}
+	h := newHandler(ms, routing.NewEngine(ms), &mockToolLister{}, nil, TransportSocket, nil, nil, nil, nil)
+	h.resources = rl
+	h.sessions.clientPath = "/test"
+	h.sessions.wsChain = []routing.WorkspaceAncestor{{ID: "ws-global", RootPath: "/"}}
+	return h
+}
+
+func TestSplitResourceURI(t *testing.T) {
+	tests := []struct {
+		uri      string
+		wantNS   string
+		wantOrig string
+		wantOK   bool
+	}{
+		{"mcpx://fs/file:///tmp/a.txt", "fs", "file:///tmp/a.txt", true},
+		{"mcpx://db/postgres://host/db/schema", "db", "postgres://host/db/schema", true},
+		{"file:///tmp/a.txt", "", "", false},
+		{"mcpx://fs", "", "", false},
+		{"mcpx:///file:///x", "", "", false},
+	}
+	for _, tt := range tests {
+		ns, orig, ok := splitResourceURI(tt.uri)
+		if ns != tt.wantNS || orig != tt.wantOrig || ok != tt.wantOK {
+			t.Errorf("splitResourceURI(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.uri, ns, orig, ok, tt.wantNS, tt.wantOrig, tt.wantOK)
+		}
+	}
+}
+
+func TestHandleResourcesList_NamespacesAndFilters(t *testing.T) {
+	rl := &mockResourceLister{
+		resources: map[string]json.RawMessage{
+			"fs": json.RawMessage(`{"resources":[
+				{"uri":"file:///tmp/a.txt","name":"a.txt","mimeType":"text/plain"},
+				{"uri":"file:///secrets/key","name":"key"}
+			]}`),
+			"db": json.RawMessage(`{"resources":[{"uri":"postgres://schema","name":"schema"}]}`),
+		},
+	}
+	h := newResourceTestHandler(rl)
+
+	result, rpcErr := h.handleResourcesList(context.Background())
+	if rpcErr != nil {
+		t.Fatalf("unexpected error: %s", rpcErr.Message)
+	}
+
+	var parsed struct {
+		Resources []map[string]any `json:"resources"`
+	}
+	if err := json.Unmarshal(result, &parsed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	// The secrets resource is denied and the db server has no allow route.
+	if len(parsed.Resources) != 1 {
+		t.Fatalf("got %d resources, want 1: %v", len(parsed.Resources), parsed.Resources)
+	}
+	r := parsed.Resources[0]
+	if r["uri"] != "mcpx://fs/file:///tmp/a.txt" {
+		t.Errorf("uri = %v", r["uri"])
+	}
+	if r["mimeType"] != "text/plain" {
+		t.Errorf("mimeType not preserved: %v", r["mimeType"])
+	}
+}
+
+func TestHandleResourceTemplatesList_NamespacesTemplate(t *testing.T) {
+	rl := &mockResourceLister{
+		templates: map[string]json.RawMessage{
+			"fs": json.RawMessage(`{"resourceTemplates":[{"uriTemplate":"file:///{path}","name":"file"}]}`),
+		},
+	}
+	h := newResourceTestHandler(rl)
+
+	result, rpcErr := h.handleResourceTemplatesList(context.Background())
+	if rpcErr != nil {
+		t.Fatalf("unexpected error: %s", rpcErr.Message)
+	}
+	var parsed struct {
+		ResourceTemplates []map[string]any `json:"resourceTemplates"`
+	}
+	if err := json.Unmarshal(result, &parsed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(parsed.ResourceTemplates) != 1 {
+		t.Fatalf("got %d templates, want 1", len(parsed.ResourceTemplates))
+	}
+	if got := parsed.ResourceTemplates[0]["uriTemplate"]; got != "mcpx://fs/file:///{path}" {
+		t.Errorf("uriTemplate = %v", got)
+	}
+}
+
+func TestHandleResourcesRead_RoutesToOwningServer(t *testing.T) {
+	rl := &mockResourceLister{
+		contents: map[string]json.RawMessage{
+			"file:///tmp/a.txt": json.RawMessage(`{"contents":[{"uri":"file:///tmp/a.txt","text":"hello"}]}`),
+		},
+	}
+	h := newResourceTestHandler(rl)
+
+	params, _ := json.Marshal(ReadResourceRequest{URI: "mcpx://fs/file:///tmp/a.txt"})
+	result, rpcErr := h.handleResourcesRead(context.Background(), params)
+	if rpcErr != nil {
+		t.Fatalf("unexpected error: %s", rpcErr.Message)
+	}
+	if rl.lastRead.serverID != "fs" || rl.lastRead.uri != "file:///tmp/a.txt" {
+		t.Errorf("read dispatched to (%q, %q)", rl.lastRead.serverID, rl.lastRead.uri)
+	}
+
+	var parsed struct {
+		Contents []map[string]any `json:"contents"`
+	}
+	if err := json.Unmarshal(result, &parsed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(parsed.Contents) != 1 || parsed.Contents[0]["uri"] != "mcpx://fs/file:///tmp/a.txt" {
+		t.Errorf("contents = %v", parsed.Contents)
+	}
+}
+
+func TestHandleResourcesRead_DeniedByRoute(t *testing.T) {
+	rl := &mockResourceLister{}
+	h := newResourceTestHandler(rl)
+
+	params, _ := json.Marshal(ReadResourceRequest{URI: "mcpx://fs/file:///secrets/key"})
+	_, rpcErr := h.handleResourcesRead(context.Background(), params)
+	if rpcErr == nil {
+		t.Fatal("expected route denial")
+	}
+	if rpcErr.Code != CodeRouteNotFound {
+		t.Errorf("code = %d, want %d", rpcErr.Code, CodeRouteNotFound)
+	}
+	if rl.lastRead.uri != "" {
+		t.Errorf("downstream read should not be called, got %q", rl.lastRead.uri)
+	}
+}
+
+func TestHandleResourcesRead_UnknownURI(t *testing.T) {
+	h := newResourceTestHandler(&mockResourceLister{})
+
+	params, _ := json.Marshal(ReadResourceRequest{URI: "file:///tmp/a.txt"})
+	_, rpcErr := h.handleResourcesRead(context.Background(), params)
+	if rpcErr == nil || rpcErr.Code != CodeInvalidParams {
+		t.Fatalf("expected invalid params error, got %v", rpcErr)
+	}
+}
+
+func TestHandleResourcesSubscribe_ForwardsUpdates(t *testing.T) {
+	rl := &mockResourceLister{}
+	h := newResourceTestHandler(rl)
+	n := &recordingNotifier{}
+	h.setNotifier(n)
+
+	params, _ := json.Marshal(ReadResourceRequest{URI: "mcpx://fs/file:///tmp/a.txt"})
+	if _, rpcErr := h.handleResourcesSubscribe(context.Background(), params, true); rpcErr != nil {
+		t.Fatalf("subscribe: %s", rpcErr.Message)
+	}
+	if !rl.subscribed["file:///tmp/a.txt"] {
+		t.Fatal("subscribe not forwarded downstream")
+	}
+
+	h.notifyResourceUpdated("fs", "file:///tmp/b.txt") // not subscribed
+	h.notifyResourceUpdated("fs", "file:///tmp/a.txt")
+	if len(n.methods) != 1 || n.methods[0] != "notifications/resources/updated" {
+		t.Fatalf("notifications = %v, want one resources/updated", n.methods)
+	}
+	if p, _ := n.params[0].(map[string]string); p["uri"] != "mcpx://fs/file:///tmp/a.txt" {
+		t.Errorf("params = %v", n.params[0])
+	}
+
+	if _, rpcErr := h.handleResourcesSubscribe(context.Background(), params, false); rpcErr != nil {
+		t.Fatalf("unsubscribe: %s", rpcErr.Message)
+	}
+	h.notifyResourceUpdated("fs", "file:///tmp/a.txt")
+	if len(n.methods) != 1 {
+		t.Errorf("got %d notifications after unsubscribe, want 1", len(n.methods))
+	}
+}
+
+func TestHandleResourcesSubscribe_ReleasedWithSession(t *testing.T) {
+	rl := &mockResourceLister{}
+	h := newResourceTestHandler(rl)
+	h.setNotifier(&recordingNotifier{})
+
+	params, _ := json.Marshal(ReadResourceRequest{URI: "mcpx://fs/file:///tmp/a.txt"})
+	for range 2 {
+		if _, rpcErr := h.handleResourcesSubscribe(context.Background(), params, true); rpcErr != nil {
+			t.Fatalf("subscribe: %s", rpcErr.Message)
+		}
+	}
+	if rl.subCalls != 1 {
+		t.Fatalf("subscribe forwarded %d times, want 1", rl.subCalls)
+	}
+
+	h.releaseSubscriptions(context.Background())
+	if rl.subCalls != 2 || rl.subscribed["file:///tmp/a.txt"] {
+		t.Fatalf("subscription not released downstream (%d calls)", rl.subCalls)
+	}
+	h.releaseSubscriptions(context.Background())
+	if rl.subCalls != 2 {
+		t.Errorf("released twice")
+	}
+}
+
+func TestHandleResourcesSubscribe_ConcurrentDuplicatesForwardedOnce(t *testing.T) {
+	rl := &mockResourceLister{subStarted: make(chan struct{}, 4), subGate: make(chan struct{})}
+	h := newResourceTestHandler(rl)
+	h.setNotifier(&recordingNotifier{})
+	params, _ := json.Marshal(ReadResourceRequest{URI: "mcpx://fs/file:///tmp/a.txt"})
+
+	// The first subscribe is held downstream while a duplicate arrives.
+	done := make(chan *RPCError)
+	go func() {
+		_, rpcErr := h.handleResourcesSubscribe(context.Background(), params, true)
+		done <- rpcErr
+	}()
+	<-rl.subStarted
+	if _, rpcErr := h.handleResourcesSubscribe(context.Background(), params, true); rpcErr != nil {
+		t.Fatalf("duplicate subscribe: %s", rpcErr.Message)
+	}
+	close(rl.subGate)
+	if rpcErr := <-done; rpcErr != nil {
+		t.Fatalf("subscribe: %s", rpcErr.Message)
+	}
+	if rl.subCalls != 1 {
+		t.Fatalf("subscribe forwarded %d times, want 1", rl.subCalls)
+	}
+}
+
+func TestHandleResourcesSubscribe_UnsubscribeWhileSubscribing(t *testing.T) {
+	rl := &mockResourceLister{subStarted: make(chan struct{}, 4), subGate: make(chan struct{})}
+	h := newResourceTestHandler(rl)
+	h.setNotifier(&recordingNotifier{})
+	params, _ := json.Marshal(ReadResourceRequest{URI: "mcpx://fs/file:///tmp/a.txt"})
+
+	done := make(chan *RPCError)
+	go func() {
+		_, rpcErr := h.handleResourcesSubscribe(context.Background(), params, true)
+		done <- rpcErr
+	}()
+	<-rl.subStarted
+	if _, rpcErr := h.handleResourcesSubscribe(context.Background(), params, false); rpcErr != nil {
+		t.Fatalf("unsubscribe: %s", rpcErr.Message)
+	}
+	close(rl.subGate)
+	if rpcErr := <-done; rpcErr != nil {
+		t.Fatalf("subscribe: %s", rpcErr.Message)
+	}
+	// The subscribe finished after the client withdrew it, so it is
+	// released downstream and not left behind.
+	if rl.subCalls != 2 || rl.subscribed["file:///tmp/a.txt"] {
+		t.Fatalf("subscribed = %v after %d calls, want released", rl.subscribed, rl.subCalls)
+	}
+	h.releaseSubscriptions(context.Background())
+	if rl.subCalls != 2 {
+		t.Errorf("released again with the session (%d calls)", rl.subCalls)
+	}
+}
+
+func TestHandleResourcesRead_AppliesRouteGates(t *testing.T) {
+	ms := &mockStore{
+		servers: []store.DownstreamServer{
+			{ID: "fs", Name: "Filesystem", ToolNamespace: "fs", Discovery: "static"},
+			{ID: "gh", Name: "GitHub", ToolNamespace: "github", Discovery: "static"},
+		},
+		workspaces: []mockWorkspace{{id: "ws-global", rootPath: "/"}},
+		routeRules: map[string][]store.RouteRule{
+			"ws-global": {
+				{
+					ID: "approve-secrets", WorkspaceID: "ws-global",
+					Priority: 10, PathGlob: "**", Policy: "allow",
+					ToolMatch:          json.RawMessage(`["fs__file:///secrets/*"]`),
+					DownstreamServerID: "fs",
+					ApprovalMode:       "all",
+				},
+				{
+					ID: "limit-fs", WorkspaceID: "ws-global",
+					Priority: 5, PathGlob: "**", Policy: "allow",
+					ToolMatch:          json.RawMessage(`["fs__*"]`),
+					DownstreamServerID: "fs",
+					ApprovalMode:       "write",
+					RateLimit:          json.RawMessage(`{"rate":1,"per":"1h"}`),
+				},
+				{
+					ID: "gh-acme", WorkspaceID: "ws-global",
+					Priority: 1, PathGlob: "**", Policy: "allow",
+					ToolMatch:          json.RawMessage(`["github__*"]`),
+					DownstreamServerID: "gh",
+					AllowedOrgs:        json.RawMessage(`["acme"]`),
+				},
+			},
+		},
+	}
+	h := newHandler(ms, routing.NewEngine(ms), &mockToolLister{}, nil, TransportSocket,
+		approval.NewManager(ms, approval.NewBus()), nil, nil, nil)
+	h.resources = &mockResourceLister{}
+	h.sessions.clientPath = "/test"
+	h.sessions.wsChain = []routing.WorkspaceAncestor{{ID: "ws-global", RootPath: "/"}}
+
+	read := func(uri string) *RPCError {
+		t.Helper()
+		params, _ := json.Marshal(ReadResourceRequest{URI: uri})
+		_, rpcErr := h.handleResourcesRead(context.Background(), params)
+		return rpcErr
+	}
+	tests := []struct {
+		name     string
+		uri      string
+		wantCode int // 0 = allowed
+	}{
+		{"requires approval", "mcpx://fs/file:///secrets/key", CodeInvalidRequest},
+		{"write approval passes reads", "mcpx://fs/file:///tmp/a.txt", 0},
+		{"rate limited", "mcpx://fs/file:///tmp/a.txt", CodeRateLimited},
+		{"repo outside allowlist", "mcpx://github/repo://other/site/contents/README.md", CodeInvalidParams},
+		{"repo in allowlist", "mcpx://github/repo://acme/site/contents/README.md", 0},
+	}
+	for _, tt := range tests {
+		rpcErr := read(tt.uri)
+		switch {
+		case tt.wantCode == 0 && rpcErr != nil:
+			t.Errorf("%s: unexpected error: %s", tt.name, rpcErr.Message)
+		case tt.wantCode != 0 && (rpcErr == nil || rpcErr.Code != tt.wantCode):
+			t.Errorf("%s: error = %+v, want code %d", tt.name, rpcErr, tt.wantCode)
+		}
+	}
+}
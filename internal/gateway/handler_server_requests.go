@@ -0,0 +1,108 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/downstream"
+	"github.com/revittco/mcplexer/internal/routing"
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// relayableServerMethods maps downstream→client request methods the gateway
+// forwards to the client capability they require.
+var relayableServerMethods = map[string]string{
+	"sampling/createMessage": "sampling",
+	"elicitation/create":     "elicitation",
+}
+
+// serverRequestRelay returns a handler that relays requests initiated by a
+// downstream server during a tool call to the connected client.
+//
+// Each request is routed as "<namespace>__<method>" (e.g.
+// "github__sampling/createMessage") so route rules can deny it or require
+// approval, and is audited under that name.
+func (h *handler) serverRequestRelay(namespace, serverName string) downstream.ServerRequestHandler {
+	return func(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+		start := time.Now()
+		name := namespace + "__" + method
+
+		capability, ok := relayableServerMethods[method]
+		if !ok {
+			return nil, &downstream.RequestError{
+				Code:    CodeMethodNotFound,
+				Message: fmt.Sprintf("method not supported: %s", method),
+			}
+		}
+
+		route, err := h.engine.RouteWithFallback(ctx, routing.RouteContext{
+			ToolName: name,
+			Git:      h.sessions.gitInfo(),
+		}, h.sessions.clientRoot(), h.sessions.workspaceAncestors(ctx))
+		if err != nil {
+			rpcErr := mapRouteError(err)
+			h.recordAuditBlocked(ctx, name, params, nil, nil, rpcErr, start)
+			return nil, &downstream.RequestError{Code: rpcErr.Code, Message: rpcErr.Message}
+		}
+
+		if h.requester == nil || !h.sessions.clientSupports(capability) {
+			rpcErr := &RPCError{
+				Code:    CodeMethodNotFound,
+				Message: fmt.Sprintf("client does not support %s", capability),
+			}
+			h.recordAudit(ctx, name, params, route, nil, rpcErr, start)
+			return nil, &downstream.RequestError{Code: rpcErr.Code, Message: rpcErr.Message}
+		}
+
+		// Server-initiated requests have no agent to supply a justification,
+		// so any approval mode other than "none" goes straight to a human.
+		if route.ApprovalMode != "" && route.ApprovalMode != "none" && h.approvals != nil {
+			rec := &store.ToolApproval{
+				RequestSessionID:   h.sessions.sessionID(),
+				RequestClientType:  h.sessions.clientType(),
+				RequestModel:       h.sessions.modelHint(),
+				WorkspaceID:        h.sessions.workspaceID(),
+				WorkspaceName:      h.sessions.workspaceName(),
+				ToolName:           name,
+				Arguments:          string(params),
+				Justification:      fmt.Sprintf("%s server requested %s", serverName, method),
+				RouteRuleID:        route.MatchedRuleID,
+				DownstreamServerID: route.DownstreamServerID,
+				AuthScopeID:        route.AuthScopeID,
+				TimeoutSec:         route.ApprovalTimeout,
+			}
+			if rec.TimeoutSec <= 0 {
+				rec.TimeoutSec = 300
+			}
+			approved, err := h.approvals.RequestApproval(ctx, rec)
+			if err != nil || !approved {
+				rpcErr := &RPCError{
+					Code:    CodeRouteNotFound,
+					Message: fmt.Sprintf("%s request was not approved", method),
+				}
+				if err != nil {
+					rpcErr.Message = fmt.Sprintf("approval request failed: %v", err)
+				}
+				h.recordAuditBlocked(ctx, name, params, route, nil, rpcErr, start)
+				return nil, &downstream.RequestError{Code: rpcErr.Code, Message: rpcErr.Message}
+			}
+		}
+
+		result, err := h.requester.Request(ctx, method, params)
+		if err != nil {
+			rpcErr := &RPCError{Code: CodeInternalError, Message: err.Error()}
+			var clientErr *RPCError
+			if errors.As(err, &clientErr) {
+				rpcErr = clientErr
+			}
+			h.recordAudit(ctx, name, params, route, nil, rpcErr, start)
+			return nil, &downstream.RequestError{Code: rpcErr.Code, Message: rpcErr.Message}
+		}
+
+		h.recordAudit(ctx, name, params, route, result, nil, start)
+		return result, nil
+	}
+}
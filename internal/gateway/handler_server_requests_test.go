@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/downstream"
+	"github.com/revittco/mcplexer/internal/routing"
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// fakeRequester implements ClientRequester for testing.
+type fakeRequester struct {
+	result json.RawMessage
+	err    error
+	calls  []string
+}
+
+func (f *fakeRequester) Request(_ context.Context, method string, _ json.RawMessage) (json.RawMessage, error) {
+	f.calls = append(f.calls, method)
+	return f.result, f.err
+}
+
+func newServerRequestTestHandler(req ClientRequester) *handler {
+	ms := &mockStore{
+		servers: []store.DownstreamServer{
+			{ID: "gh", Name: "GitHub", ToolNamespace: "github", Discovery: "static"},
+		},
+		workspaces: []mockWorkspace{{id: "ws-global", rootPath: "/"}},
+		routeRules: map[string][]store.RouteRule{
+			"ws-global": {
+				{
+					ID: "deny-elicit", WorkspaceID: "ws-global",
+					Priority: 10, PathGlob: "**", Policy: "deny",
+					ToolMatch:          json.RawMessage(`["github__elicitation/create"]`),
+					DownstreamServerID: "gh",
+				},
+				{
+					ID: "allow-gh", WorkspaceID: "ws-global",
+					Priority: 1, PathGlob: "**", Policy: "allow",
+					ToolMatch:          json.RawMessage(`["github__*"]`),
+					DownstreamServerID: "gh",
+				},
+			},
+		},
+	}
+	h := newHandler(ms, routing.NewEngine(ms), &mockToolLister{}, nil, TransportSocket, nil, nil, nil, nil)
+	h.requester = req
+	h.sessions.clientPath = "/test"
+	h.sessions.wsChain = []routing.WorkspaceAncestor{{ID: "ws-global", RootPath: "/"}}
+	h.sessions.setClientCapabilities(map[string]any{"sampling": map[string]any{}})
+	return h
+}
+
+func TestServerRequestRelay_ForwardsToClient(t *testing.T) {
+	req := &fakeRequester{result: json.RawMessage(`{"role":"assistant"}`)}
+	h := newServerRequestTestHandler(req)
+
+	relay := h.serverRequestRelay("github", "GitHub")
+	result, err := relay(context.Background(), "sampling/createMessage", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(result) != `{"role":"assistant"}` {
+		t.Errorf("result = %s", result)
+	}
+	if len(req.calls) != 1 || req.calls[0] != "sampling/createMessage" {
+		t.Errorf("client calls = %v", req.calls)
+	}
+}
+
+func TestServerRequestRelay_DeniedByRoute(t *testing.T) {
+	req := &fakeRequester{}
+	h := newServerRequestTestHandler(req)
+	h.sessions.setClientCapabilities(map[string]any{"elicitation": map[string]any{}})
+
+	relay := h.serverRequestRelay("github", "GitHub")
+	_, err := relay(context.Background(), "elicitation/create", json.RawMessage(`{}`))
+
+	var reqErr *downstream.RequestError
+	if !errors.As(err, &reqErr) || reqErr.Code != CodeRouteNotFound {
+		t.Fatalf("expected route denial, got %v", err)
+	}
+	if len(req.calls) != 0 {
+		t.Errorf("client should not be called, got %v", req.calls)
+	}
+}
+
+func TestServerRequestRelay_ClientLacksCapability(t *testing.T) {
+	req := &fakeRequester{}
+	h := newServerRequestTestHandler(req)
+	h.sessions.setClientCapabilities(map[string]any{})
+
+	relay := h.serverRequestRelay("github", "GitHub")
+	_, err := relay(context.Background(), "sampling/createMessage", json.RawMessage(`{}`))
+
+	var reqErr *downstream.RequestError
+	if !errors.As(err, &reqErr) || reqErr.Code != CodeMethodNotFound {
+		t.Fatalf("expected method not found, got %v", err)
+	}
+	if len(req.calls) != 0 {
+		t.Errorf("client should not be called, got %v", req.calls)
+	}
+}
+
+func TestServerRequestRelay_ClientError(t *testing.T) {
+	req := &fakeRequester{err: &RPCError{Code: -1, Message: "user rejected"}}
+	h := newServerRequestTestHandler(req)
+
+	relay := h.serverRequestRelay("github", "GitHub")
+	_, err := relay(context.Background(), "sampling/createMessage", json.RawMessage(`{}`))
+
+	var reqErr *downstream.RequestError
+	if !errors.As(err, &reqErr) || reqErr.Code != -1 || reqErr.Message != "user rejected" {
+		t.Fatalf("expected client error to pass through, got %v", err)
+	}
+}
+
+func TestServerRequest_RoundTrip(t *testing.T) {
+	h := newServerRequestTestHandler(nil)
+	s := &Server{handler: h}
+
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- s.RunConn(ctx, serverR, serverW) }()
+
+	// A ping round-trip guarantees the server loop is running before
+	// Request is used.
+	out := bufio.NewScanner(clientR)
+	if _, err := io.WriteString(clientW, `{"jsonrpc":"2.0","id":1,"method":"ping"}`+"\n"); err != nil {
+		t.Fatalf("write ping: %v", err)
+	}
+	if !out.Scan() {
+		t.Fatal("no ping response")
+	}
+
+	type result struct {
+		data json.RawMessage
+		err  error
+	}
+	got := make(chan result, 1)
+	go func() {
+		data, err := s.Request(ctx, "sampling/createMessage", json.RawMessage(`{"maxTokens":5}`))
+		got <- result{data, err}
+	}()
+
+	if !out.Scan() {
+		t.Fatal("no request written to client")
+	}
+	var req Request
+	if err := json.Unmarshal(out.Bytes(), &req); err != nil {
+		t.Fatalf("unmarshal request: %v", err)
+	}
+	if req.Method != "sampling/createMessage" {
+		t.Fatalf("method = %q", req.Method)
+	}
+
+	reply, _ := json.Marshal(Response{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`{"ok":true}`)})
+	if _, err := clientW.Write(append(reply, '\n')); err != nil {
+		t.Fatalf("write reply: %v", err)
+	}
+
+	select {
+	case r := <-got:
+		if r.err != nil {
+			t.Fatalf("request error: %v", r.err)
+		}
+		if string(r.data) != `{"ok":true}` {
+			t.Errorf("result = %s", r.data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for client response")
+	}
+
+	clientW.Close()
+	go io.Copy(io.Discard, clientR) //nolint:errcheck
+	if err := <-done; err != nil {
+		t.Errorf("run: %v", err)
+	}
+}
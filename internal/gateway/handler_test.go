@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/revittco/mcplexer/internal/approval"
+	"github.com/revittco/mcplexer/internal/audit"
 	"github.com/revittco/mcplexer/internal/config"
 	"github.com/revittco/mcplexer/internal/routing"
 	"github.com/revittco/mcplexer/internal/store"
@@ -64,6 +67,7 @@ type mockStore struct {
 type mockWorkspace struct {
 	id       string
 	rootPath string
+	matchers string // JSON, optional
 }
 
 func (m *mockStore) ListDownstreamServers(_ context.Context) ([]store.DownstreamServer, error) {
@@ -88,7 +92,7 @@ func (m *mockStore) GetWorkspaceByName(_ context.Context, _ string) (*store.Work
 func (m *mockStore) ListWorkspaces(_ context.Context) ([]store.Workspace, error) {
 	out := make([]store.Workspace, len(m.workspaces))
 	for i, w := range m.workspaces {
-		out[i] = store.Workspace{ID: w.id, RootPath: w.rootPath}
+		out[i] = store.Workspace{ID: w.id, RootPath: w.rootPath, Matchers: json.RawMessage(w.matchers)}
 	}
 	return out, nil
 }
@@ -1209,6 +1213,195 @@ func TestHandleToolsCall_GitHubRepoAllowlistAllowsConfiguredRepo(t *testing.T) {
 	}
 }
 
+func TestHandleToolsCall_RateLimited(t *testing.T) {
+	lister := &mockToolLister{}
+	ms := &mockStore{
+		servers: []store.DownstreamServer{{ID: "gh", ToolNamespace: "github", Discovery: "static"}},
+		workspaces: []mockWorkspace{
+			{id: "ws-global", rootPath: "/"},
+		},
+		routeRules: map[string][]store.RouteRule{
+			"ws-global": {
+				{
+					ID: "allow-gh", WorkspaceID: "ws-global",
+					Priority: 1, PathGlob: "**", Policy: "allow",
+					ToolMatch:          json.RawMessage(`["github__*"]`),
+					DownstreamServerID: "gh",
+					RateLimit:          json.RawMessage(`{"rate":2,"per":"1h"}`),
+				},
+			},
+		},
+	}
+
+	h := newHandler(ms, routing.NewEngine(ms), lister, nil, TransportSocket, nil, nil, nil, nil)
+	h.sessions.clientPath = "/test"
+	h.sessions.wsChain = []routing.WorkspaceAncestor{{ID: "ws-global", RootPath: "/"}}
+
+	params, _ := json.Marshal(CallToolRequest{Name: "github__create_issue"})
+	for i := 0; i < 2; i++ {
+		if _, rpcErr := h.handleToolsCall(context.Background(), params); rpcErr != nil {
+			t.Fatalf("call %d: code=%d msg=%s", i, rpcErr.Code, rpcErr.Message)
+		}
+	}
+	_, rpcErr := h.handleToolsCall(context.Background(), params)
+	if rpcErr == nil || rpcErr.Code != CodeRateLimited {
+		t.Fatalf("third call: err = %+v, want code %d", rpcErr, CodeRateLimited)
+	}
+	var data struct {
+		RuleID        string `json:"rule_id"`
+		RetryAfterSec int    `json:"retry_after_sec"`
+	}
+	if err := json.Unmarshal(rpcErr.Data, &data); err != nil || data.RuleID != "allow-gh" || data.RetryAfterSec <= 0 {
+		t.Errorf("data = %s", rpcErr.Data)
+	}
+	if lister.callCount != 2 {
+		t.Fatalf("downstream call count = %d, want 2", lister.callCount)
+	}
+}
+
+func TestHandleToolsCall_RefusedCallsAreRefunded(t *testing.T) {
+	lister := &mockToolLister{}
+	ms := &mockStore{
+		servers: []store.DownstreamServer{{ID: "gh", ToolNamespace: "github", Discovery: "static"}},
+		workspaces: []mockWorkspace{
+			{id: "ws-global", rootPath: "/"},
+		},
+		routeRules: map[string][]store.RouteRule{
+			"ws-global": {
+				{
+					ID: "approve-merge", WorkspaceID: "ws-global",
+					Priority: 10, PathGlob: "**", Policy: "allow",
+					ToolMatch:          json.RawMessage(`["github__merge"]`),
+					DownstreamServerID: "gh",
+					ApprovalMode:       "all",
+					RateLimit:          json.RawMessage(`{"rate":1,"per":"1h"}`),
+				},
+				{
+					ID: "allow-gh", WorkspaceID: "ws-global",
+					Priority: 1, PathGlob: "**", Policy: "allow",
+					ToolMatch:          json.RawMessage(`["github__*"]`),
+					DownstreamServerID: "gh",
+					AllowedOrgs:        json.RawMessage(`["acme"]`),
+					RateLimit:          json.RawMessage(`{"rate":1,"per":"1h"}`),
+				},
+			},
+		},
+	}
+
+	h := newHandler(ms, routing.NewEngine(ms), lister, nil, TransportSocket,
+		approval.NewManager(ms, approval.NewBus()), nil, nil, nil)
+	h.sessions.clientPath = "/test"
+	h.sessions.wsChain = []routing.WorkspaceAncestor{{ID: "ws-global", RootPath: "/"}}
+
+	call := func(name, args string) *RPCError {
+		t.Helper()
+		params, _ := json.Marshal(CallToolRequest{Name: name, Arguments: json.RawMessage(args)})
+		_, rpcErr := h.handleToolsCall(context.Background(), params)
+		return rpcErr
+	}
+
+	// Calls held for a justification are not charged.
+	for i := 0; i < 3; i++ {
+		if rpcErr := call("github__merge", `{}`); rpcErr != nil {
+			t.Fatalf("merge %d: %s", i, rpcErr.Message)
+		}
+	}
+	// Nor are calls outside the allowlist.
+	for i := 0; i < 3; i++ {
+		if rpcErr := call("github__get_repo", `{"owner":"other","repo":"x"}`); rpcErr == nil || rpcErr.Code != CodeInvalidParams {
+			t.Fatalf("disallowed repo %d: err = %+v", i, rpcErr)
+		}
+	}
+	if rpcErr := call("github__get_repo", `{"owner":"acme","repo":"x"}`); rpcErr != nil {
+		t.Fatalf("allowed repo: %s", rpcErr.Message)
+	}
+	if rpcErr := call("github__get_repo", `{"owner":"acme","repo":"x"}`); rpcErr == nil || rpcErr.Code != CodeRateLimited {
+		t.Fatalf("second call: err = %+v, want rate limited", rpcErr)
+	}
+	if lister.callCount != 1 {
+		t.Errorf("downstream call count = %d, want 1", lister.callCount)
+	}
+}
+
+// recordingStore keeps audit records in memory, newest first.
+type recordingStore struct {
+	*mockStore
+	records []store.AuditRecord
+}
+
+func (s *recordingStore) InsertAuditRecord(_ context.Context, r *store.AuditRecord) error {
+	s.records = append([]store.AuditRecord{*r}, s.records...)
+	return nil
+}
+
+func (s *recordingStore) QueryAuditRecords(_ context.Context, f store.AuditFilter) ([]store.AuditRecord, int, error) {
+	var out []store.AuditRecord
+	for _, r := range s.records {
+		if (f.SessionID == nil || r.SessionID == *f.SessionID) && (f.Status == nil || r.Status == *f.Status) {
+			out = append(out, r)
+		}
+	}
+	return out, len(out), nil
+}
+
+func TestHandleToolsCall_ExplainLastDenial(t *testing.T) {
+	rs := &recordingStore{mockStore: &mockStore{
+		servers: []store.DownstreamServer{{ID: "gh", ToolNamespace: "github", Discovery: "static"}},
+		workspaces: []mockWorkspace{
+			{id: "ws-global", rootPath: "/"},
+		},
+		routeRules: map[string][]store.RouteRule{
+			"ws-global": {
+				{
+					ID: "builtin", WorkspaceID: "ws-global", PathGlob: "**", Policy: "allow",
+					ToolMatch: json.RawMessage(`["mcpx__*"]`), DownstreamServerID: "mcpx-builtin",
+				},
+				{
+					ID: "allow-gh", WorkspaceID: "ws-global", PathGlob: "**", Policy: "allow",
+					ToolMatch: json.RawMessage(`["github__*"]`), DownstreamServerID: "gh",
+				},
+				{
+					ID: "deny-slack", WorkspaceID: "ws-global", PathGlob: "**", Policy: "deny",
+					ToolMatch: json.RawMessage(`["slack__*"]`),
+				},
+			},
+		},
+	}}
+
+	h := newHandler(rs, routing.NewEngine(rs), &mockToolLister{}, audit.NewLogger(rs, rs, nil), TransportSocket, nil, nil, nil, nil)
+	h.sessions.clientPath = "/test"
+	h.sessions.wsChain = []routing.WorkspaceAncestor{{ID: "ws-global", RootPath: "/"}}
+
+	params, _ := json.Marshal(CallToolRequest{Name: "slack__post_message"})
+	if _, rpcErr := h.handleToolsCall(context.Background(), params); rpcErr == nil {
+		t.Fatal("slack call should be denied")
+	}
+	denied := rs.records[0]
+	if denied.Status != "blocked" || denied.RouteRuleID != "deny-slack" || len(denied.RouteTrace) == 0 {
+		t.Fatalf("audit record = %+v", denied)
+	}
+
+	params, _ = json.Marshal(CallToolRequest{Name: "mcpx__explain_last_denial"})
+	result, rpcErr := h.handleToolsCall(context.Background(), params)
+	if rpcErr != nil {
+		t.Fatalf("explain: %+v", rpcErr)
+	}
+	var res CallToolResult
+	if err := json.Unmarshal(result, &res); err != nil || len(res.Content) == 0 {
+		t.Fatalf("result = %s", result)
+	}
+	text := res.Content[0].Text
+	for _, want := range []string{
+		"slack__post_message: deny by rule deny-slack",
+		"allow-gh [allow] skipped (tool)",
+		"deny-slack [deny] matched",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("explanation missing %q:\n%s", want, text)
+		}
+	}
+}
+
 func TestExtractAndRemoveCacheBust(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -3,12 +3,14 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/revittco/mcplexer/internal/cache"
+	"github.com/revittco/mcplexer/internal/downstream"
 	"github.com/revittco/mcplexer/internal/routing"
 	"github.com/revittco/mcplexer/internal/store"
 )
@@ -140,6 +142,10 @@ func (h *handler) handleToolsList(
 		if _, ok := h.manager.(CachingCaller); ok {
 			tools = append(tools, flushCacheToolDefinition())
 		}
+
+		if h.auditor != nil {
+			tools = append(tools, explainToolDefinition())
+		}
 	}
 
 	tools = dedupeToolsByName(tools)
@@ -309,50 +315,56 @@ func (h *handler) handleToolsCall(
 	// Extract namespace from tool name (namespace__toolname).
 	originalTool := extractOriginalToolName(req.Name)
 
-	// Route ALL tools through the engine (including built-ins).
-	routeResult, err := h.engine.RouteWithFallback(ctx, routing.RouteContext{
-		ToolName: req.Name,
-	}, h.sessions.clientRoot(), h.sessions.workspaceAncestors(ctx))
+	// Coerce stringified JSON arguments (LLMs often pass objects as strings)
+	// before routing, so rule conditions see the real values.
+	req.Arguments = coerceStringifiedArgs(req.Arguments)
+	routeArgs := req.Arguments
+	if routeArgs == nil {
+		routeArgs = json.RawMessage(`{}`)
+	}
+
+	// Pick the client root(s) whose policies apply to this call.
+	roots, err := h.sessions.callRoots(ctx, req.Meta, routeArgs)
 	if err != nil {
-		rpcErr := mapRouteError(err)
+		rpcErr := &RPCError{Code: CodeInvalidParams, Message: err.Error()}
 		h.recordAuditBlocked(ctx, req.Name, req.Arguments, nil, nil, rpcErr, start)
 		return nil, rpcErr
 	}
-
-	// Coerce stringified JSON arguments (LLMs often pass objects as strings).
-	req.Arguments = coerceStringifiedArgs(req.Arguments)
-
-	// Dispatch based on whether it's a built-in or downstream tool.
-	if routeResult.DownstreamServerID == "mcpx-builtin" {
-		result, rpcErr := h.handleBuiltinCall(ctx, req)
-		h.recordAudit(ctx, req.Name, req.Arguments, routeResult, result, rpcErr, start)
-		return result, rpcErr
-	}
-
-	// Optional GitHub scope enforcement from route allowlists.
-	if strings.HasPrefix(req.Name, "github__") {
-		policy, err := newGitHubScopePolicy(routeResult.AllowedOrgs, routeResult.AllowedRepos)
+	ctx = downstream.WithCaller(withCallRoot(ctx, roots[0]), h.sessions.caller(roots[0]))
+
+	// Route ALL tools through the engine (including built-ins), tracing
+	// the decision so refusals can be explained. A call touching several
+	// roots must be allowed under each; the first root's route is used.
+	routes := make([]*routing.RouteResult, len(roots))
+	for i, root := range roots {
+		trace := &routing.Trace{}
+		result, err := h.engine.RouteWithFallback(ctx, routing.RouteContext{
+			ToolName:  req.Name,
+			Arguments: routeArgs,
+			Git:       h.sessions.gitInfoFor(root.Path),
+			Trace:     trace,
+		}, root.Path, root.Chain)
 		if err != nil {
-			rpcErr := &RPCError{
-				Code:    CodeInvalidParams,
-				Message: fmt.Sprintf("invalid route allowlist configuration: %v", err),
-			}
-			h.recordAudit(ctx, req.Name, req.Arguments, routeResult, nil, rpcErr, start)
-			return nil, rpcErr
-		}
-		if err := policy.Enforce(req.Arguments); err != nil {
-			rpcErr := &RPCError{
-				Code:    CodeInvalidParams,
-				Message: err.Error(),
-			}
-			h.recordAudit(ctx, req.Name, req.Arguments, routeResult, nil, rpcErr, start)
+			rpcErr := mapRouteError(err)
+			h.recordAuditRouteRejected(withCallRoot(ctx, root), req.Name, req.Arguments, trace, rpcErr, start)
 			return nil, rpcErr
 		}
+		routes[i] = result
+	}
+	routeResult, err := mergeRoutes(routes)
+	if err != nil {
+		rpcErr := &RPCError{Code: CodeInvalidParams, Message: err.Error()}
+		h.recordAudit(ctx, req.Name, req.Arguments, routes[0], nil, rpcErr, start)
+		return nil, rpcErr
 	}
 
-	// Look up server name for clearer error messages.
-	serverName := routeResult.DownstreamServerID
-	if serverName == "" {
+	// Optional GitHub scope enforcement from every root's route allowlist.
+	if rpcErr := enforceGitHubAllowlists(req.Name, routes, req.Arguments); rpcErr != nil {
+		h.recordAudit(ctx, req.Name, req.Arguments, routeResult, nil, rpcErr, start)
+		return nil, rpcErr
+	}
+
+	if routeResult.DownstreamServerID == "" {
 		rpcErr := &RPCError{
 			Code:    CodeInternalError,
 			Message: "matched route has no downstream server configured",
@@ -360,10 +372,33 @@ func (h *handler) handleToolsCall(
 		h.recordAudit(ctx, req.Name, req.Arguments, routeResult, nil, rpcErr, start)
 		return nil, rpcErr
 	}
+
+	// Enforce the matched rules' rate limits and daily quotas. This comes
+	// after the checks above so that refused calls are not counted; a call
+	// refused at approval is refunded.
+	refund, refused, err := h.chargeRateLimits(ctx, routes, start)
+	if err != nil {
+		rpcErr := mapRateLimitError(err)
+		h.recordAuditRateLimited(withCallRoot(ctx, roots[refused]), req.Name, req.Arguments, routes[refused], rpcErr, start)
+		return nil, rpcErr
+	}
+
+	// Dispatch based on whether it's a built-in or downstream tool.
+	if routeResult.DownstreamServerID == "mcpx-builtin" {
+		result, rpcErr := h.handleBuiltinCall(ctx, req)
+		h.recordAudit(ctx, req.Name, req.Arguments, routeResult, result, rpcErr, start)
+		return result, rpcErr
+	}
+
+	// Look up server name for clearer error messages.
+	serverName := routeResult.DownstreamServerID
 	if srv, err := h.store.GetDownstreamServer(ctx, routeResult.DownstreamServerID); err == nil && srv != nil {
 		serverName = srv.Name
 	}
 
+	// Relay progress to the client if it asked for it.
+	progress := h.progressReporter(req.Meta)
+
 	// Two-phase approval interception.
 	if routeResult.ApprovalMode != "" && routeResult.ApprovalMode != "none" && h.approvals != nil {
 		needsApproval := true
@@ -371,8 +406,9 @@ func (h *handler) handleToolsCall(
 			needsApproval = !h.isReadOnlyTool(ctx, routeResult.DownstreamServerID, originalTool)
 		}
 		if needsApproval {
-			result, rpcErr := h.handleApprovalGate(ctx, req, routeResult, originalTool, start)
+			result, rpcErr := h.handleApprovalGate(ctx, req, routeResult, originalTool, start, progress)
 			if result != nil || rpcErr != nil {
+				refund()
 				return result, rpcErr
 			}
 			// Approval granted — fall through to dispatch.
@@ -407,6 +443,14 @@ func (h *handler) handleToolsCall(
 	// Extract _cache_bust from arguments if present.
 	cacheBust := extractAndRemoveCacheBust(&req.Arguments)
 
+	// Relay sampling/elicitation requests the downstream makes mid-call.
+	namespace, _, _ := strings.Cut(req.Name, "__")
+	ctx = downstream.WithServerRequestHandler(ctx, h.serverRequestRelay(namespace, serverName))
+	if progress != nil {
+		progress.nextPhase()
+		ctx = downstream.WithProgressHandler(ctx, progress.report)
+	}
+
 	// Dispatch to downstream, with cache hit detection.
 	var result json.RawMessage
 	var cacheHit bool
@@ -529,6 +573,11 @@ func isAllowedDirectCodeModeTool(name string) bool {
 // formatDownstreamError produces a human-readable error message for downstream
 // failures, including the server name and actionable hints where possible.
 func formatDownstreamError(serverName string, err error) string {
+	var procErr *downstream.ProcessError
+	if errors.As(err, &procErr) {
+		return fmt.Sprintf("%s server is unavailable: %s", serverName, procErr.Error())
+	}
+
 	msg := err.Error()
 
 	// Extract the root cause from wrapped error chains.
@@ -0,0 +1,734 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// mcpSessionHeader carries the Streamable HTTP session ID.
+	mcpSessionHeader = "Mcp-Session-Id"
+
+	// httpSessionIdleTimeout is how long a session may go without requests
+	// or attached streams before it is closed.
+	httpSessionIdleTimeout = 30 * time.Minute
+
+	// standaloneStreamID identifies the GET (server→client) stream.
+	standaloneStreamID = "0"
+
+	// maxStandaloneEvents bounds the replay buffer of the standalone stream.
+	maxStandaloneEvents = 256
+)
+
+// HTTPHandler serves MCP over the Streamable HTTP transport (POST for
+// client messages, SSE for server messages).
+//
+// Each MCP session, identified by the Mcp-Session-Id header, is backed by
+// its own Server created on initialize. Sessions bind their workspace from
+// the roots the client declares, as for Unix socket connections, so
+// newServer should build servers with TransportSocket.
+type HTTPHandler struct {
+	ctx       context.Context
+	newServer func() *Server
+
+	mu       sync.Mutex
+	sessions map[string]*httpSession
+}
+
+// NewHTTPHandler returns a handler for the Streamable HTTP /mcp endpoint.
+// Sessions are closed when ctx is cancelled.
+func NewHTTPHandler(ctx context.Context, newServer func() *Server) *HTTPHandler {
+	h := &HTTPHandler{
+		ctx:       ctx,
+		newServer: newServer,
+		sessions:  make(map[string]*httpSession),
+	}
+	go h.reapIdle()
+	return h
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.handlePost(w, r)
+	case http.MethodGet:
+		h.handleGet(w, r)
+	case http.MethodDelete:
+		h.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeHTTPError(w, http.StatusMethodNotAllowed, CodeInvalidRequest, "method not allowed")
+	}
+}
+
+func (h *HTTPHandler) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, CodeParseError, "read body: "+err.Error())
+		return
+	}
+	msgs, batch, err := splitJSONRPCBody(body)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, CodeParseError, err.Error())
+		return
+	}
+
+	var requestIDs []string
+	initialize := false
+	for _, m := range msgs {
+		if m.id != "" && m.method != "" {
+			requestIDs = append(requestIDs, m.id)
+		}
+		if m.method == "initialize" {
+			initialize = true
+		}
+	}
+
+	var sess *httpSession
+	if id := r.Header.Get(mcpSessionHeader); id != "" {
+		if sess = h.session(id); sess == nil {
+			writeHTTPError(w, http.StatusNotFound, CodeInvalidRequest, "unknown session")
+			return
+		}
+	} else {
+		if !initialize || len(msgs) != 1 {
+			writeHTTPError(w, http.StatusBadRequest, CodeInvalidRequest,
+				"missing "+mcpSessionHeader+" header")
+			return
+		}
+		sess = h.startSession()
+		w.Header().Set(mcpSessionHeader, sess.id)
+	}
+	sess.touch()
+
+	// A cancelled request gets no response, so its stream must stop
+	// waiting for one.
+	for _, m := range msgs {
+		if m.method == "notifications/cancelled" {
+			var n struct {
+				Params CancelledParams `json:"params"`
+			}
+			if json.Unmarshal(m.raw, &n) == nil && n.Params.RequestID != nil {
+				sess.abandon(compactID(n.Params.RequestID))
+			}
+		}
+	}
+
+	// Only notifications and responses: accept without a response body.
+	if len(requestIDs) == 0 {
+		if err := sess.send(msgs); err != nil {
+			writeHTTPError(w, http.StatusNotFound, CodeInvalidRequest, "session closed")
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	useSSE := acceptsEventStream(r)
+	st := sess.openStream(requestIDs, useSSE)
+	if err := sess.send(msgs); err != nil {
+		sess.closeStream(st)
+		writeHTTPError(w, http.StatusNotFound, CodeInvalidRequest, "session closed")
+		return
+	}
+
+	if useSSE {
+		sess.serveStream(w, r, st, 1, 0)
+		return
+	}
+	sess.serveJSON(w, r, st, batch)
+}
+
+func (h *HTTPHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEventStream(r) {
+		writeHTTPError(w, http.StatusNotAcceptable, CodeInvalidRequest,
+			"GET requires Accept: text/event-stream")
+		return
+	}
+	sess := h.session(r.Header.Get(mcpSessionHeader))
+	if sess == nil {
+		writeHTTPError(w, http.StatusNotFound, CodeInvalidRequest, "unknown session")
+		return
+	}
+	sess.touch()
+
+	streamID, after := standaloneStreamID, 0
+	if last := r.Header.Get("Last-Event-ID"); last != "" {
+		var ok bool
+		if streamID, after, ok = parseEventID(last); !ok {
+			writeHTTPError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid Last-Event-ID")
+			return
+		}
+	}
+
+	st, conn := sess.attach(streamID)
+	if st == nil {
+		writeHTTPError(w, http.StatusNotFound, CodeInvalidRequest,
+			fmt.Sprintf("unknown stream %q", streamID))
+		return
+	}
+	sess.serveStream(w, r, st, conn, after)
+}
+
+func (h *HTTPHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(mcpSessionHeader)
+	h.mu.Lock()
+	sess := h.sessions[id]
+	delete(h.sessions, id)
+	h.mu.Unlock()
+	if sess == nil {
+		writeHTTPError(w, http.StatusNotFound, CodeInvalidRequest, "unknown session")
+		return
+	}
+	sess.close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPHandler) session(id string) *httpSession {
+	if id == "" {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sessions[id]
+}
+
+// startSession creates a session and runs its Server until the session is
+// closed.
+func (h *HTTPHandler) startSession() *httpSession {
+	ctx, cancel := context.WithCancel(h.ctx)
+	pr, pw := io.Pipe()
+	sess := &httpSession{
+		id:         uuid.NewString(),
+		in:         pw,
+		cancel:     cancel,
+		streams:    make(map[string]*sseStream),
+		lastActive: time.Now(),
+	}
+
+	h.mu.Lock()
+	h.sessions[sess.id] = sess
+	h.mu.Unlock()
+
+	srv := h.newServer()
+	go func() {
+		if err := srv.RunConn(ctx, pr, sess); err != nil && ctx.Err() == nil {
+			slog.Warn("mcp http session error", "session", sess.id, "error", err)
+		}
+		// Unblock any POST still writing to the stopped server.
+		_ = pr.CloseWithError(io.ErrClosedPipe)
+		sess.finish()
+
+		h.mu.Lock()
+		delete(h.sessions, sess.id)
+		h.mu.Unlock()
+		slog.Info("mcp http session closed", "session", sess.id)
+	}()
+
+	slog.Info("mcp http session started", "session", sess.id)
+	return sess
+}
+
+// reapIdle closes sessions that have been inactive for longer than
+// httpSessionIdleTimeout, and all sessions on shutdown.
+func (h *HTTPHandler) reapIdle() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.ctx.Done():
+			h.mu.Lock()
+			for _, sess := range h.sessions {
+				sess.close()
+			}
+			h.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+
+		h.mu.Lock()
+		for id, sess := range h.sessions {
+			if sess.idleSince(time.Now()) > httpSessionIdleTimeout {
+				delete(h.sessions, id)
+				sess.close()
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+// httpSession adapts a line-oriented Server to Streamable HTTP. Client
+// messages are written to the Server's input pipe; the Server's output is
+// parsed and routed to SSE streams.
+type httpSession struct {
+	id     string
+	in     *io.PipeWriter
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	partial    []byte // incomplete output line
+	streams    map[string]*sseStream
+	open       []*sseStream          // POST streams awaiting responses, oldest first
+	waiting    map[string]*sseStream // request ID → stream awaiting its response
+	nextStream int
+	lastActive time.Time
+	finished   bool
+}
+
+// sseStream is an ordered, replayable sequence of server messages.
+type sseStream struct {
+	id      string
+	events  [][]byte
+	base    int           // sequence number of events[0] minus one
+	pending int           // responses still owed on a POST stream
+	closed  bool          // no further events will be added
+	sse     bool          // false for POST requests answered with plain JSON
+	conn    int           // generation of the connection reading the stream, 0 if none
+	conns   int           // connections attached so far
+	notify  chan struct{} // signalled when events are added or the stream closes
+}
+
+func (st *sseStream) last() int { return st.base + len(st.events) }
+
+func (st *sseStream) signal() {
+	select {
+	case st.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *httpSession) touch() {
+	s.mu.Lock()
+	s.lastActive = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *httpSession) idleSince(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range s.streams {
+		if st.conn != 0 {
+			return 0
+		}
+	}
+	return now.Sub(s.lastActive)
+}
+
+// send writes client messages to the Server, one per line.
+func (s *httpSession) send(msgs []httpMessage) error {
+	for _, m := range msgs {
+		if _, err := s.in.Write(append(m.raw, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openStream registers a stream for the responses to requestIDs.
+func (s *httpSession) openStream(requestIDs []string, sse bool) *sseStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextStream++
+	st := &sseStream{
+		id:      strconv.Itoa(s.nextStream),
+		pending: len(requestIDs),
+		sse:     sse,
+		conn:    1,
+		conns:   1,
+		notify:  make(chan struct{}, 1),
+	}
+	if s.finished {
+		st.closed = true
+		return st
+	}
+	if s.waiting == nil {
+		s.waiting = make(map[string]*sseStream)
+	}
+	for _, id := range requestIDs {
+		s.waiting[id] = st
+	}
+	s.streams[st.id] = st
+	s.open = append(s.open, st)
+	return st
+}
+
+// abandon stops waiting for the response to a cancelled request.
+func (s *httpSession) abandon(requestID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.waiting[requestID]
+	if st == nil {
+		return
+	}
+	delete(s.waiting, requestID)
+	st.pending--
+	if st.pending <= 0 {
+		s.closeStreamLocked(st)
+	}
+}
+
+// closeStream marks st complete and stops routing messages to it.
+func (s *httpSession) closeStream(st *sseStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeStreamLocked(st)
+}
+
+func (s *httpSession) closeStreamLocked(st *sseStream) {
+	if st.closed {
+		return
+	}
+	st.closed = true
+	for id, w := range s.waiting {
+		if w == st {
+			delete(s.waiting, id)
+		}
+	}
+	for i, o := range s.open {
+		if o == st {
+			s.open = append(s.open[:i], s.open[i+1:]...)
+			break
+		}
+	}
+	st.signal()
+}
+
+// attach claims a stream for a GET connection and returns the connection's
+// generation. A new connection supersedes one the client has abandoned but
+// the server has not yet noticed. The standalone stream is created on
+// first use.
+func (s *httpSession) attach(streamID string) (*sseStream, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.streams[streamID]
+	if st == nil && streamID == standaloneStreamID {
+		st = s.standaloneLocked()
+	}
+	if st == nil {
+		return nil, 0
+	}
+	st.conns++
+	st.conn = st.conns
+	st.signal() // wake a superseded reader so it exits
+	return st, st.conn
+}
+
+func (s *httpSession) standaloneLocked() *sseStream {
+	st := s.streams[standaloneStreamID]
+	if st == nil {
+		st = &sseStream{id: standaloneStreamID, sse: true, notify: make(chan struct{}, 1)}
+		if s.finished {
+			st.closed = true
+		}
+		s.streams[standaloneStreamID] = st
+	}
+	return st
+}
+
+// Write receives newline-delimited JSON-RPC messages from the Server.
+func (s *httpSession) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.partial = append(s.partial, p...)
+	for {
+		i := bytes.IndexByte(s.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := append([]byte(nil), s.partial[:i]...)
+		s.partial = s.partial[i+1:]
+		if len(bytes.TrimSpace(line)) > 0 {
+			s.routeLocked(line)
+		}
+	}
+	return len(p), nil
+}
+
+// routeLocked appends a server message to the stream it belongs to.
+// Responses go to the stream of the POST that carried the request; other
+// messages go to the oldest open SSE POST stream (the request currently
+// being handled), falling back to the standalone GET stream.
+func (s *httpSession) routeLocked(line []byte) {
+	var peek struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	_ = json.Unmarshal(line, &peek)
+
+	var st *sseStream
+	isResponse := peek.Method == "" && len(peek.ID) > 0
+	if isResponse {
+		st = s.waiting[compactID(peek.ID)]
+	} else {
+		for _, o := range s.open {
+			if o.sse {
+				st = o
+				break
+			}
+		}
+	}
+	if st == nil {
+		st = s.standaloneLocked()
+	}
+
+	st.events = append(st.events, line)
+	if st.id == standaloneStreamID && len(st.events) > maxStandaloneEvents {
+		drop := len(st.events) - maxStandaloneEvents
+		st.events = st.events[drop:]
+		st.base += drop
+	}
+
+	if isResponse && st.id != standaloneStreamID {
+		delete(s.waiting, compactID(peek.ID))
+		st.pending--
+		if st.pending <= 0 {
+			s.closeStreamLocked(st)
+			return
+		}
+	}
+	st.signal()
+}
+
+// serveStream writes events after sequence number after as SSE until the
+// stream closes, the client disconnects, or connection conn is superseded.
+// Undelivered events stay buffered so the client can resume with
+// Last-Event-ID.
+func (s *httpSession) serveStream(w http.ResponseWriter, r *http.Request, st *sseStream, conn, after int) {
+	defer s.detach(st, conn)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHTTPError(w, http.StatusInternalServerError, CodeInternalError, "streaming not supported")
+		return
+	}
+	// Streams outlive the server's write timeout.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		s.mu.Lock()
+		if after < st.base {
+			after = st.base // events dropped from the replay buffer
+		}
+		events := st.events[after-st.base:]
+		closed := st.closed
+		superseded := st.conn != conn
+		s.mu.Unlock()
+		if superseded {
+			return
+		}
+
+		for _, data := range events {
+			after++
+			if _, err := fmt.Fprintf(w, "id: %s-%d\ndata: %s\n\n", st.id, after, data); err != nil {
+				return
+			}
+		}
+		if len(events) > 0 {
+			flusher.Flush()
+			s.delivered(st, after)
+		}
+		if closed {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-st.notify:
+		case <-heartbeat.C:
+			_, _ = fmt.Fprint(w, ":\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// serveJSON waits for every response owed on st and writes them as a
+// single JSON body.
+func (s *httpSession) serveJSON(w http.ResponseWriter, r *http.Request, st *sseStream, batch bool) {
+	defer s.detach(st, 1)
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	for {
+		s.mu.Lock()
+		closed := st.closed
+		s.mu.Unlock()
+		if closed {
+			break
+		}
+		select {
+		case <-r.Context().Done():
+			s.closeStream(st)
+			return
+		case <-st.notify:
+		}
+	}
+
+	s.mu.Lock()
+	events := st.events
+	finished := s.finished
+	s.mu.Unlock()
+
+	if len(events) == 0 {
+		if !finished {
+			// Every request in the POST was cancelled.
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeHTTPError(w, http.StatusNotFound, CodeInvalidRequest, "session closed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !batch {
+		_, _ = w.Write(events[0])
+		return
+	}
+	_, _ = w.Write([]byte("["))
+	_, _ = w.Write(bytes.Join(events, []byte(",")))
+	_, _ = w.Write([]byte("]"))
+}
+
+// delivered drops a closed POST stream once all of its events have been
+// written to the client.
+func (s *httpSession) delivered(st *sseStream, seq int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st.closed && st.id != standaloneStreamID && seq >= st.last() {
+		delete(s.streams, st.id)
+	}
+}
+
+func (s *httpSession) detach(st *sseStream, conn int) {
+	s.mu.Lock()
+	if st.conn == conn {
+		st.conn = 0
+	}
+	if !st.sse {
+		delete(s.streams, st.id)
+	}
+	s.lastActive = time.Now()
+	s.mu.Unlock()
+}
+
+// finish closes all streams after the Server has stopped.
+func (s *httpSession) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished = true
+	for _, st := range s.streams {
+		s.closeStreamLocked(st)
+	}
+}
+
+// close stops the session's Server.
+func (s *httpSession) close() {
+	_ = s.in.Close()
+	s.cancel()
+}
+
+// httpMessage is a single JSON-RPC message from a POST body.
+type httpMessage struct {
+	raw    []byte // compacted to a single line
+	id     string // compacted request ID, empty for notifications
+	method string
+}
+
+// splitJSONRPCBody parses a POST body holding one JSON-RPC message or a
+// batch array of them.
+func splitJSONRPCBody(body []byte) ([]httpMessage, bool, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, false, fmt.Errorf("empty body")
+	}
+
+	var raws []json.RawMessage
+	batch := body[0] == '['
+	if batch {
+		if err := json.Unmarshal(body, &raws); err != nil {
+			return nil, false, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if len(raws) == 0 {
+			return nil, false, fmt.Errorf("empty batch")
+		}
+	} else {
+		raws = []json.RawMessage{body}
+	}
+
+	msgs := make([]httpMessage, 0, len(raws))
+	for _, raw := range raws {
+		var peek struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.Unmarshal(raw, &peek); err != nil {
+			return nil, false, fmt.Errorf("invalid JSON-RPC message: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, raw); err != nil {
+			return nil, false, fmt.Errorf("invalid JSON: %w", err)
+		}
+		m := httpMessage{raw: buf.Bytes(), method: peek.Method}
+		if len(peek.ID) > 0 && string(peek.ID) != "null" {
+			m.id = compactID(peek.ID)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, batch, nil
+}
+
+func compactID(id json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, id); err != nil {
+		return string(id)
+	}
+	return buf.String()
+}
+
+// parseEventID splits an SSE event ID of the form "<stream>-<seq>".
+func parseEventID(id string) (string, int, bool) {
+	stream, seq, ok := strings.Cut(id, "-")
+	if !ok {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(seq)
+	if err != nil || n < 0 {
+		return "", 0, false
+	}
+	return stream, n, true
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+func writeHTTPError(w http.ResponseWriter, status, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(Response{
+		JSONRPC: "2.0",
+		Error:   &RPCError{Code: code, Message: msg},
+	})
+}
@@ -0,0 +1,250 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/revittco/mcplexer/internal/routing"
+)
+
+func newHTTPTestServer(t *testing.T) (*httptest.Server, chan *Server) {
+	t.Helper()
+	ms := &mockStore{workspaces: []mockWorkspace{{id: "ws-global", rootPath: "/"}}}
+	servers := make(chan *Server, 4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := NewHTTPHandler(ctx, func() *Server {
+		srv := NewServer(ms, routing.NewEngine(ms), &mockToolLister{}, nil, TransportSocket)
+		servers <- srv
+		return srv
+	})
+	ts := httptest.NewServer(h)
+	t.Cleanup(func() {
+		ts.Close()
+		cancel()
+	})
+	return ts, servers
+}
+
+func postMCP(t *testing.T, ts *httptest.Server, sessionID, accept, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", accept)
+	if sessionID != "" {
+		req.Header.Set(mcpSessionHeader, sessionID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// readSSEEvent reads the next event from an SSE stream, skipping comments.
+func readSSEEvent(t *testing.T, r *bufio.Reader) (id, data string) {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if data != "" {
+				return id, data
+			}
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func initializeMCP(t *testing.T, ts *httptest.Server) string {
+	t.Helper()
+	resp := postMCP(t, ts, "", "application/json, text/event-stream",
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"test"},"roots":[{"uri":"file:///work"}]}}`)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("initialize status = %d", resp.StatusCode)
+	}
+	sessionID := resp.Header.Get(mcpSessionHeader)
+	if sessionID == "" {
+		t.Fatal("missing Mcp-Session-Id header")
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	id, data := readSSEEvent(t, bufio.NewReader(resp.Body))
+	if id != "1-1" {
+		t.Errorf("event id = %q, want 1-1", id)
+	}
+	var r Response
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if string(r.ID) != "1" || r.Error != nil {
+		t.Fatalf("initialize response = %s", data)
+	}
+	return sessionID
+}
+
+func TestHTTPHandler_SessionLifecycle(t *testing.T) {
+	ts, _ := newHTTPTestServer(t)
+	sessionID := initializeMCP(t, ts)
+
+	resp := postMCP(t, ts, sessionID, "application/json, text/event-stream",
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("notification status = %d, want 202", resp.StatusCode)
+	}
+
+	// A JSON-only client gets a plain JSON response.
+	resp = postMCP(t, ts, sessionID, "application/json",
+		`{"jsonrpc":"2.0","id":"p","method":"ping"}`)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("content type = %q", ct)
+	}
+	if string(body) != `{"jsonrpc":"2.0","id":"p","result":{}}` {
+		t.Errorf("ping body = %s", body)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL, nil)
+	req.Header.Set(mcpSessionHeader, sessionID)
+	dresp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dresp.Body.Close()
+	if dresp.StatusCode != http.StatusNoContent {
+		t.Errorf("delete status = %d, want 204", dresp.StatusCode)
+	}
+
+	resp = postMCP(t, ts, sessionID, "application/json",
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("post after delete status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestHTTPHandler_RequiresSession(t *testing.T) {
+	ts, _ := newHTTPTestServer(t)
+
+	resp := postMCP(t, ts, "", "application/json",
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("missing session status = %d, want 400", resp.StatusCode)
+	}
+
+	resp = postMCP(t, ts, "does-not-exist", "application/json",
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown session status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestHTTPHandler_BatchJSON(t *testing.T) {
+	ts, _ := newHTTPTestServer(t)
+	sessionID := initializeMCP(t, ts)
+
+	resp := postMCP(t, ts, sessionID, "application/json", `[
+		{"jsonrpc":"2.0","id":1,"method":"ping"},
+		{"jsonrpc":"2.0","id":2,"method":"ping"}
+	]`)
+	defer resp.Body.Close()
+
+	var out []Response
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out) != 2 || string(out[0].ID) != "1" || string(out[1].ID) != "2" {
+		t.Errorf("batch response = %+v", out)
+	}
+}
+
+func TestHTTPHandler_StandaloneStreamResumes(t *testing.T) {
+	ts, servers := newHTTPTestServer(t)
+	sessionID := initializeMCP(t, ts)
+	srv := <-servers
+
+	// Notifications sent with no stream attached are buffered for the
+	// standalone GET stream.
+	if err := srv.Notify("notifications/tools/list_changed", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	openGet := func(lastEventID string) (*http.Response, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set(mcpSessionHeader, sessionID)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, cancel
+	}
+
+	resp, cancel := openGet("")
+	id, data := readSSEEvent(t, bufio.NewReader(resp.Body))
+	if id != "0-1" || !strings.Contains(data, "notifications/tools/list_changed") {
+		t.Fatalf("event %q = %s", id, data)
+	}
+	cancel()
+	resp.Body.Close()
+
+	if err := srv.Notify("notifications/prompts/list_changed", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, cancel = openGet("0-1")
+	defer cancel()
+	defer resp.Body.Close()
+	id, data = readSSEEvent(t, bufio.NewReader(resp.Body))
+	if id != "0-2" || !strings.Contains(data, "notifications/prompts/list_changed") {
+		t.Fatalf("resumed event %q = %s", id, data)
+	}
+}
+
+func TestParseEventID(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantID  string
+		wantSeq int
+		wantOK  bool
+	}{
+		{"0-12", "0", 12, true},
+		{"3-1", "3", 1, true},
+		{"7", "", 0, false},
+		{"1-x", "", 0, false},
+	}
+	for _, tt := range tests {
+		id, seq, ok := parseEventID(tt.id)
+		if id != tt.wantID || seq != tt.wantSeq || ok != tt.wantOK {
+			t.Errorf("parseEventID(%q) = (%q, %d, %v)", tt.id, id, seq, ok)
+		}
+	}
+}
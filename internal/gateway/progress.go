@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/downstream"
+)
+
+// approvalProgressInterval is how often a call blocked in the approval gate
+// reports that it is still waiting.
+const approvalProgressInterval = 10 * time.Second
+
+// ProgressParams is the payload of notifications/progress.
+type ProgressParams struct {
+	ProgressToken json.RawMessage `json:"progressToken"`
+	Progress      float64         `json:"progress"`
+	Total         *float64        `json:"total,omitempty"`
+	Message       string          `json:"message,omitempty"`
+}
+
+// progressReporter sends notifications/progress for one client request
+// under the token the client chose. Downstream servers see a token of the
+// gateway's choosing, so their progress is rewritten on the way back.
+//
+// Progress must increase with every notification, but a call reports in
+// phases (waiting for approval, then the downstream call) that each count
+// from zero. Each phase is therefore offset past the last value sent.
+// A nil reporter discards progress.
+type progressReporter struct {
+	notifier Notifier
+	token    json.RawMessage
+
+	mu   sync.Mutex
+	base float64 // offset for the current phase
+	last float64
+	sent bool
+}
+
+// progressReporter returns a reporter for a request carrying
+// _meta.progressToken, or nil if the client did not ask for progress.
+func (h *handler) progressReporter(meta *RequestMeta) *progressReporter {
+	if meta == nil || len(meta.ProgressToken) == 0 || h.notifier == nil {
+		return nil
+	}
+	return &progressReporter{notifier: h.notifier, token: meta.ProgressToken}
+}
+
+// report sends p to the client. Updates that would not increase progress
+// are dropped.
+func (r *progressReporter) report(p downstream.Progress) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	progress := r.base + p.Progress
+	if r.sent && progress <= r.last {
+		r.mu.Unlock()
+		return
+	}
+	r.last, r.sent = progress, true
+	params := ProgressParams{
+		ProgressToken: r.token,
+		Progress:      progress,
+		Message:       p.Message,
+	}
+	if p.Total != nil {
+		total := r.base + *p.Total
+		params.Total = &total
+	}
+	// Notify under the lock so notifications leave in order.
+	defer r.mu.Unlock()
+	if err := r.notifier.Notify("notifications/progress", params); err != nil {
+		slog.Debug("failed to send progress notification", "error", err)
+	}
+}
+
+// nextPhase starts a new phase whose progress counts from zero.
+func (r *progressReporter) nextPhase() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sent {
+		r.base = r.last + 1
+	}
+}
+
+// awaitingApproval reports that the call is waiting for approval, then
+// keeps reporting every approvalProgressInterval until the returned stop
+// function is called.
+func (r *progressReporter) awaitingApproval(toolName string) (stop func()) {
+	if r == nil {
+		return func() {}
+	}
+	start := time.Now()
+	r.report(downstream.Progress{Message: "awaiting approval for " + toolName})
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(approvalProgressInterval)
+		defer ticker.Stop()
+		for n := 1; ; n++ {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				r.report(downstream.Progress{
+					Progress: float64(n),
+					Message: fmt.Sprintf("awaiting approval for %s (%s)",
+						toolName, time.Since(start).Round(time.Second)),
+				})
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/revittco/mcplexer/internal/downstream"
+)
+
+func TestProgressReporter_RewritesTokenAndStaysMonotonic(t *testing.T) {
+	n := &recordingNotifier{}
+	h := &handler{notifier: n}
+	r := h.progressReporter(&RequestMeta{ProgressToken: json.RawMessage(`"client-tok"`)})
+
+	total := 10.0
+	r.report(downstream.Progress{Message: "awaiting approval for github__merge"})
+	r.report(downstream.Progress{Progress: 1, Message: "awaiting approval for github__merge (10s)"})
+	r.nextPhase()
+	r.report(downstream.Progress{Progress: 0, Total: &total})
+	r.report(downstream.Progress{Progress: 5, Total: &total})
+	r.report(downstream.Progress{Progress: 4, Total: &total}) // regressed, dropped
+
+	want := []float64{0, 1, 2, 7}
+	if len(n.params) != len(want) {
+		t.Fatalf("sent %d notifications, want %d", len(n.params), len(want))
+	}
+	for i, raw := range n.params {
+		p := raw.(ProgressParams)
+		if n.methods[i] != "notifications/progress" || string(p.ProgressToken) != `"client-tok"` {
+			t.Errorf("notification %d = %s %+v", i, n.methods[i], p)
+		}
+		if p.Progress != want[i] {
+			t.Errorf("notification %d progress = %v, want %v", i, p.Progress, want[i])
+		}
+	}
+	if p := n.params[3].(ProgressParams); p.Total == nil || *p.Total != 12 {
+		t.Errorf("total = %v, want offset by phase", p.Total)
+	}
+}
+
+func TestProgressReporter_NoTokenNoReporter(t *testing.T) {
+	h := &handler{notifier: &recordingNotifier{}}
+	if r := h.progressReporter(nil); r != nil {
+		t.Fatal("expected nil reporter without _meta")
+	}
+	if r := h.progressReporter(&RequestMeta{}); r != nil {
+		t.Fatal("expected nil reporter without progressToken")
+	}
+
+	// A nil reporter is safe to use.
+	var r *progressReporter
+	r.report(downstream.Progress{Progress: 1})
+	r.nextPhase()
+	r.awaitingApproval("x")()
+}
+
+func TestProgressReporter_AwaitingApproval(t *testing.T) {
+	n := &recordingNotifier{}
+	h := &handler{notifier: n}
+	r := h.progressReporter(&RequestMeta{ProgressToken: json.RawMessage(`7`)})
+
+	stop := r.awaitingApproval("github__merge")
+	stop()
+
+	if len(n.params) != 1 {
+		t.Fatalf("sent %d notifications, want 1", len(n.params))
+	}
+	if p := n.params[0].(ProgressParams); p.Message != "awaiting approval for github__merge" {
+		t.Errorf("message = %q", p.Message)
+	}
+}
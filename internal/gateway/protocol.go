@@ -1,6 +1,9 @@
 package gateway
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+)
 
 // JSON-RPC 2.0 types.
 
@@ -22,8 +25,13 @@ type Response struct {
 
 // RPCError is a JSON-RPC 2.0 error.
 type RPCError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
 }
 
 // JSON-RPC error codes.
@@ -35,6 +43,7 @@ const (
 	CodeInternalError  = -32603
 
 	// MCP-specific error codes.
+	CodeRateLimited   = -32004
 	CodeRouteNotFound = -32003
 	CodeProcessError  = -32002
 	CodeTimeout       = -32001
@@ -56,6 +65,11 @@ type Root struct {
 	Name string `json:"name,omitempty"`
 }
 
+// ListRootsResult is the client's response to roots/list.
+type ListRootsResult struct {
+	Roots []Root `json:"roots"`
+}
+
 // ClientInfo describes the connecting client.
 type ClientInfo struct {
 	Name    string `json:"name"`
@@ -71,7 +85,9 @@ type InitializeResult struct {
 
 // ServerCapability declares server capabilities.
 type ServerCapability struct {
-	Tools *ToolCapability `json:"tools,omitempty"`
+	Tools     *ToolCapability     `json:"tools,omitempty"`
+	Resources *ResourceCapability `json:"resources,omitempty"`
+	Prompts   *PromptCapability   `json:"prompts,omitempty"`
 }
 
 // ToolCapability declares tool-related capabilities.
@@ -79,6 +95,17 @@ type ToolCapability struct {
 	ListChanged bool `json:"listChanged"`
 }
 
+// ResourceCapability declares resource-related capabilities.
+type ResourceCapability struct {
+	Subscribe   bool `json:"subscribe"`
+	ListChanged bool `json:"listChanged"`
+}
+
+// PromptCapability declares prompt-related capabilities.
+type PromptCapability struct {
+	ListChanged bool `json:"listChanged"`
+}
+
 // ServerInfo identifies the server.
 type ServerInfo struct {
 	Name    string `json:"name"`
@@ -159,6 +186,16 @@ func (t *Tool) UnmarshalJSON(data []byte) error {
 type CallToolRequest struct {
 	Name      string          `json:"name"`
 	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Meta      *RequestMeta    `json:"_meta,omitempty"`
+}
+
+// RequestMeta is the _meta object a client may attach to request params.
+type RequestMeta struct {
+	ProgressToken json.RawMessage `json:"progressToken,omitempty"`
+
+	// Root selects which client root's policies govern a tools/call: a
+	// root name, path or file URI.
+	Root string `json:"mcplexer/root,omitempty"`
 }
 
 // CallToolResult is the result of tools/call.
@@ -172,3 +209,15 @@ type ToolContent struct {
 	Type string `json:"type"`
 	Text string `json:"text,omitempty"`
 }
+
+// ReadResourceRequest is the params for resources/read, resources/subscribe
+// and resources/unsubscribe.
+type ReadResourceRequest struct {
+	URI string `json:"uri"`
+}
+
+// GetPromptRequest is the params for prompts/get.
+type GetPromptRequest struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
@@ -8,6 +8,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/revittco/mcplexer/internal/gitinfo"
 	"github.com/revittco/mcplexer/internal/routing"
 	"github.com/revittco/mcplexer/internal/store"
 )
@@ -179,21 +180,29 @@ func (h *handler) handleSearchTools(ctx context.Context, query, namespace string
 	return marshalToolResult(formatSearchResults(matches)), nil
 }
 
-// filterByWorkspaceRoutes removes tools that the current session's workspace
-// chain cannot route to. All tools (including built-ins) are subject to routing.
+// filterByWorkspaceRoutes removes tools that none of the session's roots
+// can route to. All tools (including built-ins) are subject to routing.
 func (h *handler) filterByWorkspaceRoutes(ctx context.Context, tools []Tool) []Tool {
-	ancestors := h.sessions.workspaceAncestors(ctx)
+	roots := h.sessions.roots(ctx)
+	gits := make([]*gitinfo.Info, len(roots))
+	for i, root := range roots {
+		gits[i] = h.sessions.gitInfoFor(root.Path)
+	}
 
 	filtered := make([]Tool, 0, len(tools))
 	for _, t := range tools {
-		if len(ancestors) == 0 {
-			continue
-		}
-		_, err := h.engine.RouteWithFallback(ctx, routing.RouteContext{
-			ToolName: t.Name,
-		}, h.sessions.clientRoot(), ancestors)
-		if err == nil {
-			filtered = append(filtered, t)
+		for i, root := range roots {
+			if len(root.Chain) == 0 {
+				continue
+			}
+			_, err := h.engine.RouteWithFallback(ctx, routing.RouteContext{
+				ToolName: t.Name,
+				Git:      gits[i],
+			}, root.Path, root.Chain)
+			if err == nil {
+				filtered = append(filtered, t)
+				break
+			}
 		}
 	}
 	return filtered
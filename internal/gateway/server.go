@@ -9,12 +9,14 @@ import (
 	"log/slog"
 	"os"
 	"sync"
+	"sync/atomic"
 
 	"github.com/revittco/mcplexer/internal/addon"
 	"github.com/revittco/mcplexer/internal/approval"
 	"github.com/revittco/mcplexer/internal/audit"
 	"github.com/revittco/mcplexer/internal/cache"
 	"github.com/revittco/mcplexer/internal/config"
+	"github.com/revittco/mcplexer/internal/downstream"
 	"github.com/revittco/mcplexer/internal/routing"
 	"github.com/revittco/mcplexer/internal/store"
 )
@@ -24,11 +26,36 @@ type Notifier interface {
 	Notify(method string, params any) error
 }
 
+// ClientRequester sends JSON-RPC requests to the connected client and
+// waits for its response.
+type ClientRequester interface {
+	Request(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error)
+}
+
 // Server is the MCP gateway server.
 type Server struct {
 	handler *handler
 	mu      sync.Mutex // protects stdout writes
 	w       io.Writer  // set at start of run(), used for notifications
+
+	// Outbound (server→client) requests awaiting a response, keyed by raw ID.
+	nextID    atomic.Int64
+	pending   map[string]chan *Response
+	pendingMu sync.Mutex
+
+	// Inbound (client→server) requests not yet answered, keyed by
+	// compacted ID, so notifications/cancelled can abort them.
+	inflight   map[string]*inflightRequest
+	inflightMu sync.Mutex
+
+	broadcaster *Broadcaster // joined while running, if set
+}
+
+// queuedRequest is a client message waiting for the worker.
+type queuedRequest struct {
+	ctx  context.Context
+	line []byte
+	key  string // in-flight key, empty if the message cannot be cancelled
 }
 
 // NewServer creates a new MCP gateway server.
@@ -44,9 +71,10 @@ func NewServer(
 	for _, o := range opts {
 		o.apply(&sopts)
 	}
-	return &Server{
-		handler: newHandler(s, engine, manager, auditor, transport, sopts.approvals, sopts.settingsSvc, sopts.addonRegistry, sopts.addonExecutor),
-	}
+	h := newHandler(s, engine, manager, auditor, transport, sopts.approvals, sopts.settingsSvc, sopts.addonRegistry, sopts.addonExecutor)
+	h.resources = sopts.resources
+	h.prompts = sopts.prompts
+	return &Server{handler: h, broadcaster: sopts.broadcaster}
 }
 
 // serverOptions holds optional configuration applied via ServerOption.
@@ -55,6 +83,9 @@ type serverOptions struct {
 	settingsSvc   *config.SettingsService
 	addonRegistry *addon.Registry
 	addonExecutor *addon.Executor
+	resources     ResourceLister
+	prompts       PromptLister
+	broadcaster   *Broadcaster
 }
 
 // ServerOption configures optional server features.
@@ -91,6 +122,28 @@ func WithAddons(r *addon.Registry, e *addon.Executor) ServerOption {
 	return withAddons{r: r, e: e}
 }
 
+type withResources struct{ r ResourceLister }
+
+func (o withResources) apply(opts *serverOptions) { opts.resources = o.r }
+
+// WithResources enables proxying of downstream MCP resources.
+func WithResources(r ResourceLister) ServerOption { return withResources{r} }
+
+type withPrompts struct{ p PromptLister }
+
+func (o withPrompts) apply(opts *serverOptions) { opts.prompts = o.p }
+
+// WithPrompts enables proxying of downstream MCP prompts.
+func WithPrompts(p PromptLister) ServerOption { return withPrompts{p} }
+
+type withBroadcaster struct{ b *Broadcaster }
+
+func (o withBroadcaster) apply(opts *serverOptions) { opts.broadcaster = o.b }
+
+// WithBroadcaster makes the server receive the change notifications sent
+// through b while it runs.
+func WithBroadcaster(b *Broadcaster) ServerOption { return withBroadcaster{b} }
+
 // RunStdio runs the MCP server over stdio (stdin/stdout).
 func (s *Server) RunStdio(ctx context.Context) error {
 	return s.run(ctx, os.Stdin, os.Stdout)
@@ -103,36 +156,154 @@ func (s *Server) RunConn(ctx context.Context, r io.Reader, w io.Writer) error {
 
 func (s *Server) run(ctx context.Context, r io.Reader, w io.Writer) error {
 	defer s.handler.sessions.disconnect(ctx) //nolint:errcheck
+	defer func() {
+		if r, ok := s.handler.manager.(SessionReleaser); ok && s.handler.sessions.sessionID() != "" {
+			r.ReleaseSession(s.handler.sessions.sessionID())
+		}
+	}()
+	defer s.handler.releaseSubscriptions(ctx)
 
 	s.w = w
 	s.handler.setNotifier(s)
+	s.handler.requester = s
 	s.handler.bgCtx = ctx
+	if s.broadcaster != nil {
+		s.broadcaster.add(s)
+		defer s.broadcaster.remove(s)
+	}
+
+	// Requests are handled sequentially on a worker goroutine so the reader
+	// stays free to deliver client responses to server-initiated requests
+	// (e.g. sampling) that an in-flight tools/call is blocked on.
+	queue := make(chan queuedRequest, 64)
+	workerDone := make(chan error, 1)
+	go func() {
+		defer close(workerDone)
+		for q := range queue {
+			if q.ctx.Err() != nil && s.finishRequest(q.key) {
+				continue // cancelled before it started
+			}
+			resp := s.dispatch(q.ctx, q.line)
+			if s.finishRequest(q.key) {
+				continue // the client no longer wants a response
+			}
+			if resp == nil {
+				continue // notification, no response needed
+			}
+			if err := s.writeResponse(w, resp); err != nil {
+				workerDone <- fmt.Errorf("write response: %w", err)
+				return
+			}
+		}
+	}()
 
 	scanner := bufio.NewScanner(r)
 	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
 
+	var runErr error
 	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+		if err := ctx.Err(); err != nil {
+			runErr = err
+			break
 		}
 
 		line := scanner.Bytes()
 		if len(line) == 0 {
 			continue
 		}
+		if s.deliverResponse(line) || s.handleCancellation(line) {
+			continue
+		}
 
-		resp := s.dispatch(ctx, line)
-		if resp == nil {
-			continue // notification, no response needed
+		var peek struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
 		}
+		_ = json.Unmarshal(line, &peek)
+		reqCtx, key := s.trackRequest(ctx, peek.ID, peek.Method)
 
-		if err := s.writeResponse(w, resp); err != nil {
-			return fmt.Errorf("write response: %w", err)
+		select {
+		case queue <- queuedRequest{ctx: reqCtx, line: append([]byte(nil), line...), key: key}:
+		case err := <-workerDone:
+			s.cancelAll("client disconnected")
+			return err
 		}
 	}
-	return scanner.Err()
+	if runErr == nil {
+		runErr = scanner.Err()
+	}
+
+	// The client is gone: abort whatever is still in flight rather than
+	// waiting for results nobody will read.
+	s.cancelAll("client disconnected")
+	close(queue)
+	if err := <-workerDone; err != nil && runErr == nil {
+		runErr = err
+	}
+	return runErr
+}
+
+// deliverResponse routes a client response to a pending server-initiated
+// request. Returns false if line is not a response to one of ours.
+func (s *Server) deliverResponse(line []byte) bool {
+	var peek struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if err := json.Unmarshal(line, &peek); err != nil || peek.ID == nil || peek.Method != "" {
+		return false
+	}
+
+	s.pendingMu.Lock()
+	ch, ok := s.pending[string(peek.ID)]
+	delete(s.pending, string(peek.ID))
+	s.pendingMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		resp = Response{ID: peek.ID, Error: &RPCError{Code: CodeParseError, Message: err.Error()}}
+	}
+	ch <- &resp
+	return true
+}
+
+// Request sends a JSON-RPC request to the client and waits for the response.
+func (s *Server) Request(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+	if s.w == nil {
+		return nil, fmt.Errorf("server not running")
+	}
+
+	id := fmt.Sprintf(`"mcpx-%d"`, s.nextID.Add(1))
+	ch := make(chan *Response, 1)
+	s.pendingMu.Lock()
+	if s.pending == nil {
+		s.pending = make(map[string]chan *Response)
+	}
+	s.pending[id] = ch
+	s.pendingMu.Unlock()
+	defer func() {
+		s.pendingMu.Lock()
+		delete(s.pending, id)
+		s.pendingMu.Unlock()
+	}()
+
+	req := Request{JSONRPC: "2.0", ID: json.RawMessage(id), Method: method, Params: params}
+	if err := s.writeLine(req); err != nil {
+		return nil, fmt.Errorf("write request: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	}
 }
 
 func (s *Server) dispatch(ctx context.Context, line []byte) *Response {
@@ -149,10 +320,14 @@ func (s *Server) dispatch(ctx context.Context, line []byte) *Response {
 
 	// Notifications have no ID; don't send a response.
 	if req.ID == nil {
-		s.handleNotification(req)
+		s.handleNotification(ctx, req)
 		return nil
 	}
 
+	// Downstream servers started by this request run for the session's
+	// primary workspace; tools/call narrows this to the selected root.
+	ctx = downstream.WithCaller(ctx, s.handler.sessions.caller(s.handler.sessions.primaryRoot(ctx)))
+
 	var result json.RawMessage
 	var rpcErr *RPCError
 
@@ -165,6 +340,20 @@ func (s *Server) dispatch(ctx context.Context, line []byte) *Response {
 		result, rpcErr = s.handler.handleToolsList(ctx)
 	case "tools/call":
 		result, rpcErr = s.handler.handleToolsCall(ctx, req.Params)
+	case "prompts/list":
+		result, rpcErr = s.handler.handlePromptsList(ctx)
+	case "prompts/get":
+		result, rpcErr = s.handler.handlePromptsGet(ctx, req.Params)
+	case "resources/list":
+		result, rpcErr = s.handler.handleResourcesList(ctx)
+	case "resources/templates/list":
+		result, rpcErr = s.handler.handleResourceTemplatesList(ctx)
+	case "resources/read":
+		result, rpcErr = s.handler.handleResourcesRead(ctx, req.Params)
+	case "resources/subscribe":
+		result, rpcErr = s.handler.handleResourcesSubscribe(ctx, req.Params, true)
+	case "resources/unsubscribe":
+		result, rpcErr = s.handler.handleResourcesSubscribe(ctx, req.Params, false)
 	default:
 		rpcErr = &RPCError{
 			Code:    CodeMethodNotFound,
@@ -181,10 +370,13 @@ func (s *Server) dispatch(ctx context.Context, line []byte) *Response {
 	return resp
 }
 
-func (s *Server) handleNotification(req Request) {
+func (s *Server) handleNotification(ctx context.Context, req Request) {
 	switch req.Method {
 	case "notifications/initialized":
 		slog.Info("client initialized")
+		s.handler.refreshRoots(ctx)
+	case "notifications/roots/list_changed":
+		s.handler.refreshRoots(ctx)
 	default:
 		slog.Debug("unhandled notification", "method", req.Method)
 	}
@@ -201,6 +393,25 @@ func (s *Server) InvalidateAndNotifyToolsChanged() {
 	s.handler.InvalidateAndNotifyToolsChanged()
 }
 
+// InvalidateAndNotifyPromptsChanged flushes the prompts/list cache and sends
+// a prompts/list_changed notification to the connected client.
+func (s *Server) InvalidateAndNotifyPromptsChanged() {
+	s.handler.InvalidateAndNotifyPromptsChanged()
+}
+
+// NotifyResourcesChanged sends a resources/list_changed notification to
+// the connected client. Unlike tools and prompts, resource lists are not
+// cached, so there is nothing to invalidate.
+func (s *Server) NotifyResourcesChanged() {
+	s.handler.notifyResourcesChanged()
+}
+
+// NotifyResourceUpdated forwards a downstream notifications/resources/updated
+// to the client if the session is subscribed to the resource.
+func (s *Server) NotifyResourceUpdated(serverID, uri string) {
+	s.handler.notifyResourceUpdated(serverID, uri)
+}
+
 // Notify sends a JSON-RPC notification (no id field) to the client.
 func (s *Server) Notify(method string, params any) error {
 	if s.w == nil {
@@ -217,10 +428,15 @@ func (s *Server) Notify(method string, params any) error {
 		Params:  params,
 	}
 
+	return s.writeLine(notif)
+}
+
+// writeLine writes a newline-delimited JSON message to the client.
+func (s *Server) writeLine(v any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	data, err := json.Marshal(notif)
+	data, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
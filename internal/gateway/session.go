@@ -2,14 +2,16 @@ package gateway
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 	"net/url"
 	"os"
-	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/revittco/mcplexer/internal/gitinfo"
 	"github.com/revittco/mcplexer/internal/routing"
 	"github.com/revittco/mcplexer/internal/store"
 )
@@ -38,6 +40,12 @@ type sessionManager struct {
 	clientPath string                     // trusted client CWD
 	wsChain    []routing.WorkspaceAncestor // resolved workspace ancestors, most specific first
 	lastWSVer  int64                       // last seen Engine.WorkspaceVersion
+	git        *gitinfo.Resolver           // git state of clientPath, for routing
+	rootName   string                      // client's name for clientPath, if reported
+	extraRoots []sessionRoot               // other client roots, for per-call selection
+
+	// capabilities the client declared in initialize (e.g. sampling).
+	capabilities map[string]json.RawMessage
 
 	// Active tool set for dynamic tool loading.
 	activeTools map[string]Tool
@@ -45,7 +53,10 @@ type sessionManager struct {
 }
 
 func newSessionManager(s store.Store, e *routing.Engine, t TransportMode) *sessionManager {
-	return &sessionManager{store: s, engine: e, transport: t}
+	return &sessionManager{
+		store: s, engine: e, transport: t,
+		git: gitinfo.NewResolver(gitInfoTTL),
+	}
 }
 
 func (sm *sessionManager) create(ctx context.Context, clientInfo ClientInfo, roots []Root) error {
@@ -57,6 +68,7 @@ func (sm *sessionManager) create(ctx context.Context, clientInfo ClientInfo, roo
 
 	sm.clientPath = sm.detectClientRoot(roots)
 	sm.wsChain = sm.resolveChainForPath(ctx, sm.clientPath)
+	sm.setExtraRoots(ctx, roots)
 	if sm.engine != nil {
 		sm.lastWSVer = sm.engine.WorkspaceVersion()
 	}
@@ -67,33 +79,16 @@ func (sm *sessionManager) create(ctx context.Context, clientInfo ClientInfo, roo
 	return sm.store.CreateSession(ctx, sm.session)
 }
 
-// resolveChainForPath finds all workspaces whose root path is an ancestor
-// of clientRoot, ordered from most specific to least.
+// resolveChainForPath finds all workspaces matching clientRoot, by root
+// path or by their matchers (extra roots, path globs, git remote), ordered
+// from most specific to least.
 func (sm *sessionManager) resolveChainForPath(ctx context.Context, clientRoot string) []routing.WorkspaceAncestor {
 	workspaces, err := sm.store.ListWorkspaces(ctx)
 	if err != nil {
 		slog.Warn("failed to list workspaces for session binding", "error", err)
 		return nil
 	}
-
-	// Collect all ancestor workspaces.
-	var ancestors []store.Workspace
-	for _, ws := range workspaces {
-		if ws.RootPath != "" && isPathAncestor(ws.RootPath, clientRoot) {
-			ancestors = append(ancestors, ws)
-		}
-	}
-
-	// Sort by path length descending (most specific first).
-	sort.Slice(ancestors, func(i, j int) bool {
-		return len(ancestors[i].RootPath) > len(ancestors[j].RootPath)
-	})
-
-	chain := make([]routing.WorkspaceAncestor, len(ancestors))
-	for i, ws := range ancestors {
-		chain[i] = routing.WorkspaceAncestor{ID: ws.ID, Name: ws.Name, RootPath: ws.RootPath}
-	}
-	return chain
+	return routing.ResolveWorkspaceChain(workspaces, clientRoot, sm.gitInfoFor(clientRoot))
 }
 
 // detectClientRoot determines the client's working directory based on the
@@ -214,6 +209,9 @@ func (sm *sessionManager) workspaceAncestors(ctx context.Context) []routing.Work
 	if sm.engine != nil {
 		if v := sm.engine.WorkspaceVersion(); v != sm.lastWSVer {
 			sm.wsChain = sm.resolveChainForPath(ctx, sm.clientPath)
+			for i := range sm.extraRoots {
+				sm.extraRoots[i].Chain = sm.resolveChainForPath(ctx, sm.extraRoots[i].Path)
+			}
 			sm.lastWSVer = v
 			slog.Info("session workspace chain refreshed",
 				"session", sm.sessionID(),
@@ -227,6 +225,28 @@ func (sm *sessionManager) clientRoot() string {
 	return sm.clientPath
 }
 
+// gitInfoTTL bounds how stale the git state used for routing can be after
+// a checkout or edit.
+const gitInfoTTL = 2 * time.Second
+
+// gitInfo returns the state of the git repository containing the client
+// root, or nil if it is not in one.
+func (sm *sessionManager) gitInfo() *gitinfo.Info {
+	return sm.gitInfoFor(sm.clientPath)
+}
+
+func (sm *sessionManager) gitInfoFor(dir string) *gitinfo.Info {
+	if sm.git == nil || dir == "" {
+		return nil
+	}
+	info, err := sm.git.Resolve(dir)
+	if err != nil {
+		slog.Debug("failed to read git state", "root", dir, "error", err)
+		return nil
+	}
+	return info
+}
+
 func (sm *sessionManager) clientType() string {
 	if sm.session == nil {
 		return ""
@@ -234,6 +254,23 @@ func (sm *sessionManager) clientType() string {
 	return sm.session.ClientType
 }
 
+// setClientCapabilities records the capabilities object from initialize.
+func (sm *sessionManager) setClientCapabilities(caps any) {
+	sm.capabilities = nil
+	data, err := json.Marshal(caps)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &sm.capabilities)
+}
+
+// clientSupports reports whether the client declared the named capability
+// (e.g. "sampling", "elicitation", "roots").
+func (sm *sessionManager) clientSupports(capability string) bool {
+	_, ok := sm.capabilities[capability]
+	return ok
+}
+
 func (sm *sessionManager) modelHint() string {
 	if sm.session == nil {
 		return ""
@@ -0,0 +1,255 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/downstream"
+	"github.com/revittco/mcplexer/internal/routing"
+)
+
+// sessionRoot is one client root and the workspace chain it resolves to.
+type sessionRoot struct {
+	Path  string
+	Name  string
+	Chain []routing.WorkspaceAncestor
+}
+
+// workspaceRoot is the root of the most specific workspace matching r, or
+// r's own path when only a catch-all "/" workspace (or none) matches.
+func (r sessionRoot) workspaceRoot() string {
+	if len(r.Chain) > 0 && r.Chain[0].RootPath != "/" {
+		return r.Chain[0].RootPath
+	}
+	return r.Path
+}
+
+// caller describes the session to downstream servers started for calls
+// under root r.
+func (sm *sessionManager) caller(r sessionRoot) downstream.Caller {
+	return downstream.Caller{
+		SessionID: sm.sessionID(), WorkspaceRoot: r.workspaceRoot(), ClientRoot: r.Path,
+	}
+}
+
+// rootsListTimeout bounds how long a roots/list request to the client may
+// hold up the session.
+const rootsListTimeout = 5 * time.Second
+
+// setExtraRoots records the client roots other than the primary one, each
+// with its own workspace chain. In stdio mode only roots inside the trusted
+// working directory are kept, so a client cannot claim an unrelated
+// project's policies.
+func (sm *sessionManager) setExtraRoots(ctx context.Context, roots []Root) {
+	sm.rootName = ""
+	sm.extraRoots = nil
+	seen := map[string]bool{sm.clientPath: true}
+	for _, r := range roots {
+		p := uriToPath(r.URI)
+		if p == sm.clientPath && sm.rootName == "" {
+			sm.rootName = r.Name
+		}
+		if !path.IsAbs(p) || seen[p] {
+			continue
+		}
+		seen[p] = true
+		if sm.transport == TransportStdio && !isPathAncestor(sm.clientPath, p) {
+			slog.Warn("ignoring client root outside working directory",
+				"root", p, "cwd", sm.clientPath)
+			continue
+		}
+		sm.extraRoots = append(sm.extraRoots, sessionRoot{
+			Path: p, Name: r.Name, Chain: sm.resolveChainForPath(ctx, p),
+		})
+	}
+}
+
+// updateRoots replaces the session's roots with a fresh list from the
+// client. In socket mode the first root becomes the primary root; in stdio
+// mode the working directory stays primary. It reports whether any root or
+// workspace chain changed.
+func (sm *sessionManager) updateRoots(ctx context.Context, roots []Root) bool {
+	before := sm.rootsKey()
+	if sm.transport != TransportStdio {
+		sm.clientPath = sm.detectClientRoot(roots)
+		sm.wsChain = sm.resolveChainForPath(ctx, sm.clientPath)
+	}
+	sm.setExtraRoots(ctx, roots)
+	slog.Info("session roots updated",
+		"session", sm.sessionID(), "primary", sm.clientPath, "roots", len(sm.extraRoots)+1)
+	return sm.rootsKey() != before
+}
+
+// rootsKey identifies the session's roots and the workspaces they bind to.
+func (sm *sessionManager) rootsKey() string {
+	var b strings.Builder
+	add := func(root string, chain []routing.WorkspaceAncestor) {
+		b.WriteString(root)
+		for _, a := range chain {
+			b.WriteString("," + a.ID)
+		}
+		b.WriteString("\n")
+	}
+	add(sm.clientPath, sm.wsChain)
+	for _, r := range sm.extraRoots {
+		add(r.Path, r.Chain)
+	}
+	return b.String()
+}
+
+// primaryRoot returns the root the session is bound to.
+func (sm *sessionManager) primaryRoot(ctx context.Context) sessionRoot {
+	return sessionRoot{Path: sm.clientPath, Name: sm.rootName, Chain: sm.workspaceAncestors(ctx)}
+}
+
+// roots returns the primary root followed by the client's other roots,
+// with workspace chains refreshed if workspaces changed.
+func (sm *sessionManager) roots(ctx context.Context) []sessionRoot {
+	primary := sm.primaryRoot(ctx)
+	return append([]sessionRoot{primary}, sm.extraRoots...)
+}
+
+// callRoots selects the roots whose policies govern a tool call, the
+// first of which the call runs under. Every root containing an absolute
+// path or file URI among the top-level arguments applies, the deepest root
+// per path. A "mcplexer/root" _meta hint (a root name, path or file URI)
+// only picks the root the call runs under: it comes first, and the roots
+// the arguments reference still apply. Calls with neither use the primary
+// root.
+func (sm *sessionManager) callRoots(ctx context.Context, meta *RequestMeta, args json.RawMessage) ([]sessionRoot, error) {
+	roots := sm.roots(ctx)
+	var selected []sessionRoot
+	seen := make(map[string]bool)
+	add := func(r sessionRoot) {
+		if !seen[r.Path] {
+			seen[r.Path] = true
+			selected = append(selected, r)
+		}
+	}
+
+	if meta != nil && meta.Root != "" {
+		r, ok := hintedRoot(roots, meta.Root)
+		if !ok {
+			return nil, fmt.Errorf("unknown root %q", meta.Root)
+		}
+		add(r)
+	}
+	for _, p := range argumentPaths(args) {
+		if r, ok := deepestRoot(roots, p); ok {
+			add(r)
+		}
+	}
+	if len(selected) == 0 {
+		return roots[:1], nil
+	}
+	return selected, nil
+}
+
+// hintedRoot returns the root a "mcplexer/root" hint names, by root name,
+// path or file URI.
+func hintedRoot(roots []sessionRoot, hint string) (sessionRoot, bool) {
+	for _, r := range roots {
+		if r.Name != "" && r.Name == hint {
+			return r, true
+		}
+	}
+	return deepestRoot(roots, uriToPath(hint))
+}
+
+// deepestRoot returns the most specific root containing p.
+func deepestRoot(roots []sessionRoot, p string) (sessionRoot, bool) {
+	var best sessionRoot
+	found := false
+	if !path.IsAbs(p) {
+		return best, false
+	}
+	for _, r := range roots {
+		if r.Path != "" && isPathAncestor(r.Path, p) && (!found || len(r.Path) > len(best.Path)) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+// argumentPaths returns the absolute paths among a call's top-level string
+// arguments and string array elements, in argument name order. file://
+// URIs are converted to paths.
+func argumentPaths(args json.RawMessage) []string {
+	var m map[string]any
+	if err := json.Unmarshal(args, &m); err != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var paths []string
+	add := func(v any) {
+		s, ok := v.(string)
+		if !ok {
+			return
+		}
+		if strings.HasPrefix(s, "file://") {
+			s = uriToPath(s)
+		}
+		if path.IsAbs(s) {
+			paths = append(paths, path.Clean(s))
+		}
+	}
+	for _, k := range keys {
+		if arr, ok := m[k].([]any); ok {
+			for _, v := range arr {
+				add(v)
+			}
+			continue
+		}
+		add(m[k])
+	}
+	return paths
+}
+
+type callRootKey struct{}
+
+// withCallRoot records the root a tool call was routed under, for auditing.
+func withCallRoot(ctx context.Context, r sessionRoot) context.Context {
+	return context.WithValue(ctx, callRootKey{}, r)
+}
+
+// auditRoot returns the root a call was routed under, or the primary root.
+func (sm *sessionManager) auditRoot(ctx context.Context) sessionRoot {
+	if r, ok := ctx.Value(callRootKey{}).(sessionRoot); ok {
+		return r
+	}
+	return sm.primaryRoot(ctx)
+}
+
+// refreshRoots asks the client for its current roots and rebinds the
+// session. Tools are re-listed when the roots' workspaces changed.
+func (h *handler) refreshRoots(ctx context.Context) {
+	if h.requester == nil || !h.sessions.clientSupports("roots") {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, rootsListTimeout)
+	defer cancel()
+	raw, err := h.requester.Request(ctx, "roots/list", nil)
+	if err != nil {
+		slog.Warn("roots/list request failed", "error", err)
+		return
+	}
+	var res ListRootsResult
+	if err := json.Unmarshal(raw, &res); err != nil {
+		slog.Warn("invalid roots/list result", "error", err)
+		return
+	}
+	if h.sessions.updateRoots(ctx, res.Roots) {
+		h.InvalidateAndNotifyToolsChanged()
+	}
+}
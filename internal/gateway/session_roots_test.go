@@ -0,0 +1,290 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/routing"
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// newMultiRootTestHandler returns a session with roots /work/api and
+// /work/web. ws-web denies github__push, before any webRules.
+func newMultiRootTestHandler(t *testing.T, lister *mockToolLister, webRules ...store.RouteRule) *handler {
+	t.Helper()
+	ms := &mockStore{
+		servers: []store.DownstreamServer{{ID: "gh", ToolNamespace: "github", Discovery: "static"}},
+		workspaces: []mockWorkspace{
+			{id: "ws-global", rootPath: "/"},
+			{id: "ws-api", rootPath: "/work/api"},
+			{id: "ws-web", rootPath: "/work/web"},
+		},
+		routeRules: map[string][]store.RouteRule{
+			"ws-web": append([]store.RouteRule{{
+				ID: "web-no-push", WorkspaceID: "ws-web",
+				Priority: 10, PathGlob: "**", Policy: "deny",
+				ToolMatch: json.RawMessage(`["github__push"]`),
+			}}, webRules...),
+			"ws-global": {{
+				ID: "allow-gh", WorkspaceID: "ws-global",
+				Priority: 1, PathGlob: "**", Policy: "allow",
+				ToolMatch:          json.RawMessage(`["github__*"]`),
+				DownstreamServerID: "gh",
+			}},
+		},
+	}
+	h := newHandler(ms, routing.NewEngine(ms), lister, nil, TransportSocket, nil, nil, nil, nil)
+	err := h.sessions.create(context.Background(), ClientInfo{Name: "editor"}, []Root{
+		{URI: "file:///work/api", Name: "api"},
+		{URI: "file:///work/web", Name: "web"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestCreate_KeepsAllRoots(t *testing.T) {
+	h := newMultiRootTestHandler(t, &mockToolLister{})
+	var got []string
+	for _, r := range h.sessions.roots(context.Background()) {
+		got = append(got, r.Name+"="+r.Path+":"+r.Chain[0].ID)
+	}
+	want := "api=/work/api:ws-api web=/work/web:ws-web"
+	if strings.Join(got, " ") != want {
+		t.Errorf("roots = %v, want %s", got, want)
+	}
+}
+
+func TestHandleToolsCall_SelectsRoot(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     string
+		meta     *RequestMeta
+		wantCode int // 0 = allowed
+	}{
+		{"primary root", `{}`, nil, 0},
+		{"relative path uses primary", `{"path":"src/main.go"}`, nil, 0},
+		{"argument path", `{"path":"/work/web/README.md"}`, nil, CodeRouteNotFound},
+		{"file uri in array", `{"files":["file:///work/web/a.go"]}`, nil, CodeRouteNotFound},
+		{"paths in both roots", `{"from":"/work/api/a","to":"/work/web/b"}`, nil, CodeRouteNotFound},
+		{"meta root name", `{}`, &RequestMeta{Root: "web"}, CodeRouteNotFound},
+		{"meta root uri", `{}`, &RequestMeta{Root: "file:///work/web/src"}, CodeRouteNotFound},
+		{"meta does not override arguments", `{"path":"/work/web/x"}`, &RequestMeta{Root: "api"}, CodeRouteNotFound},
+		{"unknown meta root", `{}`, &RequestMeta{Root: "/elsewhere"}, CodeInvalidParams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lister := &mockToolLister{}
+			h := newMultiRootTestHandler(t, lister)
+			params, _ := json.Marshal(CallToolRequest{
+				Name: "github__push", Arguments: json.RawMessage(tt.args), Meta: tt.meta,
+			})
+			_, rpcErr := h.handleToolsCall(context.Background(), params)
+			switch {
+			case tt.wantCode == 0 && rpcErr != nil:
+				t.Fatalf("unexpected error: %s", rpcErr.Message)
+			case tt.wantCode != 0 && (rpcErr == nil || rpcErr.Code != tt.wantCode):
+				t.Fatalf("error = %+v, want code %d", rpcErr, tt.wantCode)
+			}
+			if want := map[bool]int{true: 1, false: 0}[tt.wantCode == 0]; lister.callCount != want {
+				t.Errorf("downstream calls = %d, want %d", lister.callCount, want)
+			}
+		})
+	}
+}
+
+func TestCallRoots_HintPicksRootToRunUnder(t *testing.T) {
+	h := newMultiRootTestHandler(t, &mockToolLister{})
+	roots, err := h.sessions.callRoots(context.Background(), &RequestMeta{Root: "web"},
+		json.RawMessage(`{"from":"/work/api/a","to":"/work/web/b"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range roots {
+		got = append(got, r.Name)
+	}
+	if strings.Join(got, " ") != "web api" {
+		t.Errorf("roots = %v, want web first, then api", got)
+	}
+}
+
+func TestHandleToolsCall_ChargesEachRootsRateLimit(t *testing.T) {
+	lister := &mockToolLister{}
+	h := newMultiRootTestHandler(t, lister, store.RouteRule{
+		ID: "web-limited", WorkspaceID: "ws-web",
+		Priority: 5, PathGlob: "**", Policy: "allow",
+		ToolMatch:          json.RawMessage(`["github__*"]`),
+		DownstreamServerID: "gh",
+		RateLimit:          json.RawMessage(`{"rate":1,"per":"1h"}`),
+	})
+
+	// The api root is listed first, but the web root's limit still applies.
+	params, _ := json.Marshal(CallToolRequest{
+		Name: "github__list", Arguments: json.RawMessage(`{"from":"/work/api/a","to":"/work/web/b"}`),
+	})
+	if _, rpcErr := h.handleToolsCall(context.Background(), params); rpcErr != nil {
+		t.Fatalf("first call: %s", rpcErr.Message)
+	}
+	_, rpcErr := h.handleToolsCall(context.Background(), params)
+	if rpcErr == nil || rpcErr.Code != CodeRateLimited {
+		t.Fatalf("second call: err = %+v, want code %d", rpcErr, CodeRateLimited)
+	}
+	if lister.callCount != 1 {
+		t.Errorf("downstream calls = %d, want 1", lister.callCount)
+	}
+}
+
+func TestChargeRateLimits_RefundsEarlierRoots(t *testing.T) {
+	h := newMultiRootTestHandler(t, &mockToolLister{})
+	limit, err := routing.ParseRateLimit(json.RawMessage(`{"rate":1,"per":"1h"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := &routing.RouteResult{MatchedRuleID: "api", MatchedWorkspaceID: "ws-api", RateLimit: limit}
+	web := &routing.RouteResult{MatchedRuleID: "web", MatchedWorkspaceID: "ws-web", RateLimit: limit}
+	ctx, now := context.Background(), time.Now()
+	if err := h.engine.Allow(ctx, web, h.sessions.sessionID(), now); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, refused, err := h.chargeRateLimits(ctx, []*routing.RouteResult{api, web}, now); err == nil || refused != 1 {
+		t.Fatalf("refused = %d, err = %v; want the web route refused", refused, err)
+	}
+	if err := h.engine.Allow(ctx, api, h.sessions.sessionID(), now); err != nil {
+		t.Errorf("api route still charged: %v", err)
+	}
+}
+
+func TestMergeRoutes(t *testing.T) {
+	api := &routing.RouteResult{MatchedRuleID: "api", DownstreamServerID: "gh", ApprovalMode: "none"}
+	web := &routing.RouteResult{MatchedRuleID: "web", DownstreamServerID: "gh", ApprovalMode: "all", ApprovalTimeout: 60}
+	docs := &routing.RouteResult{MatchedRuleID: "docs", DownstreamServerID: "gh", ApprovalMode: "write"}
+
+	got, err := mergeRoutes([]*routing.RouteResult{api, docs, web})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MatchedRuleID != "api" || got.ApprovalMode != "all" || got.ApprovalTimeout != 60 {
+		t.Errorf("route = %+v, want api's route with web's approval", got)
+	}
+	if api.ApprovalMode != "none" {
+		t.Error("first route was modified")
+	}
+	if got, _ := mergeRoutes([]*routing.RouteResult{web, docs}); got != web {
+		t.Errorf("route = %+v, want the first route unchanged", got)
+	}
+
+	for _, other := range []*routing.RouteResult{
+		{MatchedRuleID: "lab", DownstreamServerID: "gitlab"},
+		{MatchedRuleID: "bot", DownstreamServerID: "gh", AuthScopeID: "bot-token"},
+	} {
+		if _, err := mergeRoutes([]*routing.RouteResult{api, other}); err == nil {
+			t.Errorf("merged routes to %s/%s, want error", other.DownstreamServerID, other.AuthScopeID)
+		}
+	}
+}
+
+func TestHandleToolsCall_EnforcesEveryRootsAllowlist(t *testing.T) {
+	lister := &mockToolLister{}
+	h := newMultiRootTestHandler(t, lister, store.RouteRule{
+		ID: "web-acme-only", WorkspaceID: "ws-web",
+		Priority: 5, PathGlob: "**", Policy: "allow",
+		ToolMatch:          json.RawMessage(`["github__*"]`),
+		DownstreamServerID: "gh",
+		AllowedOrgs:        json.RawMessage(`["acme"]`),
+	})
+
+	// The hint puts the unrestricted api root first; the web root's
+	// allowlist still applies.
+	params, _ := json.Marshal(CallToolRequest{
+		Name:      "github__list",
+		Arguments: json.RawMessage(`{"from":"/work/api/a","to":"/work/web/b","owner":"other","repo":"x"}`),
+		Meta:      &RequestMeta{Root: "api"},
+	})
+	_, rpcErr := h.handleToolsCall(context.Background(), params)
+	if rpcErr == nil || rpcErr.Code != CodeInvalidParams {
+		t.Fatalf("err = %+v, want the web root's allowlist to refuse", rpcErr)
+	}
+	if lister.callCount != 0 {
+		t.Errorf("downstream calls = %d, want 0", lister.callCount)
+	}
+}
+
+func TestHandleToolsCall_RejectsRootsRoutedToDifferentServers(t *testing.T) {
+	lister := &mockToolLister{}
+	h := newMultiRootTestHandler(t, lister, store.RouteRule{
+		ID: "web-bot", WorkspaceID: "ws-web",
+		Priority: 5, PathGlob: "**", Policy: "allow",
+		ToolMatch:          json.RawMessage(`["github__*"]`),
+		DownstreamServerID: "gh",
+		AuthScopeID:        "bot-token",
+	})
+
+	params, _ := json.Marshal(CallToolRequest{
+		Name: "github__list", Arguments: json.RawMessage(`{"from":"/work/api/a","to":"/work/web/b"}`),
+	})
+	_, rpcErr := h.handleToolsCall(context.Background(), params)
+	if rpcErr == nil || rpcErr.Code != CodeInvalidParams {
+		t.Fatalf("err = %+v, want roots with different auth scopes refused", rpcErr)
+	}
+	if lister.callCount != 0 {
+		t.Errorf("downstream calls = %d, want 0", lister.callCount)
+	}
+}
+
+func TestRefreshRoots(t *testing.T) {
+	req := &fakeRequester{result: json.RawMessage(`{"roots":[{"uri":"file:///work/web","name":"web"}]}`)}
+	h := newMultiRootTestHandler(t, &mockToolLister{})
+	h.requester = req
+
+	// Without the roots capability the client is not asked.
+	h.refreshRoots(context.Background())
+	if len(req.calls) != 0 {
+		t.Fatalf("client calls = %v, want none", req.calls)
+	}
+
+	h.sessions.setClientCapabilities(map[string]any{"roots": map[string]any{"listChanged": true}})
+	h.refreshRoots(context.Background())
+	if len(req.calls) != 1 || req.calls[0] != "roots/list" {
+		t.Fatalf("client calls = %v", req.calls)
+	}
+	if h.sessions.clientRoot() != "/work/web" || h.sessions.workspaceID() != "ws-web" {
+		t.Errorf("primary = %s (%s), want /work/web (ws-web)", h.sessions.clientRoot(), h.sessions.workspaceID())
+	}
+	if len(h.sessions.extraRoots) != 0 {
+		t.Errorf("extra roots = %+v, want none", h.sessions.extraRoots)
+	}
+}
+
+func TestSetExtraRoots_StdioKeepsRootsInsideCWD(t *testing.T) {
+	sm := &sessionManager{
+		store:      &mockStore{workspaces: []mockWorkspace{{id: "ws-global", rootPath: "/"}}},
+		transport:  TransportStdio,
+		clientPath: "/work",
+	}
+	sm.setExtraRoots(context.Background(), []Root{
+		{URI: "file:///work", Name: "work"},
+		{URI: "file:///work/api", Name: "api"},
+		{URI: "file:///etc", Name: "spoofed"},
+		{URI: "https://example.com/repo"},
+	})
+	if sm.rootName != "work" {
+		t.Errorf("rootName = %q, want work", sm.rootName)
+	}
+	if len(sm.extraRoots) != 1 || sm.extraRoots[0].Path != "/work/api" {
+		t.Errorf("extra roots = %+v, want only /work/api", sm.extraRoots)
+	}
+}
+
+func TestArgumentPaths(t *testing.T) {
+	got := argumentPaths(json.RawMessage(
+		`{"z":"/b/../c","a":["file:///x/y","rel/path",3],"n":{"path":"/nested"},"s":"not a path"}`))
+	if want := "/x/y /c"; strings.Join(got, " ") != want {
+		t.Errorf("argumentPaths = %v, want %s", got, want)
+	}
+}
@@ -2,8 +2,10 @@ package gateway
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/revittco/mcplexer/internal/gitinfo"
 	"github.com/revittco/mcplexer/internal/routing"
 )
 
@@ -159,3 +161,46 @@ func TestWorkspaceID_ReturnsFirst(t *testing.T) {
 		t.Errorf("workspaceID() = %q, want %q", got, "ws-specific")
 	}
 }
+
+func TestResolveWorkspaceChain_Matchers(t *testing.T) {
+	// A checkout of acme/api outside the workspace's root_path.
+	clone := filepath.Join(t.TempDir(), "acme-api")
+	gitDir := filepath.Join(clone, ".git")
+	if err := os.MkdirAll(filepath.Join(clone, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(gitDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		"HEAD":   "ref: refs/heads/main\n",
+		"config": "[remote \"origin\"]\n\turl = git@github.com:acme/api.git\n",
+	} {
+		if err := os.WriteFile(filepath.Join(gitDir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sm := &sessionManager{
+		store: &mockStore{
+			workspaces: []mockWorkspace{
+				{id: "ws-global", rootPath: "/"},
+				{id: "ws-api", rootPath: "/src/acme/api", matchers: `{"git_remotes":["acme/api"]}`},
+				{id: "ws-other", rootPath: "/src/other", matchers: `{"git_remotes":["acme/web"]}`},
+			},
+		},
+		git:        gitinfo.NewResolver(gitInfoTTL),
+		clientPath: filepath.Join(clone, "src"),
+	}
+
+	chain := sm.resolveChainForPath(t.Context(), sm.clientPath)
+	if len(chain) != 2 || chain[0].ID != "ws-api" || chain[1].ID != "ws-global" {
+		t.Fatalf("chain = %+v, want ws-api then ws-global", chain)
+	}
+	if chain[0].RootPath != clone {
+		t.Errorf("ws-api root = %q, want the worktree root %q", chain[0].RootPath, clone)
+	}
+	if sub := routing.ComputeSubpath(sm.clientPath, chain[0].RootPath); sub != "src" {
+		t.Errorf("subpath = %q, want src", sub)
+	}
+}
@@ -0,0 +1,280 @@
+// Package gitinfo reads repository metadata (branch, commit, origin remote,
+// dirty state) straight from a .git directory, without a git binary.
+package gitinfo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/cache"
+)
+
+// Info describes the repository containing a directory.
+type Info struct {
+	Root     string `json:"root"`             // worktree root
+	Branch   string `json:"branch,omitempty"` // empty when detached
+	Commit   string `json:"commit,omitempty"` // empty on an unborn branch
+	Detached bool   `json:"detached"`
+	Dirty    bool   `json:"dirty"` // tracked files differ from the index
+	Remote   Remote `json:"remote"`
+
+	// DirtyUnknown is set when the index could not be read, so Dirty is
+	// not known. Callers deciding on the dirty state should fail closed.
+	DirtyUnknown bool `json:"dirty_unknown,omitempty"`
+}
+
+// Remote is the repository's origin remote (or its only remote).
+type Remote struct {
+	Name  string `json:"name,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Host  string `json:"host,omitempty"`
+	Owner string `json:"owner,omitempty"` // may contain slashes, e.g. GitLab subgroups
+	Repo  string `json:"repo,omitempty"`
+}
+
+// Slug returns "owner/repo", or "" if the remote URL has no owner.
+func (r Remote) Slug() string {
+	if r.Owner == "" || r.Repo == "" {
+		return ""
+	}
+	return r.Owner + "/" + r.Repo
+}
+
+// Resolve reads git metadata for the repository containing dir. It
+// returns nil, nil if dir is not inside a git worktree. An index it cannot
+// read does not hide the branch and remote: the Info comes back with
+// DirtyUnknown set instead.
+func Resolve(dir string) (*Info, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	root, gitDir, err := findGitDir(dir)
+	if err != nil || gitDir == "" {
+		return nil, err
+	}
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = resolvePath(gitDir, strings.TrimSpace(string(data)))
+	}
+
+	info := &Info{Root: root}
+	if err := readHead(info, gitDir, commonDir); err != nil {
+		return nil, err
+	}
+	cfg, err := readConfig(filepath.Join(commonDir, "config"))
+	if err != nil {
+		return nil, err
+	}
+	info.Remote = originRemote(cfg)
+
+	hashSize := 20
+	if strings.EqualFold(cfg["extensions.objectformat"], "sha256") {
+		hashSize = 32
+	}
+	dirty, err := worktreeDirty(root, filepath.Join(gitDir, "index"), hashSize)
+	info.Dirty = dirty
+	info.DirtyUnknown = err != nil
+	return info, nil
+}
+
+// Resolver caches Resolve results briefly, so that routing every tool call
+// does not re-read the repository.
+type Resolver struct {
+	cache *cache.Cache[string, *Info]
+}
+
+// NewResolver returns a Resolver whose results are reused for ttl.
+func NewResolver(ttl time.Duration) *Resolver {
+	return &Resolver{cache: cache.New[string, *Info](64, ttl)}
+}
+
+// Resolve is like the package-level Resolve, with caching.
+func (r *Resolver) Resolve(dir string) (*Info, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	return r.cache.GetOrLoad(filepath.Clean(dir), func() (*Info, error) {
+		return Resolve(dir)
+	})
+}
+
+// findGitDir walks up from dir to the first directory containing .git,
+// which is either the git directory itself or a "gitdir:" file as used by
+// linked worktrees and submodules.
+func findGitDir(dir string) (root, gitDir string, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		p := filepath.Join(dir, ".git")
+		fi, err := os.Stat(p)
+		switch {
+		case err == nil && fi.IsDir():
+			return dir, p, nil
+		case err == nil:
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return "", "", err
+			}
+			target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+			if !ok {
+				return "", "", fmt.Errorf("%s: not a gitdir file", p)
+			}
+			return dir, resolvePath(dir, strings.TrimSpace(target)), nil
+		case !errors.Is(err, os.ErrNotExist):
+			return "", "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", nil
+		}
+		dir = parent
+	}
+}
+
+func resolvePath(base, p string) string {
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
+	}
+	return filepath.Join(base, p)
+}
+
+// readHead fills in the branch and commit from HEAD.
+func readHead(info *Info, gitDir, commonDir string) error {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return fmt.Errorf("read HEAD: %w", err)
+	}
+	head := strings.TrimSpace(string(data))
+	ref, ok := strings.CutPrefix(head, "ref:")
+	if !ok {
+		info.Detached = true
+		info.Commit = head
+		return nil
+	}
+	ref = strings.TrimSpace(ref)
+	info.Branch = strings.TrimPrefix(ref, "refs/heads/")
+	info.Commit = resolveRef(gitDir, commonDir, ref)
+	return nil
+}
+
+// resolveRef looks up a ref as a loose file, then in packed-refs. It
+// returns "" for a ref that does not exist yet (an unborn branch).
+func resolveRef(gitDir, commonDir, ref string) string {
+	for _, dir := range []string{gitDir, commonDir} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref)))
+		if err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	f, err := os.Open(filepath.Join(commonDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = f.Close() }()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		if sha, name, ok := strings.Cut(line, " "); ok && name == ref {
+			return sha
+		}
+	}
+	return ""
+}
+
+// readConfig parses the subset of git-config syntax needed here into
+// "section.subsection.key" entries. Keys and section names are lowercased;
+// subsections keep their case.
+func readConfig(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	out := map[string]string{}
+	section := ""
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				continue
+			}
+			name, sub, hasSub := strings.Cut(line[1:end], " ")
+			section = strings.ToLower(name)
+			if hasSub {
+				section += "." + strings.Trim(strings.TrimSpace(sub), `"`)
+			}
+			continue
+		}
+		key, val, _ := strings.Cut(line, "=")
+		val = strings.TrimSpace(val)
+		if i := strings.IndexAny(val, "#;"); i >= 0 && !strings.Contains(val[:i], `"`) {
+			val = strings.TrimSpace(val[:i])
+		}
+		out[section+"."+strings.ToLower(strings.TrimSpace(key))] = strings.Trim(val, `"`)
+	}
+	return out, sc.Err()
+}
+
+// originRemote returns the "origin" remote, or the only configured remote.
+func originRemote(cfg map[string]string) Remote {
+	if u := cfg["remote.origin.url"]; u != "" {
+		return parseRemote("origin", u)
+	}
+	var found []Remote
+	for k, v := range cfg {
+		if name, ok := strings.CutPrefix(k, "remote."); ok && strings.HasSuffix(name, ".url") {
+			found = append(found, parseRemote(strings.TrimSuffix(name, ".url"), v))
+		}
+	}
+	if len(found) == 1 {
+		return found[0]
+	}
+	return Remote{}
+}
+
+// parseRemote splits a remote URL into host, owner and repo. It accepts
+// URLs (https://host/owner/repo.git, ssh://git@host/owner/repo) and the
+// scp-like form git@host:owner/repo.git.
+func parseRemote(name, raw string) Remote {
+	r := Remote{Name: name, URL: raw}
+	var path string
+	if u, err := url.Parse(raw); err == nil && u.Scheme != "" && u.Host != "" {
+		r.Host = u.Hostname()
+		path = u.Path
+	} else if host, p, ok := strings.Cut(raw, ":"); ok && !strings.Contains(host, "/") {
+		if _, h, ok := strings.Cut(host, "@"); ok {
+			host = h
+		}
+		r.Host = host
+		path = p
+	} else {
+		return r // local path
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if i := strings.LastIndexByte(path, '/'); i > 0 {
+		r.Owner = path[:i]
+		r.Repo = path[i+1:]
+	}
+	return r
+}
@@ -0,0 +1,177 @@
+package gitinfo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		url                string
+		host, owner, repo string
+	}{
+		{"https://github.com/acme/api.git", "github.com", "acme", "api"},
+		{"https://github.com/acme/api", "github.com", "acme", "api"},
+		{"git@github.com:acme/api.git", "github.com", "acme", "api"},
+		{"ssh://git@gitlab.example.com:2222/group/sub/svc.git", "gitlab.example.com", "group/sub", "svc"},
+		{"/srv/git/api.git", "", "", ""},
+	}
+	for _, tt := range tests {
+		r := parseRemote("origin", tt.url)
+		if r.Host != tt.host || r.Owner != tt.owner || r.Repo != tt.repo {
+			t.Errorf("parseRemote(%q) = %+v", tt.url, r)
+		}
+	}
+}
+
+// gitRepo creates a repository with one commit using the git binary.
+func gitRepo(t *testing.T) (dir string, run func(args ...string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir = t.TempDir()
+	run = func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com",
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	run("init", "-q", "-b", "main")
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"README.md", "src/main.go"} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("hello "+f+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("add", ".")
+	run("commit", "-q", "-m", "init")
+	run("remote", "add", "origin", "git@github.com:acme/api.git")
+	return dir, run
+}
+
+func TestResolve(t *testing.T) {
+	dir, run := gitRepo(t)
+
+	info, err := Resolve(filepath.Join(dir, "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil || info.Root != dir || info.Branch != "main" || info.Detached || info.Dirty {
+		t.Fatalf("info = %+v", info)
+	}
+	if len(info.Commit) != 40 {
+		t.Errorf("commit = %q", info.Commit)
+	}
+	if info.Remote.Slug() != "acme/api" || info.Remote.Host != "github.com" {
+		t.Errorf("remote = %+v", info.Remote)
+	}
+
+	// Modifying a tracked file makes the worktree dirty, in every index version.
+	if err := os.WriteFile(filepath.Join(dir, "src/main.go"), []byte("changed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"2", "3", "4"} {
+		run("update-index", "--index-version", v)
+		if info, err := Resolve(dir); err != nil || !info.Dirty {
+			t.Errorf("index v%s: info = %+v, err = %v, want dirty", v, info, err)
+		}
+	}
+	run("checkout", "-q", "--", "src/main.go")
+	if info, _ := Resolve(dir); info.Dirty {
+		t.Error("want clean after checkout")
+	}
+
+	// A touched but unchanged file is still clean.
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("hello README.md\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := Resolve(dir); info.Dirty {
+		t.Error("rewriting identical content should not be dirty")
+	}
+
+	run("checkout", "-q", "-b", "feature/x")
+	if info, _ := Resolve(dir); info.Branch != "feature/x" || info.Commit == "" {
+		t.Errorf("after checkout -b: %+v", info)
+	}
+	run("pack-refs", "--all")
+	if info, _ := Resolve(dir); info.Commit == "" {
+		t.Error("commit not resolved from packed-refs")
+	}
+
+	run("checkout", "-q", "--detach")
+	if info, _ := Resolve(dir); !info.Detached || info.Branch != "" || len(info.Commit) != 40 {
+		t.Errorf("detached: %+v", info)
+	}
+}
+
+func TestResolve_LinkedWorktree(t *testing.T) {
+	_, run := gitRepo(t)
+	wt := filepath.Join(t.TempDir(), "wt")
+	run("worktree", "add", "-q", "-b", "hotfix", wt)
+
+	info, err := Resolve(wt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil || info.Root != wt || info.Branch != "hotfix" || info.Commit == "" || info.Dirty {
+		t.Fatalf("info = %+v", info)
+	}
+	if info.Remote.Slug() != "acme/api" {
+		t.Errorf("remote = %+v, want read from the common dir", info.Remote)
+	}
+}
+
+func TestResolve_NotARepo(t *testing.T) {
+	info, err := Resolve(t.TempDir())
+	if err != nil || info != nil {
+		t.Fatalf("Resolve = %+v, %v; want nil, nil", info, err)
+	}
+}
+
+func TestResolve_UnreadableIndex(t *testing.T) {
+	dir, _ := gitRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, ".git", "index"), []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Resolve(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil || info.Branch != "main" || info.Remote.Slug() != "acme/api" || !info.DirtyUnknown {
+		t.Fatalf("info = %+v; want branch and remote with the dirty state unknown", info)
+	}
+}
+
+func TestResolve_SparseIndex(t *testing.T) {
+	dir, run := gitRepo(t)
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docs", "guide.md"), []byte("guide\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", ".")
+	run("commit", "-q", "-m", "docs")
+	run("sparse-checkout", "set", "--cone", "--sparse-index", "src")
+
+	info, err := Resolve(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Dirty || info.DirtyUnknown {
+		t.Errorf("info = %+v; want the collapsed docs directory ignored", info)
+	}
+}
@@ -0,0 +1,199 @@
+package gitinfo
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Index entry mode types and flags (see git's Documentation/gitformat-index).
+const (
+	modeTypeMask    = 0o170000
+	modeDir         = 0o040000 // a sparse index's collapsed directory
+	modeSymlink     = 0o120000
+	modeGitlink     = 0o160000
+	flagExtended    = 0x4000
+	flagStageMask   = 0x3000
+	flagNameMask    = 0x0fff
+	extSkipWorktree = 0x4000
+	extIntentToAdd  = 0x2000
+)
+
+// indexEntry is the part of an index entry needed to detect changes.
+type indexEntry struct {
+	mtimeSec, mtimeNsec uint32
+	mode, size          uint32
+	hash                []byte
+	path                string
+	stage               int
+	skip                bool // skip-worktree or intent-to-add
+}
+
+// worktreeDirty reports whether any tracked file differs from the index:
+// it is modified, deleted, or the index has unresolved merge conflicts.
+// Files whose size and mtime match the index are trusted without hashing.
+// Untracked files and changes that are only staged are not detected.
+func worktreeDirty(root, indexPath string, hashSize int) (bool, error) {
+	data, err := os.ReadFile(indexPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil // fresh repository
+	}
+	if err != nil {
+		return false, err
+	}
+
+	dirty := false
+	err = readIndex(data, hashSize, func(e indexEntry) bool {
+		switch {
+		case e.skip, e.mode&modeTypeMask == modeGitlink, e.mode&modeTypeMask == modeDir:
+			return true
+		}
+		if e.stage != 0 || entryChanged(root, e, hashSize) {
+			dirty = true
+			return false
+		}
+		return true
+	})
+	return dirty, err
+}
+
+// readIndex decodes index versions 2 to 4, calling fn for each entry
+// until it returns false.
+func readIndex(data []byte, hashSize int, fn func(indexEntry) bool) error {
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return errors.New("not a git index")
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return fmt.Errorf("unsupported index version %d", version)
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+
+	off := 12
+	fixed := 40 + hashSize + 2
+	var prev string
+	for range count {
+		if off+fixed > len(data) {
+			return io.ErrUnexpectedEOF
+		}
+		start := off
+		b := data[off:]
+		e := indexEntry{
+			mtimeSec:  binary.BigEndian.Uint32(b[8:12]),
+			mtimeNsec: binary.BigEndian.Uint32(b[12:16]),
+			mode:      binary.BigEndian.Uint32(b[24:28]),
+			size:      binary.BigEndian.Uint32(b[36:40]),
+			hash:      b[40 : 40+hashSize],
+		}
+		flags := binary.BigEndian.Uint16(b[40+hashSize:])
+		e.stage = int(flags&flagStageMask) >> 12
+		off += fixed
+		if version >= 3 && flags&flagExtended != 0 {
+			if off+2 > len(data) {
+				return io.ErrUnexpectedEOF
+			}
+			ext := binary.BigEndian.Uint16(data[off:])
+			e.skip = ext&(extSkipWorktree|extIntentToAdd) != 0
+			off += 2
+		}
+
+		if version == 4 {
+			strip, n := readOffsetVarint(data[off:])
+			if n == 0 || int(strip) > len(prev) {
+				return errors.New("corrupt index path")
+			}
+			off += n
+			end := bytes.IndexByte(data[off:], 0)
+			if end < 0 {
+				return io.ErrUnexpectedEOF
+			}
+			e.path = prev[:len(prev)-int(strip)] + string(data[off:off+end])
+			off += end + 1
+		} else {
+			nameLen := int(flags & flagNameMask)
+			end := bytes.IndexByte(data[off:], 0)
+			if end < 0 || (nameLen < flagNameMask && end != nameLen) {
+				return errors.New("corrupt index path")
+			}
+			e.path = string(data[off : off+end])
+			// Entries are NUL-padded to a multiple of 8 bytes.
+			off = start + (off+end-start+8)&^7
+		}
+		prev = e.path
+
+		if !fn(e) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// readOffsetVarint decodes git's offset varint encoding used by index v4.
+func readOffsetVarint(b []byte) (uint64, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	val := uint64(b[0] & 0x7f)
+	n := 1
+	for b[n-1]&0x80 != 0 {
+		if n >= len(b) || n > 9 {
+			return 0, 0
+		}
+		val = ((val + 1) << 7) | uint64(b[n]&0x7f)
+		n++
+	}
+	return val, n
+}
+
+// entryChanged reports whether the worktree file for e differs from the
+// index, checking stat data first and falling back to hashing.
+func entryChanged(root string, e indexEntry, hashSize int) bool {
+	p := filepath.Join(root, filepath.FromSlash(e.path))
+	fi, err := os.Lstat(p)
+	if err != nil {
+		return true
+	}
+	isLink := fi.Mode()&os.ModeSymlink != 0
+	if isLink != (e.mode&modeTypeMask == modeSymlink) {
+		return true
+	}
+	if !isLink && (fi.Mode()&0o111 != 0) != (e.mode&0o111 != 0) {
+		return true
+	}
+	mt := fi.ModTime()
+	if uint32(fi.Size()) == e.size && uint32(mt.Unix()) == e.mtimeSec && uint32(mt.Nanosecond()) == e.mtimeNsec {
+		return false
+	}
+
+	var content []byte
+	if isLink {
+		target, err := os.Readlink(p)
+		if err != nil {
+			return true
+		}
+		content = []byte(target)
+	} else if content, err = os.ReadFile(p); err != nil {
+		return true
+	}
+	return !bytes.Equal(blobHash(content, hashSize), e.hash)
+}
+
+// blobHash returns the git object ID of a blob with the given content.
+func blobHash(content []byte, hashSize int) []byte {
+	var h hash.Hash
+	if hashSize == sha256.Size {
+		h = sha256.New()
+	} else {
+		h = sha1.New()
+	}
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return h.Sum(nil)
+}
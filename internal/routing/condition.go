@@ -0,0 +1,667 @@
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Condition is a compiled predicate over a tool call's arguments. Route
+// rules carry a list of conditions that must all hold for the rule to match.
+//
+// The language is a small CEL-like subset:
+//
+//	sql                                  // argument path; missing paths are null
+//	options.force  files[0].path  $["in"] // nested access; $ is the arguments root
+//	== != < <= > >=                      // comparisons (numbers or strings for ordering)
+//	matches startsWith endsWith contains // string operators; matches takes a regex literal
+//	x in ["a", "b"]                      // list membership, or substring of a string
+//	!  &&  ||  ( )                       // boolean logic; null and false are falsy
+//	lower(x) upper(x) trim(x) size(x)    // helpers
+//
+// Example: !(lower(trim(sql)) startsWith "drop") && branch != "main"
+type Condition struct {
+	src  string
+	root condNode
+}
+
+// ParseCondition compiles a condition expression.
+func ParseCondition(src string) (*Condition, error) {
+	toks, err := lexCondition(src)
+	if err != nil {
+		return nil, fmt.Errorf("condition %q: %w", src, err)
+	}
+	p := &condParser{toks: toks}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = fmt.Errorf("unexpected %q at offset %d", p.peek().text, p.peek().pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("condition %q: %w", src, err)
+	}
+	return &Condition{src: src, root: root}, nil
+}
+
+// ParseConditions decodes and compiles a JSON array of condition strings.
+// Empty input yields no conditions.
+func ParseConditions(raw json.RawMessage) ([]*Condition, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var srcs []string
+	if err := json.Unmarshal(raw, &srcs); err != nil {
+		return nil, fmt.Errorf("conditions must be a JSON array of strings")
+	}
+	out := make([]*Condition, 0, len(srcs))
+	for i, s := range srcs {
+		if strings.TrimSpace(s) == "" {
+			return nil, fmt.Errorf("conditions[%d]: empty string not allowed", i)
+		}
+		c, err := ParseCondition(s)
+		if err != nil {
+			return nil, fmt.Errorf("conditions[%d]: %w", i, err)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// String returns the source expression.
+func (c *Condition) String() string { return c.src }
+
+// Eval reports whether the condition holds for the decoded arguments
+// (as produced by json.Unmarshal into an any). Type errors, such as
+// ordering a string against a number, make the condition false.
+func (c *Condition) Eval(args any) bool {
+	v, err := c.root.eval(args)
+	return err == nil && truthy(v)
+}
+
+// --- lexer ---
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type condToken struct {
+	kind tokKind
+	text string // identifier, operator, or decoded string literal
+	num  float64
+	pos  int
+}
+
+// condOps lists punctuation operators, longest first.
+var condOps = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ".", ",", "$"}
+
+func lexCondition(src string) ([]condToken, error) {
+	var toks []condToken
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w at offset %d", err, i)
+			}
+			toks = append(toks, condToken{kind: tokString, text: s, pos: i})
+			i += n
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			j := i + 1
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.' || src[j] == 'e' || src[j] == 'E') {
+				j++
+			}
+			f, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", src[i:j], i)
+			}
+			toks = append(toks, condToken{kind: tokNumber, text: src[i:j], num: f, pos: i})
+			i = j
+		case isIdentByte(c):
+			j := i + 1
+			for j < len(src) && (isIdentByte(src[j]) || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, condToken{kind: tokIdent, text: src[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+			for _, o := range condOps {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			toks = append(toks, condToken{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, condToken{kind: tokEOF, pos: len(src)}), nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// lexString decodes a quoted string literal at the start of s and returns
+// it with the number of bytes consumed.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// --- parser ---
+
+type condParser struct {
+	toks []condToken
+	pos  int
+}
+
+func (p *condParser) peek() condToken { return p.toks[p.pos] }
+
+func (p *condParser) next() condToken {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator op.
+func (p *condParser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *condParser) expect(op string) error {
+	if p.accept(op) {
+		return nil
+	}
+	t := p.peek()
+	if t.kind == tokEOF {
+		return fmt.Errorf("expected %q, got end of expression", op)
+	}
+	return fmt.Errorf("expected %q at offset %d", op, t.pos)
+}
+
+func (p *condParser) parseOr() (condNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *condParser) parseAnd() (condNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *condParser) parseUnary() (condNode, error) {
+	if p.accept("!") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+// comparisonOps are the binary operators between operands.
+var comparisonOps = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"matches": true, "startsWith": true, "endsWith": true, "contains": true, "in": true,
+}
+
+func (p *condParser) parseComparison() (condNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent || !comparisonOps[t.text] {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if t.text == "matches" {
+		var pattern string
+		lit, ok := right.(*literalNode)
+		if ok {
+			pattern, ok = lit.v.(string)
+		}
+		if !ok {
+			return nil, fmt.Errorf("matches requires a string literal pattern at offset %d", t.pos)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+		}
+		return &matchNode{x: left, re: re}, nil
+	}
+	return &compareNode{op: t.text, left: left, right: right}, nil
+}
+
+// condFuncs are the helper functions callable in conditions.
+var condFuncs = map[string]func(any) (any, error){
+	"lower": stringFunc(strings.ToLower),
+	"upper": stringFunc(strings.ToUpper),
+	"trim":  stringFunc(strings.TrimSpace),
+	"size": func(v any) (any, error) {
+		switch x := v.(type) {
+		case string:
+			return float64(len([]rune(x))), nil
+		case []any:
+			return float64(len(x)), nil
+		case map[string]any:
+			return float64(len(x)), nil
+		case nil:
+			return float64(0), nil
+		}
+		return nil, fmt.Errorf("size of %T", v)
+	},
+}
+
+func stringFunc(f func(string) string) func(any) (any, error) {
+	return func(v any) (any, error) {
+		switch x := v.(type) {
+		case string:
+			return f(x), nil
+		case nil:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("expected string, got %T", v)
+	}
+}
+
+func (p *condParser) parseOperand() (condNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return &literalNode{v: t.text}, nil
+	case tokNumber:
+		return &literalNode{v: t.num}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{v: true}, nil
+		case "false":
+			return &literalNode{v: false}, nil
+		case "null":
+			return &literalNode{v: nil}, nil
+		}
+		if p.accept("(") {
+			fn, ok := condFuncs[t.text]
+			if !ok {
+				return nil, fmt.Errorf("unknown function %q", t.text)
+			}
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return &callNode{name: t.text, fn: fn, arg: arg}, nil
+		}
+		if comparisonOps[t.text] {
+			return nil, fmt.Errorf("unexpected operator %q at offset %d", t.text, t.pos)
+		}
+		return p.parsePath([]pathStep{{key: t.text}})
+	case tokOp:
+		switch t.text {
+		case "$":
+			return p.parsePath(nil)
+		case "(":
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		case "[":
+			return p.parseList()
+		}
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+	}
+	return nil, fmt.Errorf("unexpected end of expression")
+}
+
+// parsePath parses the .field and [index] steps following a path root.
+func (p *condParser) parsePath(steps []pathStep) (condNode, error) {
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokIdent {
+				return nil, fmt.Errorf("expected field name at offset %d", t.pos)
+			}
+			steps = append(steps, pathStep{key: t.text})
+		case p.accept("["):
+			t := p.next()
+			switch t.kind {
+			case tokString:
+				steps = append(steps, pathStep{key: t.text})
+			case tokNumber:
+				if t.num < 0 || t.num != float64(int(t.num)) {
+					return nil, fmt.Errorf("invalid index %s at offset %d", t.text, t.pos)
+				}
+				steps = append(steps, pathStep{index: int(t.num), isIndex: true})
+			default:
+				return nil, fmt.Errorf("expected index or quoted key at offset %d", t.pos)
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		default:
+			return &pathNode{steps: steps}, nil
+		}
+	}
+}
+
+func (p *condParser) parseList() (condNode, error) {
+	list := &listNode{}
+	if p.accept("]") {
+		return list, nil
+	}
+	for {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		list.items = append(list.items, x)
+		if p.accept("]") {
+			return list, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// --- evaluation ---
+
+type condNode interface {
+	eval(args any) (any, error)
+}
+
+type literalNode struct{ v any }
+
+func (n *literalNode) eval(any) (any, error) { return n.v, nil }
+
+type listNode struct{ items []condNode }
+
+func (n *listNode) eval(args any) (any, error) {
+	out := make([]any, 0, len(n.items))
+	for _, it := range n.items {
+		v, err := it.eval(args)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+type pathNode struct{ steps []pathStep }
+
+func (n *pathNode) eval(args any) (any, error) {
+	v := args
+	for _, s := range n.steps {
+		switch x := v.(type) {
+		case map[string]any:
+			if s.isIndex {
+				return nil, nil
+			}
+			v = x[s.key]
+		case []any:
+			if !s.isIndex || s.index >= len(x) {
+				return nil, nil
+			}
+			v = x[s.index]
+		default:
+			return nil, nil
+		}
+	}
+	return v, nil
+}
+
+type callNode struct {
+	name string
+	fn   func(any) (any, error)
+	arg  condNode
+}
+
+func (n *callNode) eval(args any) (any, error) {
+	v, err := n.arg.eval(args)
+	if err != nil {
+		return nil, err
+	}
+	out, err := n.fn(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return out, nil
+}
+
+type notNode struct{ x condNode }
+
+func (n *notNode) eval(args any) (any, error) {
+	v, err := n.x.eval(args)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+type logicNode struct {
+	and         bool
+	left, right condNode
+}
+
+func (n *logicNode) eval(args any) (any, error) {
+	l, err := n.left.eval(args)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(l) != n.and {
+		// Short-circuit: false && _, true || _.
+		return !n.and, nil
+	}
+	r, err := n.right.eval(args)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(r), nil
+}
+
+type matchNode struct {
+	x  condNode
+	re *regexp.Regexp
+}
+
+func (n *matchNode) eval(args any) (any, error) {
+	v, err := n.x.eval(args)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return false, nil
+	}
+	return n.re.MatchString(s), nil
+}
+
+type compareNode struct {
+	op          string
+	left, right condNode
+}
+
+func (n *compareNode) eval(args any) (any, error) {
+	l, err := n.left.eval(args)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return reflect.DeepEqual(l, r), nil
+	case "!=":
+		return !reflect.DeepEqual(l, r), nil
+	case "<", "<=", ">", ">=":
+		c, err := order(l, r)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case "startsWith", "endsWith":
+		ls, lok := l.(string)
+		rs, rok := r.(string)
+		if !lok || !rok {
+			return false, nil
+		}
+		if n.op == "startsWith" {
+			return strings.HasPrefix(ls, rs), nil
+		}
+		return strings.HasSuffix(ls, rs), nil
+	case "contains":
+		return contains(l, r), nil
+	case "in":
+		return contains(r, l), nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+// order compares two numbers or two strings.
+func order(l, r any) (int, error) {
+	switch lv := l.(type) {
+	case float64:
+		if rv, ok := r.(float64); ok {
+			switch {
+			case lv < rv:
+				return -1, nil
+			case lv > rv:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if rv, ok := r.(string); ok {
+			return strings.Compare(lv, rv), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot order %T and %T", l, r)
+}
+
+// contains reports whether haystack (a list, string, or object) contains
+// needle as an element, substring, or key respectively.
+func contains(haystack, needle any) bool {
+	switch h := haystack.(type) {
+	case []any:
+		for _, v := range h {
+			if reflect.DeepEqual(v, needle) {
+				return true
+			}
+		}
+	case string:
+		s, ok := needle.(string)
+		return ok && strings.Contains(h, s)
+	case map[string]any:
+		s, ok := needle.(string)
+		if ok {
+			_, found := h[s]
+			return found
+		}
+	}
+	return false
+}
+
+// truthy treats null and false as false and every other value as true.
+func truthy(v any) bool {
+	b, ok := v.(bool)
+	if ok {
+		return b
+	}
+	return v != nil
+}
@@ -0,0 +1,195 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+func TestCondition_Eval(t *testing.T) {
+	args := map[string]any{}
+	if err := json.Unmarshal([]byte(`{
+		"sql": "  DROP TABLE users",
+		"branch": "main",
+		"limit": 50,
+		"force": false,
+		"labels": ["bug", "p1"],
+		"repo": {"owner": "acme", "name": "api"},
+		"files": [{"path": "src/main.go"}],
+		"in": "keyword"
+	}`), &args); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`branch == "main"`, true},
+		{`branch != 'main'`, false},
+		{`lower(trim(sql)) startsWith "drop"`, true},
+		{`!(lower(trim(sql)) startsWith "drop")`, false},
+		{`sql matches "(?i)^\\s*drop\\b"`, true},
+		{`sql endsWith "users"`, true},
+		{`sql contains "TABLE"`, true},
+		{`limit > 10 && limit <= 50`, true},
+		{`limit < 10 || branch == "main"`, true},
+		{`limit >= -1`, true},
+		{`force`, false},
+		{`!force`, true},
+		{`missing`, false},
+		{`missing == null`, true},
+		{`labels contains "p1"`, true},
+		{`"bug" in labels`, true},
+		{`branch in ["main", "release"]`, true},
+		{`size(labels) == 2`, true},
+		{`repo.owner == "acme" && repo["name"] == "api"`, true},
+		{`files[0].path startsWith "src/"`, true},
+		{`files[3].path == null`, true},
+		{`$["in"] == "keyword"`, true},
+		{`repo contains "owner"`, true},
+		// Type errors make the condition false rather than failing.
+		{`branch > 3`, false},
+		{`!(branch > 3)`, false},
+		{`upper(limit) == "50"`, false},
+	}
+	for _, tt := range tests {
+		c, err := ParseCondition(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCondition(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := c.Eval(args); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseCondition_Errors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`branch ==`,
+		`branch == "main`,
+		`(branch == "main"`,
+		`branch == "main")`,
+		`sql matches branch`,
+		`sql matches "("`,
+		`nope(sql)`,
+		`branch # 1`,
+		`files[-1]`,
+		`== "x"`,
+	} {
+		if _, err := ParseCondition(expr); err == nil {
+			t.Errorf("ParseCondition(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestParseConditions(t *testing.T) {
+	conds, err := ParseConditions(json.RawMessage(`["a == 1", "b"]`))
+	if err != nil || len(conds) != 2 || conds[1].String() != "b" {
+		t.Fatalf("ParseConditions = %v, %v", conds, err)
+	}
+	for _, raw := range []string{``, `null`, `[]`} {
+		if conds, err := ParseConditions(json.RawMessage(raw)); err != nil || len(conds) != 0 {
+			t.Errorf("ParseConditions(%q) = %v, %v", raw, conds, err)
+		}
+	}
+	for _, raw := range []string{`"a"`, `[""]`, `["a =="]`} {
+		if _, err := ParseConditions(json.RawMessage(raw)); err == nil {
+			t.Errorf("ParseConditions(%q) succeeded, want error", raw)
+		}
+	}
+}
+
+func TestMatchRoute_Conditions(t *testing.T) {
+	rules := parseRules([]store.RouteRule{
+		{
+			ID: "pg-allow", PathGlob: "**", ToolMatch: json.RawMessage(`["postgres__query"]`),
+			DownstreamServerID: "pg", Policy: "allow",
+			Conditions: json.RawMessage(`["!(lower(trim(sql)) startsWith \"drop\")"]`),
+		},
+		{
+			ID: "push-main", PathGlob: "**", ToolMatch: json.RawMessage(`["git__push"]`),
+			DownstreamServerID: "git", Policy: "allow", ApprovalMode: "all",
+			Conditions: json.RawMessage(`["branch == \"main\""]`),
+		},
+		{
+			ID: "push-any", PathGlob: "**", ToolMatch: json.RawMessage(`["git__push"]`),
+			DownstreamServerID: "git", Policy: "allow", Priority: 100,
+		},
+		{
+			ID: "broken-deny", PathGlob: "**", ToolMatch: json.RawMessage(`["fs__*"]`),
+			Policy:     "deny",
+			Conditions: json.RawMessage(`["path =="]`),
+		},
+		{
+			ID: "fs-allow", PathGlob: "**", ToolMatch: json.RawMessage(`["fs__*"]`),
+			DownstreamServerID: "fs", Policy: "allow",
+		},
+	})
+	sortRules(rules)
+
+	tests := []struct {
+		name      string
+		ctx       RouteContext
+		wantRule  string
+		wantConds int
+		wantErr   error
+	}{
+		{
+			"select allowed",
+			RouteContext{ToolName: "postgres__query", Arguments: json.RawMessage(`{"sql":"SELECT 1"}`)},
+			"pg-allow", 1, nil,
+		},
+		{
+			"drop not allowed",
+			RouteContext{ToolName: "postgres__query", Arguments: json.RawMessage(`{"sql":" drop table x"}`)},
+			"", 0, ErrNoRoute,
+		},
+		{
+			"listing matches conditioned allow",
+			RouteContext{ToolName: "postgres__query"},
+			"pg-allow", 1, nil,
+		},
+		{
+			"conditioned rule beats higher-priority sibling",
+			RouteContext{ToolName: "git__push", Arguments: json.RawMessage(`{"branch":"main"}`)},
+			"push-main", 1, nil,
+		},
+		{
+			"falls through to unconditioned sibling",
+			RouteContext{ToolName: "git__push", Arguments: json.RawMessage(`{"branch":"dev"}`)},
+			"push-any", 0, nil,
+		},
+		{
+			"invalid deny condition fails closed",
+			RouteContext{ToolName: "fs__read", Arguments: json.RawMessage(`{}`)},
+			"", 0, ErrDenied,
+		},
+		{
+			"listing skips conditioned deny",
+			RouteContext{ToolName: "fs__read"},
+			"fs-allow", 0, nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := matchRoute(rules, tt.ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if result.MatchedRuleID != tt.wantRule {
+				t.Errorf("rule = %q, want %q", result.MatchedRuleID, tt.wantRule)
+			}
+			if len(result.MatchedConditions) != tt.wantConds {
+				t.Errorf("conditions = %v, want %d", result.MatchedConditions, tt.wantConds)
+			}
+		})
+	}
+}
@@ -9,15 +9,18 @@ import (
 	"time"
 
 	"github.com/revittco/mcplexer/internal/cache"
+	"github.com/revittco/mcplexer/internal/gitinfo"
 	"github.com/revittco/mcplexer/internal/store"
 )
 
-// WorkspaceAncestor pairs a workspace ID with its root path for subpath
-// computation during routing.
+// WorkspaceAncestor pairs a workspace ID with the root it matched the
+// client at (its root_path or a matcher's root) for subpath computation
+// during routing.
 type WorkspaceAncestor struct {
-	ID       string
-	Name     string
-	RootPath string
+	ID          string
+	Name        string
+	RootPath    string
+	InheritMode string // how the workspace falls back to its ancestors; "" = InheritModeInherit
 }
 
 // RouteContext is the input to the routing engine.
@@ -25,6 +28,25 @@ type RouteContext struct {
 	WorkspaceID string
 	Subpath     string
 	ToolName    string
+
+	// Arguments are the tool call arguments, checked against rule
+	// conditions. Leave nil when routing without a call, such as when
+	// filtering tools/list; tool calls without arguments pass "{}".
+	Arguments json.RawMessage
+
+	// Git is the state of the repository containing the client root, or
+	// nil outside a git repository. Checked against rule git_match.
+	Git *gitinfo.Info
+
+	// Now is the time checked against rule schedules. Zero means the
+	// current time.
+	Now time.Time
+
+	// Trace, if set, collects every workspace and rule considered.
+	Trace *Trace
+
+	workspaceName string    // set by RouteWithFallback for the trace
+	phase         rulePhase // which rules RouteWithFallback is evaluating
 }
 
 // RouteResult is the output of a successful route match.
@@ -37,6 +59,9 @@ type RouteResult struct {
 	AllowedRepos       json.RawMessage
 	ApprovalMode       string
 	ApprovalTimeout    int
+	MatchedConditions  []string   // conditions of the matched rule, if any
+	MatchedSchedule    *Schedule  // schedule of the matched rule, if any
+	RateLimit          *RateLimit // rate limit of the matched rule, checked by Engine.Allow
 
 	// Set by RouteWithFallback to record which workspace and subpath matched.
 	MatchedWorkspaceID   string
@@ -55,7 +80,9 @@ var (
 
 // DeniedError wraps ErrDenied with the ID of the rule that denied the request.
 type DeniedError struct {
-	RuleID string
+	RuleID     string
+	Conditions []string  // conditions of the deny rule, if any
+	Schedule   *Schedule // schedule of the deny rule, if any
 }
 
 func (e *DeniedError) Error() string {
@@ -71,20 +98,35 @@ type Engine struct {
 	store      store.Store
 	rulesCache *cache.Cache[string, []parsedRule]
 	wsVersion  atomic.Int64 // bumped when workspaces change
+	limiter    *Limiter
 }
 
 // NewEngine creates a new routing engine.
 func NewEngine(s store.Store) *Engine {
-	return &Engine{
+	e := &Engine{
 		store:      s,
 		rulesCache: cache.New[string, []parsedRule](100, 30*time.Second),
 	}
+	e.limiter = NewLimiter(e.countQuota)
+	return e
 }
 
 // Route finds the best matching route for the given context.
 func (e *Engine) Route(ctx context.Context, rc RouteContext) (*RouteResult, error) {
-	parsed, err := e.rulesCache.GetOrLoad(rc.WorkspaceID, func() ([]parsedRule, error) {
-		rules, err := e.store.ListRouteRules(ctx, rc.WorkspaceID)
+	parsed, err := e.rules(ctx, rc.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := matchRoute(parsed, rc)
+	rc.Trace.finish(result, err)
+	return result, err
+}
+
+// rules returns the parsed, sorted rules of a workspace from the cache.
+func (e *Engine) rules(ctx context.Context, workspaceID string) ([]parsedRule, error) {
+	return e.rulesCache.GetOrLoad(workspaceID, func() ([]parsedRule, error) {
+		rules, err := e.store.ListRouteRules(ctx, workspaceID)
 		if err != nil {
 			return nil, err
 		}
@@ -93,11 +135,6 @@ func (e *Engine) Route(ctx context.Context, rc RouteContext) (*RouteResult, erro
 		sortRules(p)
 		return p, nil
 	})
-	if err != nil {
-		return nil, err
-	}
-
-	return matchRoute(parsed, rc)
 }
 
 // InvalidateWorkspace removes cached rules for a specific workspace.
@@ -123,6 +160,60 @@ func (e *Engine) RouteStats() cache.Stats {
 	return e.rulesCache.Stats()
 }
 
+// Allow charges a routed call against the matched rule's rate limit,
+// returning a *RateLimitedError (wrapping ErrRateLimited) when the rate
+// or daily quota is exhausted. sessionID keys session-scoped limits;
+// workspace-scoped limits use the matched workspace.
+func (e *Engine) Allow(ctx context.Context, result *RouteResult, sessionID string, now time.Time) error {
+	if result == nil || result.RateLimit == nil {
+		return nil
+	}
+	return e.limiter.Take(ctx, result.MatchedRuleID, result.RateLimit, rateLimitScopeKey(result, sessionID), now)
+}
+
+// Refund gives back a call Allow charged at now, for calls refused after
+// they were charged.
+func (e *Engine) Refund(result *RouteResult, sessionID string, now time.Time) {
+	if result == nil || result.RateLimit == nil {
+		return
+	}
+	e.limiter.Refund(result.MatchedRuleID, result.RateLimit, rateLimitScopeKey(result, sessionID), now)
+}
+
+// rateLimitScopeKey returns the session or workspace a routed call's rate
+// limit is counted under, empty for global limits.
+func rateLimitScopeKey(result *RouteResult, sessionID string) string {
+	switch result.RateLimit.scope() {
+	case RateLimitScopeSession:
+		return sessionID
+	case RateLimitScopeWorkspace:
+		return result.MatchedWorkspaceID
+	}
+	return ""
+}
+
+// countQuota counts the calls a rule served since a time from the audit
+// log, so daily quotas carry over a restart.
+func (e *Engine) countQuota(ctx context.Context, ruleID, scope, scopeKey string, since time.Time) (int, error) {
+	f := store.AuditFilter{RouteRuleID: &ruleID, After: &since, Limit: 1}
+	switch scope {
+	case RateLimitScopeSession:
+		f.SessionID = &scopeKey
+	case RateLimitScopeWorkspace:
+		f.WorkspaceID = &scopeKey
+	}
+	total := 0
+	for _, status := range []string{"success", "error"} {
+		f.Status = &status
+		_, n, err := e.store.QueryAuditRecords(ctx, f)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
 // resolveNamespaces looks up the tool_namespace for each rule's downstream
 // server so that matchRoute can enforce namespace-aware matching.
 func (e *Engine) resolveNamespaces(ctx context.Context, rules []parsedRule) {
@@ -153,29 +244,21 @@ func (e *Engine) resolveNamespaces(ctx context.Context, rules []parsedRule) {
 
 // RouteWithFallback tries routing through a chain of workspace ancestors (most
 // specific first), computing the subpath for each workspace from the client's
-// root directory. A deny at any level stops the search. ErrNoRoute continues
-// to the next ancestor. Returns the first successful match or ErrNoRoute.
+// root directory.
+//
+// Final denies are tried first, outermost workspace first, so that no
+// descendant can override them. The remaining rules are then tried most
+// specific workspace first: a deny at any level stops the search, and
+// ErrNoRoute continues to the next ancestor unless the workspace's
+// inheritance mode stops it. Returns the first successful match or
+// ErrNoRoute.
 func (e *Engine) RouteWithFallback(ctx context.Context, rc RouteContext, clientRoot string, ancestors []WorkspaceAncestor) (*RouteResult, error) {
 	if len(ancestors) == 0 {
 		return e.Route(ctx, rc)
 	}
-
-	for _, ws := range ancestors {
-		rc.WorkspaceID = ws.ID
-		rc.Subpath = ComputeSubpath(clientRoot, ws.RootPath)
-		result, err := e.Route(ctx, rc)
-		if err == nil {
-			result.MatchedWorkspaceID = ws.ID
-			result.MatchedWorkspaceName = ws.Name
-			result.Subpath = rc.Subpath
-			return result, nil
-		}
-		if errors.Is(err, ErrDenied) {
-			return nil, err
-		}
-		// ErrNoRoute: continue to next ancestor.
-	}
-	return nil, ErrNoRoute
+	return routeChain(func(id string) ([]parsedRule, error) {
+		return e.rules(ctx, id)
+	}, rc, clientRoot, ancestors)
 }
 
 // ComputeSubpath returns the relative path of clientRoot within wsRoot.
@@ -210,25 +293,59 @@ func ComputeSubpath(clientRoot, wsRoot string) string {
 // matchRoute evaluates sorted rules against the route context.
 // The first rule to match (by priority and specificity) wins.
 func matchRoute(rules []parsedRule, rc RouteContext) (*RouteResult, error) {
+	args := &lazyArgs{raw: rc.Arguments}
+	now := rc.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if rc.phase == phaseFinal && !hasFinal(rules) {
+		return nil, ErrNoRoute
+	}
+	tw := rc.Trace.enter(rc)
 	for i := range rules {
 		r := &rules[i]
 
+		if !rc.phase.includes(r) {
+			continue
+		}
 		if !GlobMatch(r.PathGlob, rc.Subpath) {
+			tw.skip(r, TraceSkipGlob)
 			continue
 		}
 		if !matchTool(rc.ToolName, r.toolPatterns) {
+			tw.skip(r, TraceSkipTool)
 			continue
 		}
 		// Namespace guard: if the rule's downstream has a tool namespace,
 		// the tool must belong to that namespace. Prevents a wildcard rule
 		// pointing to one server from catching tools for another.
 		if r.namespace != "" && !strings.HasPrefix(rc.ToolName, r.namespace+"__") {
+			tw.skip(r, TraceSkipNamespace)
+			continue
+		}
+		if !r.matchGit(rc.Git) {
+			tw.skip(r, TraceSkipGit)
+			continue
+		}
+		if !r.matchSchedule(now) {
+			tw.skip(r, TraceSkipSchedule)
+			continue
+		}
+		if !r.matchConditions(args) {
+			tw.skip(r, TraceSkipConditions)
 			continue
 		}
 
 		if r.Policy == "deny" {
+			tw.add(r, TraceMatched, "")
+			return nil, &DeniedError{RuleID: r.ID, Conditions: r.conditionStrings(), Schedule: r.schedule}
+		}
+		// A limit that cannot be enforced fails closed.
+		if r.rateLimitErr != nil {
+			tw.add(r, TraceMatched, r.rateLimitErr.Error()+"; denied")
 			return nil, &DeniedError{RuleID: r.ID}
 		}
+		tw.add(r, TraceMatched, "")
 
 		return &RouteResult{
 			DownstreamServerID: r.DownstreamServerID,
@@ -239,6 +356,9 @@ func matchRoute(rules []parsedRule, rc RouteContext) (*RouteResult, error) {
 			AllowedRepos:       r.AllowedRepos,
 			ApprovalMode:       r.ApprovalMode,
 			ApprovalTimeout:    r.ApprovalTimeout,
+			MatchedConditions:  r.conditionStrings(),
+			MatchedSchedule:    r.schedule,
+			RateLimit:          r.rateLimit,
 		}, nil
 	}
 
@@ -0,0 +1,139 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/revittco/mcplexer/internal/gitinfo"
+)
+
+// GitMatch restricts a route rule to sessions whose client root is in a
+// git repository in a given state. Empty fields match anything; a rule
+// with any field set never matches outside a git repository.
+type GitMatch struct {
+	// Branch is a glob over the branch name, e.g. "feature/**" or
+	// "release-*". A leading "!" negates it. A detached HEAD has no branch.
+	Branch string `json:"branch,omitempty" yaml:"branch,omitempty"`
+
+	// Remote is a glob over the origin remote's "owner/repo", e.g. "acme/*".
+	// Matching is case-insensitive.
+	Remote string `json:"remote,omitempty" yaml:"remote,omitempty"`
+
+	Detached *bool `json:"detached,omitempty" yaml:"detached,omitempty"`
+	Dirty    *bool `json:"dirty,omitempty" yaml:"dirty,omitempty"`
+}
+
+// ParseGitMatch decodes a rule's git_match column. Empty input and "{}"
+// yield nil.
+func ParseGitMatch(raw json.RawMessage) (*GitMatch, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" || string(raw) == "{}" {
+		return nil, nil
+	}
+	var m GitMatch
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("git_match: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if m.IsZero() {
+		return nil, nil
+	}
+	return &m, nil
+}
+
+// IsZero reports whether m places no constraints.
+func (m *GitMatch) IsZero() bool {
+	return m == nil || *m == GitMatch{}
+}
+
+// Validate checks the branch and remote globs.
+func (m *GitMatch) Validate() error {
+	if m == nil {
+		return nil
+	}
+	for field, pattern := range map[string]string{"branch": m.Branch, "remote": m.Remote} {
+		p := strings.TrimPrefix(pattern, "!")
+		if pattern != "" && p == "" {
+			return fmt.Errorf("git_match.%s: empty pattern", field)
+		}
+		for _, seg := range strings.Split(p, "/") {
+			if _, err := path.Match(seg, ""); err != nil {
+				return fmt.Errorf("git_match.%s: invalid glob %q", field, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// Match reports whether the repository state satisfies m. A nil info
+// (not a git repository) never matches, and neither does a dirty
+// constraint when the dirty state is unknown.
+func (m *GitMatch) Match(info *gitinfo.Info) bool {
+	return m.match(info, false)
+}
+
+// match is Match, with unknownDirty deciding a dirty constraint when the
+// repository's dirty state could not be read.
+func (m *GitMatch) match(info *gitinfo.Info, unknownDirty bool) bool {
+	if info == nil {
+		return false
+	}
+	if m.Branch != "" && !refGlobMatch(m.Branch, info.Branch) {
+		return false
+	}
+	if m.Remote != "" && !refGlobMatch(strings.ToLower(m.Remote), strings.ToLower(info.Remote.Slug())) {
+		return false
+	}
+	if m.Detached != nil && *m.Detached != info.Detached {
+		return false
+	}
+	if m.Dirty != nil {
+		if info.DirtyUnknown {
+			return unknownDirty
+		}
+		if *m.Dirty != info.Dirty {
+			return false
+		}
+	}
+	return true
+}
+
+// refGlobMatch matches a slash-separated name against a glob in which each
+// segment may use path.Match wildcards and "**" spans segments. A leading
+// "!" negates the pattern. Empty names only match negated patterns.
+func refGlobMatch(pattern, name string) bool {
+	negate := strings.HasPrefix(pattern, "!")
+	pattern = strings.TrimPrefix(pattern, "!")
+	matched := name != "" && segmentsMatch(strings.Split(pattern, "/"), strings.Split(name, "/"))
+	return matched != negate
+}
+
+func segmentsMatch(pat, seg []string) bool {
+	for len(pat) > 0 {
+		p := pat[0]
+		pat = pat[1:]
+		if p == "**" {
+			for i := 0; i <= len(seg); i++ {
+				if segmentsMatch(pat, seg[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(seg) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(p, seg[0]); !ok {
+			return false
+		}
+		seg = seg[1:]
+	}
+	return len(seg) == 0
+}
@@ -0,0 +1,104 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/revittco/mcplexer/internal/gitinfo"
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+func TestRefGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"main", "main", true},
+		{"main", "master", false},
+		{"feature/*", "feature/login", true},
+		{"feature/*", "feature/a/b", false},
+		{"feature/**", "feature/a/b", true},
+		{"release-*", "release-1.2", true},
+		{"!main", "feature/x", true},
+		{"!main", "main", false},
+		{"!main", "", true},
+		{"*", "", false},
+		{"acme/*", "acme/api", true},
+		{"acme/*", "other/api", false},
+	}
+	for _, tt := range tests {
+		if got := refGlobMatch(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("refGlobMatch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseGitMatch(t *testing.T) {
+	for _, raw := range []string{``, `null`, `{}`} {
+		if m, err := ParseGitMatch(json.RawMessage(raw)); err != nil || m != nil {
+			t.Errorf("ParseGitMatch(%q) = %v, %v; want nil", raw, m, err)
+		}
+	}
+	m, err := ParseGitMatch(json.RawMessage(`{"branch":"feature/**","dirty":false}`))
+	if err != nil || m.Branch != "feature/**" || m.Dirty == nil || *m.Dirty {
+		t.Fatalf("ParseGitMatch = %+v, %v", m, err)
+	}
+	for _, raw := range []string{`{"branhc":"main"}`, `{"branch":"[a"}`, `{"remote":"!"}`, `[]`} {
+		if _, err := ParseGitMatch(json.RawMessage(raw)); err == nil {
+			t.Errorf("ParseGitMatch(%q) succeeded, want error", raw)
+		}
+	}
+}
+
+func TestMatchRoute_GitMatch(t *testing.T) {
+	rules := parseRules([]store.RouteRule{
+		{
+			ID: "gh-writes-feature", PathGlob: "**", ToolMatch: json.RawMessage(`["github__*"]`),
+			DownstreamServerID: "gh", AuthScopeID: "acme-token", Policy: "allow",
+			GitMatch: json.RawMessage(`{"branch":"feature/**","remote":"Acme/*"}`),
+		},
+		{
+			ID: "gh-deny-dirty-main", PathGlob: "**", ToolMatch: json.RawMessage(`["github__*"]`),
+			Policy:   "deny",
+			GitMatch: json.RawMessage(`{"branch":"main","dirty":true}`),
+		},
+		{
+			ID: "gh-default", PathGlob: "**", ToolMatch: json.RawMessage(`["github__*"]`),
+			DownstreamServerID: "gh", Policy: "allow", Priority: 100,
+		},
+	})
+	sortRules(rules)
+
+	repo := func(branch, owner string, dirty bool) *gitinfo.Info {
+		return &gitinfo.Info{
+			Branch: branch, Detached: branch == "", Dirty: dirty,
+			Remote: gitinfo.Remote{Owner: owner, Repo: "api"},
+		}
+	}
+	tests := []struct {
+		name     string
+		git      *gitinfo.Info
+		wantRule string
+		wantErr  error
+	}{
+		{"feature branch of acme repo", repo("feature/login", "acme", true), "gh-writes-feature", nil},
+		{"feature branch of other repo", repo("feature/login", "other", false), "gh-default", nil},
+		{"dirty main denied", repo("main", "acme", true), "", ErrDenied},
+		{"clean main", repo("main", "acme", false), "gh-default", nil},
+		{"detached", repo("", "acme", false), "gh-default", nil},
+		{"not a repo", nil, "gh-default", nil},
+		{"unknown dirty state denied", &gitinfo.Info{Branch: "main", DirtyUnknown: true}, "", ErrDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := matchRoute(rules, RouteContext{ToolName: "github__create_pr", Git: tt.git})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && result.MatchedRuleID != tt.wantRule {
+				t.Errorf("rule = %q, want %q", result.MatchedRuleID, tt.wantRule)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package routing
+
+import (
+	"errors"
+	"strings"
+)
+
+// Workspace inheritance modes: how a workspace falls back to the rules of
+// its ancestors when none of its own rules match a call.
+const (
+	// InheritModeInherit falls back to the ancestors (the default).
+	InheritModeInherit = "inherit"
+	// InheritModeIsolate never falls back; only the ancestors' final
+	// denies still apply.
+	InheritModeIsolate = "isolate"
+	// InheritModeOverride falls back only for tools that none of the
+	// workspace's rules mention, so its rules for a tool replace the
+	// ancestors' instead of adding to them.
+	InheritModeOverride = "override"
+)
+
+// ValidInheritMode reports whether m is an inheritance mode; "" means
+// InheritModeInherit.
+func ValidInheritMode(m string) bool {
+	switch m {
+	case "", InheritModeInherit, InheritModeIsolate, InheritModeOverride:
+		return true
+	}
+	return false
+}
+
+// rulePhase selects the rules matchRoute considers.
+type rulePhase int
+
+const (
+	phaseAll      rulePhase = iota // every rule; final denies sort first
+	phaseFinal                     // only final denies
+	phaseNonFinal                  // every rule but the final denies
+)
+
+func (p rulePhase) includes(r *parsedRule) bool {
+	switch p {
+	case phaseFinal:
+		return r.binds()
+	case phaseNonFinal:
+		return !r.binds()
+	default:
+		return true
+	}
+}
+
+func hasFinal(rules []parsedRule) bool {
+	for i := range rules {
+		if rules[i].binds() {
+			return true
+		}
+	}
+	return false
+}
+
+// routeChain implements RouteWithFallback, loading each workspace's
+// parsed and sorted rules with load.
+func routeChain(load func(workspaceID string) ([]parsedRule, error), rc RouteContext, clientRoot string, ancestors []WorkspaceAncestor) (result *RouteResult, err error) {
+	defer func() { rc.Trace.finish(result, err) }()
+
+	try := func(ws WorkspaceAncestor, phase rulePhase) ([]parsedRule, *RouteResult, error) {
+		rules, err := load(ws.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		wrc := rc
+		wrc.WorkspaceID = ws.ID
+		wrc.workspaceName = ws.Name
+		wrc.Subpath = ComputeSubpath(clientRoot, ws.RootPath)
+		wrc.phase = phase
+		result, err := matchRoute(rules, wrc)
+		if err == nil {
+			result.MatchedWorkspaceID = ws.ID
+			result.MatchedWorkspaceName = ws.Name
+			result.Subpath = wrc.Subpath
+		}
+		return rules, result, err
+	}
+
+	// Final denies bind every descendant, so the outermost workspace's
+	// are tried first. Only a deny ends this phase: final allows are
+	// ordinary rules, or an ancestor could override a descendant's deny.
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		_, result, err := try(ancestors[i], phaseFinal)
+		if err == nil || errors.Is(err, ErrDenied) {
+			return result, err
+		}
+	}
+
+	for _, ws := range ancestors {
+		rules, result, err := try(ws, phaseNonFinal)
+		if err == nil || errors.Is(err, ErrDenied) {
+			return result, err
+		}
+		// ErrNoRoute: continue to the next ancestor, unless the
+		// workspace's inheritance mode stops here.
+		switch ws.InheritMode {
+		case InheritModeIsolate:
+			rc.Trace.stop(InheritModeIsolate)
+			return nil, ErrNoRoute
+		case InheritModeOverride:
+			if mentionsTool(rules, rc.ToolName) {
+				rc.Trace.stop(InheritModeOverride)
+				return nil, ErrNoRoute
+			}
+		}
+	}
+	return nil, ErrNoRoute
+}
+
+// mentionsTool reports whether the tool patterns and namespace guard of
+// any rule other than a final deny admit tool, regardless of its path and
+// other checks.
+func mentionsTool(rules []parsedRule, tool string) bool {
+	for i := range rules {
+		r := &rules[i]
+		if r.binds() || !matchTool(tool, r.toolPatterns) {
+			continue
+		}
+		if r.namespace == "" || strings.HasPrefix(tool, r.namespace+"__") {
+			return true
+		}
+	}
+	return false
+}
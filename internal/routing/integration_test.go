@@ -447,3 +447,107 @@ func TestIntegration_ComputeSubpath_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestIntegration_InheritanceModes(t *testing.T) {
+	final := func(r store.RouteRule) store.RouteRule {
+		r.Final = true
+		return r
+	}
+	engine := NewEngine(&mockRouteStore{
+		rules: map[string][]store.RouteRule{
+			"ws-org": {
+				final(rrule("org-no-drop", "ws-org", "**", "deny", 0, tm("db__drop*"), "")),
+				rrule("org-no-db", "ws-org", "**", "deny", 0, tm("db__*"), ""),
+				rrule("org-gh", "ws-org", "**", "allow", 0, tm("github__*"), "gh-srv"),
+				rrule("org-slack", "ws-org", "**", "allow", 0, tm("slack__*"), "slack-srv"),
+				final(rrule("org-slack-read", "ws-org", "**", "allow", 0, tm("slack__read*"), "slack-srv")),
+			},
+			"ws-inherit": {
+				rrule("inh-db", "ws-inherit", "**", "allow", 0, tm("db__*"), "db-srv"),
+				rrule("inh-no-slack-read", "ws-inherit", "**", "deny", 0, tm("slack__read*"), ""),
+			},
+			"ws-isolate": {
+				rrule("iso-gh", "ws-isolate", "**", "allow", 0, tm("github__*"), "gh-srv"),
+			},
+			"ws-override": {
+				rrule("ovr-gh-get", "ws-override", "docs/**", "allow", 0, tm("github__get*"), "gh-srv"),
+			},
+		},
+	})
+	org := WorkspaceAncestor{ID: "ws-org", RootPath: "/org"}
+	chains := map[string][]WorkspaceAncestor{
+		"inherit":  {{ID: "ws-inherit", RootPath: "/org/inherit"}, org},
+		"isolate":  {{ID: "ws-isolate", RootPath: "/org/isolate", InheritMode: InheritModeIsolate}, org},
+		"override": {{ID: "ws-override", RootPath: "/org/override", InheritMode: InheritModeOverride}, org},
+	}
+
+	tests := []struct {
+		name, chain, clientRoot, tool, wantID string
+		wantErr                               error
+	}{
+		{"child allow overrides parent deny", "inherit", "/org/inherit", "db__query", "inh-db", nil},
+		{"final parent deny beats child allow", "inherit", "/org/inherit", "db__drop_table", "org-no-drop", ErrDenied},
+		{"final parent allow does not beat child deny", "inherit", "/org/inherit", "slack__read_channel", "inh-no-slack-read", ErrDenied},
+		{"inherit falls back", "inherit", "/org/inherit", "slack__post", "org-slack", nil},
+		{"isolate uses own rules", "isolate", "/org/isolate/src", "github__push", "iso-gh", nil},
+		{"isolate does not fall back", "isolate", "/org/isolate/src", "slack__post", "", ErrNoRoute},
+		{"isolate still bound by final", "isolate", "/org/isolate", "db__drop_table", "org-no-drop", ErrDenied},
+		{"override own rule matches", "override", "/org/override/docs", "github__get_issue", "ovr-gh-get", nil},
+		{"override replaces parent allow", "override", "/org/override/src", "github__get_issue", "", ErrNoRoute},
+		{"override falls back for unmentioned tool", "override", "/org/override/src", "github__push", "org-gh", nil},
+		{"override falls back to parent deny", "override", "/org/override", "db__query", "org-no-db", ErrDenied},
+		{"parent workspace itself", "inherit", "/org", "db__drop_db", "org-no-drop", ErrDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ancestors := chains[tt.chain]
+			if tt.clientRoot == "/org" {
+				ancestors = []WorkspaceAncestor{org}
+			}
+			result, err := engine.RouteWithFallback(t.Context(), RouteContext{
+				ToolName: tt.tool,
+			}, tt.clientRoot, ancestors)
+			assertRoute(t, result, err, tt.wantID, tt.wantErr)
+			if err == nil && result.MatchedWorkspaceID == "" {
+				t.Error("MatchedWorkspaceID not set")
+			}
+		})
+	}
+}
+
+func TestIntegration_FinalDenySortsFirst(t *testing.T) {
+	final := rrule("final-deny", "ws1", "**", "deny", 0, tm("*"), "")
+	final.Final = true
+	engine := NewEngine(&mockRouteStore{
+		rules: map[string][]store.RouteRule{
+			"ws1": {
+				rrule("src-allow", "ws1", "src/**", "allow", 100, tm("github__push"), "gh-srv"),
+				final,
+			},
+		},
+	})
+
+	result, err := engine.Route(t.Context(), RouteContext{
+		WorkspaceID: "ws1", Subpath: "src/api", ToolName: "github__push",
+	})
+	assertRoute(t, result, err, "final-deny", ErrDenied)
+}
+
+func TestIntegration_FinalAllowIsOrdinary(t *testing.T) {
+	final := rrule("final-allow", "ws1", "**", "allow", 0, tm("*"), "audit-srv")
+	final.Final = true
+	engine := NewEngine(&mockRouteStore{
+		rules: map[string][]store.RouteRule{
+			"ws1": {
+				rrule("src-deny", "ws1", "src/**", "deny", 100, tm("github__push"), ""),
+				final,
+			},
+		},
+	})
+
+	result, err := engine.Route(t.Context(), RouteContext{
+		WorkspaceID: "ws1", Subpath: "src/api", ToolName: "github__push",
+	})
+	assertRoute(t, result, err, "src-deny", ErrDenied)
+}
@@ -0,0 +1,426 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// Lint finding severities.
+const (
+	LintError   = "error"   // the rule is broken or can never match
+	LintWarning = "warning" // the rule is partly or fully unreachable
+	LintInfo    = "info"    // worth a look, but may be intended
+)
+
+// Lint finding kinds.
+const (
+	LintInvalid           = "invalid"
+	LintShadowed          = "shadowed"
+	LintOverlap           = "overlap"
+	LintMissingServer     = "missing_server"
+	LintDisabledServer    = "disabled_server"
+	LintMissingAuthScope  = "missing_auth_scope"
+	LintNamespaceMismatch = "namespace_mismatch"
+	LintFinalAllow        = "final_allow"
+)
+
+// LintFinding is a problem found in the stored route rules.
+type LintFinding struct {
+	Severity    string `json:"severity"`
+	Kind        string `json:"kind"`
+	RuleID      string `json:"rule_id"`
+	WorkspaceID string `json:"workspace_id"`
+	OtherRuleID string `json:"other_rule_id,omitempty"`
+	Message     string `json:"message"`
+}
+
+// Lint statically checks all route rules, workspace by workspace, in the
+// order Route evaluates them. It reports rules that an earlier rule fully
+// shadows, allow/deny pairs that overlap, rules whose downstream server or
+// auth scope is missing or disabled, and tool patterns that the namespace
+// guard makes unreachable.
+//
+// A workspace's rules are also checked against the final denies of the
+// workspaces containing its root, which are evaluated before them whatever
+// its inherit mode. Its ancestors' other rules are only reached when none
+// of its own match, so they cannot shadow them.
+//
+// The analysis is conservative: a rule is only reported as shadowed when
+// every call it could match is provably taken by an earlier rule without
+// conditions, git_match or a schedule.
+func (e *Engine) Lint(ctx context.Context) ([]LintFinding, error) {
+	rules, err := e.store.ListRouteRules(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("list route rules: %w", err)
+	}
+	workspaces, err := e.store.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces: %w", err)
+	}
+	sets := e.ruleSets(ctx, rules)
+
+	wsIDs := make([]string, 0, len(sets))
+	for ws := range sets {
+		wsIDs = append(wsIDs, ws)
+	}
+	sort.Strings(wsIDs)
+
+	findings := []LintFinding{}
+	servers := make(map[string]*store.DownstreamServer)
+	scopes := make(map[string]bool)
+	for _, ws := range wsIDs {
+		set := sets[ws]
+		ancestors := finalAncestors(workspaces, ws, sets)
+		for i := range set {
+			r := &set[i]
+			findings = append(findings, lintInvalid(r)...)
+			findings = append(findings, e.lintRefs(ctx, r, servers, scopes)...)
+			findings = append(findings, lintNamespace(r)...)
+			findings = append(findings, lintFinal(r)...)
+			inherited, shadowed := lintAgainstAncestors(ancestors, r)
+			findings = append(findings, inherited...)
+			if !shadowed {
+				findings = append(findings, lintAgainstEarlier(set[:i], r)...)
+			}
+		}
+	}
+	return findings, nil
+}
+
+func finding(r *parsedRule, severity, kind, format string, args ...any) LintFinding {
+	return LintFinding{
+		Severity:    severity,
+		Kind:        kind,
+		RuleID:      r.ID,
+		WorkspaceID: r.WorkspaceID,
+		Message:     fmt.Sprintf(format, args...),
+	}
+}
+
+// lintInvalid reports fields that fail to parse. Invalid conditions, git
+// matches and schedules fail closed: allow rules never match and deny
+// rules always do.
+func lintInvalid(r *parsedRule) []LintFinding {
+	var out []LintFinding
+	effect := "the rule never matches"
+	if r.Policy == "deny" {
+		effect = "the rule denies every call it would otherwise match"
+	}
+	for _, err := range []error{r.conditionErr, r.gitErr, r.scheduleErr} {
+		if err != nil {
+			out = append(out, finding(r, LintError, LintInvalid, "%v; %s", err, effect))
+		}
+	}
+	if r.rateLimitErr != nil && r.Policy != "deny" {
+		out = append(out, finding(r, LintError, LintInvalid, "%v; every call it matches is denied", r.rateLimitErr))
+	}
+	return out
+}
+
+// lintRefs checks the downstream server and auth scope an allow rule routes
+// to. Lookups are cached across rules.
+func (e *Engine) lintRefs(ctx context.Context, r *parsedRule, servers map[string]*store.DownstreamServer, scopes map[string]bool) []LintFinding {
+	if r.Policy == "deny" {
+		return nil
+	}
+	var out []LintFinding
+	if r.DownstreamServerID == "" {
+		out = append(out, finding(r, LintError, LintMissingServer, "allow rule has no downstream server"))
+	} else {
+		srv, ok := servers[r.DownstreamServerID]
+		if !ok {
+			srv, _ = e.store.GetDownstreamServer(ctx, r.DownstreamServerID)
+			servers[r.DownstreamServerID] = srv
+		}
+		switch {
+		case srv == nil:
+			out = append(out, finding(r, LintError, LintMissingServer,
+				"downstream server %q does not exist", r.DownstreamServerID))
+		case srv.Disabled:
+			out = append(out, finding(r, LintWarning, LintDisabledServer,
+				"downstream server %q is disabled", srv.Name))
+		}
+	}
+	if r.AuthScopeID != "" {
+		ok, seen := scopes[r.AuthScopeID]
+		if !seen {
+			scope, err := e.store.GetAuthScope(ctx, r.AuthScopeID)
+			ok = err == nil && scope != nil
+			scopes[r.AuthScopeID] = ok
+		}
+		if !ok {
+			out = append(out, finding(r, LintError, LintMissingAuthScope,
+				"auth scope %q does not exist", r.AuthScopeID))
+		}
+	}
+	return out
+}
+
+// lintNamespace reports tool patterns that can never pass the namespace
+// guard of the rule's downstream server.
+func lintNamespace(r *parsedRule) []LintFinding {
+	if r.namespace == "" {
+		return nil
+	}
+	var dead []string
+	for _, p := range r.toolPatterns {
+		if _, ok := guardPattern(p, r.namespace); !ok {
+			dead = append(dead, p)
+		}
+	}
+	switch {
+	case len(dead) == 0:
+		return nil
+	case len(dead) == len(r.toolPatterns):
+		return []LintFinding{finding(r, LintError, LintNamespaceMismatch,
+			"no tool pattern can match namespace %q; the rule never matches", r.namespace)}
+	default:
+		return []LintFinding{finding(r, LintWarning, LintNamespaceMismatch,
+			"tool patterns %s can never match namespace %q", strings.Join(dead, ", "), r.namespace)}
+	}
+}
+
+// lintFinal reports final on an allow rule, where routing ignores it.
+func lintFinal(r *parsedRule) []LintFinding {
+	if !r.Final || r.binds() {
+		return nil
+	}
+	return []LintFinding{finding(r, LintWarning, LintFinalAllow,
+		"final has no effect on allow rules; only denies bind descendant workspaces")}
+}
+
+// ancestorFinals are the final denies of one of a workspace's ancestors.
+type ancestorFinals struct {
+	name   string
+	prefix string // the workspace's root relative to the ancestor's
+	rules  []parsedRule
+}
+
+// finalAncestors returns the ancestors of workspace id that have final
+// denies, outermost first, as routing tries them. A workspace matched
+// only by git remote or path glob has no fixed place in the tree, so it
+// has none.
+func finalAncestors(workspaces []store.Workspace, id string, sets map[string][]parsedRule) []ancestorFinals {
+	root := ""
+	for _, ws := range workspaces {
+		if ws.ID == id {
+			root = replayRoot(ws)
+		}
+	}
+	if root == "" {
+		return nil
+	}
+	chain := ResolveWorkspaceChain(workspaces, root, nil)
+	var out []ancestorFinals
+	for i := len(chain) - 1; i >= 0; i-- {
+		a := chain[i]
+		if a.ID == id {
+			continue
+		}
+		var finals []parsedRule
+		for _, r := range sets[a.ID] {
+			if r.binds() {
+				finals = append(finals, r)
+			}
+		}
+		if len(finals) == 0 {
+			continue
+		}
+		name := a.Name
+		if name == "" {
+			name = a.ID
+		}
+		out = append(out, ancestorFinals{name: name, prefix: ComputeSubpath(root, a.RootPath), rules: finals})
+	}
+	return out
+}
+
+// lintAgainstAncestors compares r with its ancestors' final denies,
+// reporting whether one of them shadows it.
+func lintAgainstAncestors(ancestors []ancestorFinals, r *parsedRule) ([]LintFinding, bool) {
+	var out []LintFinding
+	for _, a := range ancestors {
+		// Compare in the ancestor's paths, which its globs are relative to.
+		rel := *r
+		if a.prefix != "" {
+			rel.PathGlob = a.prefix + "/" + r.PathGlob
+		}
+		for _, f := range lintAgainstEarlier(a.rules, &rel) {
+			f.Message += fmt.Sprintf(" (a final deny of workspace %s)", a.name)
+			out = append(out, f)
+			if f.Kind == LintShadowed {
+				return out, true
+			}
+		}
+	}
+	return out, false
+}
+
+// lintAgainstEarlier compares r with the rules evaluated before it. A
+// shadowing rule ends the search: later rules cannot take calls from r.
+func lintAgainstEarlier(earlier []parsedRule, r *parsedRule) []LintFinding {
+	var out []LintFinding
+	for i := range earlier {
+		a := &earlier[i]
+		if !a.alwaysApplies() {
+			continue
+		}
+		if a.covers(r) {
+			f := finding(r, LintWarning, LintShadowed,
+				"never reached: rule %s matches every call this rule could match", a.ID)
+			f.OtherRuleID = a.ID
+			return append(out, f)
+		}
+	}
+	for i := range earlier {
+		a := &earlier[i]
+		if (a.Policy == "deny") == (r.Policy == "deny") || !a.overlaps(r) {
+			continue
+		}
+		f := finding(r, LintInfo, LintOverlap,
+			"overlaps %s rule %s, which is evaluated first and wins where both match", a.Policy, a.ID)
+		f.OtherRuleID = a.ID
+		out = append(out, f)
+	}
+	return out
+}
+
+// alwaysApplies reports whether the rule takes every call matching its
+// path, tool patterns and namespace.
+func (r *parsedRule) alwaysApplies() bool {
+	if r.Policy == "deny" {
+		return r.conditions == nil && r.git == nil && r.schedule == nil
+	}
+	return !r.narrowed()
+}
+
+// covers reports whether every call b could match is matched by r.
+func (r *parsedRule) covers(b *parsedRule) bool {
+	if !globCovers(r.PathGlob, b.PathGlob) {
+		return false
+	}
+	reachable := false
+	for _, bp := range b.toolPatterns {
+		bp, ok := guardPattern(bp, b.namespace)
+		if !ok {
+			continue
+		}
+		reachable = true
+		covered := false
+		for _, ap := range r.toolPatterns {
+			if toolCovers(ap, bp) {
+				covered = true
+				break
+			}
+		}
+		if !covered || (r.namespace != "" && !toolCovers(r.namespace+"__*", bp)) {
+			return false
+		}
+	}
+	return reachable
+}
+
+// overlaps reports whether some call could match both rules' path and tool
+// patterns. Path overlap is only detected when one glob covers the other.
+func (r *parsedRule) overlaps(b *parsedRule) bool {
+	if !globCovers(r.PathGlob, b.PathGlob) && !globCovers(b.PathGlob, r.PathGlob) {
+		return false
+	}
+	for _, ap := range r.toolPatterns {
+		ap, ok := guardPattern(ap, r.namespace)
+		if !ok {
+			continue
+		}
+		for _, bp := range b.toolPatterns {
+			if bp, ok := guardPattern(bp, b.namespace); ok && toolOverlaps(ap, bp) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// guardPattern narrows tool pattern p to the tools the namespace guard
+// lets through, reporting false if there are none.
+func guardPattern(p, namespace string) (string, bool) {
+	if namespace == "" {
+		return p, true
+	}
+	ns := namespace + "__*"
+	switch {
+	case toolCovers(p, ns):
+		return ns, true
+	case toolOverlaps(p, ns):
+		return p, true
+	default:
+		return "", false
+	}
+}
+
+// globCovers reports whether every path matching glob b also matches
+// glob a. It may return false for some covering pairs, never true for a
+// pair that is not.
+func globCovers(a, b string) bool {
+	return segmentsCover(strings.Split(a, "/"), strings.Split(b, "/"))
+}
+
+func segmentsCover(a, b []string) bool {
+	if len(a) == 0 {
+		return len(b) == 0
+	}
+	if a[0] == "**" {
+		// Let ** absorb nothing, or one more segment of b (including a
+		// wildcard, since ** matches anything a single segment can).
+		return segmentsCover(a[1:], b) || (len(b) > 0 && segmentsCover(a, b[1:]))
+	}
+	if len(b) == 0 || b[0] == "**" {
+		return false
+	}
+	if a[0] == "*" || a[0] == b[0] {
+		return segmentsCover(a[1:], b[1:])
+	}
+	return false
+}
+
+// toolCovers reports whether every tool name matching pattern b also
+// matches pattern a.
+func toolCovers(a, b string) bool {
+	if a == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(a, "*"); ok {
+		return strings.HasPrefix(strings.TrimSuffix(b, "*"), prefix)
+	}
+	return a == b
+}
+
+// toolOverlaps reports whether some tool name matches both patterns.
+func toolOverlaps(a, b string) bool {
+	pa, wa := strings.CutSuffix(a, "*")
+	pb, wb := strings.CutSuffix(b, "*")
+	switch {
+	case wa && wb:
+		return strings.HasPrefix(pa, pb) || strings.HasPrefix(pb, pa)
+	case wa:
+		return strings.HasPrefix(b, pa)
+	case wb:
+		return strings.HasPrefix(a, pb)
+	default:
+		return a == b
+	}
+}
+
+// LintErrors counts findings with error severity.
+func LintErrors(findings []LintFinding) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity == LintError {
+			n++
+		}
+	}
+	return n
+}
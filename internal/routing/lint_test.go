@@ -0,0 +1,179 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// lintStore adds auth scopes and workspaces to mockRouteStore.
+type lintStore struct {
+	*mockRouteStore
+	scopes     map[string]bool
+	workspaces []store.Workspace
+}
+
+func (s *lintStore) ListWorkspaces(context.Context) ([]store.Workspace, error) {
+	return s.workspaces, nil
+}
+
+func (s *lintStore) GetAuthScope(_ context.Context, id string) (*store.AuthScope, error) {
+	if s.scopes[id] {
+		return &store.AuthScope{ID: id}, nil
+	}
+	return nil, store.ErrNotFound
+}
+
+func TestGlobCovers(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"**", "src/**", true},
+		{"**", "", true},
+		{"src/**", "src/api/*", true},
+		{"src/**", "src", true},
+		{"src/*", "src/api", true},
+		{"src/*", "src/**", false},
+		{"src/api", "src/*", false},
+		{"*/api", "src/api", true},
+		{"src/**/test", "src/a/b/test", true},
+		{"src/**/test", "src/**", false},
+		{"docs/**", "src/**", false},
+	}
+	for _, tt := range tests {
+		if got := globCovers(tt.a, tt.b); got != tt.want {
+			t.Errorf("globCovers(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestToolPatternRelations(t *testing.T) {
+	tests := []struct {
+		a, b            string
+		covers, overlap bool
+	}{
+		{"*", "github__*", true, true},
+		{"github__*", "github__create_issue", true, true},
+		{"github__*", "github__create*", true, true},
+		{"github__create*", "github__*", false, true},
+		{"github__*", "*", false, true},
+		{"github__*", "slack__*", false, false},
+		{"github__push", "github__push", true, true},
+		{"github__push", "github__pull", false, false},
+	}
+	for _, tt := range tests {
+		if got := toolCovers(tt.a, tt.b); got != tt.covers {
+			t.Errorf("toolCovers(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.covers)
+		}
+		if got := toolOverlaps(tt.a, tt.b); got != tt.overlap {
+			t.Errorf("toolOverlaps(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.overlap)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	withScope := func(r store.RouteRule, scope string) store.RouteRule {
+		r.AuthScopeID = scope
+		return r
+	}
+	withSchedule := func(r store.RouteRule) store.RouteRule {
+		r.Schedule = json.RawMessage(`{"windows":["Mon-Fri"]}`)
+		return r
+	}
+	withConditions := func(r store.RouteRule, raw string) store.RouteRule {
+		r.Conditions = json.RawMessage(raw)
+		return r
+	}
+	final := func(r store.RouteRule) store.RouteRule {
+		r.Final = true
+		return r
+	}
+	s := &lintStore{
+		mockRouteStore: &mockRouteStore{
+			rules: map[string][]store.RouteRule{"": {
+				// acme: a catch-all github rule shadows a narrower one at lower priority.
+				rrule("gh-all", "acme", "**", "allow", 10, tm("github__*"), "gh"),
+				rrule("gh-src", "acme", "**", "allow", 0, tm("github__*"), "gh"),
+				// A scheduled rule is evaluated first but does not shadow anything.
+				withSchedule(rrule("gh-weekdays", "acme", "**", "allow", 0, tm("github__*"), "gh")),
+				// More specific deny overlaps the allows.
+				rrule("gh-deny-push", "acme", "**", "deny", 0, tm("github__push_files"), ""),
+				// Namespace mismatches.
+				rrule("gh-slack", "acme", "services/**", "allow", 0, tm("slack__*"), "gh"),
+				rrule("gh-mixed", "acme", "docs/**", "allow", 0, tm("github__get*", "slack__post"), "gh"),
+				// Broken references.
+				withScope(rrule("old", "other", "**", "allow", 0, tm("legacy__*"), "legacy"), "gone"),
+				rrule("off", "other", "**", "allow", 0, tm("db__*"), "db"),
+				withConditions(rrule("bad", "other", "**", "allow", 0, tm("db__query"), "db"), `[{"arg":"sql","op":"matches","value":"("}]`),
+				// final only binds on denies.
+				final(rrule("gh-final", "final", "**", "allow", 0, tm("github__get*"), "gh")),
+				final(rrule("gh-final-deny", "final", "**", "deny", 0, tm("github__delete*"), "")),
+				// team is inside org, whose final denies bind it even though it isolates.
+				final(rrule("org-no-delete", "org", "**", "deny", 0, tm("github__delete*"), "")),
+				final(rrule("org-no-push", "org", "team/src/**", "deny", 0, tm("github__push"), "")),
+				rrule("team-delete", "team", "**", "allow", 0, tm("github__delete_repo"), "gh"),
+				rrule("team-push", "team", "src/**", "allow", 0, tm("github__push"), "gh"),
+				rrule("team-gh", "team", "**", "allow", 0, tm("github__*"), "gh"),
+			}},
+			downstreams: map[string]*store.DownstreamServer{
+				"gh": {ID: "gh", Name: "github", ToolNamespace: "github"},
+				"db": {ID: "db", Name: "db", ToolNamespace: "db", Disabled: true},
+			},
+		},
+		scopes: map[string]bool{},
+		workspaces: []store.Workspace{
+			{ID: "org", Name: "Org", RootPath: "/org"},
+			{ID: "team", Name: "Team", RootPath: "/org/team", InheritMode: InheritModeIsolate},
+		},
+	}
+
+	findings, err := NewEngine(s).Lint(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string][]string)
+	for _, f := range findings {
+		got[f.RuleID] = append(got[f.RuleID], f.Kind+"/"+f.Severity+"/"+f.OtherRuleID)
+	}
+	for _, kinds := range got {
+		sort.Strings(kinds)
+	}
+	want := map[string][]string{
+		"gh-src":        {"shadowed/warning/gh-all"},
+		"gh-all":        {"overlap/info/gh-deny-push"},
+		"gh-weekdays":   {"overlap/info/gh-deny-push"},
+		"gh-slack":      {"namespace_mismatch/error/"},
+		"gh-mixed":      {"namespace_mismatch/warning/"},
+		"old":           {"missing_auth_scope/error/", "missing_server/error/"},
+		"off":           {"disabled_server/warning/"},
+		"bad":           {"disabled_server/warning/", "invalid/error/"},
+		"gh-deny-push":  nil,
+		"gh-final":      {"final_allow/warning/"},
+		"gh-final-deny": nil,
+		"org-no-delete": nil,
+		"org-no-push":   nil,
+		"team-delete":   {"shadowed/warning/org-no-delete"},
+		"team-push":     {"shadowed/warning/org-no-push"},
+		"team-gh":       {"overlap/info/org-no-delete", "overlap/info/org-no-push"},
+	}
+	for id, w := range want {
+		if len(got[id]) != len(w) {
+			t.Errorf("%s: findings %v, want %v", id, got[id], w)
+			continue
+		}
+		for i := range w {
+			if got[id][i] != w[i] {
+				t.Errorf("%s: findings %v, want %v", id, got[id], w)
+				break
+			}
+		}
+	}
+	if n := LintErrors(findings); n != 4 {
+		t.Errorf("LintErrors = %d, want 4", n)
+	}
+}
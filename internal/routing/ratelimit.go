@@ -0,0 +1,318 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate limit scopes: whose calls share a rule's counters.
+const (
+	RateLimitScopeSession   = "session"
+	RateLimitScopeWorkspace = "workspace"
+	RateLimitScopeGlobal    = "global"
+)
+
+// RateLimit caps how often calls may be routed through an allow rule.
+// Rate and Burst form a token bucket refilled at Rate calls per Per;
+// Daily caps calls per calendar day (server local time). Either may be
+// set alone.
+type RateLimit struct {
+	// Scope is "session" (default), "workspace" or "global".
+	Scope string `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Rate  int    `json:"rate,omitempty" yaml:"rate,omitempty"`
+	Per   string `json:"per,omitempty" yaml:"per,omitempty"`     // Go duration; default "1m"
+	Burst int    `json:"burst,omitempty" yaml:"burst,omitempty"` // default Rate
+	Daily int    `json:"daily,omitempty" yaml:"daily,omitempty"`
+
+	per time.Duration
+}
+
+// ParseRateLimit decodes and validates a rule's rate_limit column. Empty
+// input and "{}" yield nil.
+func ParseRateLimit(raw json.RawMessage) (*RateLimit, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" || string(raw) == "{}" {
+		return nil, nil
+	}
+	var l RateLimit
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&l); err != nil {
+		return nil, fmt.Errorf("rate_limit: %w", err)
+	}
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Validate checks the scope, counts and period.
+func (l *RateLimit) Validate() error {
+	if l == nil {
+		return nil
+	}
+	switch l.Scope {
+	case "", RateLimitScopeSession, RateLimitScopeWorkspace, RateLimitScopeGlobal:
+	default:
+		return fmt.Errorf("rate_limit: scope must be session, workspace or global, got %q", l.Scope)
+	}
+	if l.Rate < 0 || l.Burst < 0 || l.Daily < 0 {
+		return fmt.Errorf("rate_limit: rate, burst and daily must not be negative")
+	}
+	if l.Rate == 0 && l.Daily == 0 {
+		return fmt.Errorf("rate_limit: rate or daily is required")
+	}
+	if l.Rate == 0 && (l.Burst != 0 || l.Per != "") {
+		return fmt.Errorf("rate_limit: burst and per require rate")
+	}
+	l.per = time.Minute
+	if l.Per != "" {
+		d, err := time.ParseDuration(l.Per)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("rate_limit: invalid per %q", l.Per)
+		}
+		l.per = d
+	}
+	return nil
+}
+
+// scope returns the effective scope.
+func (l *RateLimit) scope() string {
+	if l.Scope == "" {
+		return RateLimitScopeSession
+	}
+	return l.Scope
+}
+
+// burst returns the token bucket size.
+func (l *RateLimit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Rate)
+}
+
+// refill returns the bucket refill rate in tokens per second.
+func (l *RateLimit) refill() float64 {
+	return float64(l.Rate) / l.per.Seconds()
+}
+
+// String summarizes the limit, e.g. "10/1m, 200/day per session".
+func (l *RateLimit) String() string {
+	var parts []string
+	if l.Rate > 0 {
+		per := l.Per
+		if per == "" {
+			per = "1m"
+		}
+		p := strconv.Itoa(l.Rate) + "/" + per
+		if l.Burst > 0 {
+			p += " (burst " + strconv.Itoa(l.Burst) + ")"
+		}
+		parts = append(parts, p)
+	}
+	if l.Daily > 0 {
+		parts = append(parts, strconv.Itoa(l.Daily)+"/day")
+	}
+	return strings.Join(parts, ", ") + " per " + l.scope()
+}
+
+// ErrRateLimited means a rule's rate limit or daily quota is exhausted.
+// RateLimitedError wraps this sentinel to carry details.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimitedError wraps ErrRateLimited with the rule that limited the call.
+type RateLimitedError struct {
+	RuleID     string
+	Scope      string
+	Quota      bool          // the daily quota, rather than the rate, ran out
+	RetryAfter time.Duration // until a call would be allowed
+}
+
+func (e *RateLimitedError) Error() string {
+	what := "rate limit"
+	if e.Quota {
+		what = "daily quota"
+	}
+	retry := e.RetryAfter.Round(time.Second)
+	if retry < time.Second {
+		retry = time.Second
+	}
+	return fmt.Sprintf("%s exceeded for rule %s (per %s); retry in %s", what, e.RuleID, e.Scope, retry)
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// QuotaCounter reports how many calls a rule has already served in a
+// scope since a time, so daily quotas survive restarts. scopeKey is the
+// session or workspace ID, empty for global limits.
+type QuotaCounter func(ctx context.Context, ruleID, scope, scopeKey string, since time.Time) (int, error)
+
+// Limiter holds the token buckets and daily counters of rate-limited
+// rules. It is safe for concurrent use.
+type Limiter struct {
+	count QuotaCounter // nil = quotas start at zero
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	daily   map[string]*dayCount
+	takes   int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket will have refilled completely
+}
+
+type dayCount struct {
+	day string
+	n   int
+}
+
+// sweepEvery is how many calls pass between evictions of idle counters.
+const sweepEvery = 1024
+
+// NewLimiter creates a Limiter. count seeds daily quotas the first time a
+// counter is used each day; it may be nil.
+func NewLimiter(count QuotaCounter) *Limiter {
+	return &Limiter{
+		count:   count,
+		buckets: make(map[string]*bucket),
+		daily:   make(map[string]*dayCount),
+	}
+}
+
+// Take charges one call against l for the given rule and scope key,
+// returning a *RateLimitedError if the rate or daily quota is exhausted.
+// Nothing is charged when the call is refused.
+func (lim *Limiter) Take(ctx context.Context, ruleID string, l *RateLimit, scopeKey string, now time.Time) error {
+	if l == nil {
+		return nil
+	}
+	key := ruleID + "\x00" + l.scope() + "\x00" + scopeKey
+	now = now.Local()
+	day := now.Format(time.DateOnly)
+
+	if l.Daily > 0 {
+		lim.seedDaily(ctx, key, ruleID, l.scope(), scopeKey, day, now)
+	}
+
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	lim.takes++
+	if lim.takes%sweepEvery == 0 {
+		lim.sweep(now, day)
+	}
+
+	var dc *dayCount
+	if l.Daily > 0 {
+		dc = lim.daily[key]
+		if dc == nil || dc.day != day {
+			dc = &dayCount{day: day}
+			lim.daily[key] = dc
+		}
+		if dc.n >= l.Daily {
+			y, m, d := now.Date()
+			midnight := time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+			return &RateLimitedError{RuleID: ruleID, Scope: l.scope(), Quota: true, RetryAfter: midnight.Sub(now)}
+		}
+	}
+
+	if l.Rate > 0 {
+		burst, refill := l.burst(), l.refill()
+		b := lim.buckets[key]
+		if b == nil {
+			b = &bucket{tokens: burst, last: now}
+			lim.buckets[key] = b
+		}
+		if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+			b.tokens += elapsed * refill
+			b.last = now
+		}
+		b.tokens = math.Min(b.tokens, burst)
+		if b.tokens < 1 {
+			wait := time.Duration((1 - b.tokens) / refill * float64(time.Second))
+			return &RateLimitedError{RuleID: ruleID, Scope: l.scope(), RetryAfter: wait}
+		}
+		b.tokens--
+		b.full = now.Add(time.Duration((burst - b.tokens) / refill * float64(time.Second)))
+	}
+
+	if dc != nil {
+		dc.n++
+	}
+	return nil
+}
+
+// Refund gives back a call Take charged at now, for calls refused by a
+// later check.
+func (lim *Limiter) Refund(ruleID string, l *RateLimit, scopeKey string, now time.Time) {
+	if l == nil {
+		return
+	}
+	key := ruleID + "\x00" + l.scope() + "\x00" + scopeKey
+	now = now.Local()
+
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	if dc := lim.daily[key]; dc != nil && dc.day == now.Format(time.DateOnly) && dc.n > 0 {
+		dc.n--
+	}
+	if b := lim.buckets[key]; b != nil {
+		burst := l.burst()
+		b.tokens = math.Min(b.tokens+1, burst)
+		b.full = b.last.Add(time.Duration((burst - b.tokens) / l.refill() * float64(time.Second)))
+	}
+}
+
+// seedDaily loads today's count for key from the QuotaCounter if it has
+// not been loaded yet. The store is queried without holding the lock.
+func (lim *Limiter) seedDaily(ctx context.Context, key, ruleID, scope, scopeKey, day string, now time.Time) {
+	if lim.count == nil {
+		return
+	}
+	lim.mu.Lock()
+	dc := lim.daily[key]
+	lim.mu.Unlock()
+	if dc != nil && dc.day == day {
+		return
+	}
+
+	y, m, d := now.Date()
+	n, err := lim.count(ctx, ruleID, scope, scopeKey, time.Date(y, m, d, 0, 0, 0, 0, now.Location()))
+	if err != nil {
+		return
+	}
+
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	if dc := lim.daily[key]; dc == nil || dc.day != day {
+		lim.daily[key] = &dayCount{day: day, n: n}
+	}
+}
+
+// sweep drops buckets that have refilled and counters from earlier days;
+// both behave the same as fresh ones. Called with mu held.
+func (lim *Limiter) sweep(now time.Time, day string) {
+	for k, b := range lim.buckets {
+		if !now.Before(b.full) {
+			delete(lim.buckets, k)
+		}
+	}
+	for k, dc := range lim.daily {
+		if dc.day != day {
+			delete(lim.daily, k)
+		}
+	}
+}
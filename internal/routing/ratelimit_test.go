@@ -0,0 +1,198 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustRateLimit(t *testing.T, raw string) *RateLimit {
+	t.Helper()
+	l, err := ParseRateLimit(json.RawMessage(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestParseRateLimit(t *testing.T) {
+	for _, raw := range []string{``, `null`, `{}`} {
+		if l, err := ParseRateLimit(json.RawMessage(raw)); err != nil || l != nil {
+			t.Errorf("ParseRateLimit(%q) = %v, %v; want nil", raw, l, err)
+		}
+	}
+	for _, raw := range []string{
+		`{"scope":"session"}`,
+		`{"rate":-1}`,
+		`{"rate":5,"per":"soon"}`,
+		`{"rate":5,"per":"-1m"}`,
+		`{"daily":5,"burst":2}`,
+		`{"rate":5,"scope":"tenant"}`,
+		`{"rate":5,"window":"1m"}`,
+	} {
+		if _, err := ParseRateLimit(json.RawMessage(raw)); err == nil {
+			t.Errorf("ParseRateLimit(%s) succeeded, want error", raw)
+		}
+	}
+	l := mustRateLimit(t, `{"rate":10,"burst":20,"daily":500,"scope":"workspace"}`)
+	if got := l.String(); got != "10/1m (burst 20), 500/day per workspace" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestLimiter_TokenBucket(t *testing.T) {
+	lim := NewLimiter(nil)
+	l := mustRateLimit(t, `{"rate":2,"per":"1m"}`)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local)
+
+	for i := 0; i < 2; i++ {
+		if err := lim.Take(ctx, "r", l, "s1", now); err != nil {
+			t.Fatalf("take %d: %v", i, err)
+		}
+	}
+	err := lim.Take(ctx, "r", l, "s1", now)
+	var rl *RateLimitedError
+	if !errors.As(err, &rl) || rl.Quota || rl.RetryAfter != 30*time.Second {
+		t.Fatalf("burst exhausted: err = %v", err)
+	}
+	if !errors.Is(err, ErrRateLimited) {
+		t.Error("want errors.Is(err, ErrRateLimited)")
+	}
+
+	// Other scope keys have their own bucket.
+	if err := lim.Take(ctx, "r", l, "s2", now); err != nil {
+		t.Errorf("other session: %v", err)
+	}
+	// One token refills every 30s.
+	if err := lim.Take(ctx, "r", l, "s1", now.Add(30*time.Second)); err != nil {
+		t.Errorf("after refill: %v", err)
+	}
+	if err := lim.Take(ctx, "r", l, "s1", now.Add(31*time.Second)); err == nil {
+		t.Error("want limited again")
+	}
+}
+
+func TestLimiter_DailyQuota(t *testing.T) {
+	var seeded int
+	lim := NewLimiter(func(_ context.Context, ruleID, scope, scopeKey string, since time.Time) (int, error) {
+		seeded++
+		if ruleID != "r" || scope != RateLimitScopeGlobal || scopeKey != "" || since.Hour() != 0 {
+			t.Errorf("count(%q, %q, %q, %v)", ruleID, scope, scopeKey, since)
+		}
+		return 2, nil
+	})
+	l := mustRateLimit(t, `{"daily":3,"scope":"global"}`)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 2, 22, 0, 0, 0, time.Local)
+
+	// Two calls were already served today, so one remains.
+	if err := lim.Take(ctx, "r", l, "", now); err != nil {
+		t.Fatal(err)
+	}
+	err := lim.Take(ctx, "r", l, "", now)
+	var rl *RateLimitedError
+	if !errors.As(err, &rl) || !rl.Quota || rl.RetryAfter != 2*time.Hour {
+		t.Fatalf("quota exhausted: err = %v", err)
+	}
+	if seeded != 1 {
+		t.Errorf("seeded %d times, want 1", seeded)
+	}
+
+	// The quota resets at midnight.
+	if err := lim.Take(ctx, "r", l, "", now.Add(3*time.Hour)); err != nil {
+		t.Errorf("next day: %v", err)
+	}
+}
+
+func TestLimiter_RefusedCallsAreNotCharged(t *testing.T) {
+	lim := NewLimiter(nil)
+	l := mustRateLimit(t, `{"rate":1,"per":"1h","daily":2}`)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local)
+
+	if err := lim.Take(ctx, "r", l, "", now); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := lim.Take(ctx, "r", l, "", now); err == nil {
+			t.Fatal("want rate limited")
+		}
+	}
+	// The refused calls did not use up the daily quota.
+	if err := lim.Take(ctx, "r", l, "", now.Add(time.Hour)); err != nil {
+		t.Errorf("after refill: %v", err)
+	}
+}
+
+func TestLimiter_Refund(t *testing.T) {
+	lim := NewLimiter(nil)
+	l := mustRateLimit(t, `{"rate":1,"per":"1h","daily":1}`)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local)
+
+	if err := lim.Take(ctx, "r", l, "", now); err != nil {
+		t.Fatal(err)
+	}
+	lim.Refund("r", l, "", now)
+	if err := lim.Take(ctx, "r", l, "", now); err != nil {
+		t.Fatalf("after refund: %v", err)
+	}
+	if err := lim.Take(ctx, "r", l, "", now); err == nil {
+		t.Fatal("want limited once the refunded call is charged again")
+	}
+
+	// Refunds never raise a bucket above its burst.
+	rate := mustRateLimit(t, `{"rate":1,"per":"1h"}`)
+	if err := lim.Take(ctx, "r2", rate, "", now); err != nil {
+		t.Fatal(err)
+	}
+	lim.Refund("r2", rate, "", now)
+	lim.Refund("r2", rate, "", now)
+	if err := lim.Take(ctx, "r2", rate, "", now); err != nil {
+		t.Fatal(err)
+	}
+	if err := lim.Take(ctx, "r2", rate, "", now); err == nil {
+		t.Error("refunds overfilled the bucket")
+	}
+}
+
+func TestEngine_AllowScopes(t *testing.T) {
+	e := NewEngine(nil)
+	ctx := context.Background()
+	now := time.Now()
+	result := func(scope, ws string) *RouteResult {
+		return &RouteResult{
+			MatchedRuleID:      "r-" + scope,
+			MatchedWorkspaceID: ws,
+			RateLimit:          mustRateLimit(t, `{"rate":1,"per":"1h","scope":"`+scope+`"}`),
+		}
+	}
+
+	tests := []struct {
+		scope         string
+		first, second [2]string // session, workspace
+		secondLimited bool
+	}{
+		{"session", [2]string{"s1", "w1"}, [2]string{"s1", "w2"}, true},
+		{"session", [2]string{"s3", "w1"}, [2]string{"s4", "w1"}, false},
+		{"workspace", [2]string{"s1", "w1"}, [2]string{"s2", "w1"}, true},
+		{"workspace", [2]string{"s1", "w3"}, [2]string{"s1", "w4"}, false},
+		{"global", [2]string{"s1", "w1"}, [2]string{"s2", "w2"}, true},
+	}
+	for _, tt := range tests {
+		if err := e.Allow(ctx, result(tt.scope, tt.first[1]), tt.first[0], now); err != nil {
+			t.Fatalf("%s first: %v", tt.scope, err)
+		}
+		err := e.Allow(ctx, result(tt.scope, tt.second[1]), tt.second[0], now)
+		if (err != nil) != tt.secondLimited {
+			t.Errorf("%s %v then %v: err = %v, want limited %v", tt.scope, tt.first, tt.second, err, tt.secondLimited)
+		}
+	}
+
+	if err := e.Allow(ctx, &RouteResult{MatchedRuleID: "plain"}, "s1", now); err != nil {
+		t.Errorf("no rate limit: %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// RuleSetDiff is a proposed change to the stored route rules.
+type RuleSetDiff struct {
+	// Upsert adds rules, replacing any stored rule with the same ID.
+	Upsert []store.RouteRule `json:"upsert,omitempty"`
+	// Delete removes the stored rules with these IDs.
+	Delete []string `json:"delete,omitempty"`
+}
+
+// Validate checks that each upserted rule is complete and its JSON fields
+// parse.
+func (d *RuleSetDiff) Validate() error {
+	if len(d.Upsert) == 0 && len(d.Delete) == 0 {
+		return errors.New("diff is empty")
+	}
+	for i, r := range d.Upsert {
+		if r.ID == "" || r.WorkspaceID == "" {
+			return fmt.Errorf("upsert[%d]: id and workspace_id are required", i)
+		}
+		if r.Policy != "allow" && r.Policy != "deny" {
+			return fmt.Errorf("upsert[%d]: policy must be allow or deny", i)
+		}
+		if _, err := ParseConditions(r.Conditions); err != nil {
+			return fmt.Errorf("upsert[%d]: %w", i, err)
+		}
+		if _, err := ParseGitMatch(r.GitMatch); err != nil {
+			return fmt.Errorf("upsert[%d]: %w", i, err)
+		}
+		if _, err := ParseSchedule(r.Schedule); err != nil {
+			return fmt.Errorf("upsert[%d]: %w", i, err)
+		}
+		if _, err := ParseRateLimit(r.RateLimit); err != nil {
+			return fmt.Errorf("upsert[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Apply returns rules with the diff applied. rules is not modified.
+func (d *RuleSetDiff) Apply(rules []store.RouteRule) []store.RouteRule {
+	drop := make(map[string]bool, len(d.Delete)+len(d.Upsert))
+	for _, id := range d.Delete {
+		drop[id] = true
+	}
+	for _, r := range d.Upsert {
+		drop[r.ID] = true
+	}
+	out := make([]store.RouteRule, 0, len(rules)+len(d.Upsert))
+	for _, r := range rules {
+		if !drop[r.ID] {
+			out = append(out, r)
+		}
+	}
+	return append(out, d.Upsert...)
+}
+
+// Replay outcomes.
+const (
+	OutcomeAllow    = "allow"
+	OutcomeApproval = "approval"
+	OutcomeDeny     = "deny"
+	OutcomeNoRoute  = "no_route"
+)
+
+// replayDecision is how a rule set handles one call.
+type replayDecision struct {
+	Outcome string `json:"outcome"`
+	RuleID  string `json:"rule_id,omitempty"`
+}
+
+// ReplayGroup counts replayed calls to one tool in one workspace whose
+// outcome flipped the same way.
+type ReplayGroup struct {
+	ToolName      string   `json:"tool_name"`
+	WorkspaceID   string   `json:"workspace_id"`
+	WorkspaceName string   `json:"workspace_name"`
+	Before        string   `json:"before"`
+	After         string   `json:"after"`
+	BeforeRules   []string `json:"before_rules,omitempty"`
+	AfterRules    []string `json:"after_rules,omitempty"`
+	Count         int      `json:"count"`
+	RecordIDs     []string `json:"record_ids"` // up to maxReplayExamples
+}
+
+// ReplayReport summarizes a replay of audit records through the current
+// and proposed rule sets.
+type ReplayReport struct {
+	Replayed int           `json:"replayed"`
+	Changed  int           `json:"changed"`
+	Skipped  int           `json:"skipped"` // records whose workspace no longer exists
+	Groups   []ReplayGroup `json:"groups"`
+}
+
+const maxReplayExamples = 5
+
+// Replay routes recorded calls through the current rules and through the
+// rules with diff applied, reporting calls whose outcome would change.
+//
+// Calls are replayed with their recorded workspace, subpath, time and
+// redacted arguments. The repository state is not recorded, so rules with
+// git_match never match, and rate limits are not applied.
+func (e *Engine) Replay(ctx context.Context, diff RuleSetDiff, records []store.AuditRecord) (*ReplayReport, error) {
+	current, err := e.store.ListRouteRules(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("list route rules: %w", err)
+	}
+	workspaces, err := e.store.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces: %w", err)
+	}
+	wsByID := make(map[string]store.Workspace, len(workspaces))
+	for _, ws := range workspaces {
+		wsByID[ws.ID] = ws
+	}
+
+	before := e.ruleSets(ctx, current)
+	after := e.ruleSets(ctx, diff.Apply(current))
+
+	report := &ReplayReport{Groups: []ReplayGroup{}}
+	groups := make(map[[4]string]*ReplayGroup)
+	for _, rec := range records {
+		ws, ok := wsByID[rec.WorkspaceID]
+		if !ok || rec.ToolName == "" {
+			report.Skipped++
+			continue
+		}
+		report.Replayed++
+
+		root := replayRoot(ws)
+		clientRoot := path.Join(root, rec.Subpath)
+		chain := ResolveWorkspaceChain(workspaces, clientRoot, nil)
+		if !chainHas(chain, ws.ID) {
+			// Matched by git remote when recorded: replay in that workspace.
+			chain = append([]WorkspaceAncestor{{
+				ID: ws.ID, Name: ws.Name, RootPath: root, InheritMode: ws.InheritMode,
+			}}, chain...)
+		}
+		args := rec.ParamsRedacted
+		if len(args) == 0 || string(args) == "null" {
+			args = json.RawMessage(`{}`)
+		}
+		rc := RouteContext{ToolName: rec.ToolName, Arguments: args, Now: rec.Timestamp}
+		b := replayDecide(before, rc, clientRoot, chain)
+		a := replayDecide(after, rc, clientRoot, chain)
+		if a.Outcome == b.Outcome {
+			continue
+		}
+		report.Changed++
+
+		key := [4]string{rec.ToolName, rec.WorkspaceID, b.Outcome, a.Outcome}
+		g := groups[key]
+		if g == nil {
+			g = &ReplayGroup{
+				ToolName: rec.ToolName, WorkspaceID: rec.WorkspaceID, WorkspaceName: ws.Name,
+				Before: b.Outcome, After: a.Outcome,
+			}
+			groups[key] = g
+		}
+		g.Count++
+		g.BeforeRules = appendUnique(g.BeforeRules, b.RuleID)
+		g.AfterRules = appendUnique(g.AfterRules, a.RuleID)
+		if len(g.RecordIDs) < maxReplayExamples {
+			g.RecordIDs = append(g.RecordIDs, rec.ID)
+		}
+	}
+
+	for _, g := range groups {
+		report.Groups = append(report.Groups, *g)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		gi, gj := report.Groups[i], report.Groups[j]
+		if gi.Count != gj.Count {
+			return gi.Count > gj.Count
+		}
+		if gi.ToolName != gj.ToolName {
+			return gi.ToolName < gj.ToolName
+		}
+		return gi.WorkspaceID < gj.WorkspaceID
+	})
+	return report, nil
+}
+
+// ruleSets parses and sorts rules per workspace, as Route does.
+func (e *Engine) ruleSets(ctx context.Context, rules []store.RouteRule) map[string][]parsedRule {
+	byWS := make(map[string][]store.RouteRule)
+	for _, r := range rules {
+		byWS[r.WorkspaceID] = append(byWS[r.WorkspaceID], r)
+	}
+	out := make(map[string][]parsedRule, len(byWS))
+	for ws, rs := range byWS {
+		p := parseRules(rs)
+		e.resolveNamespaces(ctx, p)
+		sortRules(p)
+		out[ws] = p
+	}
+	return out
+}
+
+// replayDecide routes through the workspace chain like RouteWithFallback.
+func replayDecide(sets map[string][]parsedRule, rc RouteContext, clientRoot string, chain []WorkspaceAncestor) replayDecision {
+	result, err := routeChain(func(id string) ([]parsedRule, error) {
+		return sets[id], nil
+	}, rc, clientRoot, chain)
+	var de *DeniedError
+	switch {
+	case errors.As(err, &de):
+		return replayDecision{Outcome: OutcomeDeny, RuleID: de.RuleID}
+	case err != nil:
+		return replayDecision{Outcome: OutcomeNoRoute}
+	case result.ApprovalMode != "" && result.ApprovalMode != "none":
+		return replayDecision{Outcome: OutcomeApproval, RuleID: result.MatchedRuleID}
+	default:
+		return replayDecision{Outcome: OutcomeAllow, RuleID: result.MatchedRuleID}
+	}
+}
+
+// replayRoot returns the directory a recorded subpath is relative to: the
+// workspace's root_path, or its first extra root.
+func replayRoot(ws store.Workspace) string {
+	if ws.RootPath != "" {
+		return ws.RootPath
+	}
+	if m, err := ParseWorkspaceMatchers(ws.Matchers); err == nil && m != nil && len(m.Roots) > 0 {
+		return m.Roots[0]
+	}
+	return "/"
+}
+
+func chainHas(chain []WorkspaceAncestor, id string) bool {
+	for _, ws := range chain {
+		if ws.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, s string) []string {
+	if s == "" {
+		return list
+	}
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
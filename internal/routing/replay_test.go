@@ -0,0 +1,93 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// replayStore adds workspaces to mockRouteStore; Replay lists all rules
+// with workspace "".
+type replayStore struct {
+	*mockRouteStore
+	workspaces []store.Workspace
+}
+
+func (s *replayStore) ListWorkspaces(context.Context) ([]store.Workspace, error) {
+	return s.workspaces, nil
+}
+
+func TestReplay(t *testing.T) {
+	approval := approvalRule("acme-push-approval", "acme", "**", 10, tm("github__push_files"), "gh", 60)
+	s := &replayStore{
+		mockRouteStore: &mockRouteStore{rules: map[string][]store.RouteRule{"": {
+			rrule("global-gh", "global", "**", "allow", 0, tm("github__*"), "gh"),
+			rrule("acme-gh", "acme", "**", "allow", 0, tm("github__*"), "gh"),
+			rrule("acme-db", "acme", "services/**", "allow", 0, tm("db__*"), "db"),
+		}}},
+		workspaces: []store.Workspace{
+			{ID: "global", Name: "Global", RootPath: "/"},
+			{ID: "acme", Name: "Acme", RootPath: "/src/acme"},
+		},
+	}
+	engine := NewEngine(s)
+
+	at := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	rec := func(id, ws, subpath, tool string) store.AuditRecord {
+		return store.AuditRecord{ID: id, WorkspaceID: ws, Subpath: subpath, ToolName: tool, Timestamp: at}
+	}
+	records := []store.AuditRecord{
+		rec("1", "acme", "", "github__push_files"),
+		rec("2", "acme", "api", "github__push_files"),
+		rec("3", "acme", "", "github__get_issue"),
+		rec("4", "acme", "services/billing", "db__query"),
+		rec("5", "global", "other", "github__push_files"),
+		rec("6", "gone", "", "github__push_files"),
+	}
+
+	report, err := engine.Replay(context.Background(), RuleSetDiff{
+		Upsert: []store.RouteRule{approval},
+		Delete: []string{"acme-db"},
+	}, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Replayed != 5 || report.Skipped != 1 || report.Changed != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	if len(report.Groups) != 2 {
+		t.Fatalf("groups = %+v", report.Groups)
+	}
+
+	g := report.Groups[0]
+	if g.ToolName != "github__push_files" || g.WorkspaceName != "Acme" || g.Count != 2 ||
+		g.Before != OutcomeAllow || g.After != OutcomeApproval ||
+		len(g.AfterRules) != 1 || g.AfterRules[0] != "acme-push-approval" {
+		t.Errorf("push group = %+v", g)
+	}
+	// Removing the only acme db rule falls back to the global workspace,
+	// which has no db rule.
+	g = report.Groups[1]
+	if g.ToolName != "db__query" || g.Before != OutcomeAllow || g.After != OutcomeNoRoute || len(g.AfterRules) != 0 {
+		t.Errorf("db group = %+v", g)
+	}
+}
+
+func TestRuleSetDiff_Validate(t *testing.T) {
+	for _, d := range []RuleSetDiff{
+		{},
+		{Upsert: []store.RouteRule{{ID: "x", Policy: "allow"}}},
+		{Upsert: []store.RouteRule{{ID: "x", WorkspaceID: "w", Policy: "maybe"}}},
+		{Upsert: []store.RouteRule{{ID: "x", WorkspaceID: "w", Policy: "deny", Conditions: json.RawMessage(`["a =="]`)}}},
+	} {
+		if err := d.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want error", d)
+		}
+	}
+	if err := (&RuleSetDiff{Delete: []string{"x"}}).Validate(); err != nil {
+		t.Errorf("delete-only diff: %v", err)
+	}
+}
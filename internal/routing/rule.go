@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/revittco/mcplexer/internal/gitinfo"
 	"github.com/revittco/mcplexer/internal/store"
 )
 
@@ -15,6 +17,21 @@ type parsedRule struct {
 	specificity     int
 	toolSpecificity int
 	namespace       string // tool_namespace from downstream server
+	conditions      []*Condition
+	conditionErr    error // set if the stored conditions do not compile
+	git             *GitMatch
+	gitErr          error // set if the stored git_match is invalid
+	schedule        *Schedule
+	scheduleErr     error // set if the stored schedule is invalid
+	rateLimit       *RateLimit
+	rateLimitErr    error // set if the stored rate_limit is invalid
+}
+
+// binds reports whether the rule is a final deny. Only denies can be
+// final: a final allow would let an ancestor override its descendants'
+// denies, so the flag is ignored on allow rules (and Lint reports it).
+func (r *parsedRule) binds() bool {
+	return r.Final && r.Policy == "deny"
 }
 
 // parseRules converts store RouteRules into parsedRules.
@@ -27,6 +44,10 @@ func parseRules(rules []store.RouteRule) []parsedRule {
 		}
 		pr.toolPatterns = parseToolMatch(r.ToolMatch)
 		pr.toolSpecificity = calculateToolSpecificity(pr.toolPatterns)
+		pr.conditions, pr.conditionErr = ParseConditions(r.Conditions)
+		pr.git, pr.gitErr = ParseGitMatch(r.GitMatch)
+		pr.schedule, pr.scheduleErr = ParseSchedule(r.Schedule)
+		pr.rateLimit, pr.rateLimitErr = ParseRateLimit(r.RateLimit)
 		out = append(out, pr)
 	}
 	return out
@@ -70,18 +91,26 @@ func parseToolMatch(raw json.RawMessage) []string {
 }
 
 // sortRules sorts parsed rules by:
-// 1. Glob specificity DESC (most specific path always wins)
-// 2. Tool specificity DESC
-// 3. Priority DESC (tiebreak among equal specificity)
-// 4. ID ASC (stable tiebreak)
+// 1. Final denies first (they cannot be overridden)
+// 2. Glob specificity DESC (most specific path always wins)
+// 3. Tool specificity DESC
+// 4. Rules with conditions, git_match or a schedule first (they narrow an otherwise identical rule)
+// 5. Priority DESC (tiebreak among equal specificity)
+// 6. ID ASC (stable tiebreak)
 func sortRules(rules []parsedRule) {
 	sort.SliceStable(rules, func(i, j int) bool {
+		if bi, bj := rules[i].binds(), rules[j].binds(); bi != bj {
+			return bi
+		}
 		if rules[i].specificity != rules[j].specificity {
 			return rules[i].specificity > rules[j].specificity
 		}
 		if rules[i].toolSpecificity != rules[j].toolSpecificity {
 			return rules[i].toolSpecificity > rules[j].toolSpecificity
 		}
+		if ni, nj := rules[i].narrowed(), rules[j].narrowed(); ni != nj {
+			return ni
+		}
 		if rules[i].Priority != rules[j].Priority {
 			return rules[i].Priority > rules[j].Priority
 		}
@@ -107,3 +136,92 @@ func matchTool(toolName string, patterns []string) bool {
 	}
 	return false
 }
+
+// hasConditions reports whether the rule is restricted by argument
+// conditions, including ones that failed to compile.
+func (r *parsedRule) hasConditions() bool {
+	return len(r.conditions) > 0 || r.conditionErr != nil
+}
+
+// narrowed reports whether the rule is restricted beyond its path and
+// tool patterns.
+func (r *parsedRule) narrowed() bool {
+	return r.hasConditions() || r.git != nil || r.gitErr != nil ||
+		r.schedule != nil || r.scheduleErr != nil
+}
+
+// matchGit checks the rule's git_match against the session's repository.
+// An invalid git_match fails closed like an invalid condition, and so does
+// a dirty constraint when the dirty state could not be read.
+func (r *parsedRule) matchGit(info *gitinfo.Info) bool {
+	if r.gitErr != nil {
+		return r.Policy == "deny"
+	}
+	return r.git == nil || r.git.match(info, r.Policy == "deny")
+}
+
+// matchSchedule checks the rule's schedule at now. An invalid schedule
+// fails closed like an invalid condition.
+func (r *parsedRule) matchSchedule(now time.Time) bool {
+	if r.scheduleErr != nil {
+		return r.Policy == "deny"
+	}
+	return r.schedule == nil || r.schedule.Active(now)
+}
+
+// matchConditions evaluates the rule's conditions against the call
+// arguments. args is nil when routing without a call (e.g. filtering
+// tools/list): conditioned allow rules then match optimistically and
+// conditioned deny rules are skipped, since either may apply to some call.
+// Conditions that fail to compile make deny rules match and allow rules
+// not, so a broken rule fails closed.
+func (r *parsedRule) matchConditions(args *lazyArgs) bool {
+	if !r.hasConditions() {
+		return true
+	}
+	deny := r.Policy == "deny"
+	if !args.present() {
+		return !deny && r.conditionErr == nil
+	}
+	if r.conditionErr != nil {
+		return deny
+	}
+	v := args.value()
+	for _, c := range r.conditions {
+		if !c.Eval(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// conditionStrings returns the source of the rule's conditions.
+func (r *parsedRule) conditionStrings() []string {
+	if len(r.conditions) == 0 {
+		return nil
+	}
+	out := make([]string, len(r.conditions))
+	for i, c := range r.conditions {
+		out[i] = c.String()
+	}
+	return out
+}
+
+// lazyArgs decodes call arguments on first use, so calls that only hit
+// unconditioned rules never pay for it.
+type lazyArgs struct {
+	raw     json.RawMessage
+	decoded bool
+	v       any
+}
+
+func (a *lazyArgs) present() bool { return a.raw != nil }
+
+func (a *lazyArgs) value() any {
+	if !a.decoded {
+		a.decoded = true
+		// Undecodable arguments behave like an empty call: every path is null.
+		_ = json.Unmarshal(a.raw, &a.v)
+	}
+	return a.v
+}
@@ -0,0 +1,200 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule restricts a route rule to time windows. A rule with a schedule
+// only matches while the current time is inside one of its windows, or
+// outside all of them when Outside is set.
+//
+// Each window is "<days> <HH:MM>-<HH:MM>", where days is a comma list of
+// weekday names or ranges ("Mon-Fri", "Sat,Sun", "*"). Either part may be
+// omitted: "Sat,Sun" covers whole days and "09:00-17:00" covers every day.
+// A range that ends before it starts ("22:00-06:00") runs past midnight
+// into the next day.
+type Schedule struct {
+	Timezone string   `json:"timezone,omitempty" yaml:"timezone,omitempty"` // IANA name; default local time
+	Windows  []string `json:"windows" yaml:"windows"`
+	Outside  bool     `json:"outside,omitempty" yaml:"outside,omitempty"`
+
+	loc     *time.Location
+	windows []timeWindow
+}
+
+// timeWindow is a compiled schedule window. Minutes are since midnight;
+// end may be 24*60.
+type timeWindow struct {
+	days       [7]bool // indexed by time.Weekday
+	start, end int
+}
+
+// ParseSchedule decodes and compiles a rule's schedule column. Empty
+// input and "{}" yield nil.
+func ParseSchedule(raw json.RawMessage) (*Schedule, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" || string(raw) == "{}" {
+		return nil, nil
+	}
+	var s Schedule
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("schedule: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Validate compiles the timezone and windows, reporting the first error.
+func (s *Schedule) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if len(s.Windows) == 0 {
+		return fmt.Errorf("schedule: at least one window is required")
+	}
+	loc := time.Local
+	if s.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("schedule: unknown timezone %q", s.Timezone)
+		}
+	}
+	windows := make([]timeWindow, 0, len(s.Windows))
+	for i, w := range s.Windows {
+		tw, err := parseWindow(w)
+		if err != nil {
+			return fmt.Errorf("schedule.windows[%d]: %w", i, err)
+		}
+		windows = append(windows, tw)
+	}
+	s.loc, s.windows = loc, windows
+	return nil
+}
+
+// Active reports whether the rule applies at t.
+func (s *Schedule) Active(t time.Time) bool {
+	if s.windows == nil && s.Validate() != nil {
+		return false
+	}
+	t = t.In(s.loc)
+	day := t.Weekday()
+	minute := t.Hour()*60 + t.Minute()
+	inside := false
+	for _, w := range s.windows {
+		if w.contains(day, minute) {
+			inside = true
+			break
+		}
+	}
+	return inside != s.Outside
+}
+
+// String summarizes the schedule, e.g. "outside Mon-Fri 09:00-17:00 (Europe/London)".
+func (s *Schedule) String() string {
+	var b strings.Builder
+	if s.Outside {
+		b.WriteString("outside ")
+	}
+	b.WriteString(strings.Join(s.Windows, ", "))
+	if s.Timezone != "" {
+		b.WriteString(" (" + s.Timezone + ")")
+	}
+	return b.String()
+}
+
+func (w timeWindow) contains(day time.Weekday, minute int) bool {
+	if w.start < w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	// Overnight: the tail after midnight belongs to the previous day.
+	prev := (day + 6) % 7
+	return (w.days[day] && minute >= w.start) || (w.days[prev] && minute < w.end)
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func parseWindow(s string) (timeWindow, error) {
+	w := timeWindow{start: 0, end: 24 * 60}
+	fields := strings.Fields(s)
+	var days, hours string
+	switch {
+	case len(fields) == 1 && strings.Contains(fields[0], ":"):
+		days, hours = "*", fields[0]
+	case len(fields) == 1:
+		days = fields[0]
+	case len(fields) == 2:
+		days, hours = fields[0], fields[1]
+	default:
+		return w, fmt.Errorf("invalid window %q", s)
+	}
+
+	if err := parseDays(days, &w.days); err != nil {
+		return w, fmt.Errorf("window %q: %w", s, err)
+	}
+	if hours != "" {
+		from, to, ok := strings.Cut(hours, "-")
+		var err1, err2 error
+		w.start, err1 = parseClock(from)
+		w.end, err2 = parseClock(to)
+		if !ok || err1 != nil || err2 != nil || w.start == w.end || w.start == 24*60 {
+			return w, fmt.Errorf("window %q: invalid time range %q", s, hours)
+		}
+	}
+	return w, nil
+}
+
+func parseDays(s string, days *[7]bool) error {
+	if s == "*" || strings.EqualFold(s, "daily") {
+		for i := range days {
+			days[i] = true
+		}
+		return nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		start, ok := weekdays[strings.ToLower(from)]
+		if !ok {
+			return fmt.Errorf("unknown day %q", from)
+		}
+		end := start
+		if isRange {
+			if end, ok = weekdays[strings.ToLower(to)]; !ok {
+				return fmt.Errorf("unknown day %q", to)
+			}
+		}
+		// Ranges may wrap around the week, e.g. "Fri-Mon".
+		for d := start; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == end {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseClock parses "HH:MM" (00:00 to 24:00) into minutes since midnight.
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(s, ":")
+	if !ok || len(h) == 0 || len(h) > 2 || len(m) != 2 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	hh, err1 := strconv.Atoi(h)
+	mm, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hh < 0 || mm < 0 || mm > 59 || hh*60+mm > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return hh*60 + mm, nil
+}
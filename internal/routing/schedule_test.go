@@ -0,0 +1,108 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+func TestSchedule_Active(t *testing.T) {
+	tests := []struct {
+		windows []string
+		outside bool
+		at      string // UTC; 2026-03-02 is a Monday
+		want    bool
+	}{
+		{[]string{"Mon-Fri 09:00-17:00"}, false, "2026-03-02T09:00:00Z", true},
+		{[]string{"Mon-Fri 09:00-17:00"}, false, "2026-03-02T17:00:00Z", false},
+		{[]string{"Mon-Fri 09:00-17:00"}, false, "2026-03-07T12:00:00Z", false},
+		{[]string{"Mon-Fri 09:00-17:00"}, true, "2026-03-07T12:00:00Z", true},
+		{[]string{"Sat,Sun"}, false, "2026-03-08T23:59:00Z", true},
+		{[]string{"09:00-10:00"}, false, "2026-03-08T09:30:00Z", true},
+		{[]string{"Fri-Mon"}, false, "2026-03-04T12:00:00Z", false},
+		{[]string{"Fri-Mon"}, false, "2026-03-02T12:00:00Z", true},
+		// Overnight windows run into the next day.
+		{[]string{"Fri 22:00-06:00"}, false, "2026-03-06T23:00:00Z", true},
+		{[]string{"Fri 22:00-06:00"}, false, "2026-03-07T05:59:00Z", true},
+		{[]string{"Fri 22:00-06:00"}, false, "2026-03-07T06:00:00Z", false},
+		{[]string{"Fri 22:00-06:00"}, false, "2026-03-05T23:00:00Z", false},
+		{[]string{"* 00:00-24:00"}, false, "2026-03-05T23:59:00Z", true},
+		{[]string{"Sat", "Mon 08:00-09:00"}, false, "2026-03-02T08:30:00Z", true},
+	}
+	for _, tt := range tests {
+		s := &Schedule{Timezone: "UTC", Windows: tt.windows, Outside: tt.outside}
+		if err := s.Validate(); err != nil {
+			t.Fatalf("%v: %v", tt.windows, err)
+		}
+		at, _ := time.Parse(time.RFC3339, tt.at)
+		if got := s.Active(at); got != tt.want {
+			t.Errorf("%v outside=%v at %s = %v, want %v", tt.windows, tt.outside, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestSchedule_Timezone(t *testing.T) {
+	s, err := ParseSchedule(json.RawMessage(`{"timezone":"America/New_York","windows":["Mon-Fri 09:00-17:00"]}`))
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	// 13:30 UTC on a Monday in March (EST) is 08:30 in New York.
+	if s.Active(time.Date(2026, 3, 2, 13, 30, 0, 0, time.UTC)) {
+		t.Error("08:30 New York should be outside 09:00-17:00")
+	}
+	if !s.Active(time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)) {
+		t.Error("09:30 New York should be inside 09:00-17:00")
+	}
+}
+
+func TestParseSchedule_Errors(t *testing.T) {
+	for _, raw := range []string{
+		`{"windows":[]}`,
+		`{"windows":["Mon-Fry"]}`,
+		`{"windows":["Mon 9-17"]}`,
+		`{"windows":["Mon 09:00-09:00"]}`,
+		`{"windows":["Mon 09:00-25:00"]}`,
+		`{"windows":["Mon 09:00-17:00 extra"]}`,
+		`{"timezone":"Mars/Olympus","windows":["Mon"]}`,
+		`{"window":["Mon"]}`,
+	} {
+		if _, err := ParseSchedule(json.RawMessage(raw)); err == nil {
+			t.Errorf("ParseSchedule(%s) succeeded, want error", raw)
+		}
+	}
+	for _, raw := range []string{``, `null`, `{}`} {
+		if s, err := ParseSchedule(json.RawMessage(raw)); err != nil || s != nil {
+			t.Errorf("ParseSchedule(%q) = %v, %v; want nil", raw, s, err)
+		}
+	}
+}
+
+func TestMatchRoute_Schedule(t *testing.T) {
+	rules := parseRules([]store.RouteRule{
+		{
+			ID: "db-after-hours", PathGlob: "**", ToolMatch: json.RawMessage(`["prod_db__*"]`),
+			Policy:   "deny",
+			Schedule: json.RawMessage(`{"timezone":"UTC","windows":["Mon-Fri 09:00-18:00"],"outside":true}`),
+		},
+		{
+			ID: "db", PathGlob: "**", ToolMatch: json.RawMessage(`["prod_db__*"]`),
+			DownstreamServerID: "db", Policy: "allow",
+		},
+	})
+	sortRules(rules)
+
+	monday := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	result, err := matchRoute(rules, RouteContext{ToolName: "prod_db__query", Now: monday})
+	if err != nil || result.MatchedRuleID != "db" || result.MatchedSchedule != nil {
+		t.Fatalf("business hours: %+v, %v", result, err)
+	}
+
+	_, err = matchRoute(rules, RouteContext{ToolName: "prod_db__query", Now: monday.Add(10 * time.Hour)})
+	var de *DeniedError
+	if !errors.As(err, &de) || de.RuleID != "db-after-hours" || de.Schedule == nil {
+		t.Fatalf("after hours: err = %v", err)
+	}
+}
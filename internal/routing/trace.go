@@ -0,0 +1,170 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Trace results for a rule considered during routing. A rule either
+// matched or was skipped for the first check it failed.
+const (
+	TraceMatched        = "matched"
+	TraceSkipGlob       = "glob"
+	TraceSkipTool       = "tool"
+	TraceSkipNamespace  = "namespace"
+	TraceSkipGit        = "git"
+	TraceSkipSchedule   = "schedule"
+	TraceSkipConditions = "conditions"
+)
+
+// Trace records how a routing decision was reached: each workspace tried,
+// most specific first, and each rule considered in it. Set
+// RouteContext.Trace to collect one.
+type Trace struct {
+	ToolName   string           `json:"tool_name"`
+	Outcome    string           `json:"outcome"` // OutcomeAllow, OutcomeDeny or OutcomeNoRoute
+	RuleID     string           `json:"rule_id,omitempty"`
+	Workspaces []TraceWorkspace `json:"workspaces"`
+}
+
+// TraceWorkspace is one workspace tried while routing.
+type TraceWorkspace struct {
+	ID      string      `json:"id"`
+	Name    string      `json:"name,omitempty"`
+	Subpath string      `json:"subpath"`
+	Final   bool        `json:"final,omitempty"` // only final rules were tried
+	Stop    string      `json:"stop,omitempty"`  // inheritance mode that ended the search here
+	Rules   []TraceRule `json:"rules"`
+}
+
+// TraceRule is one rule considered, in evaluation order.
+type TraceRule struct {
+	RuleID string `json:"rule_id"`
+	Policy string `json:"policy"`
+	Result string `json:"result"` // TraceMatched or a TraceSkip* reason
+	Detail string `json:"detail,omitempty"`
+}
+
+// enter starts a new workspace in the trace. It is a no-op on a nil Trace.
+func (t *Trace) enter(rc RouteContext) *TraceWorkspace {
+	if t == nil {
+		return nil
+	}
+	t.ToolName = rc.ToolName
+	t.Workspaces = append(t.Workspaces, TraceWorkspace{
+		ID: rc.WorkspaceID, Name: rc.workspaceName, Subpath: rc.Subpath,
+		Final: rc.phase == phaseFinal, Rules: []TraceRule{},
+	})
+	return &t.Workspaces[len(t.Workspaces)-1]
+}
+
+// finish records the outcome of a Route call. The last call wins, so
+// after RouteWithFallback it holds the overall decision.
+func (t *Trace) finish(result *RouteResult, err error) {
+	if t == nil {
+		return
+	}
+	var de *DeniedError
+	switch {
+	case err == nil:
+		t.Outcome, t.RuleID = OutcomeAllow, result.MatchedRuleID
+	case errors.As(err, &de):
+		t.Outcome, t.RuleID = OutcomeDeny, de.RuleID
+	default:
+		t.Outcome, t.RuleID = OutcomeNoRoute, ""
+	}
+}
+
+// stop records that the last workspace's inheritance mode ended the
+// search before its ancestors were tried.
+func (t *Trace) stop(mode string) {
+	if t == nil || len(t.Workspaces) == 0 {
+		return
+	}
+	t.Workspaces[len(t.Workspaces)-1].Stop = mode
+}
+
+// add records a rule's result. It is a no-op on a nil workspace.
+func (tw *TraceWorkspace) add(r *parsedRule, result, detail string) {
+	if tw == nil {
+		return
+	}
+	tw.Rules = append(tw.Rules, TraceRule{RuleID: r.ID, Policy: r.Policy, Result: result, Detail: detail})
+}
+
+// skip records why rule r did not match.
+func (tw *TraceWorkspace) skip(r *parsedRule, reason string) {
+	if tw == nil {
+		return
+	}
+	var detail string
+	switch reason {
+	case TraceSkipGlob:
+		detail = fmt.Sprintf("path_glob %q does not match subpath %q", r.PathGlob, tw.Subpath)
+	case TraceSkipTool:
+		detail = "tool_match " + strings.Join(r.toolPatterns, ", ")
+	case TraceSkipNamespace:
+		detail = fmt.Sprintf("tool is outside the server's namespace %q", r.namespace)
+	case TraceSkipGit:
+		if r.gitErr != nil {
+			detail = r.gitErr.Error()
+		} else {
+			b, _ := json.Marshal(r.git)
+			detail = "git_match " + string(b)
+		}
+	case TraceSkipSchedule:
+		if r.scheduleErr != nil {
+			detail = r.scheduleErr.Error()
+		} else {
+			detail = "schedule " + r.schedule.String()
+		}
+	case TraceSkipConditions:
+		if r.conditionErr != nil {
+			detail = r.conditionErr.Error()
+		} else {
+			detail = strings.Join(r.conditionStrings(), " && ")
+		}
+	}
+	tw.add(r, reason, detail)
+}
+
+// String renders the trace as indented text, one line per rule.
+func (t *Trace) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", t.ToolName, t.Outcome)
+	if t.RuleID != "" {
+		fmt.Fprintf(&b, " by rule %s", t.RuleID)
+	}
+	b.WriteString("\n")
+	for _, ws := range t.Workspaces {
+		name := ws.Name
+		if name == "" {
+			name = ws.ID
+		}
+		label := "workspace"
+		if ws.Final {
+			label = "final rules of workspace"
+		}
+		fmt.Fprintf(&b, "\n%s %s (subpath %q):\n", label, name, ws.Subpath)
+		if len(ws.Rules) == 0 {
+			b.WriteString("  no rules\n")
+		}
+		for _, r := range ws.Rules {
+			if r.Result == TraceMatched {
+				fmt.Fprintf(&b, "  %s [%s] matched", r.RuleID, r.Policy)
+			} else {
+				fmt.Fprintf(&b, "  %s [%s] skipped (%s)", r.RuleID, r.Policy, r.Result)
+			}
+			if r.Detail != "" {
+				fmt.Fprintf(&b, ": %s", r.Detail)
+			}
+			b.WriteString("\n")
+		}
+		if ws.Stop != "" {
+			fmt.Fprintf(&b, "  not falling back to ancestors (inherit_mode %s)\n", ws.Stop)
+		}
+	}
+	return b.String()
+}
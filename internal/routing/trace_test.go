@@ -0,0 +1,147 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+func TestRouteWithFallback_Trace(t *testing.T) {
+	s := &mockRouteStore{
+		rules: map[string][]store.RouteRule{
+			"acme": {
+				rrule("acme-docs", "acme", "docs/**", "allow", 0, tm("github__*"), "gh"),
+				rrule("acme-slack", "acme", "**", "allow", 0, tm("slack__*"), "gh"),
+			},
+			"global": {
+				{
+					ID: "global-gh-api", WorkspaceID: "global", PathGlob: "**", Policy: "allow",
+					ToolMatch: tm("github__*"), DownstreamServerID: "gh",
+					Conditions: json.RawMessage(`["repo == \"api\""]`),
+				},
+				rrule("global-deny", "global", "**", "deny", 0, tm("*"), ""),
+			},
+		},
+		downstreams: map[string]*store.DownstreamServer{
+			"gh": {ID: "gh", ToolNamespace: "github"},
+		},
+	}
+	engine := NewEngine(s)
+	ancestors := []WorkspaceAncestor{
+		{ID: "acme", Name: "Acme", RootPath: "/src/acme"},
+		{ID: "global", Name: "Global", RootPath: "/"},
+	}
+
+	trace := &Trace{}
+	_, err := engine.RouteWithFallback(context.Background(), RouteContext{
+		ToolName:  "github__push_files",
+		Arguments: json.RawMessage(`{"repo":"web"}`),
+		Trace:     trace,
+	}, "/src/acme/services", ancestors)
+	if !errors.Is(err, ErrDenied) {
+		t.Fatalf("err = %v, want denied", err)
+	}
+
+	if trace.Outcome != OutcomeDeny || trace.RuleID != "global-deny" || trace.ToolName != "github__push_files" {
+		t.Fatalf("trace = %+v", trace)
+	}
+	if len(trace.Workspaces) != 2 {
+		t.Fatalf("workspaces = %+v", trace.Workspaces)
+	}
+	acme, global := trace.Workspaces[0], trace.Workspaces[1]
+	if acme.Name != "Acme" || acme.Subpath != "services" || global.Subpath != "src/acme/services" {
+		t.Errorf("workspaces = %+v", trace.Workspaces)
+	}
+
+	got := func(ws TraceWorkspace) []string {
+		var out []string
+		for _, r := range ws.Rules {
+			out = append(out, r.RuleID+":"+r.Result)
+		}
+		return out
+	}
+	if g := strings.Join(got(acme), " "); g != "acme-docs:glob acme-slack:tool" {
+		t.Errorf("acme rules = %s", g)
+	}
+	if g := strings.Join(got(global), " "); g != "global-gh-api:conditions global-deny:matched" {
+		t.Errorf("global rules = %s", g)
+	}
+
+	text := trace.String()
+	for _, want := range []string{
+		"github__push_files: deny by rule global-deny",
+		`acme-docs [allow] skipped (glob): path_glob "docs/**" does not match subpath "services"`,
+		`global-gh-api [allow] skipped (conditions): repo == "api"`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("String() missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestMatchRoute_TraceNamespace(t *testing.T) {
+	rules := parseRules([]store.RouteRule{
+		rrule("gh-any", "ws", "**", "allow", 0, tm("*"), "gh"),
+	})
+	rules[0].namespace = "github"
+
+	trace := &Trace{}
+	_, err := matchRoute(rules, RouteContext{WorkspaceID: "ws", ToolName: "slack__post", Trace: trace})
+	trace.finish(nil, err)
+	if trace.Outcome != OutcomeNoRoute || len(trace.Workspaces) != 1 {
+		t.Fatalf("trace = %+v", trace)
+	}
+	r := trace.Workspaces[0].Rules[0]
+	if r.Result != TraceSkipNamespace || !strings.Contains(r.Detail, `"github"`) {
+		t.Errorf("rule = %+v", r)
+	}
+}
+
+func TestRouteWithFallback_TraceInheritance(t *testing.T) {
+	orgFinal := rrule("org-final", "org", "**", "deny", 0, tm("db__drop*"), "")
+	orgFinal.Final = true
+	engine := NewEngine(&mockRouteStore{
+		rules: map[string][]store.RouteRule{
+			"org": {orgFinal, rrule("org-slack", "org", "**", "allow", 0, tm("slack__*"), "slack")},
+			"team": {
+				rrule("team-gh", "team", "**", "allow", 0, tm("github__*"), "gh"),
+			},
+		},
+	})
+	ancestors := []WorkspaceAncestor{
+		{ID: "team", Name: "Team", RootPath: "/org/team", InheritMode: InheritModeIsolate},
+		{ID: "org", Name: "Org", RootPath: "/org"},
+	}
+
+	trace := &Trace{}
+	_, err := engine.RouteWithFallback(context.Background(), RouteContext{
+		ToolName: "slack__post", Trace: trace,
+	}, "/org/team", ancestors)
+	if !errors.Is(err, ErrNoRoute) {
+		t.Fatalf("err = %v, want no route", err)
+	}
+	if len(trace.Workspaces) != 2 {
+		t.Fatalf("workspaces = %+v", trace.Workspaces)
+	}
+	final, team := trace.Workspaces[0], trace.Workspaces[1]
+	if final.ID != "org" || !final.Final || len(final.Rules) != 1 || final.Rules[0].Result != TraceSkipTool {
+		t.Errorf("final pass = %+v", final)
+	}
+	if team.ID != "team" || team.Final || team.Stop != InheritModeIsolate {
+		t.Errorf("team = %+v", team)
+	}
+
+	text := trace.String()
+	for _, want := range []string{
+		"final rules of workspace Org",
+		"not falling back to ancestors (inherit_mode isolate)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("trace text missing %q:\n%s", want, text)
+		}
+	}
+}
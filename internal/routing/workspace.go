@@ -0,0 +1,195 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/revittco/mcplexer/internal/gitinfo"
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+// WorkspaceMatchers are additional ways a workspace matches a client
+// directory besides its root_path, so one workspace can follow a
+// repository wherever it is checked out. Each matcher also fixes the
+// directory that rule path globs are relative to.
+type WorkspaceMatchers struct {
+	// Roots are further literal root paths, e.g. a second clone.
+	Roots []string `json:"roots,omitempty" yaml:"roots,omitempty"`
+
+	// PathGlobs are globs over absolute directories, e.g.
+	// "/tmp/**/acme-api" or "/home/*/src/acme-*". Segments may use
+	// path.Match wildcards and "**" spans segments. The deepest directory
+	// containing the client root that matches becomes the workspace root.
+	PathGlobs []string `json:"path_globs,omitempty" yaml:"path_globs,omitempty"`
+
+	// GitRemotes are globs over the origin remote of the repository
+	// containing the client root: "owner/repo", or "host/owner/repo" when
+	// the first segment contains a dot, e.g. "github.com/acme/*". Matching
+	// is case-insensitive; the worktree root becomes the workspace root.
+	GitRemotes []string `json:"git_remotes,omitempty" yaml:"git_remotes,omitempty"`
+}
+
+// ParseWorkspaceMatchers decodes a workspace's matchers column. Empty input
+// and "{}" yield nil.
+func ParseWorkspaceMatchers(raw json.RawMessage) (*WorkspaceMatchers, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" || string(raw) == "{}" {
+		return nil, nil
+	}
+	var m WorkspaceMatchers
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("matchers: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if m.IsZero() {
+		return nil, nil
+	}
+	return &m, nil
+}
+
+// IsZero reports whether m adds no matchers.
+func (m *WorkspaceMatchers) IsZero() bool {
+	return m == nil || len(m.Roots)+len(m.PathGlobs)+len(m.GitRemotes) == 0
+}
+
+// Validate checks that roots and path globs are absolute and that every
+// glob parses.
+func (m *WorkspaceMatchers) Validate() error {
+	if m == nil {
+		return nil
+	}
+	for i, r := range m.Roots {
+		if !path.IsAbs(r) {
+			return fmt.Errorf("matchers.roots[%d]: %q is not an absolute path", i, r)
+		}
+	}
+	for i, g := range m.PathGlobs {
+		if !path.IsAbs(g) {
+			return fmt.Errorf("matchers.path_globs[%d]: %q is not an absolute path", i, g)
+		}
+		if err := validateSegments(g); err != nil {
+			return fmt.Errorf("matchers.path_globs[%d]: %w", i, err)
+		}
+	}
+	for i, g := range m.GitRemotes {
+		if strings.Trim(g, "/") == "" {
+			return fmt.Errorf("matchers.git_remotes[%d]: empty pattern", i)
+		}
+		if err := validateSegments(g); err != nil {
+			return fmt.Errorf("matchers.git_remotes[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateSegments(pattern string) error {
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("invalid glob %q", pattern)
+		}
+	}
+	return nil
+}
+
+// root returns the root a matcher assigns to dir, preferring the deepest
+// one, or false if no matcher applies. git may be nil.
+func (m *WorkspaceMatchers) root(dir string, git *gitinfo.Info) (string, bool) {
+	best, ok := "", false
+	consider := func(root string) {
+		if !ok || len(root) > len(best) {
+			best, ok = root, true
+		}
+	}
+	for _, r := range m.Roots {
+		if isPathAncestor(r, dir) {
+			consider(r)
+		}
+	}
+	for _, g := range m.PathGlobs {
+		if r, found := globAncestor(g, dir); found {
+			consider(r)
+		}
+	}
+	if git != nil && git.Root != "" && isPathAncestor(git.Root, dir) {
+		for _, g := range m.GitRemotes {
+			if remoteMatch(g, git.Remote) {
+				consider(git.Root)
+				break
+			}
+		}
+	}
+	return best, ok
+}
+
+// globAncestor returns the deepest directory that contains dir (or is dir)
+// and matches glob.
+func globAncestor(glob, dir string) (string, bool) {
+	pat := strings.Split(strings.Trim(glob, "/"), "/")
+	for d := path.Clean(dir); ; d = path.Dir(d) {
+		if segmentsMatch(pat, strings.Split(strings.Trim(d, "/"), "/")) {
+			return d, true
+		}
+		if d == "/" || d == "." {
+			return "", false
+		}
+	}
+}
+
+// remoteMatch matches a git_remotes pattern against a parsed remote.
+func remoteMatch(pattern string, r gitinfo.Remote) bool {
+	name := r.Slug()
+	if first, _, _ := strings.Cut(pattern, "/"); strings.Contains(first, ".") {
+		if r.Host == "" {
+			return false
+		}
+		name = r.Host + "/" + name
+	}
+	return refGlobMatch(strings.ToLower(strings.Trim(pattern, "/")), strings.ToLower(name))
+}
+
+// isPathAncestor reports whether ancestor is dir or one of its parents.
+func isPathAncestor(ancestor, dir string) bool {
+	ancestor = strings.TrimSuffix(ancestor, "/")
+	dir = strings.TrimSuffix(dir, "/")
+	return ancestor == "" || ancestor == dir || strings.HasPrefix(dir, ancestor+"/")
+}
+
+// ResolveWorkspaceChain returns the workspaces matching dir, most specific
+// (deepest root) first. A workspace matches when its root_path contains dir
+// or one of its matchers applies; the deepest matching root is used to
+// compute rule subpaths. git is the repository containing dir, or nil.
+// Workspaces whose matchers fail to parse match by root_path only.
+func ResolveWorkspaceChain(workspaces []store.Workspace, dir string, git *gitinfo.Info) []WorkspaceAncestor {
+	if dir == "" {
+		return nil
+	}
+	var chain []WorkspaceAncestor
+	for _, ws := range workspaces {
+		root, ok := "", false
+		if ws.RootPath != "" && isPathAncestor(ws.RootPath, dir) {
+			root, ok = ws.RootPath, true
+		}
+		if m, err := ParseWorkspaceMatchers(ws.Matchers); err == nil && m != nil {
+			if r, found := m.root(dir, git); found && (!ok || len(r) > len(root)) {
+				root, ok = r, true
+			}
+		}
+		if ok {
+			chain = append(chain, WorkspaceAncestor{
+				ID: ws.ID, Name: ws.Name, RootPath: root, InheritMode: ws.InheritMode,
+			})
+		}
+	}
+	sort.SliceStable(chain, func(i, j int) bool {
+		return len(chain[i].RootPath) > len(chain[j].RootPath)
+	})
+	return chain
+}
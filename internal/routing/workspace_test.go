@@ -0,0 +1,103 @@
+package routing
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/revittco/mcplexer/internal/gitinfo"
+	"github.com/revittco/mcplexer/internal/store"
+)
+
+func TestParseWorkspaceMatchers(t *testing.T) {
+	tests := []struct {
+		raw     string
+		wantNil bool
+		wantErr string
+	}{
+		{"", true, ""},
+		{"{}", true, ""},
+		{`{"roots":[]}`, true, ""},
+		{`{"roots":["/srv/acme"],"path_globs":["/tmp/**/acme-*"],"git_remotes":["github.com/acme/*"]}`, false, ""},
+		{`{"roots":["src/acme"]}`, false, "not an absolute path"},
+		{`{"path_globs":["/tmp/[acme"]}`, false, "invalid glob"},
+		{`{"git_remotes":["/"]}`, false, "empty pattern"},
+		{`{"remotes":["acme/api"]}`, false, "unknown field"},
+	}
+	for _, tt := range tests {
+		m, err := ParseWorkspaceMatchers(json.RawMessage(tt.raw))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.raw, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.raw, err)
+			continue
+		}
+		if (m == nil) != tt.wantNil {
+			t.Errorf("%s: matchers = %+v, want nil %v", tt.raw, m, tt.wantNil)
+		}
+	}
+}
+
+func TestResolveWorkspaceChain(t *testing.T) {
+	git := &gitinfo.Info{
+		Root:   "/tmp/wt/feature-x",
+		Remote: gitinfo.Remote{Host: "github.com", Owner: "Acme", Repo: "api"},
+	}
+	workspaces := []store.Workspace{
+		{ID: "global", RootPath: "/"},
+		{ID: "home", RootPath: "/home/dev"},
+		{ID: "api", RootPath: "/home/dev/src/api", Matchers: json.RawMessage(
+			`{"roots":["/workspaces/api"],"path_globs":["/tmp/**/api-*"],"git_remotes":["github.com/acme/api"]}`)},
+		{ID: "web", Matchers: json.RawMessage(`{"git_remotes":["acme/web"]}`)},
+		{ID: "broken", RootPath: "/opt/broken", Matchers: json.RawMessage(`{"roots":"nope"}`)},
+	}
+
+	tests := []struct {
+		name, dir string
+		git       *gitinfo.Info
+		want      []string // id=root, most specific first
+	}{
+		{"root_path", "/home/dev/src/api/cmd", nil, []string{"api=/home/dev/src/api", "home=/home/dev", "global=/"}},
+		{"extra root", "/workspaces/api/internal", nil, []string{"api=/workspaces/api", "global=/"}},
+		{"path glob picks deepest match", "/tmp/a/api-1/b/api-2/pkg", nil, []string{"api=/tmp/a/api-1/b/api-2", "global=/"}},
+		{"path glob no match", "/tmp/a/web-1", nil, []string{"global=/"}},
+		{"git remote uses worktree root", "/tmp/wt/feature-x/src", git, []string{"api=/tmp/wt/feature-x", "global=/"}},
+		{"git remote ignored outside worktree", "/tmp/other", git, []string{"global=/"}},
+		{"broken matchers fall back to root_path", "/opt/broken/x", nil, []string{"broken=/opt/broken", "global=/"}},
+		{"no dir", "", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, a := range ResolveWorkspaceChain(workspaces, tt.dir, tt.git) {
+				got = append(got, a.ID+"="+a.RootPath)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("chain = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteMatch(t *testing.T) {
+	r := gitinfo.Remote{Host: "gitlab.com", Owner: "acme/platform", Repo: "api"}
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"acme/platform/api", true},
+		{"ACME/**", true},
+		{"acme/*", false},
+		{"gitlab.com/acme/**/api", true},
+		{"github.com/acme/platform/api", false},
+	}
+	for _, tt := range tests {
+		if got := remoteMatch(tt.pattern, r); got != tt.want {
+			t.Errorf("remoteMatch(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
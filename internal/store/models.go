@@ -10,8 +10,10 @@ type Workspace struct {
 	ID            string          `json:"id"`
 	Name          string          `json:"name"`
 	RootPath      string          `json:"root_path"`
+	Matchers      json.RawMessage `json:"matchers,omitempty"` // extra roots, path globs and git remotes
 	Tags          json.RawMessage `json:"tags,omitempty"`
 	DefaultPolicy string          `json:"default_policy"`
+	InheritMode   string          `json:"inherit_mode"` // inherit, isolate or override
 	Source        string          `json:"source"`
 	CreatedAt     time.Time       `json:"created_at"`
 	UpdatedAt     time.Time       `json:"updated_at"`
@@ -63,13 +65,18 @@ type DownstreamServer struct {
 	Transport         string          `json:"transport"`
 	Command           string          `json:"command"`
 	Args              json.RawMessage `json:"args,omitempty"`
+	Env               json.RawMessage `json:"env,omitempty"` // {"NAME": "value"}, merged under auth-injected env
+	Cwd               string          `json:"cwd,omitempty"` // working directory; may start with ${workspace_root}
 	URL               *string         `json:"url,omitempty"`
+	Headers           json.RawMessage `json:"headers,omitempty"` // {"Name": "value"}, sent under auth-injected headers
 	ToolNamespace     string          `json:"tool_namespace"`
 	Discovery         string          `json:"discovery"` // "static" or "dynamic"
 	CapabilitiesCache json.RawMessage `json:"capabilities_cache,omitempty"`
 	CacheConfig       json.RawMessage `json:"cache_config,omitempty"`
 	IdleTimeoutSec    int             `json:"idle_timeout_sec"`
 	MaxInstances      int             `json:"max_instances"`
+	MaxConcurrency    int             `json:"max_concurrency"` // in-flight requests per instance; 0 = default
+	InstanceScope     string          `json:"instance_scope"`  // "global" (default), "workspace" or "session"
 	RestartPolicy     string          `json:"restart_policy"`
 	Disabled          bool            `json:"disabled"`
 	Source            string          `json:"source"`
@@ -87,12 +94,17 @@ type RouteRule struct {
 	ToolMatch          json.RawMessage `json:"tool_match,omitempty"`
 	AllowedOrgs        json.RawMessage `json:"allowed_orgs,omitempty"`
 	AllowedRepos       json.RawMessage `json:"allowed_repos,omitempty"`
+	Conditions         json.RawMessage `json:"conditions,omitempty"`
+	GitMatch           json.RawMessage `json:"git_match,omitempty"`
+	Schedule           json.RawMessage `json:"schedule,omitempty"`
+	RateLimit          json.RawMessage `json:"rate_limit,omitempty"`
 	DownstreamServerID string          `json:"downstream_server_id"`
 	AuthScopeID        string          `json:"auth_scope_id"`
 	Policy             string          `json:"policy"`
 	LogLevel           string          `json:"log_level"`
 	ApprovalMode       string          `json:"approval_mode"`
 	ApprovalTimeout    int             `json:"approval_timeout"`
+	Final              bool            `json:"final"` // deny that descendant workspaces cannot override; ignored on allows
 	Source             string          `json:"source"`
 	CreatedAt          time.Time       `json:"created_at"`
 	UpdatedAt          time.Time       `json:"updated_at"`
@@ -133,6 +145,10 @@ type AuditRecord struct {
 	CacheHit             bool            `json:"cache_hit"`
 	CreatedAt            time.Time       `json:"created_at"`
 
+	// RouteTrace explains the routing decision (a routing.Trace) for calls
+	// that were denied or matched no route.
+	RouteTrace json.RawMessage `json:"route_trace,omitempty"`
+
 	// Enriched fields for UI
 	RouteRuleSummary     string `json:"route_rule_summary,omitempty"`
 	DownstreamServerName string `json:"downstream_server_name,omitempty"`
@@ -143,6 +159,7 @@ type AuditFilter struct {
 	SessionID   *string    `json:"session_id,omitempty"`
 	WorkspaceID *string    `json:"workspace_id,omitempty"`
 	ToolName    *string    `json:"tool_name,omitempty"`
+	RouteRuleID *string    `json:"route_rule_id,omitempty"`
 	Status      *string    `json:"status,omitempty"`
 	After       *time.Time `json:"after,omitempty"`
 	Before      *time.Time `json:"before,omitempty"`
@@ -196,7 +213,7 @@ type ServerHealthEntry struct {
 type ErrorBreakdownEntry struct {
 	GroupKey   string `json:"group_key"`
 	ServerName string `json:"server_name"`
-	ErrorType  string `json:"error_type"` // "error" or "blocked"
+	ErrorType  string `json:"error_type"` // "error", "blocked" or "rate_limited"
 	Count      int    `json:"count"`
 }
 
@@ -23,6 +23,7 @@ func (d *DB) InsertAuditRecord(ctx context.Context, r *store.AuditRecord) error
 	}
 
 	params := normalizeJSON(r.ParamsRedacted, "{}")
+	trace := normalizeJSON(r.RouteTrace, "")
 
 	cacheHit := 0
 	if r.CacheHit {
@@ -35,13 +36,13 @@ func (d *DB) InsertAuditRecord(ctx context.Context, r *store.AuditRecord) error
 			 workspace_name, subpath, tool_name, params_redacted, route_rule_id,
 			 downstream_server_id, downstream_instance_id, auth_scope_id,
 			 status, error_code, error_message, latency_ms, response_size,
-			 cache_hit, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			 cache_hit, created_at, route_trace)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		r.ID, formatTime(r.Timestamp), r.SessionID, r.ClientType, r.Model,
 		r.WorkspaceID, r.WorkspaceName, r.Subpath, r.ToolName, params, r.RouteRuleID,
 		r.DownstreamServerID, r.DownstreamInstanceID, r.AuthScopeID,
 		r.Status, r.ErrorCode, r.ErrorMessage, r.LatencyMs, r.ResponseSize,
-		cacheHit, formatTime(r.CreatedAt),
+		cacheHit, formatTime(r.CreatedAt), trace,
 	)
 	return err
 }
@@ -68,7 +69,7 @@ func (d *DB) QueryAuditRecords(
 		r.workspace_name, r.subpath, r.tool_name, r.params_redacted, r.route_rule_id,
 		r.downstream_server_id, r.downstream_instance_id, r.auth_scope_id,
 		r.status, r.error_code, r.error_message, r.latency_ms, r.response_size,
-		r.cache_hit, r.created_at,
+		r.cache_hit, r.created_at, r.route_trace,
 		COALESCE(rr.path_glob, '') as route_rule_summary,
 		COALESCE(ds.name, '') as downstream_server_name
 		FROM audit_records r
@@ -343,12 +344,13 @@ func (d *DB) GetErrorBreakdown(
 			COALESCE(ds.name, '') AS server_name,
 			CASE
 				WHEN r.status = 'blocked' THEN 'blocked'
+				WHEN r.status = 'rate_limited' THEN 'rate_limited'
 				ELSE 'error'
 			END AS error_type,
 			COUNT(*) AS cnt
 		FROM audit_records r
 		LEFT JOIN downstream_servers ds ON r.downstream_server_id = ds.id
-		WHERE r.status IN ('error', 'blocked') AND r.timestamp >= ? AND r.timestamp <= ?
+		WHERE r.status IN ('error', 'blocked', 'rate_limited') AND r.timestamp >= ? AND r.timestamp <= ?
 		GROUP BY r.tool_name, error_type
 		ORDER BY cnt DESC
 		LIMIT ?`,
@@ -441,6 +443,10 @@ func buildAuditWhere(f store.AuditFilter) (string, []any) {
 		conds = append(conds, "tool_name = ?")
 		args = append(args, *f.ToolName)
 	}
+	if f.RouteRuleID != nil {
+		conds = append(conds, "route_rule_id = ?")
+		args = append(args, *f.RouteRuleID)
+	}
 	if f.Status != nil {
 		conds = append(conds, "status = ?")
 		args = append(args, *f.Status)
@@ -461,20 +467,23 @@ func buildAuditWhere(f store.AuditFilter) (string, []any) {
 
 func scanAuditRow(row rowScanner) (*store.AuditRecord, error) {
 	var r store.AuditRecord
-	var ts, createdAt, params string
+	var ts, createdAt, params, trace string
 	var cacheHit int
 	err := row.Scan(
 		&r.ID, &ts, &r.SessionID, &r.ClientType, &r.Model,
 		&r.WorkspaceID, &r.WorkspaceName, &r.Subpath, &r.ToolName, &params,
 		&r.RouteRuleID, &r.DownstreamServerID, &r.DownstreamInstanceID,
 		&r.AuthScopeID, &r.Status, &r.ErrorCode, &r.ErrorMessage,
-		&r.LatencyMs, &r.ResponseSize, &cacheHit, &createdAt,
+		&r.LatencyMs, &r.ResponseSize, &cacheHit, &createdAt, &trace,
 		&r.RouteRuleSummary, &r.DownstreamServerName,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("scan audit row: %w", err)
 	}
 	r.ParamsRedacted = json.RawMessage(params)
+	if trace != "" {
+		r.RouteTrace = json.RawMessage(trace)
+	}
 	r.CacheHit = cacheHit != 0
 	r.Timestamp = parseTime(ts)
 	r.CreatedAt = parseTime(createdAt)
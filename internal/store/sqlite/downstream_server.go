@@ -23,6 +23,8 @@ func (d *DB) CreateDownstreamServer(ctx context.Context, ds *store.DownstreamSer
 	args := normalizeJSON(ds.Args, "[]")
 	caps := normalizeJSON(ds.CapabilitiesCache, "{}")
 	cacheCfg := normalizeJSON(ds.CacheConfig, "{}")
+	env := normalizeJSON(ds.Env, "{}")
+	headers := normalizeJSON(ds.Headers, "{}")
 
 	if ds.Discovery == "" {
 		ds.Discovery = "dynamic"
@@ -30,17 +32,21 @@ func (d *DB) CreateDownstreamServer(ctx context.Context, ds *store.DownstreamSer
 	if ds.Source == "" {
 		ds.Source = "api"
 	}
+	if ds.InstanceScope == "" {
+		ds.InstanceScope = "global"
+	}
 
 	_, err := d.q.ExecContext(ctx, `
 		INSERT INTO downstream_servers
 			(id, name, transport, command, args, url, tool_namespace, discovery,
 			 capabilities_cache, cache_config, idle_timeout_sec, max_instances,
-			 restart_policy, disabled, source, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			 max_concurrency, restart_policy, disabled, source, env, cwd, headers,
+			 instance_scope, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		ds.ID, ds.Name, ds.Transport, ds.Command, args, ds.URL,
 		ds.ToolNamespace, ds.Discovery, caps, cacheCfg, ds.IdleTimeoutSec,
-		ds.MaxInstances, ds.RestartPolicy, ds.Disabled, ds.Source,
-		formatTime(ds.CreatedAt), formatTime(ds.UpdatedAt),
+		ds.MaxInstances, ds.MaxConcurrency, ds.RestartPolicy, ds.Disabled, ds.Source,
+		env, ds.Cwd, headers, ds.InstanceScope, formatTime(ds.CreatedAt), formatTime(ds.UpdatedAt),
 	)
 	if err != nil {
 		return mapConstraintError(err)
@@ -52,7 +58,8 @@ func (d *DB) GetDownstreamServer(ctx context.Context, id string) (*store.Downstr
 	row := d.q.QueryRowContext(ctx, `
 		SELECT id, name, transport, command, args, url, tool_namespace, discovery,
 		       capabilities_cache, cache_config, idle_timeout_sec, max_instances,
-		       restart_policy, disabled, source, created_at, updated_at
+		       max_concurrency, restart_policy, disabled, source, env, cwd, headers,
+		       instance_scope, created_at, updated_at
 		FROM downstream_servers WHERE id = ?`, id)
 	return scanDownstreamServer(row)
 }
@@ -61,7 +68,8 @@ func (d *DB) GetDownstreamServerByName(ctx context.Context, name string) (*store
 	row := d.q.QueryRowContext(ctx, `
 		SELECT id, name, transport, command, args, url, tool_namespace, discovery,
 		       capabilities_cache, cache_config, idle_timeout_sec, max_instances,
-		       restart_policy, disabled, source, created_at, updated_at
+		       max_concurrency, restart_policy, disabled, source, env, cwd, headers,
+		       instance_scope, created_at, updated_at
 		FROM downstream_servers WHERE name = ?`, name)
 	return scanDownstreamServer(row)
 }
@@ -70,7 +78,8 @@ func (d *DB) ListDownstreamServers(ctx context.Context) ([]store.DownstreamServe
 	rows, err := d.q.QueryContext(ctx, `
 		SELECT id, name, transport, command, args, url, tool_namespace, discovery,
 		       capabilities_cache, cache_config, idle_timeout_sec, max_instances,
-		       restart_policy, disabled, source, created_at, updated_at
+		       max_concurrency, restart_policy, disabled, source, env, cwd, headers,
+		       instance_scope, created_at, updated_at
 		FROM downstream_servers ORDER BY name`)
 	if err != nil {
 		return nil, err
@@ -93,21 +102,28 @@ func (d *DB) UpdateDownstreamServer(ctx context.Context, ds *store.DownstreamSer
 	args := normalizeJSON(ds.Args, "[]")
 	caps := normalizeJSON(ds.CapabilitiesCache, "{}")
 	cacheCfg := normalizeJSON(ds.CacheConfig, "{}")
+	env := normalizeJSON(ds.Env, "{}")
+	headers := normalizeJSON(ds.Headers, "{}")
 	if ds.Source == "" {
 		ds.Source = "api"
 	}
+	if ds.InstanceScope == "" {
+		ds.InstanceScope = "global"
+	}
 
 	res, err := d.q.ExecContext(ctx, `
 		UPDATE downstream_servers
 		SET name = ?, transport = ?, command = ?, args = ?, url = ?,
 		    tool_namespace = ?, discovery = ?, capabilities_cache = ?,
 		    cache_config = ?, idle_timeout_sec = ?, max_instances = ?,
-		    restart_policy = ?, disabled = ?, source = ?, updated_at = ?
+		    max_concurrency = ?, restart_policy = ?, disabled = ?, source = ?,
+		    env = ?, cwd = ?, headers = ?, instance_scope = ?, updated_at = ?
 		WHERE id = ?`,
 		ds.Name, ds.Transport, ds.Command, args, ds.URL,
 		ds.ToolNamespace, ds.Discovery, caps, cacheCfg,
-		ds.IdleTimeoutSec, ds.MaxInstances, ds.RestartPolicy,
-		ds.Disabled, ds.Source, formatTime(ds.UpdatedAt), ds.ID,
+		ds.IdleTimeoutSec, ds.MaxInstances, ds.MaxConcurrency, ds.RestartPolicy,
+		ds.Disabled, ds.Source, env, ds.Cwd, headers, ds.InstanceScope,
+		formatTime(ds.UpdatedAt), ds.ID,
 	)
 	if err != nil {
 		return mapConstraintError(err)
@@ -152,12 +168,13 @@ func (d *DB) UpdateCapabilitiesCache(
 
 func scanDownstreamServer(row *sql.Row) (*store.DownstreamServer, error) {
 	var ds store.DownstreamServer
-	var createdAt, updatedAt, args, caps, cacheCfg string
+	var createdAt, updatedAt, args, caps, cacheCfg, env, headers string
 	err := row.Scan(
 		&ds.ID, &ds.Name, &ds.Transport, &ds.Command, &args,
 		&ds.URL, &ds.ToolNamespace, &ds.Discovery, &caps, &cacheCfg,
-		&ds.IdleTimeoutSec, &ds.MaxInstances, &ds.RestartPolicy,
-		&ds.Disabled, &ds.Source, &createdAt, &updatedAt,
+		&ds.IdleTimeoutSec, &ds.MaxInstances, &ds.MaxConcurrency, &ds.RestartPolicy,
+		&ds.Disabled, &ds.Source, &env, &ds.Cwd, &headers, &ds.InstanceScope,
+		&createdAt, &updatedAt,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, store.ErrNotFound
@@ -168,6 +185,8 @@ func scanDownstreamServer(row *sql.Row) (*store.DownstreamServer, error) {
 	ds.Args = json.RawMessage(args)
 	ds.CapabilitiesCache = json.RawMessage(caps)
 	ds.CacheConfig = json.RawMessage(cacheCfg)
+	ds.Env = json.RawMessage(env)
+	ds.Headers = json.RawMessage(headers)
 	ds.CreatedAt = parseTime(createdAt)
 	ds.UpdatedAt = parseTime(updatedAt)
 	return &ds, nil
@@ -175,12 +194,13 @@ func scanDownstreamServer(row *sql.Row) (*store.DownstreamServer, error) {
 
 func scanDownstreamServerRow(row rowScanner) (*store.DownstreamServer, error) {
 	var ds store.DownstreamServer
-	var createdAt, updatedAt, args, caps, cacheCfg string
+	var createdAt, updatedAt, args, caps, cacheCfg, env, headers string
 	err := row.Scan(
 		&ds.ID, &ds.Name, &ds.Transport, &ds.Command, &args,
 		&ds.URL, &ds.ToolNamespace, &ds.Discovery, &caps, &cacheCfg,
-		&ds.IdleTimeoutSec, &ds.MaxInstances, &ds.RestartPolicy,
-		&ds.Disabled, &ds.Source, &createdAt, &updatedAt,
+		&ds.IdleTimeoutSec, &ds.MaxInstances, &ds.MaxConcurrency, &ds.RestartPolicy,
+		&ds.Disabled, &ds.Source, &env, &ds.Cwd, &headers, &ds.InstanceScope,
+		&createdAt, &updatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -188,6 +208,8 @@ func scanDownstreamServerRow(row rowScanner) (*store.DownstreamServer, error) {
 	ds.Args = json.RawMessage(args)
 	ds.CapabilitiesCache = json.RawMessage(caps)
 	ds.CacheConfig = json.RawMessage(cacheCfg)
+	ds.Env = json.RawMessage(env)
+	ds.Headers = json.RawMessage(headers)
 	ds.CreatedAt = parseTime(createdAt)
 	ds.UpdatedAt = parseTime(updatedAt)
 	return &ds, nil
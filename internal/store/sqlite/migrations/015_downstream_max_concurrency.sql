@@ -0,0 +1,2 @@
+-- Per-server limit on in-flight requests to a stdio instance (0 = default).
+ALTER TABLE downstream_servers ADD COLUMN max_concurrency INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE route_rules ADD COLUMN conditions TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1 @@
+ALTER TABLE route_rules ADD COLUMN git_match TEXT NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE route_rules ADD COLUMN schedule TEXT NOT NULL DEFAULT '{}';
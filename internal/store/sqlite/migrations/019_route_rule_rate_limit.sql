@@ -0,0 +1 @@
+ALTER TABLE route_rules ADD COLUMN rate_limit TEXT NOT NULL DEFAULT '{}';
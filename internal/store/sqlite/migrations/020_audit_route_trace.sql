@@ -0,0 +1 @@
+ALTER TABLE audit_records ADD COLUMN route_trace TEXT NOT NULL DEFAULT '';
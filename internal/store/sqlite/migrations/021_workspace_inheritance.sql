@@ -0,0 +1,2 @@
+ALTER TABLE workspaces ADD COLUMN inherit_mode TEXT NOT NULL DEFAULT 'inherit';
+ALTER TABLE route_rules ADD COLUMN final INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE workspaces ADD COLUMN matchers TEXT NOT NULL DEFAULT '{}';
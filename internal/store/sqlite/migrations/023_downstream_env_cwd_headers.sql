@@ -0,0 +1,4 @@
+-- Per-server environment, working directory and static HTTP headers.
+ALTER TABLE downstream_servers ADD COLUMN env TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE downstream_servers ADD COLUMN cwd TEXT NOT NULL DEFAULT '';
+ALTER TABLE downstream_servers ADD COLUMN headers TEXT NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+-- Which callers share a server's instances: global, workspace or session.
+ALTER TABLE downstream_servers ADD COLUMN instance_scope TEXT NOT NULL DEFAULT 'global';
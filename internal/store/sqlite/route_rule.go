@@ -23,6 +23,10 @@ func (d *DB) CreateRouteRule(ctx context.Context, r *store.RouteRule) error {
 	toolMatch := normalizeJSON(r.ToolMatch, `["*"]`)
 	allowedOrgs := normalizeJSON(r.AllowedOrgs, `[]`)
 	allowedRepos := normalizeJSON(r.AllowedRepos, `[]`)
+	conditions := normalizeJSON(r.Conditions, `[]`)
+	gitMatch := normalizeJSON(r.GitMatch, `{}`)
+	schedule := normalizeJSON(r.Schedule, `{}`)
+	rateLimit := normalizeJSON(r.RateLimit, `{}`)
 	if r.Source == "" {
 		r.Source = "api"
 	}
@@ -33,14 +37,15 @@ func (d *DB) CreateRouteRule(ctx context.Context, r *store.RouteRule) error {
 	_, err := d.q.ExecContext(ctx, `
 		INSERT INTO route_rules
 			(id, name, priority, workspace_id, path_glob, tool_match, allowed_orgs, allowed_repos,
+			 conditions, git_match, schedule, rate_limit,
 			 downstream_server_id, auth_scope_id, policy, log_level,
-			 approval_mode, approval_timeout,
+			 approval_mode, approval_timeout, final,
 			 source, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		r.ID, r.Name, r.Priority, r.WorkspaceID, r.PathGlob, toolMatch,
-		allowedOrgs, allowedRepos,
+		allowedOrgs, allowedRepos, conditions, gitMatch, schedule, rateLimit,
 		r.DownstreamServerID, r.AuthScopeID, r.Policy, r.LogLevel,
-		r.ApprovalMode, r.ApprovalTimeout,
+		r.ApprovalMode, r.ApprovalTimeout, r.Final,
 		r.Source, formatTime(r.CreatedAt), formatTime(r.UpdatedAt),
 	)
 	if err != nil {
@@ -52,8 +57,9 @@ func (d *DB) CreateRouteRule(ctx context.Context, r *store.RouteRule) error {
 func (d *DB) GetRouteRule(ctx context.Context, id string) (*store.RouteRule, error) {
 	row := d.q.QueryRowContext(ctx, `
 		SELECT id, name, priority, workspace_id, path_glob, tool_match, allowed_orgs, allowed_repos,
+		       conditions, git_match, schedule, rate_limit,
 		       downstream_server_id, auth_scope_id, policy, log_level,
-		       approval_mode, approval_timeout,
+		       approval_mode, approval_timeout, final,
 		       source, created_at, updated_at
 		FROM route_rules WHERE id = ?`, id)
 	return scanRouteRule(row)
@@ -65,8 +71,9 @@ func (d *DB) ListRouteRules(ctx context.Context, workspaceID string) ([]store.Ro
 	if workspaceID != "" {
 		rows, err = d.q.QueryContext(ctx, `
 			SELECT id, name, priority, workspace_id, path_glob, tool_match, allowed_orgs, allowed_repos,
+			       conditions, git_match, schedule, rate_limit,
 			       downstream_server_id, auth_scope_id, policy, log_level,
-			       approval_mode, approval_timeout,
+			       approval_mode, approval_timeout, final,
 			       source, created_at, updated_at
 			FROM route_rules
 			WHERE workspace_id = ?
@@ -74,8 +81,9 @@ func (d *DB) ListRouteRules(ctx context.Context, workspaceID string) ([]store.Ro
 	} else {
 		rows, err = d.q.QueryContext(ctx, `
 			SELECT id, name, priority, workspace_id, path_glob, tool_match, allowed_orgs, allowed_repos,
+			       conditions, git_match, schedule, rate_limit,
 			       downstream_server_id, auth_scope_id, policy, log_level,
-			       approval_mode, approval_timeout,
+			       approval_mode, approval_timeout, final,
 			       source, created_at, updated_at
 			FROM route_rules
 			ORDER BY priority DESC, id ASC`)
@@ -101,6 +109,10 @@ func (d *DB) UpdateRouteRule(ctx context.Context, r *store.RouteRule) error {
 	toolMatch := normalizeJSON(r.ToolMatch, `["*"]`)
 	allowedOrgs := normalizeJSON(r.AllowedOrgs, `[]`)
 	allowedRepos := normalizeJSON(r.AllowedRepos, `[]`)
+	conditions := normalizeJSON(r.Conditions, `[]`)
+	gitMatch := normalizeJSON(r.GitMatch, `{}`)
+	schedule := normalizeJSON(r.Schedule, `{}`)
+	rateLimit := normalizeJSON(r.RateLimit, `{}`)
 	if r.Source == "" {
 		r.Source = "api"
 	}
@@ -112,14 +124,15 @@ func (d *DB) UpdateRouteRule(ctx context.Context, r *store.RouteRule) error {
 		UPDATE route_rules
 		SET name = ?, priority = ?, workspace_id = ?, path_glob = ?, tool_match = ?,
 		    allowed_orgs = ?, allowed_repos = ?,
+		    conditions = ?, git_match = ?, schedule = ?, rate_limit = ?,
 		    downstream_server_id = ?, auth_scope_id = ?, policy = ?,
-		    log_level = ?, approval_mode = ?, approval_timeout = ?,
+		    log_level = ?, approval_mode = ?, approval_timeout = ?, final = ?,
 		    source = ?, updated_at = ?
 		WHERE id = ?`,
 		r.Name, r.Priority, r.WorkspaceID, r.PathGlob, toolMatch,
-		allowedOrgs, allowedRepos,
+		allowedOrgs, allowedRepos, conditions, gitMatch, schedule, rateLimit,
 		r.DownstreamServerID, r.AuthScopeID, r.Policy,
-		r.LogLevel, r.ApprovalMode, r.ApprovalTimeout,
+		r.LogLevel, r.ApprovalMode, r.ApprovalTimeout, r.Final,
 		r.Source, formatTime(r.UpdatedAt), r.ID,
 	)
 	if err != nil {
@@ -146,11 +159,12 @@ func (d *DB) DeleteRouteRule(ctx context.Context, id string) error {
 
 func scanRouteRule(row *sql.Row) (*store.RouteRule, error) {
 	var r store.RouteRule
-	var createdAt, updatedAt, toolMatch, allowedOrgs, allowedRepos string
+	var createdAt, updatedAt, toolMatch, allowedOrgs, allowedRepos, conditions, gitMatch, schedule, rateLimit string
 	err := row.Scan(
 		&r.ID, &r.Name, &r.Priority, &r.WorkspaceID, &r.PathGlob, &toolMatch, &allowedOrgs, &allowedRepos,
+		&conditions, &gitMatch, &schedule, &rateLimit,
 		&r.DownstreamServerID, &r.AuthScopeID, &r.Policy, &r.LogLevel,
-		&r.ApprovalMode, &r.ApprovalTimeout,
+		&r.ApprovalMode, &r.ApprovalTimeout, &r.Final,
 		&r.Source, &createdAt, &updatedAt,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
@@ -162,6 +176,10 @@ func scanRouteRule(row *sql.Row) (*store.RouteRule, error) {
 	r.ToolMatch = json.RawMessage(toolMatch)
 	r.AllowedOrgs = json.RawMessage(allowedOrgs)
 	r.AllowedRepos = json.RawMessage(allowedRepos)
+	r.Conditions = json.RawMessage(conditions)
+	r.GitMatch = json.RawMessage(gitMatch)
+	r.Schedule = json.RawMessage(schedule)
+	r.RateLimit = json.RawMessage(rateLimit)
 	r.CreatedAt = parseTime(createdAt)
 	r.UpdatedAt = parseTime(updatedAt)
 	return &r, nil
@@ -169,11 +187,12 @@ func scanRouteRule(row *sql.Row) (*store.RouteRule, error) {
 
 func scanRouteRuleRow(row rowScanner) (*store.RouteRule, error) {
 	var r store.RouteRule
-	var createdAt, updatedAt, toolMatch, allowedOrgs, allowedRepos string
+	var createdAt, updatedAt, toolMatch, allowedOrgs, allowedRepos, conditions, gitMatch, schedule, rateLimit string
 	err := row.Scan(
 		&r.ID, &r.Name, &r.Priority, &r.WorkspaceID, &r.PathGlob, &toolMatch, &allowedOrgs, &allowedRepos,
+		&conditions, &gitMatch, &schedule, &rateLimit,
 		&r.DownstreamServerID, &r.AuthScopeID, &r.Policy, &r.LogLevel,
-		&r.ApprovalMode, &r.ApprovalTimeout,
+		&r.ApprovalMode, &r.ApprovalTimeout, &r.Final,
 		&r.Source, &createdAt, &updatedAt,
 	)
 	if err != nil {
@@ -182,6 +201,10 @@ func scanRouteRuleRow(row rowScanner) (*store.RouteRule, error) {
 	r.ToolMatch = json.RawMessage(toolMatch)
 	r.AllowedOrgs = json.RawMessage(allowedOrgs)
 	r.AllowedRepos = json.RawMessage(allowedRepos)
+	r.Conditions = json.RawMessage(conditions)
+	r.GitMatch = json.RawMessage(gitMatch)
+	r.Schedule = json.RawMessage(schedule)
+	r.RateLimit = json.RawMessage(rateLimit)
 	r.CreatedAt = parseTime(createdAt)
 	r.UpdatedAt = parseTime(updatedAt)
 	return &r, nil
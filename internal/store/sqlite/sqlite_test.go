@@ -34,6 +34,7 @@ func TestWorkspaceCRUD(t *testing.T) {
 	w := &store.Workspace{
 		Name:          "test-ws",
 		RootPath:      "/tmp/test",
+		Matchers:      json.RawMessage(`{"git_remotes":["acme/test"]}`),
 		Tags:          json.RawMessage(`["go","test"]`),
 		DefaultPolicy: "allow",
 	}
@@ -54,6 +55,12 @@ func TestWorkspaceCRUD(t *testing.T) {
 	if got.Name != "test-ws" {
 		t.Fatalf("name = %q, want %q", got.Name, "test-ws")
 	}
+	if got.InheritMode != "inherit" {
+		t.Fatalf("inherit mode = %q, want inherit", got.InheritMode)
+	}
+	if string(got.Matchers) != `{"git_remotes":["acme/test"]}` {
+		t.Fatalf("matchers = %s", got.Matchers)
+	}
 
 	// Get by name.
 	got, err = db.GetWorkspaceByName(ctx, "test-ws")
@@ -75,6 +82,7 @@ func TestWorkspaceCRUD(t *testing.T) {
 
 	// Update.
 	got.Name = "updated-ws"
+	got.InheritMode = "isolate"
 	if err := db.UpdateWorkspace(ctx, got); err != nil {
 		t.Fatalf("update: %v", err)
 	}
@@ -82,6 +90,9 @@ func TestWorkspaceCRUD(t *testing.T) {
 	if got2.Name != "updated-ws" {
 		t.Fatalf("name after update = %q", got2.Name)
 	}
+	if got2.InheritMode != "isolate" {
+		t.Fatalf("inherit mode after update = %q", got2.InheritMode)
+	}
 
 	// Delete.
 	if err := db.DeleteWorkspace(ctx, w.ID); err != nil {
@@ -168,7 +179,10 @@ func TestDownstreamServerCRUD(t *testing.T) {
 		ToolNamespace:  "github",
 		IdleTimeoutSec: 300,
 		MaxInstances:   1,
+		MaxConcurrency: 4,
 		RestartPolicy:  "on-failure",
+		Env:            json.RawMessage(`{"LOG_LEVEL":"debug"}`),
+		Cwd:            "${workspace_root}/tools",
 	}
 
 	if err := db.CreateDownstreamServer(ctx, ds); err != nil {
@@ -182,6 +196,15 @@ func TestDownstreamServerCRUD(t *testing.T) {
 	if got.ToolNamespace != "github" {
 		t.Fatalf("namespace = %q", got.ToolNamespace)
 	}
+	if got.MaxConcurrency != 4 {
+		t.Fatalf("max concurrency = %d", got.MaxConcurrency)
+	}
+	if string(got.Env) != `{"LOG_LEVEL":"debug"}` || got.Cwd != "${workspace_root}/tools" || string(got.Headers) != "{}" {
+		t.Fatalf("env = %s, cwd = %q, headers = %s", got.Env, got.Cwd, got.Headers)
+	}
+	if got.InstanceScope != "global" {
+		t.Fatalf("instance scope = %q, want global default", got.InstanceScope)
+	}
 
 	got, err = db.GetDownstreamServerByName(ctx, "github-mcp")
 	if err != nil {
@@ -197,9 +220,13 @@ func TestDownstreamServerCRUD(t *testing.T) {
 	}
 
 	got.Name = "github-mcp-v2"
+	got.Headers = json.RawMessage(`{"X-Team":"platform"}`)
 	if err := db.UpdateDownstreamServer(ctx, got); err != nil {
 		t.Fatalf("update: %v", err)
 	}
+	if got, _ := db.GetDownstreamServer(ctx, ds.ID); string(got.Headers) != `{"X-Team":"platform"}` {
+		t.Fatalf("headers = %s", got.Headers)
+	}
 
 	cache := json.RawMessage(`{"tools":["create_issue"]}`)
 	if err := db.UpdateCapabilitiesCache(ctx, ds.ID, cache); err != nil {
@@ -239,9 +266,14 @@ func TestRouteRuleCRUD(t *testing.T) {
 		ToolMatch:          json.RawMessage(`["github__*"]`),
 		AllowedOrgs:        json.RawMessage(`["acme"]`),
 		AllowedRepos:       json.RawMessage(`["acme/mcplexer"]`),
+		Conditions:         json.RawMessage(`["branch != \"main\""]`),
+		GitMatch:           json.RawMessage(`{"branch":"feature/**"}`),
+		Schedule:           json.RawMessage(`{"windows":["Sat,Sun"]}`),
+		RateLimit:          json.RawMessage(`{"rate":10,"daily":100}`),
 		DownstreamServerID: ds.ID,
 		Policy:             "allow",
 		LogLevel:           "info",
+		Final:              true,
 	}
 
 	if err := db.CreateRouteRule(ctx, r); err != nil {
@@ -261,6 +293,21 @@ func TestRouteRuleCRUD(t *testing.T) {
 	if string(got.AllowedRepos) != `["acme/mcplexer"]` {
 		t.Fatalf("allowed repos = %s", got.AllowedRepos)
 	}
+	if string(got.Conditions) != `["branch != \"main\""]` {
+		t.Fatalf("conditions = %s", got.Conditions)
+	}
+	if string(got.GitMatch) != `{"branch":"feature/**"}` {
+		t.Fatalf("git match = %s", got.GitMatch)
+	}
+	if string(got.Schedule) != `{"windows":["Sat,Sun"]}` {
+		t.Fatalf("schedule = %s", got.Schedule)
+	}
+	if string(got.RateLimit) != `{"rate":10,"daily":100}` {
+		t.Fatalf("rate limit = %s", got.RateLimit)
+	}
+	if !got.Final {
+		t.Fatal("final = false, want true")
+	}
 
 	list, err := db.ListRouteRules(ctx, ws.ID)
 	if err != nil {
@@ -271,9 +318,13 @@ func TestRouteRuleCRUD(t *testing.T) {
 	}
 
 	got.Priority = 200
+	got.Final = false
 	if err := db.UpdateRouteRule(ctx, got); err != nil {
 		t.Fatalf("update: %v", err)
 	}
+	if list, _ := db.ListRouteRules(ctx, ws.ID); len(list) != 1 || list[0].Final {
+		t.Fatalf("after update = %+v", list)
+	}
 
 	if err := db.DeleteRouteRule(ctx, r.ID); err != nil {
 		t.Fatalf("delete: %v", err)
@@ -380,6 +431,74 @@ func TestAuditCRUD(t *testing.T) {
 	}
 }
 
+func TestAuditRateLimited(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	for i, status := range []string{"success", "rate_limited", "rate_limited", "error"} {
+		r := &store.AuditRecord{
+			Timestamp:   now.Add(time.Duration(i) * time.Second),
+			ToolName:    "github__create_issue",
+			RouteRuleID: "limited",
+			Status:      status,
+		}
+		if err := db.InsertAuditRecord(ctx, r); err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+
+	rule, status := "limited", "rate_limited"
+	_, total, err := db.QueryAuditRecords(ctx, store.AuditFilter{RouteRuleID: &rule, Status: &status, Limit: 1})
+	if err != nil || total != 2 {
+		t.Fatalf("query by rule and status: total=%d, err=%v", total, err)
+	}
+
+	entries, err := db.GetErrorBreakdown(ctx, now.Add(-time.Minute), now.Add(time.Minute), 10)
+	if err != nil {
+		t.Fatalf("error breakdown: %v", err)
+	}
+	got := map[string]int{}
+	for _, e := range entries {
+		got[e.ErrorType] = e.Count
+	}
+	if got["rate_limited"] != 2 || got["error"] != 1 || len(got) != 2 {
+		t.Fatalf("breakdown = %+v", entries)
+	}
+}
+
+func TestAuditRouteTrace(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	trace := json.RawMessage(`{"tool_name":"slack__post","outcome":"no_route","workspaces":[]}`)
+	for _, r := range []*store.AuditRecord{
+		{ToolName: "slack__post", Status: "blocked", RouteTrace: trace},
+		{ToolName: "github__get_me", Status: "success"},
+	} {
+		if err := db.InsertAuditRecord(ctx, r); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	records, _, err := db.QueryAuditRecords(ctx, store.AuditFilter{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		switch r.ToolName {
+		case "slack__post":
+			if string(r.RouteTrace) != string(trace) {
+				t.Errorf("route_trace = %s, want %s", r.RouteTrace, trace)
+			}
+		default:
+			if r.RouteTrace != nil {
+				t.Errorf("%s: route_trace = %s, want none", r.ToolName, r.RouteTrace)
+			}
+		}
+	}
+}
+
 func TestDashboardTimeSeries(t *testing.T) {
 	db := newTestDB(t)
 	ctx := context.Background()
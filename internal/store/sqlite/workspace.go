@@ -21,14 +21,18 @@ func (d *DB) CreateWorkspace(ctx context.Context, w *store.Workspace) error {
 	w.UpdatedAt = now
 
 	tags := normalizeJSON(w.Tags, "[]")
+	matchers := normalizeJSON(w.Matchers, "{}")
 	if w.Source == "" {
 		w.Source = "api"
 	}
+	if w.InheritMode == "" {
+		w.InheritMode = "inherit"
+	}
 
 	_, err := d.q.ExecContext(ctx, `
-		INSERT INTO workspaces (id, name, root_path, tags, default_policy, source, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		w.ID, w.Name, w.RootPath, tags, w.DefaultPolicy, w.Source,
+		INSERT INTO workspaces (id, name, root_path, matchers, tags, default_policy, inherit_mode, source, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		w.ID, w.Name, w.RootPath, matchers, tags, w.DefaultPolicy, w.InheritMode, w.Source,
 		formatTime(w.CreatedAt), formatTime(w.UpdatedAt),
 	)
 	if err != nil {
@@ -39,21 +43,21 @@ func (d *DB) CreateWorkspace(ctx context.Context, w *store.Workspace) error {
 
 func (d *DB) GetWorkspace(ctx context.Context, id string) (*store.Workspace, error) {
 	row := d.q.QueryRowContext(ctx, `
-		SELECT id, name, root_path, tags, default_policy, source, created_at, updated_at
+		SELECT id, name, root_path, matchers, tags, default_policy, inherit_mode, source, created_at, updated_at
 		FROM workspaces WHERE id = ?`, id)
 	return scanWorkspace(row)
 }
 
 func (d *DB) GetWorkspaceByName(ctx context.Context, name string) (*store.Workspace, error) {
 	row := d.q.QueryRowContext(ctx, `
-		SELECT id, name, root_path, tags, default_policy, source, created_at, updated_at
+		SELECT id, name, root_path, matchers, tags, default_policy, inherit_mode, source, created_at, updated_at
 		FROM workspaces WHERE name = ?`, name)
 	return scanWorkspace(row)
 }
 
 func (d *DB) ListWorkspaces(ctx context.Context) ([]store.Workspace, error) {
 	rows, err := d.q.QueryContext(ctx, `
-		SELECT id, name, root_path, tags, default_policy, source, created_at, updated_at
+		SELECT id, name, root_path, matchers, tags, default_policy, inherit_mode, source, created_at, updated_at
 		FROM workspaces ORDER BY name`)
 	if err != nil {
 		return nil, err
@@ -74,15 +78,19 @@ func (d *DB) ListWorkspaces(ctx context.Context) ([]store.Workspace, error) {
 func (d *DB) UpdateWorkspace(ctx context.Context, w *store.Workspace) error {
 	w.UpdatedAt = time.Now().UTC()
 	tags := normalizeJSON(w.Tags, "[]")
+	matchers := normalizeJSON(w.Matchers, "{}")
 	if w.Source == "" {
 		w.Source = "api"
 	}
+	if w.InheritMode == "" {
+		w.InheritMode = "inherit"
+	}
 
 	res, err := d.q.ExecContext(ctx, `
 		UPDATE workspaces
-		SET name = ?, root_path = ?, tags = ?, default_policy = ?, source = ?, updated_at = ?
+		SET name = ?, root_path = ?, matchers = ?, tags = ?, default_policy = ?, inherit_mode = ?, source = ?, updated_at = ?
 		WHERE id = ?`,
-		w.Name, w.RootPath, tags, w.DefaultPolicy, w.Source,
+		w.Name, w.RootPath, matchers, tags, w.DefaultPolicy, w.InheritMode, w.Source,
 		formatTime(w.UpdatedAt), w.ID,
 	)
 	if err != nil {
@@ -113,15 +121,16 @@ func (d *DB) DeleteWorkspace(ctx context.Context, id string) error {
 
 func scanWorkspace(row *sql.Row) (*store.Workspace, error) {
 	var w store.Workspace
-	var createdAt, updatedAt, tags string
-	err := row.Scan(&w.ID, &w.Name, &w.RootPath, &tags,
-		&w.DefaultPolicy, &w.Source, &createdAt, &updatedAt)
+	var createdAt, updatedAt, matchers, tags string
+	err := row.Scan(&w.ID, &w.Name, &w.RootPath, &matchers, &tags,
+		&w.DefaultPolicy, &w.InheritMode, &w.Source, &createdAt, &updatedAt)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, store.ErrNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
+	w.Matchers = json.RawMessage(matchers)
 	w.Tags = json.RawMessage(tags)
 	w.CreatedAt = parseTime(createdAt)
 	w.UpdatedAt = parseTime(updatedAt)
@@ -134,12 +143,13 @@ type rowScanner interface {
 
 func scanWorkspaceRow(row rowScanner) (*store.Workspace, error) {
 	var w store.Workspace
-	var createdAt, updatedAt, tags string
-	err := row.Scan(&w.ID, &w.Name, &w.RootPath, &tags,
-		&w.DefaultPolicy, &w.Source, &createdAt, &updatedAt)
+	var createdAt, updatedAt, matchers, tags string
+	err := row.Scan(&w.ID, &w.Name, &w.RootPath, &matchers, &tags,
+		&w.DefaultPolicy, &w.InheritMode, &w.Source, &createdAt, &updatedAt)
 	if err != nil {
 		return nil, err
 	}
+	w.Matchers = json.RawMessage(matchers)
 	w.Tags = json.RawMessage(tags)
 	w.CreatedAt = parseTime(createdAt)
 	w.UpdatedAt = parseTime(updatedAt)
@@ -2,12 +2,23 @@ export interface Workspace {
   id: string
   name: string
   root_path: string
+  matchers?: WorkspaceMatchers
   tags: Record<string, string>
   default_policy: 'allow' | 'deny'
+  inherit_mode?: 'inherit' | 'isolate' | 'override'
   created_at: string
   updated_at: string
 }
 
+export interface WorkspaceMatchers {
+  // further literal roots, e.g. other clones
+  roots?: string[]
+  // globs over absolute directories, e.g. "/tmp/**/acme-api"
+  path_globs?: string[]
+  // globs over the origin remote: "owner/repo" or "host/owner/repo"
+  git_remotes?: string[]
+}
+
 export interface EnvField {
   key: string
   label: string
@@ -127,15 +138,20 @@ export interface ServerCacheConfig {
 export interface DownstreamServer {
   id: string
   name: string
-  transport: 'stdio' | 'http'
+  transport: 'stdio' | 'http' | 'sse' | 'websocket'
   command: string
   args: string[]
+  env?: Record<string, string>
+  cwd?: string
+  instance_scope?: 'global' | 'workspace' | 'session'
   url: string | null
+  headers?: Record<string, string>
   tool_namespace: string
   capabilities_cache: Record<string, unknown>
   cache_config?: ServerCacheConfig
   idle_timeout_sec: number
   max_instances: number
+  max_concurrency: number
   restart_policy: string
   disabled: boolean
   created_at: string
@@ -149,12 +165,17 @@ export interface RouteRule {
   workspace_id: string
   path_glob: string
   tool_match: string[]
+  conditions?: string[]
+  git_match?: GitMatch
+  schedule?: RouteSchedule
+  rate_limit?: RouteRateLimit
   downstream_server_id: string
   auth_scope_id: string
   policy: 'allow' | 'deny'
   log_level: string
   approval_mode: 'none' | 'write' | 'all'
   approval_timeout: number
+  final?: boolean
   created_at: string
   updated_at: string
 }
@@ -174,7 +195,7 @@ export interface AuditRecord {
   downstream_server_id: string
   downstream_instance_id: string
   auth_scope_id: string
-  status: 'success' | 'error' | 'blocked'
+  status: 'success' | 'error' | 'blocked' | 'rate_limited' | 'cancelled'
   error_code: string
   error_message: string
   latency_ms: number
@@ -182,12 +203,39 @@ export interface AuditRecord {
   cache_hit: boolean
   route_rule_summary?: string
   downstream_server_name?: string
+  route_trace?: RouteTrace // denied and no-route calls only
+}
+
+export interface RouteTraceRule {
+  rule_id: string
+  policy: string
+  // "matched", or why the rule was skipped
+  result: 'matched' | 'glob' | 'tool' | 'namespace' | 'git' | 'schedule' | 'conditions'
+  detail?: string
+}
+
+export interface RouteTraceWorkspace {
+  id: string
+  name?: string
+  subpath: string
+  // only final rules were tried
+  final?: boolean
+  // inherit_mode that stopped the fallback to ancestors here
+  stop?: 'isolate' | 'override'
+  rules: RouteTraceRule[]
+}
+
+export interface RouteTrace {
+  tool_name: string
+  outcome: 'allow' | 'deny' | 'no_route'
+  rule_id?: string
+  workspaces: RouteTraceWorkspace[]
 }
 
 export interface AuditFilter {
   workspace_id?: string
   tool_name?: string
-  status?: 'success' | 'error' | 'blocked'
+  status?: 'success' | 'error' | 'blocked' | 'rate_limited' | 'cancelled'
   after?: string
   before?: string
   limit?: number
@@ -235,7 +283,7 @@ export interface ServerHealthEntry {
 export interface ErrorBreakdownEntry {
   group_key: string
   server_name: string
-  error_type: 'error' | 'blocked'
+  error_type: 'error' | 'blocked' | 'rate_limited'
   count: number
 }
 
@@ -300,12 +348,59 @@ export interface DownstreamStatus {
   instance_count: number
   state: string
   disabled: boolean
+  instances: InstanceStatus[]
+  failures?: number
+  last_error?: string
+  stderr_tail?: string
+  retry_at?: string
+}
+
+export interface InstanceStatus {
+  auth_scope_id?: string
+  scope?: string
+  index: number
+  state: string
+  in_flight: number
 }
 
 export interface DryRunRequest {
   workspace_id: string
   subpath: string
   tool_name: string
+  arguments?: Record<string, unknown>
+  git?: Partial<GitInfo>
+  client_root?: string
+  at?: string // RFC 3339
+}
+
+export interface RouteSchedule {
+  timezone?: string
+  windows: string[] // e.g. "Mon-Fri 09:00-17:00", "Sat,Sun"
+  outside?: boolean
+}
+
+export interface RouteRateLimit {
+  scope?: 'session' | 'workspace' | 'global'
+  rate?: number // calls per `per`
+  per?: string // Go duration, default "1m"
+  burst?: number
+  daily?: number
+}
+
+export interface GitMatch {
+  branch?: string
+  remote?: string
+  detached?: boolean
+  dirty?: boolean
+}
+
+export interface GitInfo {
+  root: string
+  branch?: string
+  commit?: string
+  detached: boolean
+  dirty: boolean
+  remote: { name?: string; url?: string; host?: string; owner?: string; repo?: string }
 }
 
 export interface DryRunAuthScope {
@@ -324,6 +419,11 @@ export interface DryRunResult {
   auth_scope_id: string
   auth_scope: DryRunAuthScope | null
   candidate_rules: RouteRule[]
+  matched_conditions?: string[]
+  git?: GitInfo
+  evaluated_at: string
+  matched_schedule?: RouteSchedule
+  trace: RouteTrace
 }
 
 export interface PaginatedResponse<T> {
@@ -6,6 +6,7 @@ import {
   DialogTitle,
 } from '@/components/ui/dialog'
 import type { AuditRecord } from '@/api/types'
+import { RouteTraceView } from '@/components/RouteTraceView'
 
 export function getErrorReason(record: AuditRecord): string {
   if (record.status === 'success') return ''
@@ -87,6 +88,16 @@ export function AuditDetailDialog({
             value={record.auth_scope_id ? asName(record.auth_scope_id) : '-'}
             mono
           />
+          {record.route_trace && (
+            <div className="pt-2">
+              <span className="text-xs font-medium uppercase tracking-wider text-muted-foreground">
+                Routing Trace
+              </span>
+              <div className="mt-2 max-h-64 overflow-auto rounded-md border border-border bg-background p-3">
+                <RouteTraceView trace={record.route_trace} />
+              </div>
+            </div>
+          )}
           {Object.keys(record.params_redacted ?? {}).length > 0 && (
             <div className="pt-2">
               <span className="text-xs font-medium uppercase tracking-wider text-muted-foreground">
@@ -0,0 +1,61 @@
+import { Badge } from '@/components/ui/badge'
+import type { RouteTrace } from '@/api/types'
+
+const skipLabels: Record<string, string> = {
+  glob: 'path glob',
+  tool: 'tool pattern',
+  namespace: 'namespace',
+  git: 'git state',
+  schedule: 'schedule',
+  conditions: 'conditions',
+}
+
+export function RouteTraceView({ trace }: { trace: RouteTrace }) {
+  return (
+    <div className="space-y-3 font-mono text-xs">
+      {trace.workspaces.map((ws, i) => (
+        <div key={`${ws.id}-${i}`}>
+          <p className="mb-1 text-muted-foreground">
+            {ws.final && 'final rules of '}
+            {ws.name || ws.id}
+            <span className="text-muted-foreground/60"> /{ws.subpath}</span>
+          </p>
+          {ws.rules.length === 0 && (
+            <p className="px-2 text-muted-foreground/60">no rules</p>
+          )}
+          <div className="space-y-1">
+            {ws.rules.map((r) => {
+              const matched = r.result === 'matched'
+              return (
+                <div
+                  key={r.rule_id}
+                  title={r.detail}
+                  className={`flex items-center gap-2 rounded px-2 py-1 ${
+                    matched
+                      ? r.policy === 'deny'
+                        ? 'bg-destructive/10 text-destructive'
+                        : 'bg-chart-2/10 text-chart-2'
+                      : 'text-muted-foreground'
+                  }`}
+                >
+                  <span className="truncate">{r.rule_id}</span>
+                  <span className="min-w-0 truncate text-muted-foreground/60">
+                    {matched ? 'matched' : `skipped: ${skipLabels[r.result] ?? r.result}`}
+                  </span>
+                  <Badge variant="outline" className="ml-auto px-1 py-0 text-[10px]">
+                    {r.policy}
+                  </Badge>
+                </div>
+              )
+            })}
+          </div>
+          {ws.stop && (
+            <p className="mt-1 px-2 text-muted-foreground/60">
+              not falling back to ancestors (inherit_mode {ws.stop})
+            </p>
+          )}
+        </div>
+      ))}
+    </div>
+  )
+}
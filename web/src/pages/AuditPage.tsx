@@ -158,7 +158,7 @@ export function AuditPage() {
                         {record.workspace_name || (record.workspace_id ? wsName(record.workspace_id) : '-')}
                       </TableCell>
                       <TableCell>
-                        <Badge variant={record.status === 'success' ? 'secondary' : 'destructive'}>
+                        <Badge variant={record.status === 'success' ? 'secondary' : record.status === 'cancelled' || record.status === 'rate_limited' ? 'outline' : 'destructive'}>
                           {record.status}
                         </Badge>
                       </TableCell>
@@ -299,7 +299,7 @@ function FilterBar({
             onValueChange={(v) =>
               setFilter((f) => ({
                 ...f,
-                status: v === 'all' ? undefined : (v as 'success' | 'error'),
+                status: v === 'all' ? undefined : (v as 'success' | 'error' | 'rate_limited' | 'cancelled'),
                 offset: 0,
               }))
             }
@@ -311,6 +311,8 @@ function FilterBar({
               <SelectItem value="all">All statuses</SelectItem>
               <SelectItem value="success">Success</SelectItem>
               <SelectItem value="error">Error</SelectItem>
+              <SelectItem value="rate_limited">Rate Limited</SelectItem>
+              <SelectItem value="cancelled">Cancelled</SelectItem>
             </SelectContent>
           </Select>
 
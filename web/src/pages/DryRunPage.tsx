@@ -3,6 +3,7 @@ import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
 import { Button } from '@/components/ui/button'
 import { Input } from '@/components/ui/input'
 import { Label } from '@/components/ui/label'
+import { Textarea } from '@/components/ui/textarea'
 import {
   Select,
   SelectContent,
@@ -11,6 +12,7 @@ import {
   SelectValue,
 } from '@/components/ui/select'
 import { Badge } from '@/components/ui/badge'
+import { RouteTraceView } from '@/components/RouteTraceView'
 import { useApi } from '@/hooks/use-api'
 import { discoverTools, dryRun, listDownstreams, listWorkspaces } from '@/api/client'
 import type { DownstreamServer, DryRunResult } from '@/api/types'
@@ -43,6 +45,9 @@ export function DryRunPage() {
   const [subpath, setSubpath] = useState('')
   const [serverId, setServerId] = useState('')
   const [toolName, setToolName] = useState('')
+  const [argsText, setArgsText] = useState('')
+  const [clientRoot, setClientRoot] = useState('')
+  const [at, setAt] = useState('')
   const [result, setResult] = useState<DryRunResult | null>(null)
   const [error, setError] = useState<string | null>(null)
   const [running, setRunning] = useState(false)
@@ -96,13 +101,26 @@ export function DryRunPage() {
     e.preventDefault()
     setError(null)
     setResult(null)
-    setRunning(true)
 
+    let args: Record<string, unknown> | undefined
+    if (argsText.trim()) {
+      try {
+        args = JSON.parse(argsText)
+      } catch {
+        setError('Arguments must be valid JSON')
+        return
+      }
+    }
+
+    setRunning(true)
     try {
       const res = await dryRun({
         workspace_id: workspaceId,
         subpath,
         tool_name: toolName,
+        arguments: args,
+        client_root: clientRoot || undefined,
+        at: at ? new Date(at).toISOString() : undefined,
       })
       setResult(res)
     } catch (err: unknown) {
@@ -233,6 +251,32 @@ export function DryRunPage() {
                 )}
               </div>
 
+              <div className="space-y-2">
+                <Label className="text-xs text-muted-foreground">Client Root (optional, for git rules)</Label>
+                <Input
+                  className="font-mono text-sm"
+                  placeholder="/Users/me/src/project"
+                  value={clientRoot}
+                  onChange={(e) => setClientRoot(e.target.value)}
+                />
+              </div>
+
+              <div className="space-y-2">
+                <Label className="text-xs text-muted-foreground">Evaluate At (optional, for scheduled rules)</Label>
+                <Input type="datetime-local" value={at} onChange={(e) => setAt(e.target.value)} />
+              </div>
+
+              <div className="space-y-2">
+                <Label className="text-xs text-muted-foreground">Arguments (JSON, optional)</Label>
+                <Textarea
+                  className="font-mono text-xs"
+                  rows={4}
+                  placeholder='{"sql": "SELECT 1"}'
+                  value={argsText}
+                  onChange={(e) => setArgsText(e.target.value)}
+                />
+              </div>
+
               <Button type="submit" disabled={running || !workspaceId || !toolName} className="w-full">
                 <Play className="mr-2 h-4 w-4" />
                 {running ? 'Simulating...' : 'Simulate'}
@@ -339,6 +383,20 @@ function DryRunResultCard({ result }: { result: DryRunResult }) {
                 <span className="text-muted-foreground">policy:</span>{' '}
                 {result.matched_rule.policy}
               </p>
+              {result.matched_schedule && (
+                <p>
+                  <span className="text-muted-foreground">schedule:</span>{' '}
+                  {result.matched_schedule.outside ? 'outside ' : ''}
+                  {result.matched_schedule.windows.join(', ')}
+                  {result.matched_schedule.timezone ? ` (${result.matched_schedule.timezone})` : ''}
+                </p>
+              )}
+              {result.matched_conditions?.map((c) => (
+                <p key={c}>
+                  <span className="text-muted-foreground">when:</span>{' '}
+                  <span className="text-accent-foreground">{c}</span>
+                </p>
+              ))}
             </div>
           ) : (
             <p className="text-muted-foreground/60">
@@ -347,6 +405,29 @@ function DryRunResultCard({ result }: { result: DryRunResult }) {
           )}
         </div>
 
+        {result.git && (
+          <div>
+            <h4 className="mb-2 text-xs font-medium uppercase tracking-wider text-muted-foreground">
+              Git
+            </h4>
+            <div className="space-y-1 rounded-md border border-border bg-background p-3 font-mono text-xs">
+              <p>
+                <span className="text-muted-foreground">branch:</span>{' '}
+                {result.git.detached ? `(detached ${result.git.commit?.slice(0, 7)})` : result.git.branch}
+              </p>
+              {result.git.remote.owner && (
+                <p>
+                  <span className="text-muted-foreground">remote:</span>{' '}
+                  {result.git.remote.owner}/{result.git.remote.repo}
+                </p>
+              )}
+              <p>
+                <span className="text-muted-foreground">dirty:</span> {String(result.git.dirty)}
+              </p>
+            </div>
+          </div>
+        )}
+
         <div>
           <h4 className="mb-2 text-xs font-medium uppercase tracking-wider text-muted-foreground">
             Downstream Server
@@ -401,6 +482,15 @@ function DryRunResultCard({ result }: { result: DryRunResult }) {
           </div>
         )}
 
+        {result.trace && (
+          <div>
+            <h4 className="mb-2 text-xs font-medium uppercase tracking-wider text-muted-foreground">
+              Trace
+            </h4>
+            <RouteTraceView trace={result.trace} />
+          </div>
+        )}
+
         {(result.candidate_rules ?? []).length > 0 && (
           <div>
             <h4 className="mb-2 text-xs font-medium uppercase tracking-wider text-muted-foreground">
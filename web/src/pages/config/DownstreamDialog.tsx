@@ -24,13 +24,14 @@ import type { ServerCacheConfig } from '@/api/types'
 
 export interface DownstreamFormData {
   name: string
-  transport: 'stdio' | 'http'
+  transport: 'stdio' | 'http' | 'sse' | 'websocket'
   command: string
   args: string[]
   url: string | null
   tool_namespace: string
   idle_timeout_sec: number
   max_instances: number
+  max_concurrency: number
   restart_policy: string
   disabled: boolean
   cache_config?: ServerCacheConfig
@@ -45,6 +46,7 @@ export const emptyDownstreamForm: DownstreamFormData = {
   tool_namespace: '',
   idle_timeout_sec: 300,
   max_instances: 1,
+  max_concurrency: 0,
   restart_policy: 'on-failure',
   disabled: false,
 }
@@ -117,7 +119,7 @@ export function DownstreamDialog({
                 <Select
                   value={form.transport}
                   onValueChange={(value) =>
-                    setForm((current) => ({ ...current, transport: value as 'stdio' | 'http' }))
+                    setForm((current) => ({ ...current, transport: value as 'stdio' | 'http' | 'sse' | 'websocket' }))
                   }
                 >
                   <SelectTrigger>
@@ -126,10 +128,12 @@ export function DownstreamDialog({
                   <SelectContent>
                     <SelectItem value="stdio">stdio</SelectItem>
                     <SelectItem value="http">http</SelectItem>
+                    <SelectItem value="sse">sse</SelectItem>
+                    <SelectItem value="websocket">websocket</SelectItem>
                   </SelectContent>
                 </Select>
                 <p className="text-xs text-muted-foreground/70">
-                  Use `stdio` for local processes and `http` for remote MCP endpoints.
+                  Use `stdio` for local processes, `http` for remote MCP endpoints, `sse` for servers on the older HTTP+SSE transport and `websocket` for WebSocket endpoints.
                 </p>
               </div>
 
@@ -217,7 +221,7 @@ export function DownstreamDialog({
               </p>
             </div>
 
-            <div className="grid gap-4 md:grid-cols-3">
+            <div className="grid gap-4 md:grid-cols-2">
               <div className="space-y-2">
                 <Label className="text-xs text-muted-foreground">Idle Timeout (sec)</Label>
                 <Input
@@ -246,6 +250,22 @@ export function DownstreamDialog({
                   }
                 />
               </div>
+              <div className="space-y-2">
+                <Label className="text-xs text-muted-foreground">
+                  Max Concurrent Requests (0 = default)
+                </Label>
+                <Input
+                  type="number"
+                  min={0}
+                  value={form.max_concurrency}
+                  onChange={(e) =>
+                    setForm((current) => ({
+                      ...current,
+                      max_concurrency: Number(e.target.value),
+                    }))
+                  }
+                />
+              </div>
               <div className="space-y-2">
                 <Label className="text-xs text-muted-foreground">Restart Policy</Label>
                 <Select
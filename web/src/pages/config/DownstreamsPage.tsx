@@ -103,6 +103,7 @@ export function DownstreamsPage() {
       tool_namespace: `${ds.tool_namespace}_copy`,
       idle_timeout_sec: ds.idle_timeout_sec,
       max_instances: ds.max_instances,
+      max_concurrency: ds.max_concurrency,
       restart_policy: ds.restart_policy,
       disabled: false,
       cache_config: ds.cache_config ? { ...ds.cache_config } : undefined,
@@ -122,6 +123,7 @@ export function DownstreamsPage() {
       tool_namespace: ds.tool_namespace,
       idle_timeout_sec: ds.idle_timeout_sec,
       max_instances: ds.max_instances,
+      max_concurrency: ds.max_concurrency,
       restart_policy: ds.restart_policy,
       disabled: ds.disabled,
       cache_config: ds.cache_config ? { ...ds.cache_config } : undefined,
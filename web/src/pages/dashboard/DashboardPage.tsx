@@ -47,7 +47,7 @@ export function DashboardPage() {
   const recentErrors = useMemo(() => {
     const dbErrors = data?.recent_errors ?? []
     const seen = new Set(liveRecords.map((r) => r.id))
-    const liveErrors = liveRecords.filter((r) => r.status === 'error' || r.status === 'blocked')
+    const liveErrors = liveRecords.filter((r) => r.status === 'error' || r.status === 'blocked' || r.status === 'rate_limited')
     const merged = [...liveErrors, ...dbErrors.filter((r) => !seen.has(r.id))]
     merged.sort((a, b) => new Date(b.timestamp).getTime() - new Date(a.timestamp).getTime())
     return merged.slice(0, 20)
@@ -81,7 +81,7 @@ export function DashboardPage() {
   const latencyData = prepareChartData(ts, (p) => Math.round(p.avg_latency_ms))
 
   const activeServers = (data.active_downstreams ?? []).filter(
-    (d) => d.state !== 'stopped',
+    (d) => !['stopped', 'degraded', 'crashed'].includes(d.state),
   ).length
   const totalServers = (data.active_downstreams ?? []).length
 
@@ -13,7 +13,7 @@ import { ReasonBadge } from '@/components/AuditDetailDialog'
 import { formatTime } from './chart-components'
 
 function isBlocked(record: AuditRecord): boolean {
-  return record.status === 'blocked'
+  return record.status === 'blocked' || record.status === 'rate_limited'
 }
 
 export function RecentCallsTable({
@@ -106,8 +106,8 @@ export function RecentCallsTable({
                   </TableCell>
                   <TableCell>
                     <Badge
-                      variant={call.status === 'success' ? 'secondary' : call.status === 'blocked' ? 'outline' : 'destructive'}
-                      className={call.status === 'blocked' ? 'border-amber-500/40 text-amber-500' : ''}
+                      variant={call.status === 'success' ? 'secondary' : isBlocked(call) ? 'outline' : 'destructive'}
+                      className={isBlocked(call) ? 'border-amber-500/40 text-amber-500' : ''}
                     >
                       {call.status}
                     </Badge>
@@ -191,7 +191,7 @@ export function RecentErrorsTable({
                         : 'border-destructive/40 text-destructive'
                       }
                     >
-                      {blocked ? 'blocked' : 'error'}
+                      {err.status === 'rate_limited' ? 'rate limited' : blocked ? 'blocked' : 'error'}
                     </Badge>
                   </TableCell>
                   <TableCell>
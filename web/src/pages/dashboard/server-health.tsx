@@ -1,7 +1,7 @@
 import { useState } from 'react'
 import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
 import { Badge } from '@/components/ui/badge'
-import type { ServerHealthEntry } from '@/api/types'
+import type { DownstreamStatus, ServerHealthEntry } from '@/api/types'
 
 function errorRateColor(rate: number): string {
   if (rate < 5) return 'text-chart-2'
@@ -10,7 +10,7 @@ function errorRateColor(rate: number): string {
 }
 
 function healthBorderColor(entry: ServerHealthEntry, state: string): string {
-  if (state === 'stopped') return 'border-destructive/50'
+  if (state === 'stopped' || state === 'degraded' || state === 'crashed') return 'border-destructive/50'
   if (state === 'external') return 'border-border/50'
   if (entry.error_rate > 25) return 'border-destructive/50'
   if (entry.error_rate > 10) return 'border-amber-500/50'
@@ -22,7 +22,7 @@ export function ServerHealthCards({
   downstreams,
 }: {
   entries: ServerHealthEntry[]
-  downstreams: { server_id: string; state: string; disabled?: boolean }[]
+  downstreams: DownstreamStatus[]
 }) {
   const [showIdle, setShowIdle] = useState(false)
   const [showDisabled, setShowDisabled] = useState(false)
@@ -58,7 +58,7 @@ export function ServerHealthCards({
         <div className="flex items-center justify-between">
           <span className="text-sm font-medium truncate max-w-[10rem]">{serverName}</span>
           <Badge variant={
-            state === 'stopped' ? 'destructive'
+            state === 'stopped' || state === 'degraded' || state === 'crashed' ? 'destructive'
             : state === 'disabled' ? 'outline'
             : state === 'external' ? 'outline'
             : 'secondary'
@@ -66,6 +66,38 @@ export function ServerHealthCards({
             {state}
           </Badge>
         </div>
+        {!isDisabled && (ds?.instances?.length ?? 0) > 1 && (
+          <div className="flex flex-wrap gap-1">
+            {ds!.instances!.map((inst) => (
+              <span
+                key={`${inst.auth_scope_id ?? ''}-${inst.index}`}
+                className="rounded border border-border/50 px-1.5 py-0.5 font-mono text-[10px] text-muted-foreground"
+                title={inst.auth_scope_id ? `auth scope ${inst.auth_scope_id}` : undefined}
+              >
+                #{inst.index + 1} {inst.state}
+                {inst.in_flight > 0 && ` \u00B7 ${inst.in_flight}`}
+              </span>
+            ))}
+          </div>
+        )}
+        {!isDisabled && (ds?.failures ?? 0) > 0 && (
+          <div className="space-y-1 text-xs">
+            <div className="text-destructive">
+              {ds!.failures} recent failure{ds!.failures !== 1 ? 's' : ''}
+              {ds!.retry_at && ` \u00B7 retry at ${new Date(ds!.retry_at).toLocaleTimeString()}`}
+            </div>
+            {ds!.last_error && (
+              <div className="truncate font-mono text-muted-foreground" title={ds!.last_error}>
+                {ds!.last_error}
+              </div>
+            )}
+            {ds!.stderr_tail && (
+              <pre className="max-h-24 overflow-auto whitespace-pre-wrap rounded bg-muted/50 p-1.5 font-mono text-[10px] text-muted-foreground">
+                {ds!.stderr_tail}
+              </pre>
+            )}
+          </div>
+        )}
         {!isDisabled && health && health.call_count > 0 ? (
           <div className="grid grid-cols-3 gap-2 text-xs">
             <div>
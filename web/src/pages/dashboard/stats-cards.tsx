@@ -20,7 +20,7 @@ export function ErrorBreakdownCard({ entries }: { entries: ErrorBreakdownEntry[]
       </CardHeader>
       <CardContent className="space-y-2">
         {entries.map((e) => {
-          const blocked = e.error_type === 'blocked'
+          const blocked = e.error_type !== 'error'
           return (
             <div key={`${e.group_key}-${e.error_type}`} className="space-y-1">
               <div className="flex items-center justify-between text-sm">
@@ -34,7 +34,7 @@ export function ErrorBreakdownCard({ entries }: { entries: ErrorBreakdownEntry[]
                         : 'border-destructive/40 text-destructive'
                     }`}
                   >
-                    {blocked ? 'blocked' : 'error'}
+                    {e.error_type === 'rate_limited' ? 'rate limited' : blocked ? 'blocked' : 'error'}
                   </Badge>
                 </div>
                 <span className={`font-mono ${blocked ? 'text-amber-500' : 'text-destructive'}`}>